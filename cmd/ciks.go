@@ -0,0 +1,17 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// ciksCmd represents the ciks command
+var ciksCmd = &cobra.Command{
+	Use:   "ciks",
+	Short: "Manage the CIKs known from the registries and the filings",
+	Long:  `Manage the CIKs known from the CIK lookup file, the company tickers files and the filings`,
+}
+
+func init() {
+	rootCmd.AddCommand(ciksCmd)
+}
@@ -0,0 +1,89 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/seccik"
+	"github.com/spf13/cobra"
+)
+
+var GlobalCIKsLookupFile string
+var GlobalCIKsList string
+
+// ciksReconcileCmd represents the ciks reconcile command
+var ciksReconcileCmd = &cobra.Command{
+	Use:   "reconcile",
+	Short: "Reconcile the CIKs of the registries and the filings",
+	Long: `Reconcile the CIKs of the CIK lookup file (cik-lookup-data.txt), the company
+tickers files and the indexed filings into sec.entities, one row per CIK with
+its name, the dates it was first and last seen and whether it files XBRL.
+Every name of a CIK is kept in sec.entity_names with its source.
+
+CIKs with filings but in no registry are flagged "unregistered", CIKs in a
+registry without filings "unfiled". Use --list to print either.
+
+The CIK lookup file is read from Archives/edgar/cik-lookup-data.txt in the
+cache, download it from https://www.sec.gov/Archives/edgar/cik-lookup-data.txt.
+Without it, the tickers files are the only registry.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if GlobalCIKsList != "" && GlobalCIKsList != seccik.FlagUnregistered && GlobalCIKsList != seccik.FlagUnfiled {
+			return fmt.Errorf("invalid --list %v, expected %v or %v", GlobalCIKsList, seccik.FlagUnregistered, seccik.FlagUnfiled)
+		}
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		lookupFile := GlobalCIKsLookupFile
+		if lookupFile == "" {
+			lookupFile = filepath.Join(S.Config.Main.CacheDir, "Archives", "edgar", "cik-lookup-data.txt")
+			if _, err := os.Stat(lookupFile); err != nil {
+				S.Log(fmt.Sprintf("%v not found, reconciling without the CIK lookup file", lookupFile))
+				lookupFile = ""
+			}
+		}
+
+		summary, err := seccik.ReconcileCIKs(DB, lookupFile)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("%d names of %d CIKs, %d filing XBRL\n", summary.Names, summary.Entities, summary.FilesXBRL)
+		fmt.Printf("%d with filings but in no registry (%v)\n", summary.Unregistered, seccik.FlagUnregistered)
+		fmt.Printf("%d in a registry without filings (%v)\n", summary.Unfiled, seccik.FlagUnfiled)
+
+		if GlobalCIKsList == "" {
+			return nil
+		}
+
+		entities, err := seccik.GetFlaggedEntities(DB, GlobalCIKsList)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "\nCIK\tNAME\tFIRST SEEN\tLAST SEEN\tXBRL")
+		for _, e := range entities {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", e.CIK, e.Name, formatSeen(e.FirstSeen.Time, e.FirstSeen.Valid), formatSeen(e.LastSeen.Time, e.LastSeen.Valid), e.FilesXBRL)
+		}
+		return w.Flush()
+	},
+}
+
+func formatSeen(t time.Time, valid bool) string {
+	if !valid {
+		return "-"
+	}
+	return t.Format("2006-01-02")
+}
+
+func init() {
+	ciksCmd.AddCommand(ciksReconcileCmd)
+
+	ciksReconcileCmd.Flags().StringVar(&GlobalCIKsLookupFile, "lookup", "", "Path of cik-lookup-data.txt, instead of the one in the cache")
+	ciksReconcileCmd.Flags().StringVar(&GlobalCIKsList, "list", "", "List the CIKs flagged unregistered or unfiled")
+}
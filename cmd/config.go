@@ -6,12 +6,10 @@ import (
 	"fmt"
 	"os"
 	"os/user"
-	"path/filepath"
 	"strings"
 
 	"github.com/equres/sec/pkg/config"
 	"github.com/spf13/cobra"
-	"github.com/spf13/viper"
 )
 
 // configCmd represents the config command
@@ -24,170 +22,59 @@ var configCmd = &cobra.Command{
 	},
 }
 
-func GenerateConfig() error {
-	reader := bufio.NewReader(os.Stdin)
-
-	user, err := user.Current()
-	if err != nil {
-		return err
-	}
-
-	url := "https://www.sec.gov"
-	fmt.Printf("URL [default: '%v']: ", url)
-	err = AcceptInput(reader, &url)
-	if err != nil {
-		return err
-	}
-
-	websiteURL := "https://equres.com/"
-	fmt.Printf("Website URL [default: '%v']: ", websiteURL)
-	err = AcceptInput(reader, &websiteURL)
-	if err != nil {
-		return err
-	}
-
-	port := ":8000"
-	fmt.Printf("Server Port [default: '%v']: ", port)
-	err = AcceptInput(reader, &port)
-	if err != nil {
-		return err
-	}
-
-	rateLimit := "100"
-	fmt.Printf("Rate Limit [default: '%v' Milliseconds]: ", rateLimit)
-	err = AcceptInput(reader, &rateLimit)
-	if err != nil {
-		return err
-	}
-
-	retrylimit := "3"
-	fmt.Printf("Rate Limit [default: '%v']: ", retrylimit)
-	err = AcceptInput(reader, &retrylimit)
-	if err != nil {
-		return err
-	}
-
-	dbUser := user.Username
-	fmt.Println("Database Config:")
-	fmt.Printf("User [default: '%v']: ", dbUser)
-	err = AcceptInput(reader, &dbUser)
-	if err != nil {
-		return err
-	}
-
-	dbPassword := ""
-	fmt.Printf("Password [default: '%v']: ", dbPassword)
-	err = AcceptInput(reader, &dbPassword)
-	if err != nil {
-		return err
-	}
-
-	host := "localhost"
-	fmt.Printf("Host [default: '%v']: ", host)
-	err = AcceptInput(reader, &host)
-	if err != nil {
-		return err
-	}
-
-	dbName := user.Username
-	fmt.Printf("DB Name [default: '%v']: ", dbName)
-	err = AcceptInput(reader, &dbName)
-	if err != nil {
-		return err
-	}
-
-	fmt.Println("Index Mode Config:")
-	financialStatementDataSets := "enabled"
-	fmt.Printf("Financial Statement Data Sets [default: '%v'] (enabled/disabled): ", financialStatementDataSets)
-	err = AcceptInput(reader, &financialStatementDataSets)
-	if err != nil {
-		return err
-	}
-
-	companyfacts := "enabled"
-	fmt.Printf("Company Facts [default: '%v'] (enabled/disabled): ", companyfacts)
-	err = AcceptInput(reader, &companyfacts)
-	if err != nil {
-		return err
-	}
+// configPrompt asks for the value of a config key in GenerateConfig
+type configPrompt struct {
+	Section string
+	Title   string
+	Key     string
+	Hint    string
+}
 
-	fmt.Println("Redis Config:")
-	redisHost := "localhost"
-	fmt.Printf("Host [default: '%v']: ", redisHost)
-	err = AcceptInput(reader, &redisHost)
-	if err != nil {
-		return err
-	}
+var configPrompts = []configPrompt{
+	{"", "URL", "main.baseurl", ""},
+	{"", "Website URL", "main.websiteurl", ""},
+	{"", "Server Port", "main.serverport", ""},
+	{"", "Rate Limit", "main.ratelimitms", " Milliseconds"},
+	{"", "Retry Limit", "main.retrylimit", ""},
+	{"Database Config:", "User", "database.user", ""},
+	{"", "Password", "database.password", ""},
+	{"", "Host", "database.host", ""},
+	{"", "DB Name", "database.name", ""},
+	{"Index Mode Config:", "Financial Statement Data Sets", "indexmode.financialstatementdatasets", " (enabled/disabled)"},
+	{"", "Company Facts", "indexmode.companyfacts", " (enabled/disabled)"},
+	{"Cache Config:", "Backend", "cache.backend", " (redis/memory)"},
+	{"Redis Config:", "Host", "redis.host", ""},
+	{"", "Port", "redis.port", ""},
+	{"", "Password", "redis.password", ""},
+}
 
-	redisPort := "6379"
-	fmt.Printf("Port [default: '%v']: ", redisPort)
-	err = AcceptInput(reader, &redisPort)
-	if err != nil {
-		return err
-	}
+func GenerateConfig() error {
+	reader := bufio.NewReader(os.Stdin)
 
-	redisPassword := ""
-	fmt.Printf("Password [default: '%v']: ", redisPassword)
-	err = AcceptInput(reader, &redisPassword)
+	defaults, err := configDefaults()
 	if err != nil {
 		return err
 	}
 
-	cfg := viper.New()
-
-	if _, err = os.Stat(cfgFile); err != nil {
-		err = os.MkdirAll(cfgFile, 0755)
-		if err != nil {
-			return err
+	values := make(map[string]string)
+	for _, prompt := range configPrompts {
+		if prompt.Section != "" {
+			fmt.Println(prompt.Section)
 		}
-	}
 
-	if _, err = os.Stat(filepath.Join(cfgFile, "config.yaml")); err != nil {
-		_, err = os.Create(filepath.Join(cfgFile, "config.yaml"))
+		value := ""
+		if def, ok := defaults[prompt.Key]; ok {
+			value = fmt.Sprint(def)
+		}
+		fmt.Printf("%v [default: '%v'%v]: ", prompt.Title, value, prompt.Hint)
+		err = AcceptInput(reader, &value)
 		if err != nil {
 			return err
 		}
+		values[prompt.Key] = value
 	}
 
-	cfg.AddConfigPath(cfgFile)
-	cfg.SetConfigType("yaml")
-	cfg.SetConfigName("config")
-
-	cfg.SetDefault("main", config.MainConfig{
-		BaseURL:          url,
-		WebsiteURL:       websiteURL,
-		CacheDir:         "./cache",
-		RateLimitMs:      rateLimit,
-		RetryLimit:       retrylimit,
-		CacheDirUnpacked: "./unzipped_cache",
-		ServerPort:       port,
-	})
-
-	cfg.SetDefault("indexmode", config.IndexModeConfig{
-		FinancialStatementDataSets: financialStatementDataSets,
-		CompanyFacts:               companyfacts,
-	})
-
-	cfg.SetDefault("database", config.DatabaseConfig{
-		Driver:   "postgres",
-		Host:     host,
-		Port:     5432,
-		Name:     dbName,
-		Password: dbPassword,
-		User:     dbUser,
-	})
-
-	cfg.SetDefault("redis", config.RedisConfig{
-		Host:     redisHost,
-		Port:     redisPort,
-		Password: redisPassword,
-	})
-
-	err = cfg.WriteConfig()
-	if err != nil {
-		return err
-	}
-	err = cfg.ReadInConfig()
+	_, err = config.Init(cfgFile, "", values, defaults)
 	if err != nil {
 		return err
 	}
@@ -197,6 +84,16 @@ func GenerateConfig() error {
 	return nil
 }
 
+// configDefaults are the config values used for the keys not given to
+// "sec config" and "sec config init"
+func configDefaults() (map[string]interface{}, error) {
+	user, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+	return config.Defaults(user.Username), nil
+}
+
 func AcceptInput(reader *bufio.Reader, data *string) error {
 	input, err := reader.ReadString('\n')
 	if err != nil {
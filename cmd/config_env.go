@@ -0,0 +1,35 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configEnvCmd represents the config env command
+var configEnvCmd = &cobra.Command{
+	Use:   "env",
+	Short: "List the SEC_* environment variables overriding the config",
+	Long: `List the environment variables overriding each key of the config file, e.g.
+SEC_DATABASE_PASSWORD for database.password and SEC_REDIS_PASSWORD for
+redis.password. Lists are comma separated and durations written like "15s".
+Values aren't shown, only whether the variable is set.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VARIABLE\tKEY\tTYPE\tSET")
+		for _, env := range config.EnvVars() {
+			_, set := os.LookupEnv(env.Name)
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", env.Name, env.Key, env.Type, set)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configEnvCmd)
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configInitCmd represents the config init command
+var configInitCmd = &cobra.Command{
+	Use:   "init",
+	Short: "Write the config file from flags and SEC_* environment variables",
+	Long: `Write the config file without asking questions, for provisioning. Every key is
+set with a flag named after it, e.g. --database.host, or else with its SEC_*
+environment variable, e.g. SEC_DATABASE_HOST (see "sec config env"). Keys
+that aren't set keep their value in the file, or get the default of
+"sec config".
+
+With --profile the keys given are written to that profile instead, e.g.
+
+    sec config init --profile ci --database.host postgres
+
+writes profiles.ci.database.host, used by commands run with --profile ci or
+SEC_PROFILE=ci. The config is validated before it's written, and the file is
+replaced atomically.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		defaults, err := configDefaults()
+		if err != nil {
+			return err
+		}
+
+		values := make(map[string]string)
+		for _, env := range config.EnvVars() {
+			if cmd.Flags().Changed(env.Key) {
+				values[env.Key], _ = cmd.Flags().GetString(env.Key)
+			} else if value, ok := os.LookupEnv(env.Name); ok {
+				values[env.Key] = value
+			}
+		}
+
+		cmd.SilenceUsage = true
+		_, err = config.Init(cfgFile, Profile, values, defaults)
+		if err != nil {
+			return fmt.Errorf("config not written: %v", err)
+		}
+
+		file := filepath.Join(cfgFile, "config.yaml")
+		if Profile != "" {
+			fmt.Printf("Wrote profile %v to %v\n", Profile, file)
+			return nil
+		}
+		fmt.Printf("Wrote %v\n", file)
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configInitCmd)
+
+	for _, env := range config.EnvVars() {
+		configInitCmd.Flags().String(env.Key, "", fmt.Sprintf("%v (%v, or $%v)", env.Key, env.Type, env.Name))
+	}
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/spf13/cobra"
+)
+
+// configShowCmd represents the config show command
+var configShowCmd = &cobra.Command{
+	Use:   "show",
+	Short: "Print the effective config with the passwords redacted",
+	Long: `Print the config the other commands run with: the config file, overridden by
+the profile selected with --profile or SEC_PROFILE, overridden by the SEC_*
+environment variables. Passwords are redacted.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		cmd.SilenceUsage = true
+		if _, ok := ConfigErr.(config.ValidationErrors); ConfigErr != nil && !ok {
+			return ConfigErr
+		}
+
+		data, err := RootConfig.Redact().YAML()
+		if err != nil {
+			return err
+		}
+		fmt.Print(string(data))
+
+		if ConfigErr != nil {
+			fmt.Fprintf(os.Stderr, "The config is invalid:\n%v\n", ConfigErr)
+		}
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configShowCmd)
+}
@@ -0,0 +1,31 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// configValidateCmd represents the config validate command
+var configValidateCmd = &cobra.Command{
+	Use:   "validate",
+	Short: "Check the config file and the SEC_* environment variables",
+	Long: `Check the config file, with the SEC_* environment variables overriding it, and
+list every invalid key. Other commands refuse to run with an invalid config.`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if ConfigErr != nil {
+			cmd.SilenceUsage = true
+			return fmt.Errorf("invalid config %v:\n%v", viper.ConfigFileUsed(), ConfigErr)
+		}
+
+		fmt.Printf("Config %v is valid\n", viper.ConfigFileUsed())
+		return nil
+	},
+}
+
+func init() {
+	configCmd.AddCommand(configValidateCmd)
+}
@@ -0,0 +1,33 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secmetrics"
+	"github.com/spf13/cobra"
+)
+
+var GlobalDeriveFull bool
+
+// deriveCmd represents the derive command
+var deriveCmd = &cobra.Command{
+	Use:   "derive",
+	Short: "Compute annual and trailing-twelve-month metrics from the financial statement data sets",
+	Long: `Compute annual and trailing-twelve-month metrics (revenues, margins, ROE, current ratio,
+debt/equity, EPS...) per CIK and period from fsds.num into fsds.metrics.
+
+Only companies with submissions indexed after their last derivation are recomputed,
+unless --full is given.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return secmetrics.Derive(S, DB, GlobalDeriveFull)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(deriveCmd)
+
+	deriveCmd.Flags().BoolVarP(&GlobalDeriveFull, "full", "f", false, "Recompute metrics for every company")
+}
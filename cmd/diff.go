@@ -0,0 +1,124 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/dustin/go-humanize"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secdiff"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/spf13/cobra"
+)
+
+var GlobalDiffSections []string
+
+// diffCmd represents the diff command
+var diffCmd = &cobra.Command{
+	Use:   "diff <accession-a> [accession-b]",
+	Short: "Show what changed between two filings",
+	Long: `Show what changed between two filings of a company, usually a 10-K and the prior
+one: the text of sections such as Item 1A (Risk Factors) and Item 7 (MD&A), and
+the values of the financial statement line items. The same as the /diff page.
+
+With a single accession number, the filing is compared with the previous filing
+of the same form (e.g. sec diff 0000320193-21-000105). Sections are selected
+with --section by name (risk_factors, mdna, market_risk, ...) or item (1A, 7,
+II-1A for a 10-Q), as found by sec sections.`,
+	Args: cobra.RangeArgs(1, 2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		for _, accession := range args {
+			if !secfiling.IsAccessionNumber(accession) {
+				return fmt.Errorf("invalid accession number %v, expected e.g. 0000320193-21-000105", accession)
+			}
+		}
+
+		var accessionA, accessionB string
+		if len(args) == 2 {
+			accessionA, accessionB = args[0], args[1]
+		} else {
+			accessionB = args[0]
+
+			filing, err := secfiling.GetFiling(DB, accessionB)
+			if err != nil {
+				return err
+			}
+
+			accessionA, err = secdiff.PreviousFiling(DB, filing)
+			if err != nil {
+				return err
+			}
+			if accessionA == "" {
+				return fmt.Errorf("no %v filed before %v", filing.FormType, accessionB)
+			}
+		}
+
+		result, err := secdiff.Compare(DB, accessionA, accessionB, GlobalDiffSections)
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("--- %v %v %v filed %v\n", result.A.Accession, result.A.CompanyName, result.A.FormType, result.A.FilingDate.Format("2006-01-02"))
+		fmt.Printf("+++ %v %v %v filed %v\n", result.B.Accession, result.B.CompanyName, result.B.FormType, result.B.FilingDate.Format("2006-01-02"))
+
+		for _, section := range result.Sections {
+			fmt.Printf("\n=== %v\n", section.Label)
+			if section.Note != "" {
+				fmt.Println(section.Note)
+			}
+			for _, edit := range section.Edits {
+				switch edit.Op {
+				case secdiff.OpSkip:
+					fmt.Printf("@@ %v @@\n", edit.Text)
+				case secdiff.OpEqual:
+					fmt.Printf("  %v\n", edit.Text)
+				default:
+					fmt.Printf("%v %v\n", edit.Op, edit.Text)
+				}
+			}
+		}
+
+		formatValue := func(v float64, ok bool) string {
+			if !ok {
+				return "-"
+			}
+			return humanize.Commaf(v)
+		}
+
+		for _, statement := range result.Statements() {
+			fmt.Printf("\n=== %v\n", statement.Name)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+			fmt.Fprintln(w, "LINE ITEM\tUNIT\tA\tB\tCHANGE\t%\t")
+			for _, delta := range statement.LineItems {
+				change := "-"
+				if delta.Change.Valid {
+					change = fmt.Sprintf("%.2f%%", delta.Change.Float64*100)
+				}
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\t%v\t\n", strings.TrimSpace(delta.Label), delta.UOM,
+					formatValue(delta.A.Float64, delta.A.Valid),
+					formatValue(delta.B.Float64, delta.B.Valid),
+					formatValue(delta.Delta.Float64, delta.Delta.Valid),
+					change)
+			}
+			err = w.Flush()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+
+	diffCmd.Flags().StringSliceVarP(&GlobalDiffSections, "section", "s", nil, "Sections to compare, by name or item, e.g. risk_factors,7A (default: risk_factors,mdna)")
+}
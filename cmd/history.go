@@ -0,0 +1,99 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/seccik"
+	"github.com/spf13/cobra"
+)
+
+var GlobalHistoryDate string
+
+// historyCmd represents the history command
+var historyCmd = &cobra.Command{
+	Use:   "history <CIK or ticker>",
+	Short: "Show the tickers and names of a company over time",
+	Long: `Show the tickers a company traded under and the names it filed under over time.
+Former tickers resolve to the company that used them, e.g. "sec history FB".
+
+With --date, only the ticker and the name on that date are shown, and a ticker
+resolves to the company trading under it on that date.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		var date time.Time
+		if GlobalHistoryDate != "" {
+			var err error
+			date, err = time.Parse("2006-01-02", GlobalHistoryDate)
+			if err != nil {
+				return fmt.Errorf("invalid date %v, expected e.g. 2012-05-18", GlobalHistoryDate)
+			}
+		}
+
+		cik, err := strconv.Atoi(args[0])
+		if err != nil {
+			cik, err = seccik.GetCIKFromTicker(DB, args[0], date)
+			if err != nil {
+				return err
+			}
+			if cik == 0 {
+				return fmt.Errorf("no company traded under %v", args[0])
+			}
+		}
+
+		if !date.IsZero() {
+			ticker, err := seccik.GetCompanyTickerOn(DB, cik, date)
+			if err != nil {
+				return err
+			}
+			name, err := seccik.GetCompanyNameOn(DB, cik, date)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("CIK %v on %v: %v %v\n", cik, date.Format("2006-01-02"), name, ticker)
+			return nil
+		}
+
+		tickers, err := seccik.GetTickerHistory(DB, cik)
+		if err != nil {
+			return err
+		}
+		names, err := seccik.GetNameHistory(DB, cik)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "CIK %v\n\n", cik)
+		fmt.Fprintln(w, "FROM\tTO\tNAME")
+		for _, name := range names {
+			fmt.Fprintf(w, "%v\t%v\t%v\n", name.ValidFrom.Format("2006-01-02"), historyEnd(name.ValidTo.Time, name.Current()), name.Name)
+		}
+		fmt.Fprintln(w, "\nFROM\tTO\tTICKER\tEXCHANGE")
+		for _, ticker := range tickers {
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\n", ticker.ValidFrom.Format("2006-01-02"), historyEnd(ticker.ValidTo.Time, ticker.Current()), ticker.Ticker, ticker.Exchange)
+		}
+		return w.Flush()
+	},
+}
+
+func historyEnd(validTo time.Time, current bool) string {
+	if current {
+		return "now"
+	}
+	return validTo.Format("2006-01-02")
+}
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+
+	historyCmd.Flags().StringVarP(&GlobalHistoryDate, "date", "d", "", "Show the ticker and the name on this date (YYYY-MM-DD)")
+}
@@ -13,6 +13,7 @@ import (
 	"github.com/equres/sec/pkg/sec"
 	"github.com/equres/sec/pkg/secdata"
 	"github.com/equres/sec/pkg/secindex"
+	"github.com/equres/sec/pkg/secmetrics"
 	"github.com/equres/sec/pkg/secticker"
 	"github.com/equres/sec/pkg/secutil"
 	"github.com/equres/sec/pkg/secworklist"
@@ -70,6 +71,12 @@ var indexCmd = &cobra.Command{
 				return err
 			}
 
+			S.Log("Deriving metrics from Financial Statement Data Sets...")
+			err = secmetrics.Derive(S, DB, false)
+			if err != nil {
+				return err
+			}
+
 			S.Log("Indexing Mutual Fund Data...")
 			secData = secdata.NewSECData(secdata.NewSECDataOpsMFD())
 			err = secData.IndexData(S, DB)
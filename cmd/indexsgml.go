@@ -0,0 +1,37 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"path/filepath"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secindex"
+	"github.com/spf13/cobra"
+)
+
+// indexsgmlCmd represents the indexsgml command
+var indexsgmlCmd = &cobra.Command{
+	Use:   "indexsgml [file or directory...]",
+	Short: "Index full submission .txt files into the DB",
+	Long: `Index full submission .txt files, the SGML files EDGAR keeps with every filing,
+into sec.secItemFile. Older filings, and any filed without XBRL, are not in the
+monthly RSS feeds and exist only in this form.
+
+Each document of a submission is decoded, unpacked into the unpacked cache next
+to the documents of ZIP files, and stored as a row of its own. Without
+arguments, the submissions under Archives/edgar/data in the cache are indexed.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		paths := args
+		if len(paths) == 0 {
+			paths = []string{filepath.Join(S.Config.Main.CacheDir, "Archives", "edgar", "data")}
+		}
+		return secindex.IndexSubmissions(DB, S, paths)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(indexsgmlCmd)
+}
@@ -3,8 +3,11 @@ package cmd
 
 import (
 	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sec"
 	"github.com/equres/sec/pkg/secindex"
 	"github.com/equres/sec/pkg/secutil"
+	"github.com/equres/sec/pkg/secworklist"
+	"github.com/jmoiron/sqlx"
 	"github.com/spf13/cobra"
 )
 
@@ -17,7 +20,15 @@ var indexzCmd = &cobra.Command{
 		return database.CheckMigration(RootConfig)
 	},
 	RunE: func(cmd *cobra.Command, args []string) error {
-		return secutil.ForEachWorklist(S, DB, secindex.IndexZIPFileContent, "")
+		invalidation, err := secindex.NewCacheInvalidation(DB)
+		if err != nil {
+			return err
+		}
+		defer invalidation.Flush(DB, S)
+
+		return secutil.ForEachWorklist(S, DB, func(db *sqlx.DB, s *sec.SEC, rssFile sec.RSSFile, worklist []secworklist.Worklist) error {
+			return secindex.IndexZIPFileContent(db, s, rssFile, worklist, invalidation)
+		}, "")
 	},
 }
 
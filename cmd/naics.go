@@ -0,0 +1,54 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secsic"
+	"github.com/spf13/cobra"
+)
+
+// naicsCmd represents the naics command
+var naicsCmd = &cobra.Command{
+	Use:   "naics [crosswalk.csv]",
+	Short: "Load the SIC to NAICS crosswalk into the DB",
+	Long: `Load the SIC to NAICS crosswalk shown on the SIC pages into sec.sic_naics,
+replacing the one loaded before.
+
+Without arguments, the bundled crosswalk of all SICs EDGAR assigns is loaded.
+Another crosswalk can be given as a CSV file with a header and sic, naics and
+title columns.`,
+	Args: cobra.MaximumNArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			count, err := secsic.IndexBundledNAICS(DB)
+			if err != nil {
+				return err
+			}
+			S.Log(fmt.Sprintf("Loaded %d SIC to NAICS rows", count))
+			return nil
+		}
+
+		file, err := os.Open(args[0])
+		if err != nil {
+			return err
+		}
+		defer file.Close()
+
+		count, err := secsic.IndexNAICS(DB, file)
+		if err != nil {
+			return fmt.Errorf("%v: %v", args[0], err)
+		}
+		S.Log(fmt.Sprintf("Loaded %d SIC to NAICS rows from %v", count, args[0]))
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(naicsCmd)
+}
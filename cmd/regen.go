@@ -5,12 +5,16 @@ import (
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 
-	"github.com/equres/sec/pkg/sec"
 	"github.com/equres/sec/pkg/seccache"
+	"github.com/equres/sec/pkg/secsearch"
 	"github.com/equres/sec/pkg/secsic"
-	"github.com/equres/sec/pkg/server"
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/equres/sec/pkg/secworklist"
 	"github.com/jmoiron/sqlx"
 	log "github.com/sirupsen/logrus"
 	"github.com/snabb/sitemap"
@@ -18,11 +22,16 @@ import (
 	"github.com/spf13/cobra"
 )
 
+var GlobalRegenFull bool
+
 // regenCmd represents the regen command
 var regenCmd = &cobra.Command{
 	Use:   "regen",
 	Short: "Generate a new sitemap for the website",
-	Long:  `Generate a new sitemap for the website`,
+	Long: `Generate a new sitemap for the website, the stats or the data for the web pages.
+
+By default only the pages and sitemaps of filings indexed since the last
+regen are rebuilt. Use --full to rebuild everything.`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		if len(args) == 0 {
 			log.Info("please type 'sitemap' to generate the sitemap,'stats' to generate the stats, and 'pages' to generate the data for the web pages (e.g. sec regen sitemap)")
@@ -33,7 +42,7 @@ var regenCmd = &cobra.Command{
 		switch args[0] {
 		case "sitemap":
 			S.Log("Generating a sitemap.xml file...")
-			err := GenerateSitemap(sc)
+			err := GenerateSitemap(sc, GlobalRegenFull)
 			if err != nil {
 				return err
 			}
@@ -84,38 +93,18 @@ var regenCmd = &cobra.Command{
 				return err
 			}
 		case "pages":
-			S.Log("Generating & caching pages in redis...")
-			err := sc.GenerateHomePageDataCache()
-			if err != nil {
-				return err
-			}
-
-			S.Log("Generating & caching Months in Year page in redis...")
-			err = sc.GenerateMonthDayCIKDataCache()
-			if err != nil {
-				return err
-			}
-
-			S.Log("Generating & caching Companies page in redis...")
-			err = sc.GenerateCompanySlugsDataCache()
-			if err != nil {
-				return err
+			var err error
+			if GlobalRegenFull {
+				err = GeneratePagesFull(sc)
+			} else {
+				err = GeneratePagesIncremental(sc)
 			}
-
-			S.Log("Generating & caching SIC page in redis...")
-			err = sc.GenerateSICPageDataCache()
-			if err != nil {
-				return err
-			}
-
-			S.Log("Generating & caching Download Stats page in redis...")
-			err = sc.GenerateHourlyDownloadStatsPageDataCache()
 			if err != nil {
 				return err
 			}
 
-			S.Log("Generating & caching Companies Data in redis...")
-			err = sc.GenerateCompaniesDataCache()
+			// Rendered pages are rebuilt from the new data on the next request
+			err = S.Cache.DeletePages()
 			if err != nil {
 				return err
 			}
@@ -131,27 +120,135 @@ var regenCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(regenCmd)
 
-	// Here you will define your flags and configuration settings.
+	regenCmd.Flags().BoolVarP(&GlobalRegenFull, "full", "f", false, "Regenerate all pages and sitemaps, not only the ones of changed filings")
+}
+
+// GeneratePagesFull rebuilds the data of every page.
+func GeneratePagesFull(sc *seccache.SECCache) error {
+	mark, err := seccache.CurrentRegenMark(DB, seccache.RegenPages)
+	if err != nil {
+		return err
+	}
+
+	S.Log("Generating & caching pages in redis...")
+	err = sc.GenerateHomePageDataCache()
+	if err != nil {
+		return err
+	}
+
+	S.Log("Generating & caching Months in Year page in redis...")
+	err = sc.GenerateMonthDayCIKDataCache()
+	if err != nil {
+		return err
+	}
+
+	S.Log("Syncing company slugs...")
+	err = secslug.Sync(DB)
+	if err != nil {
+		return err
+	}
+
+	S.Log("Generating & caching Companies page in redis...")
+	err = sc.GenerateCompanySlugsDataCache()
+	if err != nil {
+		return err
+	}
+
+	S.Log("Rebuilding the company search...")
+	err = secsearch.Rebuild(DB)
+	if err != nil {
+		return err
+	}
+
+	S.Log("Generating & caching SIC page in redis...")
+	err = sc.GenerateSICPageDataCache()
+	if err != nil {
+		return err
+	}
+
+	S.Log("Generating & caching Download Stats page in redis...")
+	err = sc.GenerateHourlyDownloadStatsPageDataCache()
+	if err != nil {
+		return err
+	}
+
+	S.Log("Generating & caching Companies Data in redis...")
+	err = sc.GenerateCompaniesDataCache()
+	if err != nil {
+		return err
+	}
+
+	return seccache.SaveRegenMark(DB, mark)
+}
+
+// GeneratePagesIncremental rebuilds the data of the pages whose filings
+// changed since the last regen. The home page and download stats are cheap
+// and always rebuilt.
+func GeneratePagesIncremental(sc *seccache.SECCache) error {
+	current, err := seccache.CurrentRegenMark(DB, seccache.RegenPages)
+	if err != nil {
+		return err
+	}
+
+	mark, err := seccache.GetRegenMark(DB, seccache.RegenPages)
+	if err != nil {
+		return err
+	}
+
+	changes, err := seccache.GetChanges(DB, mark)
+	if err != nil {
+		return err
+	}
+
+	S.Log(fmt.Sprintf("Filings changed since %v: %d days, %d CIKs, %d SICs", mark.SECItemFileUpdatedAt.Format(time.RFC3339), len(changes.Days), len(changes.CIKs), len(changes.SICs)))
+
+	S.Log("Generating & caching pages in redis...")
+	err = sc.GenerateHomePageDataCache()
+	if err != nil {
+		return err
+	}
+
+	err = sc.GenerateChangedPagesDataCache(changes)
+	if err != nil {
+		return err
+	}
+
+	// Renamed companies get a new canonical slug, links and the search use
+	// it
+	S.Log("Syncing company slugs...")
+	err = secslug.Sync(DB)
+	if err != nil {
+		return err
+	}
+
+	// Tickers and names change without new filings, the search is always
+	// rebuilt
+	S.Log("Rebuilding the company search...")
+	err = secsearch.Rebuild(DB)
+	if err != nil {
+		return err
+	}
 
-	// Cobra supports Persistent Flags which will work for this command
-	// and all subcommands, e.g.:
-	// regenCmd.PersistentFlags().String("foo", "", "A help for foo")
+	S.Log("Generating & caching Download Stats page in redis...")
+	err = sc.GenerateHourlyDownloadStatsPageDataCache()
+	if err != nil {
+		return err
+	}
 
-	// Cobra supports local flags which will only run when this command
-	// is called directly, e.g.:
-	// regenCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
+	return seccache.SaveRegenMark(DB, current)
 }
 
+// GenerateCompanyPageURLs returns the URLs of the company pages under their
+// canonical slugs. Former slugs redirect and are left out.
 func GenerateCompanyPageURLs(db *sqlx.DB, baseURL string) ([]string, error) {
-	companies, err := sec.GetAllCompanies(db)
+	slugs, err := secslug.GetCanonicalSlugs(db)
 	if err != nil {
 		return nil, err
 	}
-	companySlugs := server.GetCompanySlugs(companies)
 
 	var urls []string
-	for slug := range companySlugs {
-		urls = append(urls, fmt.Sprintf("%vcompany/%v", baseURL, slug))
+	for _, s := range slugs {
+		urls = append(urls, fmt.Sprintf("%vcompany/%v", baseURL, s.Slug))
 	}
 
 	return urls, nil
@@ -171,88 +268,225 @@ func GenerateSICPageURLs(db *sqlx.DB, baseURL string) ([]string, error) {
 	return urls, nil
 }
 
-func GenerateSitemap(sc *seccache.SECCache) error {
-	// Generating sitemap.xml
-	var sitemapURLs []string
-	sitemapURLs = append(sitemapURLs, "sitemap.xml")
-
-	var mainURLs []string
-	mainURLs = append(mainURLs, S.Config.Main.WebsiteURL)
-	mainURLs = append(mainURLs, fmt.Sprintf("%vabout", S.Config.Main.WebsiteURL))
-	mainURLs = append(mainURLs, fmt.Sprintf("%vcompany", S.Config.Main.WebsiteURL))
+// GenerateSitemap writes sitemap.xml, the companies and SIC sitemaps and
+// one filings sitemap per month. Unless full is set, only the months,
+// companies and SICs with filings changed since the last run are rewritten.
+func GenerateSitemap(sc *seccache.SECCache, full bool) error {
+	current, err := seccache.CurrentRegenMark(DB, seccache.RegenSitemap)
+	if err != nil {
+		return err
+	}
 
-	yearMonthDayCIKURLs, err := sc.GenerateYearMonthDayCIKURLs(DB, S.Config.Main.WebsiteURL)
+	// Incremental runs don't rewrite the shards of the former numbered
+	// layout, robots.txt would keep listing them
+	err = removeLegacySitemapFiles()
 	if err != nil {
 		return err
 	}
 
-	var filingSitemapURLs []string
-	URLsLeft := yearMonthDayCIKURLs
-	for i := 1; ; i++ {
-		filingSitemapURLs = append(filingSitemapURLs, fmt.Sprintf("%vfilings-sitemap-%d.xml", S.Config.Main.WebsiteURL, i))
+	var months []seccache.Month
+	updateCompanies := full
+	updateSICs := full
+
+	if full {
+		err = removeSitemapFiles("filings-sitemap-*.xml")
+		if err != nil {
+			return err
+		}
 
-		if len(URLsLeft) < 25000 && len(URLsLeft) > 0 {
-			err = createAndSaveSitemapFile(fmt.Sprintf("filings-sitemap-%d.xml", i), URLsLeft)
+		years, err := secworklist.UniqueYears(DB)
+		if err != nil {
+			return err
+		}
+		for _, year := range years {
+			yearMonths, err := secworklist.MonthsInYear(DB, year)
 			if err != nil {
 				return err
 			}
-			break
+			for _, month := range yearMonths {
+				months = append(months, seccache.Month{Year: year, Month: month})
+			}
+		}
+	} else {
+		mark, err := seccache.GetRegenMark(DB, seccache.RegenSitemap)
+		if err != nil {
+			return err
 		}
 
-		splitURLs := URLsLeft[:25000]
-		URLsLeft = URLsLeft[25000:]
-
-		err = createAndSaveSitemapFile(fmt.Sprintf("filings-sitemap-%d.xml", i), splitURLs)
+		changes, err := seccache.GetChanges(DB, mark)
 		if err != nil {
 			return err
 		}
 
-		sitemapURLs = append(sitemapURLs, fmt.Sprintf("filings-sitemap-%d.xml", i))
+		months = changes.Months
+		updateCompanies = len(changes.CIKs) > 0
+		updateSICs = len(changes.SICs) > 0
 	}
 
-	err = GenerateRobotsTXT(filingSitemapURLs)
+	var changedSitemaps []string
+
+	// Generating sitemap.xml
+	var mainURLs []string
+	mainURLs = append(mainURLs, S.Config.Main.WebsiteURL)
+	mainURLs = append(mainURLs, fmt.Sprintf("%vabout", S.Config.Main.WebsiteURL))
+	mainURLs = append(mainURLs, fmt.Sprintf("%vcompany", S.Config.Main.WebsiteURL))
+
+	years, err := secworklist.UniqueYears(DB)
 	if err != nil {
 		return err
 	}
+	for _, year := range years {
+		mainURLs = append(mainURLs, fmt.Sprintf("%vfilings/%v", S.Config.Main.WebsiteURL, year))
+	}
 
 	err = createAndSaveSitemapFile("sitemap.xml", mainURLs)
 	if err != nil {
 		return err
 	}
+	changedSitemaps = append(changedSitemaps, "sitemap.xml")
 
-	// Generating companies-sitemap.xml
-	var companyURLs []string
-	companyPageURLs, err := GenerateCompanyPageURLs(DB, S.Config.Main.WebsiteURL)
+	// Generating filings-sitemap-YYYY-MM.xml
+	for _, month := range months {
+		S.Log(fmt.Sprintf("Generating the filings sitemap of %v-%02d...", month.Year, month.Month))
+
+		filenames, err := GenerateMonthSitemap(sc, month)
+		if err != nil {
+			return err
+		}
+		changedSitemaps = append(changedSitemaps, filenames...)
+	}
+
+	filingSitemaps, err := filepath.Glob(filepath.Join(S.Config.Main.CacheDir, "filings-sitemap-*.xml"))
 	if err != nil {
 		return err
 	}
-	companyURLs = append(companyURLs, companyPageURLs...)
+	sort.Strings(filingSitemaps)
 
-	sitemapURLs = append(sitemapURLs, "companies-sitemap.xml")
+	var filingSitemapURLs []string
+	for _, filingSitemap := range filingSitemaps {
+		filingSitemapURLs = append(filingSitemapURLs, fmt.Sprintf("%v%v", S.Config.Main.WebsiteURL, filepath.Base(filingSitemap)))
+	}
 
-	err = createAndSaveSitemapFile("companies-sitemap.xml", companyURLs)
+	err = GenerateRobotsTXT(filingSitemapURLs)
 	if err != nil {
 		return err
 	}
 
+	// Generating companies-sitemap.xml
+	if updateCompanies {
+		err = secslug.Sync(DB)
+		if err != nil {
+			return err
+		}
+
+		companyPageURLs, err := GenerateCompanyPageURLs(DB, S.Config.Main.WebsiteURL)
+		if err != nil {
+			return err
+		}
+
+		err = createAndSaveSitemapFile("companies-sitemap.xml", companyPageURLs)
+		if err != nil {
+			return err
+		}
+		changedSitemaps = append(changedSitemaps, "companies-sitemap.xml")
+	}
+
 	// Generating sic-sitemap.xml
-	var sicURLs []string
-	sicPagesURLs, err := GenerateSICPageURLs(DB, S.Config.Main.WebsiteURL)
+	if updateSICs {
+		sicPagesURLs, err := GenerateSICPageURLs(DB, S.Config.Main.WebsiteURL)
+		if err != nil {
+			return err
+		}
+
+		err = createAndSaveSitemapFile("sic-sitemap.xml", sicPagesURLs)
+		if err != nil {
+			return err
+		}
+		changedSitemaps = append(changedSitemaps, "sic-sitemap.xml")
+	}
+
+	// Ping to Google Search Engine
+	for _, sitemap := range changedSitemaps {
+		_, err = http.Get(fmt.Sprintf("https://www.google.com/ping?sitemap=https://equres.com/%v", sitemap))
+		if err != nil {
+			return err
+		}
+	}
+
+	return seccache.SaveRegenMark(DB, current)
+}
+
+// GenerateMonthSitemap writes the filings sitemap of a month, split in
+// files of 25000 URLs: filings-sitemap-2021-08.xml, then
+// filings-sitemap-2021-08-2.xml and so on. It returns the files written.
+func GenerateMonthSitemap(sc *seccache.SECCache, month seccache.Month) ([]string, error) {
+	urls, err := sc.GenerateMonthURLs(S.Config.Main.WebsiteURL, month.Year, month.Month)
+	if err != nil {
+		return nil, err
+	}
+
+	// The month might have needed more files the last time
+	err = removeSitemapFiles(fmt.Sprintf("filings-sitemap-%d-%02d-*.xml", month.Year, month.Month))
+	if err != nil {
+		return nil, err
+	}
+
+	var filenames []string
+	for i := 1; len(urls) > 0; i++ {
+		filename := fmt.Sprintf("filings-sitemap-%d-%02d.xml", month.Year, month.Month)
+		if i > 1 {
+			filename = fmt.Sprintf("filings-sitemap-%d-%02d-%d.xml", month.Year, month.Month, i)
+		}
+
+		count := len(urls)
+		if count > 25000 {
+			count = 25000
+		}
+
+		err = createAndSaveSitemapFile(filename, urls[:count])
+		if err != nil {
+			return nil, err
+		}
+		urls = urls[count:]
+
+		filenames = append(filenames, filename)
+	}
+
+	return filenames, nil
+}
+
+func removeSitemapFiles(pattern string) error {
+	files, err := filepath.Glob(filepath.Join(S.Config.Main.CacheDir, pattern))
 	if err != nil {
 		return err
 	}
-	sicURLs = append(sicURLs, sicPagesURLs...)
 
-	sitemapURLs = append(sitemapURLs, "sic-sitemap.xml")
+	for _, file := range files {
+		err = os.Remove(file)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
 
-	err = createAndSaveSitemapFile("sic-sitemap.xml", sicURLs)
+// removeLegacySitemapFiles removes the filings sitemaps numbered from 1,
+// e.g. filings-sitemap-3.xml, written before there was one per month
+func removeLegacySitemapFiles() error {
+	files, err := filepath.Glob(filepath.Join(S.Config.Main.CacheDir, "filings-sitemap-*.xml"))
 	if err != nil {
 		return err
 	}
 
-	// Ping to Google Search Engine
-	for _, sitemap := range sitemapURLs {
-		_, err = http.Get(fmt.Sprintf("https://www.google.com/ping?sitemap=https://equres.com/%v", sitemap))
+	for _, file := range files {
+		shard := strings.TrimSuffix(strings.TrimPrefix(filepath.Base(file), "filings-sitemap-"), ".xml")
+		_, err = strconv.Atoi(shard)
+		if err != nil {
+			continue
+		}
+
+		err = os.Remove(file)
 		if err != nil {
 			return err
 		}
@@ -7,6 +7,7 @@ import (
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strings"
 	"syscall"
 	"time"
 
@@ -18,10 +19,12 @@ import (
 	log "github.com/sirupsen/logrus"
 	logrus_syslog "github.com/sirupsen/logrus/hooks/syslog"
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 	"github.com/spf13/viper"
 )
 
 var cfgFile string
+var Profile string
 var RootConfig config.Config
 var defaultCfgPath string
 var RateLimit time.Duration
@@ -31,6 +34,13 @@ var SyslogEnabled bool
 var DB *sqlx.DB
 var S *sec.SEC
 
+// ConfigErr is why the config couldn't be loaded or lists its invalid
+// keys, commands other than "sec config" don't run with an invalid config
+var ConfigErr error
+
+// configMissing is set if there's no config.yaml in cfgFile yet
+var configMissing bool
+
 // rootCmd represents the base command when called without any subcommands
 var rootCmd = &cobra.Command{
 	Use:   "sec",
@@ -43,12 +53,107 @@ This application is a tool to generate the needed files
 to quickly create a Cobra application.`,
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		if cmd == configCmd || cmd == configInitCmd {
+			return nil
+		}
+		if configMissing {
+			cmd.SilenceUsage = true
+			if cfgFile != defaultCfgPath {
+				return fmt.Errorf("file config '%v' was not found, create it with sec config init", filepath.Join(cfgFile, "config.yaml"))
+			}
+			log.Info("you do not have a config file. Please create it by answering the questions below")
+			err := GenerateConfig()
+			if err != nil {
+				return err
+			}
+			os.Exit(0)
+		}
+		if cmd == configValidateCmd || cmd == configEnvCmd || cmd == configShowCmd {
+			return nil
+		}
+		if ConfigErr == nil {
+			ConfigErr = RootConfig.Validate(commandKeys(cmd)...)
+		}
+		if ConfigErr == nil {
+			return nil
+		}
+		cmd.SilenceUsage = true
+		return fmt.Errorf("invalid config %v:\n%v\nfix it or override the keys with the environment variables in parentheses", viper.ConfigFileUsed(), ConfigErr)
+	},
+}
+
+// commandKeys are the config keys only cmd and a few other commands read,
+// they're required for cmd on top of the ones every command needs
+func commandKeys(cmd *cobra.Command) []string {
+	switch cmd {
+	case serveCmd:
+		return []string{"main.websiteurl", "main.cachedirunpacked"}
+	case regenCmd:
+		return []string{"main.websiteurl"}
+	case indexCmd, indexzCmd, indexsgmlCmd, unzipCmd, compareCmd, dowDataCmd, xbrlCmd, sectionsCmd, diffCmd, showCmd:
+		return []string{"main.cachedirunpacked"}
+	}
+	return nil
 }
 
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
-	cobra.CheckErr(rootCmd.Execute())
+	err := rootCmd.Execute()
+
+	// Saves the memory cache to cache.file
+	if S != nil {
+		closeErr := S.Cache.Close()
+		if closeErr != nil {
+			log.Error("could not close the cache: ", closeErr)
+		}
+	}
+
+	cobra.CheckErr(err)
+}
+
+// ExecuteArgs runs sec with args instead of os.Args and returns the error
+// of the command, so tests can drive sec end to end in the process. The
+// database connection of the run is closed and the flags are reset
+// afterwards.
+func ExecuteArgs(args ...string) error {
+	rootCmd.SetArgs(args)
+	defer rootCmd.SetArgs(nil)
+	defer resetFlags(rootCmd)
+
+	err := rootCmd.Execute()
+	if DB != nil {
+		DB.Close()
+		DB = nil
+	}
+	return err
+}
+
+// resetFlags sets the flags of c and its subcommands back to their
+// defaults, the variables they're bound to keep the values of a run
+func resetFlags(c *cobra.Command) {
+	reset := func(f *pflag.Flag) {
+		if !f.Changed {
+			return
+		}
+		if v, ok := f.Value.(pflag.SliceValue); ok {
+			var values []string
+			if def := strings.Trim(f.DefValue, "[]"); def != "" {
+				values = strings.Split(def, ",")
+			}
+			v.Replace(values)
+		} else {
+			f.Value.Set(f.DefValue)
+		}
+		f.Changed = false
+	}
+
+	c.Flags().VisitAll(reset)
+	c.PersistentFlags().VisitAll(reset)
+	for _, sub := range c.Commands() {
+		resetFlags(sub)
+	}
 }
 
 func init() {
@@ -62,6 +167,7 @@ func init() {
 	rootCmd.PersistentFlags().BoolVar(&Debug, "debug", false, "Display additional details for debugging")
 	rootCmd.PersistentFlags().BoolVar(&SyslogEnabled, "syslog", false, "Add logs into log files")
 	rootCmd.PersistentFlags().StringVar(&cfgFile, "config", defaultCfgPath, fmt.Sprintf("config file (default is %v)", defaultCfgPath))
+	rootCmd.PersistentFlags().StringVar(&Profile, "profile", os.Getenv("SEC_PROFILE"), "config profile overriding the top level of the config file, e.g. prod, staging or ci (default is $SEC_PROFILE)")
 
 	// Cobra also supports local flags, which will only run
 	// when this action is called directly.
@@ -72,22 +178,17 @@ func initConfig() {
 	var err error
 	var cfg config.Config
 	if cfgFile != defaultCfgPath {
-		if _, err = os.Stat(cfgFile); err != nil {
-			err = fmt.Errorf("file config '%v' was not found", cfgFile)
-			cobra.CheckErr(err)
-		}
 		// Use config file from the flag.
 		viper.SetConfigFile(cfgFile)
-	} else {
-		filePath := filepath.Join(defaultCfgPath, "config.yaml")
-		if _, err := os.Stat(filePath); err != nil {
-			log.Info("you do not have a config file. Please create it by answering the questions below")
-			err = GenerateConfig()
-			if err != nil {
-				cobra.CheckErr(err)
-			}
-			os.Exit(0)
-		}
+	}
+
+	// Without a config file only "sec config" and "sec config init" run,
+	// see rootCmd
+	configMissing = false
+	ConfigErr = nil
+	if _, err = os.Stat(filepath.Join(cfgFile, "config.yaml")); err != nil {
+		configMissing = true
+		return
 	}
 
 	log.SetOutput(os.Stdout)
@@ -101,24 +202,27 @@ func initConfig() {
 		log.SetOutput(hook.Writer)
 	}
 
-	viper.AutomaticEnv() // read in environment variables that match
-
 	// If a config file is found, read it in.
 	if err := viper.ReadInConfig(); err == nil {
 		fmt.Fprintln(os.Stderr, "Using config file:", viper.ConfigFileUsed())
 	}
 
-	cfg, err = config.LoadConfig(cfgFile)
+	// Nested keys are overridden by SEC_* environment variables, e.g.
+	// SEC_DATABASE_PASSWORD, see "sec config env"
+	cfg, err = config.LoadConfig(cfgFile, Profile)
 	if err != nil {
-		cobra.CheckErr(err)
+		ConfigErr = fmt.Errorf("could not load the config: %v", err)
+		return
 	}
 	RootConfig = cfg
 
-	RateLimit, err = time.ParseDuration(fmt.Sprintf("%vms", RootConfig.Main.RateLimitMs))
-	if err != nil {
-		cobra.CheckErr(err)
+	ConfigErr = RootConfig.Validate()
+	if ConfigErr != nil {
+		return
 	}
 
+	RateLimit = RootConfig.Main.RateLimit()
+
 	DB, err = database.ConnectDB(RootConfig)
 	if err != nil {
 		cobra.CheckErr(err)
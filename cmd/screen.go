@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secscreener"
+	"github.com/spf13/cobra"
+)
+
+var GlobalScreenLimit int
+
+// screenCmd represents the screen command
+var screenCmd = &cobra.Command{
+	Use:   "screen",
+	Short: "Screen companies by their latest annual fundamentals and print the result as CSV",
+	Long: `Screen companies by their latest annual fundamentals and print the result as CSV.
+
+Example:
+  sec screen "Revenues > 1e9 AND NetIncomeLoss/Revenues > 0.1 AND stprba = 'CA'"`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return fmt.Errorf("please enter a filter (e.g. sec screen \"Revenues > 1e9 AND stprba = 'CA'\")")
+		}
+
+		q, results, err := secscreener.Screen(context.Background(), DB, strings.Join(args, " "), GlobalScreenLimit)
+		if err != nil {
+			return err
+		}
+
+		writer := csv.NewWriter(os.Stdout)
+		err = writer.Write(q.Header())
+		if err != nil {
+			return err
+		}
+
+		for _, result := range results {
+			err = writer.Write(result.Row())
+			if err != nil {
+				return err
+			}
+		}
+
+		writer.Flush()
+		return writer.Error()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(screenCmd)
+
+	screenCmd.Flags().IntVarP(&GlobalScreenLimit, "limit", "l", secscreener.DefaultLimit, "Maximum number of companies to return")
+}
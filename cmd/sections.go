@@ -0,0 +1,72 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/equres/sec/pkg/secsection"
+	"github.com/spf13/cobra"
+)
+
+var GlobalSectionsAll bool
+
+// sectionsCmd represents the sections command
+var sectionsCmd = &cobra.Command{
+	Use:   "sections [accession...]",
+	Short: "Split 10-K and 10-Q filings into their Items",
+	Long: `Split the primary document of 10-K and 10-Q filings into their standard Items
+(Business, Risk Factors, MD&A, Market Risk, Financial Statements, ...) and store
+them in sec.filing_sections. Filings are segmented when indexed, this command
+covers the filings indexed before and re-segments them with --all.
+
+With accession numbers, only those filings are segmented and their sections are
+listed.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		if len(args) == 0 {
+			return secsection.SegmentFilings(S, DB, GlobalSectionsAll)
+		}
+
+		for _, accession := range args {
+			if !secfiling.IsAccessionNumber(accession) {
+				return fmt.Errorf("invalid accession number %v, expected e.g. 0000320193-21-000105", accession)
+			}
+
+			filing, err := secfiling.GetFiling(DB, accession)
+			if err != nil {
+				return err
+			}
+
+			sections, err := secsection.SegmentFiling(DB, filing)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("%v %v %v\n", filing.Accession, filing.CompanyName, filing.FormType)
+
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(w, "PART\tITEM\tNAME\tSIZE\tHEADING")
+			for _, section := range sections {
+				fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", section.Part, section.Item, section.Name, len(section.Body), section.Title)
+			}
+			err = w.Flush()
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(sectionsCmd)
+
+	sectionsCmd.Flags().BoolVarP(&GlobalSectionsAll, "all", "a", false, "Segment every 10-K and 10-Q again, not only those without sections")
+}
@@ -2,7 +2,10 @@
 package cmd
 
 import (
+	"context"
 	"embed"
+	"os/signal"
+	"syscall"
 
 	"github.com/equres/sec/pkg/server"
 	"github.com/spf13/cobra"
@@ -22,7 +25,13 @@ var serveCmd = &cobra.Command{
 			return err
 		}
 
-		err = server.StartServer(GlobalServerPort)
+		// Replace the handler from initConfig, which exits right away, so
+		// in-flight requests can finish on SIGINT/SIGTERM
+		signal.Reset(syscall.SIGINT, syscall.SIGTERM)
+		ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer stop()
+
+		err = server.StartServer(ctx, GlobalServerPort)
 		if err != nil {
 			return err
 		}
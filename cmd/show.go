@@ -0,0 +1,103 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/equres/sec/pkg/secutil"
+	"github.com/spf13/cobra"
+	"jaytaylor.com/html2text"
+)
+
+var GlobalShowDocument bool
+
+// showCmd represents the show command
+var showCmd = &cobra.Command{
+	Use:   "show <accession>",
+	Short: "Show the details and documents of a filing",
+	Long: `Show the form, the filer and the documents of a filing, the same data as the
+/filing/{accession} page (e.g. sec show 0000320193-21-000105).
+
+Use --document to also print the primary document as text.`,
+	Args: cobra.ExactArgs(1),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		accession := args[0]
+		if !secfiling.IsAccessionNumber(accession) {
+			return fmt.Errorf("invalid accession number %v, expected e.g. 0000320193-21-000105", accession)
+		}
+
+		filing, err := secfiling.GetFiling(DB, accession)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "Company:\t%v\n", filing.CompanyName)
+		fmt.Fprintf(w, "CIK:\t%v\n", filing.CIK)
+		fmt.Fprintf(w, "Form:\t%v (%v)\n", filing.FormType, secutil.GetFullFormType(filing.FormType))
+		fmt.Fprintf(w, "Accession number:\t%v\n", filing.Accession)
+		fmt.Fprintf(w, "Filing date:\t%v\n", filing.FilingDate.Format("2006-01-02"))
+		fmt.Fprintf(w, "Accepted:\t%v\n", filing.AcceptanceDatetime)
+		fmt.Fprintf(w, "Period:\t%v\n", filing.Period)
+		fmt.Fprintf(w, "File number:\t%v\n", filing.FileNumber)
+		fmt.Fprintf(w, "SIC:\t%v\n", filing.AssignedSIC)
+		fmt.Fprintf(w, "Fiscal year end:\t%04d\n", filing.FiscalYearEnd)
+		fmt.Fprintf(w, "SEC:\t%v\n", filing.SECURL())
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+
+		fmt.Println()
+
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "SEQ\tTYPE\tSIZE\tFILE\tDESCRIPTION")
+		for _, file := range filing.Files {
+			sequence := ""
+			if file.Sequence != 0 {
+				sequence = fmt.Sprint(file.Sequence)
+			}
+			fmt.Fprintf(w, "%v\t%v\t%v\t%v\t%v\n", sequence, file.Type, file.Size, file.Name, file.Description)
+		}
+		err = w.Flush()
+		if err != nil {
+			return err
+		}
+
+		if !GlobalShowDocument {
+			return nil
+		}
+
+		primary, ok := filing.PrimaryDocument()
+		if !ok {
+			return fmt.Errorf("filing %v has no primary document", accession)
+		}
+
+		rendered, err := secfiling.RenderDocument(S, filing, primary)
+		if err != nil {
+			return err
+		}
+
+		text, err := html2text.FromString(rendered, html2text.Options{PrettyTables: false})
+		if err != nil {
+			return err
+		}
+
+		fmt.Printf("\n%v\n", text)
+
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(showCmd)
+
+	showCmd.Flags().BoolVarP(&GlobalShowDocument, "document", "d", false, "Print the primary document as text")
+}
@@ -0,0 +1,39 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package cmd
+
+import (
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/xbrl"
+	"github.com/spf13/cobra"
+)
+
+var GlobalXbrlAll bool
+var GlobalXbrlRetry bool
+
+// xbrlCmd represents the xbrl command
+var xbrlCmd = &cobra.Command{
+	Use:   "xbrl",
+	Short: "Index facts from XBRL instance and inline XBRL documents into xbrl.num",
+	Long: `Index facts from the XBRL instance (EX-101.INS) and inline XBRL documents of each filing
+into xbrl.num, which has the same shape as fsds.num.
+
+By default only filings made after the last indexed financial statement data sets quarter
+are processed. Use --all to process every filing.
+
+The facts of a filing are written in one transaction and its status is kept in xbrl.filings.
+Filings whose documents are missing or fail to parse are marked as failed and skipped on
+later runs. Use --retry to process them again.`,
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		return database.CheckMigration(RootConfig)
+	},
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return xbrl.IndexFilings(S, DB, GlobalXbrlAll, GlobalXbrlRetry)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(xbrlCmd)
+
+	xbrlCmd.Flags().BoolVarP(&GlobalXbrlAll, "all", "a", false, "Index the XBRL facts of every filing, not only those newer than the financial statement data sets")
+	xbrlCmd.Flags().BoolVarP(&GlobalXbrlRetry, "retry", "r", false, "Index the XBRL facts of filings that failed before again")
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package main
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/equres/sec/cmd"
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secevent"
+	"github.com/equres/sec/pkg/sectest"
+	"github.com/equres/sec/pkg/server"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	filingDir = "Archives/edgar/data/320193/000032019321000056"
+	filingZIP = filingDir + "/0000320193-21-000056-xbrl.zip"
+	fsdsZIP   = "files/dera/data/financial-statement-data-sets/2021q2.zip"
+	mfdZIP    = "files/dera/data/mutual-fund-prospectus-risk/return-summary-data-sets/2021q2_rr1.zip"
+	sicList   = "corpfin/division-of-corporation-finance-standard-industrial-classification-sic-code-list"
+)
+
+// newEDGAR serves the fixtures of testdata: the tickers, the RSS index of
+// 2021/04 with a 10-Q of Apple, its XBRL ZIP file, the financial statement
+// data sets of 2021q2 with the 10-Q and the mutual fund data sets with a
+// prospectus of Vanguard
+func newEDGAR(t *testing.T) *sectest.EDGAR {
+	edgar := sectest.NewEDGAR(t)
+	edgar.AddDir("testdata/edgar")
+	edgar.AddZip(filingZIP, filepath.Join("testdata/edgar", filingDir), "aapl-20210327.htm", "aapl-20210327.xsd")
+	edgar.AddZip(fsdsZIP, "testdata/fsds/2021q2", "sub.txt", "tag.txt", "num.txt", "pre.txt")
+	edgar.AddZip(mfdZIP, "testdata/mfd/2021q2_rr1")
+	return edgar
+}
+
+func TestEndToEnd(t *testing.T) {
+	cmd.GlobalMigrationsFS = migrations
+	server.GlobalAssetsFS = assets
+
+	sectest.ForEachDatabase(t, testEndToEnd)
+}
+
+func testEndToEnd(t *testing.T, dbConfig config.DatabaseConfig) {
+	edgar := newEDGAR(t)
+	dir, cfg := sectest.Config(t, edgar, dbConfig)
+
+	run := func(args ...string) {
+		t.Helper()
+		err := cmd.ExecuteArgs(append([]string{"--config", dir}, args...)...)
+		if err != nil {
+			t.Fatalf("sec %v: %v", strings.Join(args, " "), err)
+		}
+	}
+
+	run("migrate", "up")
+	run("de", "2021/04")
+	run("dow", "index", "--secdata")
+	run("dowz")
+	run("unzip")
+	run("indexz")
+
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	for _, p := range []string{
+		"files/company_tickers.json",
+		"files/company_tickers_exchange.json",
+		"Archives/edgar/monthly/xbrlrss-2021-04.xml",
+		filingZIP,
+		fsdsZIP,
+		mfdZIP,
+	} {
+		assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.downloads WHERE url = $1", edgar.URL+"/"+p)
+
+		_, err := os.Stat(filepath.Join(cfg.Main.CacheDir, p))
+		if err != nil {
+			t.Errorf("%v wasn't downloaded: %v", p, err)
+		}
+	}
+
+	_, err = os.Stat(filepath.Join(cfg.Main.CacheDirUnpacked, filingDir, "aapl-20210327.htm"))
+	if err != nil {
+		t.Errorf("the filing wasn't unzipped: %v", err)
+	}
+
+	var bodies []string
+	err = db.Select(&bodies, "SELECT xbrlbody FROM sec.secItemFile WHERE ciknumber = 320193 AND xbrlfile = 'aapl-20210327.htm'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(bodies) != 1 || !strings.Contains(bodies[0], "Total net sales") {
+		t.Errorf("xbrlbody of aapl-20210327.htm = %q, want the text of the 10-Q", bodies)
+	}
+
+	assertCount(t, db, 6, secevent.Query(db, "SELECT COUNT(*) FROM sec.events WHERE ev->>'event' = 'download' AND ev->>'status' = 'success'"))
+	assertCount(t, db, 0, secevent.Query(db, "SELECT COUNT(*) FROM sec.events WHERE ev->>'status' = 'failed'"))
+	assertCount(t, db, 2, secevent.Query(db, "SELECT COUNT(*) FROM sec.events WHERE ev->>'event' = 'index' AND ev->>'status' = 'success'"))
+
+	// Files with the same ETag are only checked again
+	requests := len(edgar.Requests())
+	run("dow", "index")
+	for _, r := range edgar.Requests()[requests:] {
+		if strings.HasPrefix(r, "GET ") {
+			t.Errorf("sec dow index downloaded again: %v", r)
+		}
+	}
+
+	// The SIC codes are downloaded by "sec refresh" from www.sec.gov
+	err = os.MkdirAll(filepath.Join(cfg.Main.CacheDir, filepath.Dir(sicList)), 0755)
+	if err == nil {
+		err = ioutil.WriteFile(filepath.Join(cfg.Main.CacheDir, sicList), edgar.File(sicList), 0644)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	run("index", "--secdata")
+
+	assertCount(t, db, 2, "SELECT COUNT(*) FROM sec.tickers WHERE exchange = 'Nasdaq'")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.tickers WHERE ticker = 'VOO' AND exchange = 'NYSE'")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.ciks WHERE cik = 320193")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.sics WHERE sic = 3571 AND title = 'ELECTRONIC COMPUTERS'")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM fsds.sub WHERE adsh = '0000320193-21-000056'")
+	assertCount(t, db, 2, "SELECT COUNT(*) FROM fsds.tag")
+	// NotInTagTxt isn't in tag.txt
+	assertCount(t, db, 2, "SELECT COUNT(*) FROM fsds.num")
+	assertCount(t, db, 2, "SELECT COUNT(*) FROM fsds.pre")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM mfd.sub WHERE cik = 36405")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.ticker_history WHERE cik = 320193 AND valid_to IS NULL")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.name_history WHERE cik = 320193")
+	assertCount(t, db, 2, "SELECT COUNT(*) FROM sec.secItemFile WHERE accessionnumber = '0000320193-21-000056'")
+	assertCount(t, db, 0, secevent.Query(db, "SELECT COUNT(*) FROM sec.events WHERE ev->>'status' = 'failed'"))
+
+	// The data of the web pages and the search are built from the index
+	run("regen", "pages", "--full")
+	run("regen", "pages")
+	run("ciks", "reconcile")
+
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.company_slugs WHERE cik = 320193 AND canonical")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.company_search WHERE cik = 320193 AND kind = 'ticker' AND term = 'AAPL'")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.entities WHERE cik = 320193 AND in_filings AND in_registry")
+	assertCount(t, db, 1, "SELECT COUNT(*) FROM sec.regen_marks WHERE name = 'pages'")
+
+	var slug string
+	err = db.Get(&slug, "SELECT slug FROM sec.company_slugs WHERE cik = 320193 AND canonical")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The pages of sec serve, those listing the 10-Q show Apple
+	s, err := server.NewServer(db, cfg, templates)
+	if err != nil {
+		t.Fatal(err)
+	}
+	router, err := s.GenerateRouter()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, page := range []struct {
+		path  string
+		apple bool
+	}{
+		{"/", true},
+		{"/filings/2021", false},
+		{"/filings/2021/04", false},
+		{"/filings/2021/04/29", true},
+		{"/filings/2021/04/29/320193", true},
+		{"/company", true},
+		{"/company/" + slug, true},
+		{"/filing/0000320193-21-000056", true},
+		{"/diff?a=0000320193-21-000056&b=0000320193-21-000056", true},
+		{"/sic", false},
+		{"/sic/3571", true},
+		{"/sic/presets/tech", true},
+		{"/states", false},
+		{"/states/CA", true},
+		{"/screener?q=NetIncomeLoss+%3E+0", false},
+		{"/funds", false},
+		{"/search?q=apple", true},
+		{"/api/v1/companies/suggest?q=aapl", true},
+	} {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest("GET", page.path, nil))
+		if w.Code != http.StatusOK {
+			t.Errorf("GET %v = %v: %s", page.path, w.Code, w.Body)
+		}
+		if page.apple && !strings.Contains(strings.ToLower(w.Body.String()), "apple") {
+			t.Errorf("GET %v doesn't show Apple: %s", page.path, w.Body)
+		}
+	}
+}
+
+func assertCount(t *testing.T, db *sqlx.DB, want int, query string, args ...interface{}) {
+	t.Helper()
+
+	var count int
+	err := db.Get(&count, query, args...)
+	if err != nil {
+		t.Fatalf("%v: %v", query, err)
+	}
+	if count != want {
+		t.Errorf("%v = %d, want %d", query, count, want)
+	}
+}
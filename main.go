@@ -9,7 +9,7 @@ import (
 	_ "github.com/lib/pq"
 )
 
-//go:embed migrations
+//go:embed migrations migrations_sqlite
 var migrations embed.FS
 
 //go:embed templates/*
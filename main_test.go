@@ -3,55 +3,65 @@ package main
 
 import (
 	"fmt"
-	"net/http"
-	"net/http/httptest"
+	"os"
+	"path/filepath"
 	"testing"
 
+	"github.com/equres/sec/cmd"
 	"github.com/equres/sec/pkg/config"
 	"github.com/equres/sec/pkg/database"
 	"github.com/equres/sec/pkg/download"
 	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/sectest"
 )
 
-// Serve file in HTTP and download to testdata directory
+// Serve file in HTTP and download it to the cache directory
 func TestHTTPDownloadFile(t *testing.T) {
-	cfg, err := config.LoadConfig("./ci")
-	if err != nil {
-		t.Errorf(err.Error())
-	}
+	cmd.GlobalMigrationsFS = migrations
 
-	// Path for files
-	filesPath := "./cache/Archives/edgar/monthly/"
-	testServer := httptest.NewServer(http.FileServer(http.Dir(filesPath)))
+	sectest.ForEachDatabase(t, func(t *testing.T, dbConfig config.DatabaseConfig) {
+		edgar := newEDGAR(t)
+		dir, cfg := sectest.Config(t, edgar, dbConfig)
 
-	defer testServer.Close()
+		err := cmd.ExecuteArgs("--config", dir, "migrate", "up")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		s, err := sec.NewSEC(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
 
-	cfg.Main.BaseURL = testServer.URL
-	cfg.Main.CacheDir = "./testdata"
+		db, err := database.ConnectDB(cfg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
 
-	s, err := sec.NewSEC(cfg)
-	if err != nil {
-		t.Errorf(err.Error())
-	}
+		downloader := download.NewDownloader(cfg)
+		downloader.TotalDownloadsCount = 1
+		downloader.CurrentDownloadCount = 0
+		downloader.IsEtag = true
 
-	db, err := database.ConnectDB(cfg)
-	if err != nil {
-		t.Errorf(err.Error())
-	}
+		filePath := "Archives/edgar/monthly/xbrlrss-2021-04.xml"
+		fileURL := fmt.Sprintf("%v/%v", s.BaseURL, filePath)
 
-	downloader := download.NewDownloader(cfg)
-	downloader.TotalDownloadsCount = 1
-	downloader.CurrentDownloadCount = 0
+		not_download, err := downloader.FileCorrect(db, fileURL, 0, "")
+		if err != nil {
+			t.Error(err)
+		}
 
-	not_download, err := downloader.FileCorrect(db, fmt.Sprintf("%v/%v", s.BaseURL, "xbrlrss-2021-04.xml"), 0, "")
-	if err != nil {
-		t.Errorf(err.Error())
-	}
+		if !not_download {
+			err = downloader.DownloadFile(db, fileURL)
+			if err != nil {
+				t.Error(err)
+			}
+		}
 
-	if !not_download {
-		err = downloader.DownloadFile(db, fmt.Sprintf("%v/%v", s.BaseURL, "xbrlrss-2021-04.xml"))
+		_, err = os.Stat(filepath.Join(cfg.Main.CacheDir, filePath))
 		if err != nil {
-			t.Errorf(err.Error())
+			t.Errorf("%v wasn't downloaded: %v", filePath, err)
 		}
-	}
+	})
 }
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS fsds.metrics CASCADE;
\ No newline at end of file
@@ -0,0 +1,34 @@
+-- Derived metrics computed by `sec derive` from fsds.sub and fsds.num
+CREATE TABLE fsds.metrics (
+    id serial PRIMARY KEY,
+    cik integer,
+    adsh text,
+    ddate text,
+    fy text,
+    fp text,
+    form text,
+    kind text,
+    revenues numeric,
+    gross_profit numeric,
+    operating_income numeric,
+    net_income numeric,
+    eps_diluted numeric,
+    assets numeric,
+    liabilities numeric,
+    equity numeric,
+    current_assets numeric,
+    current_liabilities numeric,
+    long_term_debt numeric,
+    shares_outstanding numeric,
+    gross_margin numeric,
+    operating_margin numeric,
+    net_margin numeric,
+    roe numeric,
+    current_ratio numeric,
+    debt_to_equity numeric,
+    sub_updated_at timestamp with time zone,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT metrics_cik_ddate_kind UNIQUE (cik, ddate, kind)
+);
@@ -0,0 +1 @@
+DROP SCHEMA IF EXISTS xbrl;
\ No newline at end of file
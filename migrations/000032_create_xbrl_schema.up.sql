@@ -0,0 +1 @@
+CREATE SCHEMA IF NOT EXISTS xbrl;
\ No newline at end of file
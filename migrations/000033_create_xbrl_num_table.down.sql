@@ -0,0 +1 @@
+DROP TABLE IF EXISTS xbrl.num CASCADE;
\ No newline at end of file
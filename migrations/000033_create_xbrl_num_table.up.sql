@@ -0,0 +1,19 @@
+-- Same shape as fsds.num, filled by `sec xbrl` from XBRL and inline XBRL documents
+CREATE TABLE xbrl.num (
+    id serial PRIMARY KEY,
+    adsh text,
+    cik integer,
+    tag text,
+    version text,
+    coreg text,
+    ddate text,
+    qtrs text,
+    uom text,
+    value text,
+    segments text,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT xbrl_num_unique_keys UNIQUE (adsh, tag, version, coreg, ddate, qtrs, uom, segments)
+);
+CREATE INDEX xbrl_num_cik_tag ON xbrl.num (cik, tag);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sec.regen_marks CASCADE;
\ No newline at end of file
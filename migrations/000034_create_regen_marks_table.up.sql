@@ -0,0 +1,12 @@
+-- High-water marks of `sec regen`: the latest updated_at of sec.secItemFile
+-- and fsds.sub covered by the last run of each target (pages, sitemap)
+CREATE TABLE sec.regen_marks (
+    id serial PRIMARY KEY,
+    name text,
+    secitemfile_updated_at timestamp with time zone,
+    sub_updated_at timestamp with time zone,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT regen_mark UNIQUE (name)
+);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sec.filing_sections CASCADE;
\ No newline at end of file
@@ -0,0 +1,18 @@
+-- Items of the primary document of 10-K and 10-Q filings, filled by `sec sections` and when indexing
+CREATE TABLE sec.filing_sections (
+    id serial PRIMARY KEY,
+    accessionnumber text NOT NULL,
+    xbrlfile text,
+    formtype text,
+    part text NOT NULL DEFAULT '',
+    item text NOT NULL,
+    name text,
+    title text,
+    body text,
+    position integer,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT filing_sections_unique_keys UNIQUE (accessionnumber, part, item)
+);
+CREATE INDEX filing_sections_name ON sec.filing_sections (name);
@@ -0,0 +1,2 @@
+ALTER TABLE sec.secItemFile
+DROP COLUMN xbrlbodyoffsets;
\ No newline at end of file
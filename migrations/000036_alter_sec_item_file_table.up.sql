@@ -0,0 +1,2 @@
+ALTER TABLE sec.secItemFile
+ADD xbrlbodyoffsets bigint[];
\ No newline at end of file
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sec.ticker_history CASCADE;
\ No newline at end of file
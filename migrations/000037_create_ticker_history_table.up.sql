@@ -0,0 +1,22 @@
+-- Tickers of each CIK over time, valid_to is NULL while the ticker is listed.
+-- Filled from each company_tickers_exchange.json refresh, starting from sec.tickers
+CREATE TABLE sec.ticker_history (
+    id serial PRIMARY KEY,
+    cik integer NOT NULL,
+    ticker text NOT NULL,
+    exchange text NOT NULL DEFAULT '',
+    valid_from date NOT NULL,
+    valid_to date,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT fk_cik FOREIGN KEY (cik) REFERENCES sec.ciks (cik)
+);
+CREATE UNIQUE INDEX ticker_history_current ON sec.ticker_history (cik, ticker) WHERE valid_to IS NULL;
+CREATE INDEX ticker_history_ticker ON sec.ticker_history (UPPER(ticker));
+
+INSERT INTO sec.ticker_history (cik, ticker, exchange, valid_from, created_at, updated_at)
+SELECT DISTINCT ON (cik, ticker) cik, ticker, COALESCE(exchange, ''), COALESCE(created_at, NOW())::date, NOW(), NOW()
+FROM sec.tickers
+WHERE cik IS NOT NULL AND ticker IS NOT NULL AND ticker <> ''
+ORDER BY cik, ticker, exchange DESC NULLS LAST;
@@ -0,0 +1,15 @@
+-- Names of each CIK over time, rebuilt from fsds.sub (name, former, changed),
+-- the company names of indexed filings and sec.tickers. valid_to is NULL for
+-- the current name
+CREATE TABLE sec.name_history (
+    id serial PRIMARY KEY,
+    cik integer NOT NULL,
+    name text NOT NULL,
+    valid_from date NOT NULL,
+    valid_to date,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT name_history_unique_keys UNIQUE (cik, valid_from)
+);
+CREATE INDEX name_history_name ON sec.name_history (UPPER(name));
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sec.company_search CASCADE;
\ No newline at end of file
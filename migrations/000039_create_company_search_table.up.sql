@@ -0,0 +1,19 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Names, former names and tickers companies are searched by, one row each,
+-- rebuilt by `sec regen pages`. term is normalized: upper case, letters and
+-- digits separated by single spaces
+CREATE TABLE sec.company_search (
+    id serial PRIMARY KEY,
+    cik integer NOT NULL,
+    term text NOT NULL,
+    kind text NOT NULL,
+    name text NOT NULL,
+    ticker text NOT NULL DEFAULT '',
+    slug text NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone
+);
+CREATE INDEX company_search_term ON sec.company_search USING gin (term gin_trgm_ops);
+CREATE INDEX company_search_cik ON sec.company_search (cik);
@@ -0,0 +1 @@
+DROP TABLE IF EXISTS sec.company_slugs CASCADE;
\ No newline at end of file
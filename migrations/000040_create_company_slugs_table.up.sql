@@ -0,0 +1,15 @@
+-- URL slugs of company pages. The canonical slug of a CIK is its latest name
+-- followed by the CIK, e.g. apple-inc-320193. Slugs of former names and the
+-- name-only slugs used before are kept and redirect to the canonical one
+CREATE TABLE sec.company_slugs (
+    id serial PRIMARY KEY,
+    slug text NOT NULL,
+    cik integer NOT NULL,
+    name text NOT NULL,
+    canonical boolean NOT NULL DEFAULT false,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT company_slugs_unique_keys UNIQUE (slug)
+);
+CREATE UNIQUE INDEX company_slugs_canonical ON sec.company_slugs (cik) WHERE canonical;
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS sec.sic_naics CASCADE;
+DROP TABLE IF EXISTS sec.sic_industry_groups CASCADE;
+DROP TABLE IF EXISTS sec.sic_major_groups CASCADE;
+DROP TABLE IF EXISTS sec.sic_divisions CASCADE;
\ No newline at end of file
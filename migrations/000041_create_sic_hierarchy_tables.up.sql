@@ -0,0 +1,563 @@
+-- SIC divisions, major groups and industry groups, from the OSHA SIC
+-- manual. A SIC's major group is its first two digits and its industry
+-- group its first three
+CREATE TABLE sec.sic_divisions (
+    id serial PRIMARY KEY,
+    division text NOT NULL,
+    title text NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT sic_divisions_unique_keys UNIQUE (division)
+);
+
+CREATE TABLE sec.sic_major_groups (
+    id serial PRIMARY KEY,
+    major_group integer NOT NULL,
+    division text NOT NULL,
+    title text NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT sic_major_groups_unique_keys UNIQUE (major_group),
+    CONSTRAINT fk_division FOREIGN KEY (division) REFERENCES sec.sic_divisions (division)
+);
+
+CREATE TABLE sec.sic_industry_groups (
+    id serial PRIMARY KEY,
+    industry_group integer NOT NULL,
+    major_group integer NOT NULL,
+    title text NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT sic_industry_groups_unique_keys UNIQUE (industry_group),
+    CONSTRAINT fk_major_group FOREIGN KEY (major_group) REFERENCES sec.sic_major_groups (major_group)
+);
+
+-- SIC to NAICS crosswalk, loaded by "sec naics"
+CREATE TABLE sec.sic_naics (
+    id serial PRIMARY KEY,
+    sic integer NOT NULL,
+    naics text NOT NULL,
+    title text NOT NULL,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT sic_naics_unique_keys UNIQUE (sic, naics)
+);
+
+INSERT INTO sec.sic_divisions (division, title, created_at, updated_at) VALUES
+('A', 'Agriculture, Forestry, And Fishing', NOW(), NOW()),
+('B', 'Mining', NOW(), NOW()),
+('C', 'Construction', NOW(), NOW()),
+('D', 'Manufacturing', NOW(), NOW()),
+('E', 'Transportation, Communications, Electric, Gas, And Sanitary Services', NOW(), NOW()),
+('F', 'Wholesale Trade', NOW(), NOW()),
+('G', 'Retail Trade', NOW(), NOW()),
+('H', 'Finance, Insurance, And Real Estate', NOW(), NOW()),
+('I', 'Services', NOW(), NOW()),
+('J', 'Public Administration', NOW(), NOW());
+
+INSERT INTO sec.sic_major_groups (major_group, division, title, created_at, updated_at) VALUES
+(1, 'A', 'Agricultural Production - Crops', NOW(), NOW()),
+(2, 'A', 'Agricultural Production - Livestock And Animal Specialties', NOW(), NOW()),
+(7, 'A', 'Agricultural Services', NOW(), NOW()),
+(8, 'A', 'Forestry', NOW(), NOW()),
+(9, 'A', 'Fishing, Hunting, And Trapping', NOW(), NOW()),
+(10, 'B', 'Metal Mining', NOW(), NOW()),
+(12, 'B', 'Coal Mining', NOW(), NOW()),
+(13, 'B', 'Oil And Gas Extraction', NOW(), NOW()),
+(14, 'B', 'Mining And Quarrying Of Nonmetallic Minerals, Except Fuels', NOW(), NOW()),
+(15, 'C', 'Building Construction General Contractors And Operative Builders', NOW(), NOW()),
+(16, 'C', 'Heavy Construction Other Than Building Construction Contractors', NOW(), NOW()),
+(17, 'C', 'Construction Special Trade Contractors', NOW(), NOW()),
+(20, 'D', 'Food And Kindred Products', NOW(), NOW()),
+(21, 'D', 'Tobacco Products', NOW(), NOW()),
+(22, 'D', 'Textile Mill Products', NOW(), NOW()),
+(23, 'D', 'Apparel And Other Finished Products Made From Fabrics And Similar Materials', NOW(), NOW()),
+(24, 'D', 'Lumber And Wood Products, Except Furniture', NOW(), NOW()),
+(25, 'D', 'Furniture And Fixtures', NOW(), NOW()),
+(26, 'D', 'Paper And Allied Products', NOW(), NOW()),
+(27, 'D', 'Printing, Publishing, And Allied Industries', NOW(), NOW()),
+(28, 'D', 'Chemicals And Allied Products', NOW(), NOW()),
+(29, 'D', 'Petroleum Refining And Related Industries', NOW(), NOW()),
+(30, 'D', 'Rubber And Miscellaneous Plastics Products', NOW(), NOW()),
+(31, 'D', 'Leather And Leather Products', NOW(), NOW()),
+(32, 'D', 'Stone, Clay, Glass, And Concrete Products', NOW(), NOW()),
+(33, 'D', 'Primary Metal Industries', NOW(), NOW()),
+(34, 'D', 'Fabricated Metal Products, Except Machinery And Transportation Equipment', NOW(), NOW()),
+(35, 'D', 'Industrial And Commercial Machinery And Computer Equipment', NOW(), NOW()),
+(36, 'D', 'Electronic And Other Electrical Equipment And Components, Except Computer Equipment', NOW(), NOW()),
+(37, 'D', 'Transportation Equipment', NOW(), NOW()),
+(38, 'D', 'Measuring, Analyzing, And Controlling Instruments; Photographic, Medical And Optical Goods; Watches And Clocks', NOW(), NOW()),
+(39, 'D', 'Miscellaneous Manufacturing Industries', NOW(), NOW()),
+(40, 'E', 'Railroad Transportation', NOW(), NOW()),
+(41, 'E', 'Local And Suburban Transit And Interurban Highway Passenger Transportation', NOW(), NOW()),
+(42, 'E', 'Motor Freight Transportation And Warehousing', NOW(), NOW()),
+(43, 'E', 'United States Postal Service', NOW(), NOW()),
+(44, 'E', 'Water Transportation', NOW(), NOW()),
+(45, 'E', 'Transportation By Air', NOW(), NOW()),
+(46, 'E', 'Pipelines, Except Natural Gas', NOW(), NOW()),
+(47, 'E', 'Transportation Services', NOW(), NOW()),
+(48, 'E', 'Communications', NOW(), NOW()),
+(49, 'E', 'Electric, Gas, And Sanitary Services', NOW(), NOW()),
+(50, 'F', 'Wholesale Trade - Durable Goods', NOW(), NOW()),
+(51, 'F', 'Wholesale Trade - Non-durable Goods', NOW(), NOW()),
+(52, 'G', 'Building Materials, Hardware, Garden Supply, And Mobile Home Dealers', NOW(), NOW()),
+(53, 'G', 'General Merchandise Stores', NOW(), NOW()),
+(54, 'G', 'Food Stores', NOW(), NOW()),
+(55, 'G', 'Automotive Dealers And Gasoline Service Stations', NOW(), NOW()),
+(56, 'G', 'Apparel And Accessory Stores', NOW(), NOW()),
+(57, 'G', 'Home Furniture, Furnishings, And Equipment Stores', NOW(), NOW()),
+(58, 'G', 'Eating And Drinking Places', NOW(), NOW()),
+(59, 'G', 'Miscellaneous Retail', NOW(), NOW()),
+(60, 'H', 'Depository Institutions', NOW(), NOW()),
+(61, 'H', 'Non-depository Credit Institutions', NOW(), NOW()),
+(62, 'H', 'Security And Commodity Brokers, Dealers, Exchanges, And Services', NOW(), NOW()),
+(63, 'H', 'Insurance Carriers', NOW(), NOW()),
+(64, 'H', 'Insurance Agents, Brokers, And Service', NOW(), NOW()),
+(65, 'H', 'Real Estate', NOW(), NOW()),
+(67, 'H', 'Holding And Other Investment Offices', NOW(), NOW()),
+(70, 'I', 'Hotels, Rooming Houses, Camps, And Other Lodging Places', NOW(), NOW()),
+(72, 'I', 'Personal Services', NOW(), NOW()),
+(73, 'I', 'Business Services', NOW(), NOW()),
+(75, 'I', 'Automotive Repair, Services, And Parking', NOW(), NOW()),
+(76, 'I', 'Miscellaneous Repair Services', NOW(), NOW()),
+(78, 'I', 'Motion Pictures', NOW(), NOW()),
+(79, 'I', 'Amusement And Recreation Services', NOW(), NOW()),
+(80, 'I', 'Health Services', NOW(), NOW()),
+(81, 'I', 'Legal Services', NOW(), NOW()),
+(82, 'I', 'Educational Services', NOW(), NOW()),
+(83, 'I', 'Social Services', NOW(), NOW()),
+(84, 'I', 'Museums, Art Galleries, And Botanical And Zoological Gardens', NOW(), NOW()),
+(86, 'I', 'Membership Organizations', NOW(), NOW()),
+(87, 'I', 'Engineering, Accounting, Research, Management, And Related Services', NOW(), NOW()),
+(88, 'I', 'Private Households', NOW(), NOW()),
+(89, 'I', 'Miscellaneous Services', NOW(), NOW()),
+(91, 'J', 'Executive, Legislative, And General Government, Except Finance', NOW(), NOW()),
+(92, 'J', 'Justice, Public Order, And Safety', NOW(), NOW()),
+(93, 'J', 'Public Finance, Taxation, And Monetary Policy', NOW(), NOW()),
+(94, 'J', 'Administration Of Human Resource Programs', NOW(), NOW()),
+(95, 'J', 'Administration Of Environmental Quality And Housing Programs', NOW(), NOW()),
+(96, 'J', 'Administration Of Economic Programs', NOW(), NOW()),
+(97, 'J', 'National Security And International Affairs', NOW(), NOW()),
+(99, 'J', 'Nonclassifiable Establishments', NOW(), NOW());
+
+INSERT INTO sec.sic_industry_groups (industry_group, major_group, title, created_at, updated_at) VALUES
+(11, 1, 'Cash Grains', NOW(), NOW()),
+(13, 1, 'Field Crops, Except Cash Grains', NOW(), NOW()),
+(16, 1, 'Vegetables And Melons', NOW(), NOW()),
+(17, 1, 'Fruits And Tree Nuts', NOW(), NOW()),
+(18, 1, 'Horticultural Specialties', NOW(), NOW()),
+(19, 1, 'General Farms, Primarily Crop', NOW(), NOW()),
+(21, 2, 'Livestock, Except Dairy And Poultry', NOW(), NOW()),
+(24, 2, 'Dairy Farms', NOW(), NOW()),
+(25, 2, 'Poultry And Eggs', NOW(), NOW()),
+(27, 2, 'Animal Specialties', NOW(), NOW()),
+(29, 2, 'General Farms, Primarily Livestock And Animal Specialties', NOW(), NOW()),
+(71, 7, 'Soil Preparation Services', NOW(), NOW()),
+(72, 7, 'Crop Services', NOW(), NOW()),
+(74, 7, 'Veterinary Services', NOW(), NOW()),
+(75, 7, 'Animal Services, Except Veterinary', NOW(), NOW()),
+(76, 7, 'Farm Labor And Management Services', NOW(), NOW()),
+(78, 7, 'Landscape And Horticultural Services', NOW(), NOW()),
+(81, 8, 'Timber Tracts', NOW(), NOW()),
+(83, 8, 'Forest Nurseries And Gathering Of Forest Products', NOW(), NOW()),
+(85, 8, 'Forestry Services', NOW(), NOW()),
+(91, 9, 'Commercial Fishing', NOW(), NOW()),
+(92, 9, 'Fish Hatcheries And Preserves', NOW(), NOW()),
+(97, 9, 'Hunting And Trapping, And Game Propagation', NOW(), NOW()),
+(101, 10, 'Iron Ores', NOW(), NOW()),
+(102, 10, 'Copper Ores', NOW(), NOW()),
+(103, 10, 'Lead And Zinc Ores', NOW(), NOW()),
+(104, 10, 'Gold And Silver Ores', NOW(), NOW()),
+(106, 10, 'Ferroalloy Ores, Except Vanadium', NOW(), NOW()),
+(108, 10, 'Metal Mining Services', NOW(), NOW()),
+(109, 10, 'Miscellaneous Metal Ores', NOW(), NOW()),
+(122, 12, 'Bituminous Coal And Lignite Mining', NOW(), NOW()),
+(123, 12, 'Anthracite Mining', NOW(), NOW()),
+(124, 12, 'Coal Mining Services', NOW(), NOW()),
+(131, 13, 'Crude Petroleum And Natural Gas', NOW(), NOW()),
+(132, 13, 'Natural Gas Liquids', NOW(), NOW()),
+(138, 13, 'Oil And Gas Field Services', NOW(), NOW()),
+(141, 14, 'Dimension Stone', NOW(), NOW()),
+(142, 14, 'Crushed And Broken Stone, Including Riprap', NOW(), NOW()),
+(144, 14, 'Sand And Gravel', NOW(), NOW()),
+(145, 14, 'Clay, Ceramic, And Refractory Minerals', NOW(), NOW()),
+(147, 14, 'Chemical And Fertilizer Mineral Mining', NOW(), NOW()),
+(148, 14, 'Nonmetallic Minerals Services, Except Fuels', NOW(), NOW()),
+(149, 14, 'Miscellaneous Nonmetallic Minerals, Except Fuels', NOW(), NOW()),
+(152, 15, 'General Building Contractors-residential Buildings', NOW(), NOW()),
+(153, 15, 'Operative Builders', NOW(), NOW()),
+(154, 15, 'General Building Contractors-nonresidential Buildings', NOW(), NOW()),
+(161, 16, 'Highway And Street Construction, Except Elevated Highways', NOW(), NOW()),
+(162, 16, 'Heavy Construction, Except Highway And Street Construction', NOW(), NOW()),
+(171, 17, 'Plumbing, Heating And Air-conditioning', NOW(), NOW()),
+(172, 17, 'Painting And Paper Hanging', NOW(), NOW()),
+(173, 17, 'Electrical Work', NOW(), NOW()),
+(174, 17, 'Masonry, Stonework, Tile Setting, And Plastering', NOW(), NOW()),
+(175, 17, 'Carpentry And Floor Work', NOW(), NOW()),
+(176, 17, 'Roofing, Siding, And Sheet Metal Work', NOW(), NOW()),
+(177, 17, 'Concrete Work', NOW(), NOW()),
+(178, 17, 'Water Well Drilling', NOW(), NOW()),
+(179, 17, 'Miscellaneous Special Trade Contractors', NOW(), NOW()),
+(201, 20, 'Meat Products', NOW(), NOW()),
+(202, 20, 'Dairy Products', NOW(), NOW()),
+(203, 20, 'Canned, Frozen, And Preserved Fruits, Vegetables, And Food Specialties', NOW(), NOW()),
+(204, 20, 'Grain Mill Products', NOW(), NOW()),
+(205, 20, 'Bakery Products', NOW(), NOW()),
+(206, 20, 'Sugar And Confectionery Products', NOW(), NOW()),
+(207, 20, 'Fats And Oils', NOW(), NOW()),
+(208, 20, 'Beverages', NOW(), NOW()),
+(209, 20, 'Miscellaneous Food Preparations And Kindred Products', NOW(), NOW()),
+(211, 21, 'Cigarettes', NOW(), NOW()),
+(212, 21, 'Cigars', NOW(), NOW()),
+(213, 21, 'Chewing And Smoking Tobacco And Snuff', NOW(), NOW()),
+(214, 21, 'Tobacco Stemming And Redrying', NOW(), NOW()),
+(221, 22, 'Broadwoven Fabric Mills, Cotton', NOW(), NOW()),
+(222, 22, 'Broadwoven Fabric Mills, Manmade Fiber And Silk', NOW(), NOW()),
+(223, 22, 'Broadwoven Fabric Mills, Wool (including Dyeing And Finishing)', NOW(), NOW()),
+(224, 22, 'Narrow Fabric And Other Smallwares Mills: Cotton, Wool, Silk, And Manmade Fiber', NOW(), NOW()),
+(225, 22, 'Knitting Mills', NOW(), NOW()),
+(226, 22, 'Dyeing And Finishing Textiles, Except Wool Fabrics And Knit Goods', NOW(), NOW()),
+(227, 22, 'Carpets And Rugs', NOW(), NOW()),
+(228, 22, 'Yarn And Thread Mills', NOW(), NOW()),
+(229, 22, 'Miscellaneous Textile Goods', NOW(), NOW()),
+(231, 23, 'Men''s And Boys'' Suits, Coats, And Overcoats', NOW(), NOW()),
+(232, 23, 'Men''s And Boys'' Furnishings, Work Clothing, And Allied Garments', NOW(), NOW()),
+(233, 23, 'Women''s, Misses'', And Juniors'' Outerwear', NOW(), NOW()),
+(234, 23, 'Women''s, Misses'', Children''s, And Infants'' Undergarments', NOW(), NOW()),
+(235, 23, 'Hats, Caps, And Millinery', NOW(), NOW()),
+(236, 23, 'Girls'', Children''s, And Infants'' Outerwear', NOW(), NOW()),
+(237, 23, 'Fur Goods', NOW(), NOW()),
+(238, 23, 'Miscellaneous Apparel And Accessories', NOW(), NOW()),
+(239, 23, 'Miscellaneous Fabricated Textile Products', NOW(), NOW()),
+(241, 24, 'Logging', NOW(), NOW()),
+(242, 24, 'Sawmills And Planing Mills', NOW(), NOW()),
+(243, 24, 'Millwork, Veneer, Plywood, And Structural Wood Members', NOW(), NOW()),
+(244, 24, 'Wood Containers', NOW(), NOW()),
+(245, 24, 'Wood Buildings And Mobile Homes', NOW(), NOW()),
+(249, 24, 'Miscellaneous Wood Products', NOW(), NOW()),
+(251, 25, 'Household Furniture', NOW(), NOW()),
+(252, 25, 'Office Furniture', NOW(), NOW()),
+(253, 25, 'Public Building And Related Furniture', NOW(), NOW()),
+(254, 25, 'Partitions, Shelving, Lockers, And Office And Store Fixtures', NOW(), NOW()),
+(259, 25, 'Miscellaneous Furniture And Fixtures', NOW(), NOW()),
+(261, 26, 'Pulp Mills', NOW(), NOW()),
+(262, 26, 'Paper Mills', NOW(), NOW()),
+(263, 26, 'Paperboard Mills', NOW(), NOW()),
+(265, 26, 'Paperboard Containers And Boxes', NOW(), NOW()),
+(267, 26, 'Converted Paper And Paperboard Products, Except Containers And Boxes', NOW(), NOW()),
+(271, 27, 'Newspapers: Publishing, Or Publishing And Printing', NOW(), NOW()),
+(272, 27, 'Periodicals: Publishing, Or Publishing And Printing', NOW(), NOW()),
+(273, 27, 'Books', NOW(), NOW()),
+(274, 27, 'Miscellaneous Publishing', NOW(), NOW()),
+(275, 27, 'Commercial Printing', NOW(), NOW()),
+(276, 27, 'Manifold Business Forms', NOW(), NOW()),
+(277, 27, 'Greeting Cards', NOW(), NOW()),
+(278, 27, 'Blankbooks, Looseleaf Binders, And Bookbinding And Related Work', NOW(), NOW()),
+(279, 27, 'Service Industries For The Printing Trade', NOW(), NOW()),
+(281, 28, 'Industrial Inorganic Chemicals', NOW(), NOW()),
+(282, 28, 'Plastics Materials And Synthetic Resins, Synthetic Rubber, Cellulosic And Other Manmade Fibers, Except Glass', NOW(), NOW()),
+(283, 28, 'Drugs', NOW(), NOW()),
+(284, 28, 'Soap, Detergents, And Cleaning Preparations; Perfumes, Cosmetics, And Other Toilet Preparations', NOW(), NOW()),
+(285, 28, 'Paints, Varnishes, Lacquers, Enamels, And Allied Products', NOW(), NOW()),
+(286, 28, 'Industrial Organic Chemicals', NOW(), NOW()),
+(287, 28, 'Agricultural Chemicals', NOW(), NOW()),
+(289, 28, 'Miscellaneous Chemical Products', NOW(), NOW()),
+(291, 29, 'Petroleum Refining', NOW(), NOW()),
+(295, 29, 'Asphalt Paving And Roofing Materials', NOW(), NOW()),
+(299, 29, 'Miscellaneous Products Of Petroleum And Coal', NOW(), NOW()),
+(301, 30, 'Tires And Inner Tubes', NOW(), NOW()),
+(302, 30, 'Rubber And Plastics Footwear', NOW(), NOW()),
+(305, 30, 'Gaskets, Packing, And Sealing Devices And Rubber And Plastics Hose And Belting', NOW(), NOW()),
+(306, 30, 'Fabricated Rubber Products, Not Elsewhere Classified', NOW(), NOW()),
+(308, 30, 'Miscellaneous Plastics Products', NOW(), NOW()),
+(311, 31, 'Leather Tanning And Finishing', NOW(), NOW()),
+(313, 31, 'Boot And Shoe Cut Stock And Findings', NOW(), NOW()),
+(314, 31, 'Footwear, Except Rubber', NOW(), NOW()),
+(315, 31, 'Leather Gloves And Mittens', NOW(), NOW()),
+(316, 31, 'Luggage', NOW(), NOW()),
+(317, 31, 'Handbags And Other Personal Leather Goods', NOW(), NOW()),
+(319, 31, 'Leather Goods, Not Elsewhere Classified', NOW(), NOW()),
+(321, 32, 'Flat Glass', NOW(), NOW()),
+(322, 32, 'Glass And Glassware, Pressed Or Blown', NOW(), NOW()),
+(323, 32, 'Glass Products, Made Of Purchased Glass', NOW(), NOW()),
+(324, 32, 'Cement, Hydraulic', NOW(), NOW()),
+(325, 32, 'Structural Clay Products', NOW(), NOW()),
+(326, 32, 'Pottery And Related Products', NOW(), NOW()),
+(327, 32, 'Concrete, Gypsum, And Plaster Products', NOW(), NOW()),
+(328, 32, 'Cut Stone And Stone Products', NOW(), NOW()),
+(329, 32, 'Abrasive, Asbestos, And Miscellaneous Nonmetallic Mineral Products', NOW(), NOW()),
+(331, 33, 'Steel Works, Blast Furnaces, And Rolling And Finishing Mills', NOW(), NOW()),
+(332, 33, 'Iron And Steel Foundries', NOW(), NOW()),
+(333, 33, 'Primary Smelting And Refining Of Nonferrous Metals', NOW(), NOW()),
+(334, 33, 'Secondary Smelting And Refining Of Nonferrous Metals', NOW(), NOW()),
+(335, 33, 'Rolling, Drawing, And Extruding Of Nonferrous Metals', NOW(), NOW()),
+(336, 33, 'Nonferrous Foundries (castings)', NOW(), NOW()),
+(339, 33, 'Miscellaneous Primary Metal Products', NOW(), NOW()),
+(341, 34, 'Metal Cans And Shipping Containers', NOW(), NOW()),
+(342, 34, 'Cutlery, Handtools, And General Hardware', NOW(), NOW()),
+(343, 34, 'Heating Equipment, Except Electric And Warm Air; And Plumbing Fixtures', NOW(), NOW()),
+(344, 34, 'Fabricated Structural Metal Products', NOW(), NOW()),
+(345, 34, 'Screw Machine Products, And Bolts, Nuts, Screws, Rivets, And Washers', NOW(), NOW()),
+(346, 34, 'Metal Forgings And Stampings', NOW(), NOW()),
+(347, 34, 'Coating, Engraving, And Allied Services', NOW(), NOW()),
+(348, 34, 'Ordnance And Accessories, Except Vehicles And Guided Missiles', NOW(), NOW()),
+(349, 34, 'Miscellaneous Fabricated Metal Products', NOW(), NOW()),
+(351, 35, 'Engines And Turbines', NOW(), NOW()),
+(352, 35, 'Farm And Garden Machinery And Equipment', NOW(), NOW()),
+(353, 35, 'Construction, Mining, And Materials Handling Machinery And Equipment', NOW(), NOW()),
+(354, 35, 'Metalworking Machinery And Equipment', NOW(), NOW()),
+(355, 35, 'Special Industry Machinery, Except Metalworking Machinery', NOW(), NOW()),
+(356, 35, 'General Industrial Machinery And Equipment', NOW(), NOW()),
+(357, 35, 'Computer And Office Equipment', NOW(), NOW()),
+(358, 35, 'Refrigeration And Service Industry Machinery', NOW(), NOW()),
+(359, 35, 'Miscellaneous Industrial And Commercial Machinery And Equipment', NOW(), NOW()),
+(361, 36, 'Electric Transmission And Distribution Equipment', NOW(), NOW()),
+(362, 36, 'Electrical Industrial Apparatus', NOW(), NOW()),
+(363, 36, 'Household Appliances', NOW(), NOW()),
+(364, 36, 'Electric Lighting And Wiring Equipment', NOW(), NOW()),
+(365, 36, 'Household Audio And Video Equipment, And Audio Recordings', NOW(), NOW()),
+(366, 36, 'Communications Equipment', NOW(), NOW()),
+(367, 36, 'Electronic Components And Accessories', NOW(), NOW()),
+(369, 36, 'Miscellaneous Electrical Machinery, Equipment, And Supplies', NOW(), NOW()),
+(371, 37, 'Motor Vehicles And Motor Vehicle Equipment', NOW(), NOW()),
+(372, 37, 'Aircraft And Parts', NOW(), NOW()),
+(373, 37, 'Ship And Boat Building And Repairing', NOW(), NOW()),
+(374, 37, 'Railroad Equipment', NOW(), NOW()),
+(375, 37, 'Motorcycles, Bicycles, And Parts', NOW(), NOW()),
+(376, 37, 'Guided Missiles And Space Vehicles And Parts', NOW(), NOW()),
+(379, 37, 'Miscellaneous Transportation Equipment', NOW(), NOW()),
+(381, 38, 'Search, Detection, Navigation, Guidance, Aeronautical, And Nautical Systems, Instruments, And Equipment', NOW(), NOW()),
+(382, 38, 'Laboratory Apparatus And Analytical, Optical, Measuring, And Controlling Instruments', NOW(), NOW()),
+(384, 38, 'Surgical, Medical, And Dental Instruments And Supplies', NOW(), NOW()),
+(385, 38, 'Ophthalmic Goods', NOW(), NOW()),
+(386, 38, 'Photographic Equipment And Supplies', NOW(), NOW()),
+(387, 38, 'Watches, Clocks, Clockwork Operated Devices, And Parts', NOW(), NOW()),
+(391, 39, 'Jewelry, Silverware, And Plated Ware', NOW(), NOW()),
+(393, 39, 'Musical Instruments', NOW(), NOW()),
+(394, 39, 'Dolls, Toys, Games And Sporting And Athletic Goods', NOW(), NOW()),
+(395, 39, 'Pens, Mechanical Pencils, Artists'' Materials, And Similar Products', NOW(), NOW()),
+(396, 39, 'Costume Jewelry, Costume Novelties, Buttons, And Miscellaneous Notions, Except Precious Metal', NOW(), NOW()),
+(399, 39, 'Miscellaneous Manufacturing Industries', NOW(), NOW()),
+(401, 40, 'Railroads', NOW(), NOW()),
+(411, 41, 'Local And Suburban Passenger Transportation', NOW(), NOW()),
+(412, 41, 'Taxicabs', NOW(), NOW()),
+(413, 41, 'Intercity And Rural Bus Transportation', NOW(), NOW()),
+(414, 41, 'Bus Charter Service', NOW(), NOW()),
+(415, 41, 'School Buses', NOW(), NOW()),
+(417, 41, 'Terminal And Service Facilities For Motor Vehicle Passenger Transportation', NOW(), NOW()),
+(421, 42, 'Trucking And Courier Services, Except Air', NOW(), NOW()),
+(422, 42, 'Public Warehousing And Storage', NOW(), NOW()),
+(423, 42, 'Terminal And Joint Terminal Maintenance Facilities For Motor Freight Transportation', NOW(), NOW()),
+(431, 43, 'United States Postal Service', NOW(), NOW()),
+(441, 44, 'Deep Sea Foreign Transportation Of Freight', NOW(), NOW()),
+(442, 44, 'Deep Sea Domestic Transportation Of Freight', NOW(), NOW()),
+(443, 44, 'Freight Transportation On The Great Lakes - St. Lawrence Seaway', NOW(), NOW()),
+(444, 44, 'Water Transportation Of Freight, Not Elsewhere Classified', NOW(), NOW()),
+(448, 44, 'Water Transportation Of Passengers', NOW(), NOW()),
+(449, 44, 'Services Incidental To Water Transportation', NOW(), NOW()),
+(451, 45, 'Air Transportation, Scheduled, And Air Courier Services', NOW(), NOW()),
+(452, 45, 'Air Transportation, Nonscheduled', NOW(), NOW()),
+(458, 45, 'Airports, Flying Fields, And Airport Terminal Services', NOW(), NOW()),
+(461, 46, 'Pipelines, Except Natural Gas', NOW(), NOW()),
+(472, 47, 'Passenger Transportation Arrangement', NOW(), NOW()),
+(473, 47, 'Arrangement Of Transportation Of Freight And Cargo', NOW(), NOW()),
+(474, 47, 'Rental Of Railroad Cars', NOW(), NOW()),
+(478, 47, 'Miscellaneous Services Incidental To Transportation', NOW(), NOW()),
+(481, 48, 'Telephone Communications', NOW(), NOW()),
+(482, 48, 'Telegraph And Other Message Communications', NOW(), NOW()),
+(483, 48, 'Radio And Television Broadcasting Stations', NOW(), NOW()),
+(484, 48, 'Cable And Other Pay Television Services', NOW(), NOW()),
+(489, 48, 'Communications Services, Not Elsewhere Classified', NOW(), NOW()),
+(491, 49, 'Electric Services', NOW(), NOW()),
+(492, 49, 'Gas Production And Distribution', NOW(), NOW()),
+(493, 49, 'Combination Electric And Gas, And Other Utility Services', NOW(), NOW()),
+(494, 49, 'Water Supply', NOW(), NOW()),
+(495, 49, 'Sanitary Services', NOW(), NOW()),
+(496, 49, 'Steam And Air-conditioning Supply', NOW(), NOW()),
+(497, 49, 'Irrigation Systems', NOW(), NOW()),
+(501, 50, 'Motor Vehicles And Motor Vehicle Parts And Supplies', NOW(), NOW()),
+(502, 50, 'Furniture And Homefurnishings', NOW(), NOW()),
+(503, 50, 'Lumber And Other Construction Materials', NOW(), NOW()),
+(504, 50, 'Professional And Commercial Equipment And Supplies', NOW(), NOW()),
+(505, 50, 'Metals And Minerals, Except Petroleum', NOW(), NOW()),
+(506, 50, 'Electrical Goods', NOW(), NOW()),
+(507, 50, 'Hardware, And Plumbing And Heating Equipment And Supplies', NOW(), NOW()),
+(508, 50, 'Machinery, Equipment, And Supplies', NOW(), NOW()),
+(509, 50, 'Miscellaneous Durable Goods', NOW(), NOW()),
+(511, 51, 'Paper And Paper Products', NOW(), NOW()),
+(512, 51, 'Drugs, Drug Proprietaries, And Druggists'' Sundries', NOW(), NOW()),
+(513, 51, 'Apparel, Piece Goods, And Notions', NOW(), NOW()),
+(514, 51, 'Groceries And Related Products', NOW(), NOW()),
+(515, 51, 'Farm-product Raw Materials', NOW(), NOW()),
+(516, 51, 'Chemicals And Allied Products', NOW(), NOW()),
+(517, 51, 'Petroleum And Petroleum Products', NOW(), NOW()),
+(518, 51, 'Beer, Wine, And Distilled Alcoholic Beverages', NOW(), NOW()),
+(519, 51, 'Miscellaneous Nondurable Goods', NOW(), NOW()),
+(521, 52, 'Lumber And Other Building Materials Dealers', NOW(), NOW()),
+(523, 52, 'Paint, Glass, And Wallpaper Stores', NOW(), NOW()),
+(525, 52, 'Hardware Stores', NOW(), NOW()),
+(526, 52, 'Retail Nurseries, Lawn And Garden Supply Stores', NOW(), NOW()),
+(527, 52, 'Mobile Home Dealers', NOW(), NOW()),
+(531, 53, 'Department Stores', NOW(), NOW()),
+(533, 53, 'Variety Stores', NOW(), NOW()),
+(539, 53, 'Miscellaneous General Merchandise Stores', NOW(), NOW()),
+(541, 54, 'Grocery Stores', NOW(), NOW()),
+(542, 54, 'Meat And Fish (seafood) Markets, Including Freezer Provisioners', NOW(), NOW()),
+(543, 54, 'Fruit And Vegetable Markets', NOW(), NOW()),
+(544, 54, 'Candy, Nut, And Confectionery Stores', NOW(), NOW()),
+(545, 54, 'Dairy Products Stores', NOW(), NOW()),
+(546, 54, 'Retail Bakeries', NOW(), NOW()),
+(549, 54, 'Miscellaneous Food Stores', NOW(), NOW()),
+(551, 55, 'Motor Vehicle Dealers (new And Used)', NOW(), NOW()),
+(552, 55, 'Motor Vehicle Dealers (used Only)', NOW(), NOW()),
+(553, 55, 'Auto And Home Supply Stores', NOW(), NOW()),
+(554, 55, 'Gasoline Service Stations', NOW(), NOW()),
+(555, 55, 'Boat Dealers', NOW(), NOW()),
+(556, 55, 'Recreational Vehicle Dealers', NOW(), NOW()),
+(557, 55, 'Motorcycle Dealers', NOW(), NOW()),
+(559, 55, 'Automotive Dealers, Not Elsewhere Classified', NOW(), NOW()),
+(561, 56, 'Men''s And Boys'' Clothing And Accessory Stores', NOW(), NOW()),
+(562, 56, 'Women''s Clothing Stores', NOW(), NOW()),
+(563, 56, 'Women''s Accessory And Specialty Stores', NOW(), NOW()),
+(564, 56, 'Children''s And Infants'' Wear Stores', NOW(), NOW()),
+(565, 56, 'Family Clothing Stores', NOW(), NOW()),
+(566, 56, 'Shoe Stores', NOW(), NOW()),
+(569, 56, 'Miscellaneous Apparel And Accessory Stores', NOW(), NOW()),
+(571, 57, 'Home Furniture And Furnishings Stores', NOW(), NOW()),
+(572, 57, 'Household Appliance Stores', NOW(), NOW()),
+(573, 57, 'Radio, Television, Consumer Electronics, And Music Stores', NOW(), NOW()),
+(581, 58, 'Eating And Drinking Places', NOW(), NOW()),
+(591, 59, 'Drug Stores And Proprietary Stores', NOW(), NOW()),
+(592, 59, 'Liquor Stores', NOW(), NOW()),
+(593, 59, 'Used Merchandise Stores', NOW(), NOW()),
+(594, 59, 'Miscellaneous Shopping Goods Stores', NOW(), NOW()),
+(596, 59, 'Nonstore Retailers', NOW(), NOW()),
+(598, 59, 'Fuel Dealers', NOW(), NOW()),
+(599, 59, 'Retail Stores, Not Elsewhere Classified', NOW(), NOW()),
+(601, 60, 'Central Reserve Depository Institutions', NOW(), NOW()),
+(602, 60, 'Commercial Banks', NOW(), NOW()),
+(603, 60, 'Savings Institutions', NOW(), NOW()),
+(606, 60, 'Credit Unions', NOW(), NOW()),
+(608, 60, 'Foreign Banking And Branches And Agencies Of Foreign Banks', NOW(), NOW()),
+(609, 60, 'Functions Related To Depository Banking', NOW(), NOW()),
+(611, 61, 'Federal And Federally-sponsored Credit Agencies', NOW(), NOW()),
+(614, 61, 'Personal Credit Institutions', NOW(), NOW()),
+(615, 61, 'Business Credit Institutions', NOW(), NOW()),
+(616, 61, 'Mortgage Bankers And Brokers', NOW(), NOW()),
+(621, 62, 'Security Brokers, Dealers, And Flotation Companies', NOW(), NOW()),
+(622, 62, 'Commodity Contracts Brokers And Dealers', NOW(), NOW()),
+(623, 62, 'Security And Commodity Exchanges', NOW(), NOW()),
+(628, 62, 'Services Allied With The Exchange Of Securities Or Commodities', NOW(), NOW()),
+(631, 63, 'Life Insurance', NOW(), NOW()),
+(632, 63, 'Accident And Health Insurance And Medical Service Plans', NOW(), NOW()),
+(633, 63, 'Fire, Marine, And Casualty Insurance', NOW(), NOW()),
+(635, 63, 'Surety Insurance', NOW(), NOW()),
+(636, 63, 'Title Insurance', NOW(), NOW()),
+(637, 63, 'Pension, Health, And Welfare Funds', NOW(), NOW()),
+(639, 63, 'Insurance Carriers, Not Elsewhere Classified', NOW(), NOW()),
+(641, 64, 'Insurance Agents, Brokers, And Service', NOW(), NOW()),
+(651, 65, 'Real Estate Operators (except Developers) And Lessors', NOW(), NOW()),
+(653, 65, 'Real Estate Agents And Managers', NOW(), NOW()),
+(654, 65, 'Title Abstract Offices', NOW(), NOW()),
+(655, 65, 'Land Subdividers And Developers', NOW(), NOW()),
+(671, 67, 'Holding Offices', NOW(), NOW()),
+(672, 67, 'Investment Offices', NOW(), NOW()),
+(673, 67, 'Trusts', NOW(), NOW()),
+(679, 67, 'Miscellaneous Investing', NOW(), NOW()),
+(701, 70, 'Hotels And Motels', NOW(), NOW()),
+(702, 70, 'Rooming And Boarding Houses', NOW(), NOW()),
+(703, 70, 'Camps And Recreational Vehicle Parks', NOW(), NOW()),
+(704, 70, 'Organization Hotels And Lodging Houses, On Membership Basis', NOW(), NOW()),
+(721, 72, 'Laundry, Cleaning, And Garment Services', NOW(), NOW()),
+(722, 72, 'Photographic Studios, Portrait', NOW(), NOW()),
+(723, 72, 'Beauty Shops', NOW(), NOW()),
+(724, 72, 'Barber Shops', NOW(), NOW()),
+(725, 72, 'Shoe Repair Shops And Shoeshine Parlors', NOW(), NOW()),
+(726, 72, 'Funeral Service And Crematories', NOW(), NOW()),
+(729, 72, 'Miscellaneous Personal Services', NOW(), NOW()),
+(731, 73, 'Advertising', NOW(), NOW()),
+(732, 73, 'Consumer Credit Reporting, Collection Agencies', NOW(), NOW()),
+(733, 73, 'Mailing, Reproduction, Commercial Art And Photography, And Stenographic Services', NOW(), NOW()),
+(734, 73, 'Services To Dwellings And Other Buildings', NOW(), NOW()),
+(735, 73, 'Miscellaneous Equipment Rental And Leasing', NOW(), NOW()),
+(736, 73, 'Personnel Supply Services', NOW(), NOW()),
+(737, 73, 'Computer Programming, Data Processing, And Other Computer Related Services', NOW(), NOW()),
+(738, 73, 'Miscellaneous Business Services', NOW(), NOW()),
+(751, 75, 'Automotive Rental And Leasing, Without Drivers', NOW(), NOW()),
+(752, 75, 'Automobile Parking', NOW(), NOW()),
+(753, 75, 'Automotive Repair Shops', NOW(), NOW()),
+(754, 75, 'Automotive Services, Except Repair', NOW(), NOW()),
+(762, 76, 'Electrical Repair Shops', NOW(), NOW()),
+(763, 76, 'Watch, Clock, And Jewelry Repair', NOW(), NOW()),
+(764, 76, 'Reupholstery And Furniture Repair', NOW(), NOW()),
+(769, 76, 'Miscellaneous Repair Shops And Related Services', NOW(), NOW()),
+(781, 78, 'Motion Picture Production And Allied Services', NOW(), NOW()),
+(782, 78, 'Motion Picture Distribution And Allied Services', NOW(), NOW()),
+(783, 78, 'Motion Picture Theaters', NOW(), NOW()),
+(784, 78, 'Video Tape Rental', NOW(), NOW()),
+(791, 79, 'Dance Studios, Schools, And Halls', NOW(), NOW()),
+(792, 79, 'Theatrical Producers (except Motion Picture), Bands, Orchestras, And Entertainers', NOW(), NOW()),
+(793, 79, 'Bowling Centers', NOW(), NOW()),
+(794, 79, 'Commercial Sports', NOW(), NOW()),
+(799, 79, 'Miscellaneous Amusement And Recreation Services', NOW(), NOW()),
+(801, 80, 'Offices And Clinics Of Doctors Of Medicine', NOW(), NOW()),
+(802, 80, 'Offices And Clinics Of Dentists', NOW(), NOW()),
+(803, 80, 'Offices And Clinics Of Doctors Of Osteopathy', NOW(), NOW()),
+(804, 80, 'Offices And Clinics Of Other Health Practitioners', NOW(), NOW()),
+(805, 80, 'Nursing And Personal Care Facilities', NOW(), NOW()),
+(806, 80, 'Hospitals', NOW(), NOW()),
+(807, 80, 'Medical And Dental Laboratories', NOW(), NOW()),
+(808, 80, 'Home Health Care Services', NOW(), NOW()),
+(809, 80, 'Miscellaneous Health And Allied Services, Not Elsewhere Classified', NOW(), NOW()),
+(811, 81, 'Legal Services', NOW(), NOW()),
+(821, 82, 'Elementary And Secondary Schools', NOW(), NOW()),
+(822, 82, 'Colleges, Universities, Professional Schools, And Junior Colleges', NOW(), NOW()),
+(823, 82, 'Libraries', NOW(), NOW()),
+(824, 82, 'Vocational Schools', NOW(), NOW()),
+(829, 82, 'Schools And Educational Services, Not Elsewhere Classified', NOW(), NOW()),
+(832, 83, 'Individual And Family Social Services', NOW(), NOW()),
+(833, 83, 'Job Training And Vocational Rehabilitation Services', NOW(), NOW()),
+(835, 83, 'Child Day Care Services', NOW(), NOW()),
+(836, 83, 'Residential Care', NOW(), NOW()),
+(839, 83, 'Social Services, Not Elsewhere Classified', NOW(), NOW()),
+(841, 84, 'Museums And Art Galleries', NOW(), NOW()),
+(842, 84, 'Arboreta And Botanical Or Zoological Gardens', NOW(), NOW()),
+(861, 86, 'Business Associations', NOW(), NOW()),
+(862, 86, 'Professional Membership Organizations', NOW(), NOW()),
+(863, 86, 'Labor Unions And Similar Labor Organizations', NOW(), NOW()),
+(864, 86, 'Civic, Social, And Fraternal Associations', NOW(), NOW()),
+(865, 86, 'Political Organizations', NOW(), NOW()),
+(866, 86, 'Religious Organizations', NOW(), NOW()),
+(869, 86, 'Membership Organizations, Not Elsewhere Classified', NOW(), NOW()),
+(871, 87, 'Engineering, Architectural, And Surveying', NOW(), NOW()),
+(872, 87, 'Accounting, Auditing, And Bookkeeping Services', NOW(), NOW()),
+(873, 87, 'Research, Development, And Testing Services', NOW(), NOW()),
+(874, 87, 'Management And Public Relations Services', NOW(), NOW()),
+(881, 88, 'Private Households', NOW(), NOW()),
+(899, 89, 'Services, Not Elsewhere Classified', NOW(), NOW()),
+(911, 91, 'Executive Offices', NOW(), NOW()),
+(912, 91, 'Legislative Bodies', NOW(), NOW()),
+(913, 91, 'Executive And Legislative Offices Combined', NOW(), NOW()),
+(919, 91, 'General Government, Not Elsewhere Classified', NOW(), NOW()),
+(921, 92, 'Courts', NOW(), NOW()),
+(922, 92, 'Public Order And Safety', NOW(), NOW()),
+(931, 93, 'Public Finance, Taxation, And Monetary Policy', NOW(), NOW()),
+(941, 94, 'Administration Of Educational Programs', NOW(), NOW()),
+(943, 94, 'Administration Of Public Health Programs', NOW(), NOW()),
+(944, 94, 'Administration Of Social, Human Resource And Income Maintenance Programs', NOW(), NOW()),
+(945, 94, 'Administration Of Veterans'' Affairs, Except Health And Insurance', NOW(), NOW()),
+(951, 95, 'Administration Of Environmental Quality Programs', NOW(), NOW()),
+(953, 95, 'Administration Of Housing And Urban Development Programs', NOW(), NOW()),
+(961, 96, 'Administration Of General Economic Programs', NOW(), NOW()),
+(962, 96, 'Regulation And Administration Of Transportation Programs', NOW(), NOW()),
+(963, 96, 'Regulation And Administration Of Communications, Electric, Gas, And Other Utilities', NOW(), NOW()),
+(964, 96, 'Regulation Of Agricultural Marketing And Commodities', NOW(), NOW()),
+(965, 96, 'Regulation, Licensing, And Inspection Of Miscellaneous Commercial Sectors', NOW(), NOW()),
+(966, 96, 'Space Research And Technology', NOW(), NOW()),
+(971, 97, 'National Security', NOW(), NOW()),
+(972, 97, 'International Affairs', NOW(), NOW()),
+(999, 99, 'Nonclassifiable Establishments', NOW(), NOW());
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS sec.entities CASCADE;
+DROP TABLE IF EXISTS sec.entity_names CASCADE;
\ No newline at end of file
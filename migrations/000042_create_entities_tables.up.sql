@@ -0,0 +1,33 @@
+-- Names each CIK is known under, by source: the cik-lookup-data.txt
+-- registry, the company tickers files and the filings. Registry names are
+-- seen on the dates the registry was reconciled, filing names on the dates
+-- of the filings
+CREATE TABLE sec.entity_names (
+    id serial PRIMARY KEY,
+    cik integer NOT NULL,
+    name text NOT NULL,
+    source text NOT NULL,
+    first_seen date,
+    last_seen date,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT entity_names_unique_keys UNIQUE (cik, name, source)
+);
+
+-- One row per CIK known from any source, rebuilt by "sec ciks reconcile"
+CREATE TABLE sec.entities (
+    id serial PRIMARY KEY,
+    cik integer NOT NULL,
+    name text NOT NULL,
+    first_seen date,
+    last_seen date,
+    files_xbrl boolean NOT NULL DEFAULT false,
+    in_registry boolean NOT NULL DEFAULT false,
+    in_filings boolean NOT NULL DEFAULT false,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT entities_unique_keys UNIQUE (cik)
+);
+CREATE INDEX entities_unregistered ON sec.entities (cik) WHERE in_filings AND NOT in_registry;
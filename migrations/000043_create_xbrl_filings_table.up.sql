@@ -0,0 +1,20 @@
+-- Index status of the XBRL facts of each filing, kept by `sec xbrl`. A
+-- filing is "indexed" once all of its facts are in xbrl.num, written in the
+-- same transaction, or "failed" with the error that stopped it
+CREATE TABLE xbrl.filings (
+    id serial PRIMARY KEY,
+    adsh text NOT NULL,
+    status text NOT NULL,
+    error text,
+    indexed_at timestamp with time zone,
+    created_at timestamp with time zone,
+    updated_at timestamp with time zone,
+    deleted_at timestamp with time zone,
+    CONSTRAINT xbrl_filings_unique_keys UNIQUE (adsh)
+);
+
+-- Filings with facts from before the status was kept
+INSERT INTO xbrl.filings (adsh, status, indexed_at, created_at, updated_at)
+SELECT adsh, 'indexed', MAX(created_at), NOW(), NOW()
+FROM xbrl.num
+GROUP BY adsh;
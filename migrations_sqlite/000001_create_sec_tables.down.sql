@@ -0,0 +1,11 @@
+DROP TABLE IF EXISTS sec.company_slugs;
+DROP TABLE IF EXISTS sec.filing_sections;
+DROP TABLE IF EXISTS sec.regen_marks;
+DROP TABLE IF EXISTS sec.sics;
+DROP TABLE IF EXISTS sec.skipped_files;
+DROP TABLE IF EXISTS sec.events;
+DROP TABLE IF EXISTS sec.downloads;
+DROP TABLE IF EXISTS sec.secItemFile;
+DROP TABLE IF EXISTS sec.worklist;
+DROP TABLE IF EXISTS sec.tickers;
+DROP TABLE IF EXISTS sec.ciks;
\ No newline at end of file
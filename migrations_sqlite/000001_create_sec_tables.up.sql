@@ -0,0 +1,151 @@
+-- The sec schema of migrations/ as of 000042, for the tables of downloading,
+-- indexing and events. sec is a database file attached to every connection,
+-- see database.SQLiteSchemas
+CREATE TABLE sec.ciks (
+    id integer PRIMARY KEY,
+    cik integer NOT NULL,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT cik_unique UNIQUE (cik)
+);
+
+CREATE TABLE sec.tickers (
+    id integer PRIMARY KEY,
+    ticker text,
+    cik integer,
+    title text,
+    exchange text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT "ALL_UNIQUE" UNIQUE (cik, ticker, title),
+    CONSTRAINT fk_cik FOREIGN KEY (cik) REFERENCES ciks (cik)
+);
+
+CREATE TABLE sec.worklist (
+    id integer PRIMARY KEY,
+    year integer,
+    month integer,
+    will_download boolean,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT year_month UNIQUE (year, month)
+);
+
+-- Columns are lower case, as Postgres folds them and scans rely on it.
+-- xbrlbodyoffsets is text in the form of a Postgres array, e.g. {0,12,40}
+CREATE TABLE sec.secItemFile (
+    id integer PRIMARY KEY,
+    title text,
+    link text,
+    guid text,
+    enclosure_url text,
+    enclosure_length integer,
+    enclosure_type text,
+    description text,
+    pubdate timestamp,
+    companyname text,
+    formtype text,
+    fillingdate timestamp,
+    ciknumber integer,
+    accessionnumber text,
+    filenumber text,
+    acceptancedatetime text,
+    period text,
+    assistantdirector text,
+    assignedsic integer,
+    fiscalyearend integer,
+    xbrlsequence integer,
+    xbrlfile text,
+    xbrltype text,
+    xbrlsize integer,
+    xbrldescription text,
+    xbrlinlinexbrl bool,
+    xbrlurl text,
+    xbrlbody text,
+    xbrlfilepath text,
+    xbrlbodyoffsets text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT xbrl_file UNIQUE (xbrlsequence, xbrlfile, xbrltype, xbrlsize, xbrldescription, xbrlinlinexbrl, xbrlurl),
+    CONSTRAINT xbrl_zip_file UNIQUE (ciknumber, accessionnumber, xbrlfile, xbrlsize)
+);
+
+CREATE TABLE sec.downloads (
+    id integer PRIMARY KEY,
+    url text,
+    etag text,
+    size integer,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT url_constraint UNIQUE (url)
+);
+
+CREATE TABLE sec.events (
+    id integer PRIMARY KEY,
+    ev text,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE sec.skipped_files (
+    id integer PRIMARY KEY,
+    url text,
+    created_at timestamp DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE sec.sics (
+    id integer PRIMARY KEY,
+    sic integer,
+    office text,
+    title text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT sic_unique UNIQUE (sic)
+);
+
+CREATE TABLE sec.regen_marks (
+    id integer PRIMARY KEY,
+    name text,
+    secitemfile_updated_at timestamp,
+    sub_updated_at timestamp,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT regen_mark UNIQUE (name)
+);
+
+CREATE TABLE sec.filing_sections (
+    id integer PRIMARY KEY,
+    accessionnumber text NOT NULL,
+    xbrlfile text,
+    formtype text,
+    part text NOT NULL DEFAULT '',
+    item text NOT NULL,
+    name text,
+    title text,
+    body text,
+    position integer,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT filing_sections_unique_keys UNIQUE (accessionnumber, part, item)
+);
+CREATE INDEX sec.filing_sections_name ON filing_sections (name);
+
+CREATE TABLE sec.company_slugs (
+    id integer PRIMARY KEY,
+    slug text NOT NULL,
+    cik integer NOT NULL,
+    name text NOT NULL,
+    canonical boolean NOT NULL DEFAULT false,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT company_slugs_unique_keys UNIQUE (slug)
+);
+CREATE UNIQUE INDEX sec.company_slugs_canonical ON company_slugs (cik) WHERE canonical;
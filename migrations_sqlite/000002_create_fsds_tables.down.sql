@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS fsds.pre;
+DROP TABLE IF EXISTS fsds.num;
+DROP TABLE IF EXISTS fsds.tag;
+DROP TABLE IF EXISTS fsds.sub;
\ No newline at end of file
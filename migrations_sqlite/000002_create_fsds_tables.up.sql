@@ -0,0 +1,99 @@
+-- The fsds schema of migrations/ as of 000042, fsds.metrics is in 000003. Tables
+-- of different database files can't reference each other, so fsds.sub has
+-- no foreign key to sec.ciks
+CREATE TABLE fsds.sub (
+    id integer PRIMARY KEY,
+    adsh text,
+    cik integer,
+    name text,
+    sic text,
+    countryba text,
+    stprba text,
+    cityba text,
+    zipba text,
+    bas1 text,
+    bas2 text,
+    baph text,
+    countryma text,
+    strpma text,
+    cityma text,
+    zipma text,
+    mas1 text,
+    mas2 text,
+    countryinc text,
+    stprinc text,
+    ein text,
+    former text,
+    changed text,
+    afs text,
+    wksi text,
+    fye text,
+    form text,
+    period date,
+    fy text,
+    fp text,
+    filled date,
+    accepted timestamp,
+    prevrpt text,
+    detail text,
+    instance text,
+    nciks text,
+    aciks text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT sub_item UNIQUE (adsh, cik, name, sic)
+);
+
+CREATE TABLE fsds.tag (
+    id integer PRIMARY KEY,
+    tag text,
+    version text,
+    custom text,
+    abstract text,
+    datatype text,
+    lord text,
+    crdr text,
+    tlabel text,
+    doc text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT tag_version UNIQUE (tag, version)
+);
+
+CREATE TABLE fsds.num (
+    id integer PRIMARY KEY,
+    adsh text,
+    tag text,
+    version text,
+    coreg text,
+    ddate text,
+    qtrs text,
+    uom text,
+    value text,
+    footnote text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT unique_keys UNIQUE (adsh, tag, version, coreg, ddate, qtrs, uom),
+    FOREIGN KEY (tag, version) REFERENCES tag (tag, version)
+);
+
+CREATE TABLE fsds.pre (
+    id integer PRIMARY KEY,
+    adsh text,
+    report text,
+    line text,
+    stmt text,
+    inpth text,
+    rfile text,
+    tag text,
+    version text,
+    plabel text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT adsh_report_line UNIQUE (adsh, report, line),
+    FOREIGN KEY (tag, version) REFERENCES tag (tag, version)
+);
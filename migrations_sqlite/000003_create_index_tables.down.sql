@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS mfd.txt;
+DROP TABLE IF EXISTS mfd.num;
+DROP TABLE IF EXISTS mfd.cal;
+DROP TABLE IF EXISTS mfd.lab;
+DROP TABLE IF EXISTS mfd.tag;
+DROP TABLE IF EXISTS mfd.sub;
+DROP TABLE IF EXISTS fsds.metrics;
+DROP TABLE IF EXISTS sec.name_history;
+DROP TABLE IF EXISTS sec.ticker_history;
\ No newline at end of file
@@ -0,0 +1,204 @@
+-- The tables "sec index" fills besides sec and fsds of 000001 and 000002:
+-- sec.ticker_history, sec.name_history, fsds.metrics and the mfd schema of
+-- migrations/ as of 000042. mfd.sub has no foreign key to sec.ciks, see
+-- 000002
+CREATE TABLE sec.ticker_history (
+    id integer PRIMARY KEY,
+    cik integer NOT NULL,
+    ticker text NOT NULL,
+    exchange text NOT NULL DEFAULT '',
+    valid_from date NOT NULL,
+    valid_to date,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT fk_cik FOREIGN KEY (cik) REFERENCES ciks (cik)
+);
+CREATE UNIQUE INDEX sec.ticker_history_current ON ticker_history (cik, ticker) WHERE valid_to IS NULL;
+CREATE INDEX sec.ticker_history_ticker ON ticker_history (UPPER(ticker));
+
+CREATE TABLE sec.name_history (
+    id integer PRIMARY KEY,
+    cik integer NOT NULL,
+    name text NOT NULL,
+    valid_from date NOT NULL,
+    valid_to date,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT name_history_unique_keys UNIQUE (cik, valid_from)
+);
+CREATE INDEX sec.name_history_name ON name_history (UPPER(name));
+
+CREATE TABLE fsds.metrics (
+    id integer PRIMARY KEY,
+    cik integer,
+    adsh text,
+    ddate text,
+    fy text,
+    fp text,
+    form text,
+    kind text,
+    revenues numeric,
+    gross_profit numeric,
+    operating_income numeric,
+    net_income numeric,
+    eps_diluted numeric,
+    assets numeric,
+    liabilities numeric,
+    equity numeric,
+    current_assets numeric,
+    current_liabilities numeric,
+    long_term_debt numeric,
+    shares_outstanding numeric,
+    gross_margin numeric,
+    operating_margin numeric,
+    net_margin numeric,
+    roe numeric,
+    current_ratio numeric,
+    debt_to_equity numeric,
+    sub_updated_at timestamp,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT metrics_cik_ddate_kind UNIQUE (cik, ddate, kind)
+);
+
+CREATE TABLE mfd.sub (
+    id integer PRIMARY KEY,
+    adsh text UNIQUE,
+    cik integer,
+    name text,
+    countryba text,
+    stprba text,
+    cityba text,
+    zipba text,
+    bas1 text,
+    bas2 text,
+    baph text,
+    countryma text,
+    strpma text,
+    cityma text,
+    zipma text,
+    mas1 text,
+    mas2 text,
+    countryinc text,
+    stprinc text,
+    ein text,
+    former text,
+    changed text,
+    fye text,
+    pdate text,
+    effdate text,
+    form text,
+    filed text,
+    accepted timestamp,
+    instance text,
+    nciks text,
+    aciks text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp
+);
+
+CREATE TABLE mfd.tag (
+    id integer PRIMARY KEY,
+    tag text,
+    version text,
+    custom text,
+    abstract text,
+    datatype text,
+    lord text,
+    tlabel text,
+    doc text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT tag_version UNIQUE (tag, version)
+);
+
+CREATE TABLE mfd.lab (
+    id integer PRIMARY KEY,
+    adsh text,
+    tag text,
+    version text,
+    std text,
+    terse text,
+    verbose_val text,
+    total text,
+    negated text,
+    negatedterse text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT adsh_tag_version UNIQUE (adsh, tag, version)
+);
+
+CREATE TABLE mfd.cal (
+    id integer PRIMARY KEY,
+    adsh text,
+    grp text,
+    arc text,
+    negative text,
+    ptag text,
+    pversion text,
+    ctag text,
+    cversion text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT adsh_grp UNIQUE (adsh, grp, arc)
+);
+
+CREATE TABLE mfd.num (
+    id integer PRIMARY KEY,
+    adsh text,
+    tag text,
+    version text,
+    ddate text,
+    uom text,
+    series text,
+    class text,
+    measure text,
+    document text,
+    otherdims text,
+    iprx text,
+    value text,
+    footnote text,
+    footlen text,
+    dimn text,
+    dcml text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT unique_keys UNIQUE (adsh, tag, version, ddate, uom, series, class, measure, document, otherdims, iprx),
+    FOREIGN KEY (tag, version) REFERENCES tag (tag, version)
+);
+
+CREATE TABLE mfd.txt (
+    id integer PRIMARY KEY,
+    adsh text,
+    tag text,
+    version text,
+    ddate text,
+    lang text,
+    series text,
+    class text,
+    measure text,
+    document text,
+    otherdims text,
+    iprx text,
+    dcml text,
+    escaped text,
+    srclen text,
+    txtlen text,
+    footnote text,
+    footlen text,
+    context text,
+    value text,
+    created_at timestamp,
+    updated_at timestamp,
+    deleted_at timestamp,
+    CONSTRAINT txt_unique_keys UNIQUE (adsh, tag, version, ddate, series, class, measure, document, otherdims, iprx),
+    FOREIGN KEY (tag, version) REFERENCES tag (tag, version)
+);
@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS sec.sic_naics;
+DROP TABLE IF EXISTS sec.sic_industry_groups;
+DROP TABLE IF EXISTS sec.sic_major_groups;
+DROP TABLE IF EXISTS sec.sic_divisions;
+DROP TABLE IF EXISTS sec.entities;
+DROP TABLE IF EXISTS sec.entity_names;
+DROP TABLE IF EXISTS sec.company_search;
\ No newline at end of file
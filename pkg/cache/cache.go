@@ -1,16 +1,65 @@
 package cache
 
 import (
-	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/equres/sec/pkg/config"
-	"github.com/go-redis/redis/v8"
+	log "github.com/sirupsen/logrus"
 )
 
-type Cache struct {
-	Redis *redis.Client
+// DefaultTTL is how long page data filled on a cache miss is kept when
+// cachettl is not set in the config file
+const DefaultTTL = 24 * time.Hour
+
+// Cache keeps the page data generated by "sec regen" and computed on a
+// cache miss, the rendered pages and the URL stats. It's kept in Redis, or
+// in the process for tests and single node deployments, see NewCache.
+type Cache interface {
+	// Get returns the value of k, or ErrMiss if it isn't cached
+	Get(k string) (string, error)
+	MustGet(k string) (string, error)
+	Set(k, v string) error
+	MustSet(k, v string) error
+
+	// SetWithTTL stores v for ttl, or until it's deleted if ttl is zero
+	SetWithTTL(k, v string, ttl time.Duration) error
+	Delete(keys ...string) error
+
+	// DeletePrefix removes every key starting with prefix
+	DeletePrefix(prefix string) error
+
+	// GetOrFill returns the value of k. On a miss the value is computed
+	// with fill and stored for the TTL of the cache, so pages work without
+	// running "sec regen" first. If the cache is unavailable the computed
+	// value is still returned.
+	GetOrFill(k string, fill func() (string, error)) (string, error)
+
+	// DeletePages removes the rendered HTML of the pages at paths, or of
+	// every page if no path is given
+	DeletePages(paths ...string) error
+
+	// IncrURLStat counts a visit of url, see URLStats
+	IncrURLStat(url string) error
+
+	// URLStats returns the number of visits of every URL counted
+	URLStats() (map[string]int, error)
+
+	Ping() error
+	Close() error
 }
 
+// ErrMiss is returned by Get for keys that aren't cached
+var ErrMiss = errors.New("cache: key not found")
+
+// Backends of cache.backend
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+)
+
 const (
 	SECCacheStats                   string = "cache.SECCacheStats"
 	SECTopFiveRecentFilings         string = "cache.SECTopFiveRecentFilings"
@@ -26,6 +75,8 @@ const (
 	SECCompanyFilings               string = "cache.SECCompanyFilings"
 	SECCompanyFilingsHTML           string = "cache.SECCompanyFilingsHTML"
 	SECSICs                         string = "cache.SECSICs"
+	SECSICTree                      string = "cache.SECSICTree"
+	SECSICPresetCompanies           string = "cache.SECSICPresetCompanies"
 	SECCompaniesWithSIC             string = "cache.SECCompaniesWithSIC"
 	SECHourlyDownloadStats          string = "cache.SECHourlyDownloadStats"
 	SECHours                        string = "cache.SECHours"
@@ -39,52 +90,184 @@ const (
 	SECLastSuccessfulDBBackup       string = "cache.SECLastSuccessfulDBBackup"
 	SECLastSuccessfulDBBackupToCa2  string = "cache.SECLastSuccessfulDBBackupToCa2"
 	SECLastSuccessfulDBBackupToWaw1 string = "cache.SECLastSuccessfulDBBackupToWaw1"
+	SECPage                         string = "cache.SECPage"
 )
 
+// NewCache returns the cache of cache.backend in the config, Redis or
+// memory. Memory caches of the same cache.file are shared in the process,
+// so "sec serve" sees what it invalidates itself.
 func NewCache(cfg *config.Config) Cache {
-	rdb := redis.NewClient(&redis.Options{
-		Addr:     cfg.Redis.GetRedisURL(),
-		Password: cfg.Redis.Password,
-		DB:       0,
-	})
-
-	return Cache{
-		Redis: rdb,
+	ttl := cfg.Main.CacheTTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
 	}
-}
 
-func (c *Cache) Set(k, v string) error {
-	err := c.Redis.Set(context.Background(), k, v, 0).Err()
-	if err != nil {
-		return err
+	if cfg.CacheBackend() == BackendMemory {
+		return sharedMemory(cfg.Cache, ttl)
 	}
+	return NewRedis(cfg.Redis, ttl)
+}
+
+func MonthsInYearKey(year int) string {
+	return fmt.Sprintf("%v_%v", SECMonthsInYear, year)
+}
+
+func DaysInMonthKey(year int, month int) string {
+	return fmt.Sprintf("%v_%v_%v", SECDaysInMonth, year, month)
+}
+
+func CompaniesInDayKey(year int, month int, day int) string {
+	return fmt.Sprintf("%v_%v_%v_%v", SECCompaniesInDay, year, month, day)
+}
+
+func FilingsInDayKey(year int, month int, day int, cik int) string {
+	return fmt.Sprintf("%v_%v_%v_%v_%v", SECFilingsInDay, year, month, day, cik)
+}
+
+func CompanyFilingsHTMLKey(cik int) string {
+	return fmt.Sprintf("%v_%v", SECCompanyFilingsHTML, cik)
+}
+
+func CompaniesWithSICKey(sic string) string {
+	return fmt.Sprintf("%v_%v", SECCompaniesWithSIC, sic)
+}
+
+// SICPresetCompaniesKey is the key of the companies of a SIC preset,
+// refreshed by "sec regen"
+func SICPresetCompaniesKey(preset string) string {
+	return fmt.Sprintf("%v_%v", SECSICPresetCompanies, preset)
+}
 
-	return nil
+// PageKey is the key of the rendered HTML of the page at path
+func PageKey(path string) string {
+	return fmt.Sprintf("%v_%v", SECPage, path)
 }
 
-func (c *Cache) Get(k string) (string, error) {
-	v, err := c.Redis.Get(context.Background(), k).Result()
+func getOrFill(c Cache, ttl time.Duration, k string, fill func() (string, error)) (string, error) {
+	v, err := c.Get(k)
+	if err == nil {
+		return v, nil
+	}
+	if err != ErrMiss {
+		log.Error("could not read ", k, " from the cache: ", err)
+	}
+
+	v, err = fill()
 	if err != nil {
 		return "", err
 	}
 
-	return v, err
-}
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
 
-func (c *Cache) MustSet(k, v string) error {
-	err := c.Redis.Set(context.Background(), k, v, 0).Err()
+	err = c.SetWithTTL(k, v, ttl)
 	if err != nil {
-		return err
+		log.Error("could not store ", k, " in the cache: ", err)
 	}
 
-	return nil
+	return v, nil
 }
 
-func (c *Cache) MustGet(k string) (string, error) {
-	v, err := c.Redis.Get(context.Background(), k).Result()
-	if err != nil {
-		return "", err
+// Invalidation collects the keys of the page data changed while indexing,
+// so that each is deleted once with Flush at the end of the run instead of
+// after every filing, which would have the most expensive pages computed
+// over and over during a bulk index.
+type Invalidation struct {
+	keys map[string]bool
+}
+
+func NewInvalidation() *Invalidation {
+	return &Invalidation{keys: make(map[string]bool)}
+}
+
+func (inv *Invalidation) add(keys ...string) {
+	for _, k := range keys {
+		inv.keys[k] = true
 	}
+}
+
+// AddFiling adds the page data a new or updated filing shows up in: its
+// year, month and day listings and the filings of its CIK, together with
+// the rendered pages. The company page is keyed by slug and is added by
+// the caller with AddPages. A zero filingDate only adds the CIK keys.
+func (inv *Invalidation) AddFiling(filingDate time.Time, cik int) {
+	inv.add(CompanyFilingsHTMLKey(cik))
+
+	if filingDate.IsZero() {
+		return
+	}
+
+	year, month, day := filingDate.Year(), int(filingDate.Month()), filingDate.Day()
+	inv.add(
+		MonthsInYearKey(year),
+		DaysInMonthKey(year, month),
+		CompaniesInDayKey(year, month, day),
+		FilingsInDayKey(year, month, day, cik),
+		PageKey(fmt.Sprintf("/filings/%v", year)),
+		PageKey(fmt.Sprintf("/filings/%v/%v", year, month)),
+		PageKey(fmt.Sprintf("/filings/%v/%v/%v", year, month, day)),
+		PageKey(fmt.Sprintf("/filings/%v/%v/%v/%v", year, month, day, cik)),
+	)
+}
+
+// AddSIC adds the companies of the SIC with its page
+func (inv *Invalidation) AddSIC(sic string) {
+	inv.add(CompaniesWithSICKey(sic), PageKey("/sic/"+sic))
+}
 
-	return v, err
+// AddLists adds the lists of all companies and SICs, with their pages, and
+// the companies of the SIC presets. They only change when a company files
+// under a SIC for the first time, so the caller adds them only then.
+func (inv *Invalidation) AddLists(presets ...string) {
+	inv.add(
+		SECCompanies,
+		SECCompanySlugsHTML,
+		SECSICs,
+		SECSICTree,
+		PageKey("/company"),
+		PageKey("/sic"),
+	)
+	for _, preset := range presets {
+		inv.add(SICPresetCompaniesKey(preset))
+	}
+}
+
+// AddPages adds the rendered HTML of the pages at paths
+func (inv *Invalidation) AddPages(paths ...string) {
+	for _, path := range paths {
+		inv.add(PageKey(path))
+	}
+}
+
+// Keys returns the keys collected since the last Flush, sorted
+func (inv *Invalidation) Keys() []string {
+	keys := make([]string, 0, len(inv.keys))
+	for k := range inv.keys {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Flush deletes the keys collected so far from c and starts over
+func (inv *Invalidation) Flush(c Cache) error {
+	keys := inv.Keys()
+	inv.keys = make(map[string]bool)
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.Delete(keys...)
+}
+
+func deletePages(c Cache, paths []string) error {
+	if len(paths) == 0 {
+		return c.DeletePrefix(PageKey(""))
+	}
+
+	var keys []string
+	for _, path := range paths {
+		keys = append(keys, PageKey(path))
+	}
+	return c.Delete(keys...)
 }
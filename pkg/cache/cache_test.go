@@ -0,0 +1,137 @@
+package cache
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGetOrFill(t *testing.T) {
+	m := NewMemory(1<<20, "", time.Hour)
+
+	fills := 0
+	fill := func() (string, error) {
+		fills++
+		return "[]", nil
+	}
+
+	// The first call fills the key, the second one reads it
+	for i := 0; i < 2; i++ {
+		v, err := m.GetOrFill(SECSICs, fill)
+		if err != nil || v != "[]" {
+			t.Fatalf("GetOrFill() = %q, %v", v, err)
+		}
+	}
+	if fills != 1 {
+		t.Errorf("fill was called %d times, want once", fills)
+	}
+
+	// Errors of fill are returned and nothing is stored
+	_, err := m.GetOrFill(SECCompanies, func() (string, error) {
+		return "", errors.New("database is down")
+	})
+	if err == nil || !strings.Contains(err.Error(), "database is down") {
+		t.Errorf("GetOrFill() = %v, want the error of fill", err)
+	}
+	if _, err := m.Get(SECCompanies); err != ErrMiss {
+		t.Errorf("Get(%v) = %v, want nothing stored after a failed fill", SECCompanies, err)
+	}
+
+	// Filled keys expire after the TTL of the cache
+	short := NewMemory(1<<20, "", time.Nanosecond)
+	short.GetOrFill(SECSICs, fill)
+	time.Sleep(time.Millisecond)
+	if _, err := short.Get(SECSICs); err != ErrMiss {
+		t.Errorf("Get(%v) = %v, want filled keys to expire", SECSICs, err)
+	}
+}
+
+func TestInvalidation(t *testing.T) {
+	filingDate := time.Date(2022, 1, 28, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		add  func(inv *Invalidation)
+		want []string
+		kept []string
+	}{
+		{
+			name: "filing",
+			add: func(inv *Invalidation) {
+				inv.AddFiling(filingDate, 320193)
+			},
+			want: []string{
+				CompanyFilingsHTMLKey(320193),
+				MonthsInYearKey(2022),
+				DaysInMonthKey(2022, 1),
+				CompaniesInDayKey(2022, 1, 28),
+				FilingsInDayKey(2022, 1, 28, 320193),
+				PageKey("/filings/2022"),
+				PageKey("/filings/2022/1"),
+				PageKey("/filings/2022/1/28"),
+				PageKey("/filings/2022/1/28/320193"),
+			},
+			kept: []string{SECCompanies, SECSICs, FilingsInDayKey(2022, 1, 28, 1), CompaniesWithSICKey("3571")},
+		},
+		{
+			name: "filing without a date",
+			add: func(inv *Invalidation) {
+				inv.AddFiling(time.Time{}, 320193)
+			},
+			want: []string{CompanyFilingsHTMLKey(320193)},
+			kept: []string{MonthsInYearKey(2022), SECCompanies},
+		},
+		{
+			name: "SIC and pages",
+			add: func(inv *Invalidation) {
+				inv.AddSIC("3571")
+				inv.AddPages("/company/apple-inc", "/filing/0000320193-22-000007")
+			},
+			want: []string{CompaniesWithSICKey("3571"), PageKey("/sic/3571"), PageKey("/company/apple-inc"), PageKey("/filing/0000320193-22-000007")},
+			kept: []string{CompaniesWithSICKey("3572"), PageKey("/company"), PageKey("/sic")},
+		},
+		{
+			name: "lists",
+			add: func(inv *Invalidation) {
+				inv.AddLists("tech")
+			},
+			want: []string{SECCompanies, SECCompanySlugsHTML, SECSICs, SECSICTree, PageKey("/company"), PageKey("/sic"), SICPresetCompaniesKey("tech")},
+			kept: []string{CompanyFilingsHTMLKey(320193), CompaniesWithSICKey("3571")},
+		},
+	}
+
+	for _, tt := range tests {
+		m := NewMemory(1<<20, "", time.Hour)
+		for _, k := range append(append([]string{}, tt.want...), tt.kept...) {
+			m.Set(k, "cached")
+		}
+
+		inv := NewInvalidation()
+		tt.add(inv)
+		// Adding twice doesn't repeat keys
+		tt.add(inv)
+		if len(inv.Keys()) != len(tt.want) {
+			t.Errorf("%v: Keys() = %v, want %v", tt.name, inv.Keys(), tt.want)
+		}
+
+		err := inv.Flush(m)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, k := range tt.want {
+			if _, err := m.Get(k); err != ErrMiss {
+				t.Errorf("%v: Get(%v) = %v, want it deleted", tt.name, k, err)
+			}
+		}
+		for _, k := range tt.kept {
+			if _, err := m.Get(k); err != nil {
+				t.Errorf("%v: Get(%v) = %v, want it kept", tt.name, k, err)
+			}
+		}
+
+		if keys := inv.Keys(); len(keys) != 0 {
+			t.Errorf("%v: Keys() after Flush = %v, want none", tt.name, keys)
+		}
+	}
+}
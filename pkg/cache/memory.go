@@ -0,0 +1,287 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package cache
+
+import (
+	"container/list"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/equres/sec/pkg/config"
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultMaxSizeMB is the size of the memory cache when cache.maxsizemb
+// is not set in the config file
+const DefaultMaxSizeMB = 256
+
+// Memory is the cache kept in the process, for tests and single node
+// deployments without a Redis server. It holds up to MaxBytes of keys and
+// values, evicting the least recently used entries beyond it. With a file
+// it's saved on Close and loaded by NewMemory, so the page data generated
+// by "sec regen" is there for the next "sec serve". Processes running at
+// the same time don't see each other's changes.
+type Memory struct {
+	TTL      time.Duration
+	MaxBytes int64
+
+	mu      sync.Mutex
+	file    string
+	size    int64
+	order   *list.List // of *memoryEntry, most recently used first
+	entries map[string]*list.Element
+	stats   map[string]int
+}
+
+type memoryEntry struct {
+	Key     string    `json:"key"`
+	Value   string    `json:"value"`
+	Expires time.Time `json:"expires"`
+}
+
+func (e *memoryEntry) size() int64 {
+	return int64(len(e.Key) + len(e.Value))
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && now.After(e.Expires)
+}
+
+// memoryFile is the file of a memory cache, with entries in the order of
+// Memory.order
+type memoryFile struct {
+	Entries  []*memoryEntry `json:"entries"`
+	URLStats map[string]int `json:"url_stats"`
+}
+
+var memoriesMu sync.Mutex
+var memories = make(map[string]*Memory)
+
+func sharedMemory(cfg config.CacheConfig, ttl time.Duration) *Memory {
+	memoriesMu.Lock()
+	defer memoriesMu.Unlock()
+
+	m, ok := memories[cfg.File]
+	if !ok {
+		maxSizeMB := cfg.MaxSizeMB
+		if maxSizeMB <= 0 {
+			maxSizeMB = DefaultMaxSizeMB
+		}
+		m = NewMemory(int64(maxSizeMB)<<20, cfg.File, ttl)
+		memories[cfg.File] = m
+	}
+	return m
+}
+
+// NewMemory returns a memory cache of maxBytes saved to file, or not saved
+// if file is empty. Values filled by GetOrFill are kept for ttl. A file
+// that can't be read is logged and the cache starts empty.
+func NewMemory(maxBytes int64, file string, ttl time.Duration) *Memory {
+	m := &Memory{
+		TTL:      ttl,
+		MaxBytes: maxBytes,
+		file:     file,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element),
+		stats:    make(map[string]int),
+	}
+
+	if file != "" {
+		err := m.load()
+		if err != nil && !os.IsNotExist(err) {
+			log.Error("could not load the cache from ", file, ": ", err)
+		}
+	}
+	return m
+}
+
+func (m *Memory) load() error {
+	data, err := ioutil.ReadFile(m.file)
+	if err != nil {
+		return err
+	}
+
+	var f memoryFile
+	err = json.Unmarshal(data, &f)
+	if err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	for _, entry := range f.Entries {
+		if entry.expired(now) {
+			continue
+		}
+		m.removeLocked(entry.Key)
+		m.entries[entry.Key] = m.order.PushBack(entry)
+		m.size += entry.size()
+	}
+	m.evictLocked()
+
+	for url, count := range f.URLStats {
+		m.stats[url] += count
+	}
+	return nil
+}
+
+// Save writes the cache to its file, replacing it atomically
+func (m *Memory) Save() error {
+	if m.file == "" {
+		return nil
+	}
+
+	m.mu.Lock()
+	now := time.Now()
+	f := memoryFile{URLStats: make(map[string]int, len(m.stats))}
+	for e := m.order.Front(); e != nil; e = e.Next() {
+		entry := e.Value.(*memoryEntry)
+		if !entry.expired(now) {
+			f.Entries = append(f.Entries, entry)
+		}
+	}
+	for url, count := range m.stats {
+		f.URLStats[url] = count
+	}
+	data, err := json.Marshal(f)
+	m.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	return config.WriteFileAtomic(m.file, data, 0600)
+}
+
+func (m *Memory) Get(k string) (string, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	e, ok := m.entries[k]
+	if !ok {
+		return "", ErrMiss
+	}
+	entry := e.Value.(*memoryEntry)
+	if entry.expired(time.Now()) {
+		m.removeLocked(k)
+		return "", ErrMiss
+	}
+	m.order.MoveToFront(e)
+	return entry.Value, nil
+}
+
+func (m *Memory) MustGet(k string) (string, error) {
+	return m.Get(k)
+}
+
+func (m *Memory) Set(k, v string) error {
+	return m.SetWithTTL(k, v, 0)
+}
+
+func (m *Memory) MustSet(k, v string) error {
+	return m.SetWithTTL(k, v, 0)
+}
+
+func (m *Memory) SetWithTTL(k, v string, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.removeLocked(k)
+
+	entry := &memoryEntry{Key: k, Value: v}
+	if ttl > 0 {
+		entry.Expires = time.Now().Add(ttl)
+	}
+	// Like a miss for values that don't fit at all
+	if entry.size() > m.MaxBytes {
+		return nil
+	}
+
+	m.entries[k] = m.order.PushFront(entry)
+	m.size += entry.size()
+	m.evictLocked()
+	return nil
+}
+
+func (m *Memory) Delete(keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for _, k := range keys {
+		m.removeLocked(k)
+	}
+	return nil
+}
+
+func (m *Memory) DeletePrefix(prefix string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	for k := range m.entries {
+		if strings.HasPrefix(k, prefix) {
+			m.removeLocked(k)
+		}
+	}
+	return nil
+}
+
+func (m *Memory) GetOrFill(k string, fill func() (string, error)) (string, error) {
+	return getOrFill(m, m.TTL, k, fill)
+}
+
+func (m *Memory) DeletePages(paths ...string) error {
+	return deletePages(m, paths)
+}
+
+func (m *Memory) IncrURLStat(url string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.stats[url]++
+	return nil
+}
+
+func (m *Memory) URLStats() (map[string]int, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	stats := make(map[string]int, len(m.stats))
+	for url, count := range m.stats {
+		stats[url] = count
+	}
+	return stats, nil
+}
+
+func (m *Memory) Ping() error {
+	return nil
+}
+
+// Close saves the cache to its file. It can still be used afterwards.
+func (m *Memory) Close() error {
+	return m.Save()
+}
+
+func (m *Memory) removeLocked(k string) {
+	e, ok := m.entries[k]
+	if !ok {
+		return
+	}
+	m.size -= e.Value.(*memoryEntry).size()
+	m.order.Remove(e)
+	delete(m.entries, k)
+}
+
+func (m *Memory) evictLocked() {
+	for m.size > m.MaxBytes {
+		e := m.order.Back()
+		if e == nil {
+			return
+		}
+		m.removeLocked(e.Value.(*memoryEntry).Key)
+	}
+}
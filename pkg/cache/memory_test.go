@@ -0,0 +1,92 @@
+package cache
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMemoryLRU(t *testing.T) {
+	m := NewMemory(30, "", time.Hour)
+
+	// Entries are 10 bytes: a 4 byte key and a 6 byte value
+	for _, k := range []string{"key1", "key2", "key3"} {
+		err := m.Set(k, "value!")
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := m.Get("key1"); err != nil {
+		t.Fatalf("Get(key1) = %v", err)
+	}
+	m.Set("key4", "value!")
+
+	if _, err := m.Get("key2"); err != ErrMiss {
+		t.Errorf("Get(key2) = %v, want the least recently used key evicted", err)
+	}
+	for _, k := range []string{"key1", "key3", "key4"} {
+		if v, err := m.Get(k); err != nil || v != "value!" {
+			t.Errorf("Get(%v) = %q, %v", k, v, err)
+		}
+	}
+
+	m.Set("huge", "a value longer than the cache")
+	if _, err := m.Get("huge"); err != ErrMiss {
+		t.Errorf("Get(huge) = %v, want values larger than the cache not stored", err)
+	}
+
+	m.SetWithTTL("key5", "gone", time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, err := m.Get("key5"); err != ErrMiss {
+		t.Errorf("Get(key5) = %v, want expired keys missing", err)
+	}
+}
+
+func TestMemoryFile(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "cache.json")
+
+	m := NewMemory(1<<20, file, time.Hour)
+	m.Set(PageKey("/company"), "<html>")
+	m.Set(SECSICs, "[]")
+	m.SetWithTTL(SECCompanies, "[]", time.Nanosecond)
+	m.IncrURLStat("/sic")
+	m.IncrURLStat("/sic")
+
+	v, err := m.GetOrFill(CompanyFilingsHTMLKey(320193), func() (string, error) {
+		return "<ul>", nil
+	})
+	if err != nil || v != "<ul>" {
+		t.Fatalf("GetOrFill() = %q, %v", v, err)
+	}
+
+	err = m.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	loaded := NewMemory(1<<20, file, time.Hour)
+	for k, want := range map[string]string{PageKey("/company"): "<html>", SECSICs: "[]", CompanyFilingsHTMLKey(320193): "<ul>"} {
+		if v, err := loaded.Get(k); err != nil || v != want {
+			t.Errorf("Get(%v) = %q, %v, want %q", k, v, err, want)
+		}
+	}
+	if _, err := loaded.Get(SECCompanies); err != ErrMiss {
+		t.Errorf("Get(%v) = %v, want expired keys not saved", SECCompanies, err)
+	}
+
+	stats, err := loaded.URLStats()
+	if err != nil || stats["/sic"] != 2 {
+		t.Errorf("URLStats() = %v, %v, want 2 visits of /sic", stats, err)
+	}
+
+	err = loaded.DeletePages()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := loaded.Get(PageKey("/company")); err != ErrMiss {
+		t.Errorf("Get() after DeletePages() = %v, want the pages removed", err)
+	}
+	if _, err := loaded.Get(SECSICs); err != nil {
+		t.Errorf("Get(%v) after DeletePages() = %v, want page data kept", SECSICs, err)
+	}
+}
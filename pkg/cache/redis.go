@@ -0,0 +1,133 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package cache
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/go-redis/redis/v8"
+)
+
+// urlStatsPrefix prefixes the hash of the visit count of every URL
+const urlStatsPrefix = "stats:"
+
+// Redis is the cache kept in a Redis server, shared by every sec process
+type Redis struct {
+	Client *redis.Client
+	TTL    time.Duration
+}
+
+// NewRedis returns the cache of the Redis server of cfg. Values filled by
+// GetOrFill are kept for ttl.
+func NewRedis(cfg config.RedisConfig, ttl time.Duration) *Redis {
+	rdb := redis.NewClient(&redis.Options{
+		Addr:     cfg.GetRedisURL(),
+		Password: cfg.Password,
+		DB:       0,
+	})
+
+	return &Redis{
+		Client: rdb,
+		TTL:    ttl,
+	}
+}
+
+func (c *Redis) Get(k string) (string, error) {
+	v, err := c.Client.Get(context.Background(), k).Result()
+	if err == redis.Nil {
+		return "", ErrMiss
+	}
+	if err != nil {
+		return "", err
+	}
+
+	return v, err
+}
+
+func (c *Redis) MustGet(k string) (string, error) {
+	return c.Get(k)
+}
+
+func (c *Redis) Set(k, v string) error {
+	return c.SetWithTTL(k, v, 0)
+}
+
+func (c *Redis) MustSet(k, v string) error {
+	return c.SetWithTTL(k, v, 0)
+}
+
+func (c *Redis) SetWithTTL(k, v string, ttl time.Duration) error {
+	return c.Client.Set(context.Background(), k, v, ttl).Err()
+}
+
+func (c *Redis) Delete(keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return c.Client.Del(context.Background(), keys...).Err()
+}
+
+func (c *Redis) DeletePrefix(prefix string) error {
+	ctx := context.Background()
+	iter := c.Client.Scan(ctx, 0, escapeGlob(prefix)+"*", 1000).Iterator()
+	for iter.Next(ctx) {
+		err := c.Client.Del(ctx, iter.Val()).Err()
+		if err != nil {
+			return err
+		}
+	}
+	return iter.Err()
+}
+
+func (c *Redis) GetOrFill(k string, fill func() (string, error)) (string, error) {
+	return getOrFill(c, c.TTL, k, fill)
+}
+
+func (c *Redis) DeletePages(paths ...string) error {
+	return deletePages(c, paths)
+}
+
+func (c *Redis) IncrURLStat(url string) error {
+	return c.Client.HIncrBy(context.Background(), urlStatsPrefix+url, "count", 1).Err()
+}
+
+func (c *Redis) URLStats() (map[string]int, error) {
+	ctx := context.Background()
+	keys, err := c.Client.Keys(ctx, urlStatsPrefix+"*").Result()
+	if err != nil {
+		return nil, err
+	}
+
+	stats := make(map[string]int)
+	for _, key := range keys {
+		count, err := c.Client.HGet(ctx, key, "count").Int()
+		if err != nil {
+			return nil, err
+		}
+		stats[strings.TrimPrefix(key, urlStatsPrefix)] = count
+	}
+	return stats, nil
+}
+
+func (c *Redis) Ping() error {
+	return c.Client.Ping(context.Background()).Err()
+}
+
+func (c *Redis) Close() error {
+	return c.Client.Close()
+}
+
+// escapeGlob escapes the special characters of Redis patterns in s
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		if strings.ContainsRune(`*?[]\`, r) {
+			b.WriteRune('\\')
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -4,6 +4,7 @@ package config
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/spf13/viper"
 )
@@ -14,6 +15,8 @@ type Config struct {
 	IndexMode IndexModeConfig
 	Proxies   ProxiesConfig
 	Redis     RedisConfig
+	Cache     CacheConfig
+	OCR       OCRConfig
 }
 
 type DatabaseConfig struct {
@@ -23,22 +26,83 @@ type DatabaseConfig struct {
 	Name     string `mapstructure:"name"`
 	Password string `mapstructure:"password"`
 	User     string `mapstructure:"user"`
+
+	// The database file with driver sqlite3, e.g. "./sec.db". Host, port,
+	// name, user and password are only used by postgres.
+	Path string `mapstructure:"path"`
 }
 
+// RedisConfig is the Redis server of the cache with cache.backend redis
 type RedisConfig struct {
 	Host     string `mapstructure:"host"`
-	Port     string `mapstructure:"port"`
+	Port     int    `mapstructure:"port"`
 	Password string `mapstructure:"password"`
 }
 
+// CacheConfig chooses where page data and URL stats are cached
+type CacheConfig struct {
+	// "redis" or "memory". Empty uses Redis if redis.host is set, and
+	// memory otherwise.
+	Backend string `mapstructure:"backend"`
+
+	// The size of the memory cache in megabytes, least recently used
+	// entries are evicted beyond it. Zero is 256.
+	MaxSizeMB int `mapstructure:"maxsizemb"`
+
+	// The memory cache is saved to this file when sec exits and loaded
+	// from it on start, e.g. "./cache.json". Not saved if empty.
+	File string `mapstructure:"file"`
+}
+
+// CacheBackend returns the cache backend, "redis" or "memory"
+func (c Config) CacheBackend() string {
+	if c.Cache.Backend != "" {
+		return c.Cache.Backend
+	}
+	if c.Redis.Host != "" {
+		return "redis"
+	}
+	return "memory"
+}
+
 type MainConfig struct {
 	BaseURL          string `mapstructure:"baseurl"`
 	WebsiteURL       string `mapstructure:"websiteurl"`
 	CacheDir         string `mapstructure:"cachedir"`
-	RateLimitMs      string `mapstructure:"ratelimitms"`
-	RetryLimit       string `mapstructure:"retrylimit"`
+	RateLimitMs      int    `mapstructure:"ratelimitms"`
+	RetryLimit       int    `mapstructure:"retrylimit"`
 	CacheDirUnpacked string `mapstructure:"cachedirunpacked"`
 	ServerPort       string `mapstructure:"serverport"`
+
+	// HTTP server timeouts, e.g. "15s". Zero values use the server defaults.
+	ServerReadHeaderTimeout time.Duration `mapstructure:"serverreadheadertimeout"`
+	ServerReadTimeout       time.Duration `mapstructure:"serverreadtimeout"`
+	ServerWriteTimeout      time.Duration `mapstructure:"serverwritetimeout"`
+	ServerIdleTimeout       time.Duration `mapstructure:"serveridletimeout"`
+	ServerShutdownTimeout   time.Duration `mapstructure:"servershutdowntimeout"`
+	// How long /api/v1/ready fails on shutdown before the listeners close,
+	// so load balancers stop sending requests. Negative values don't wait.
+	ServerDrainDelay time.Duration `mapstructure:"serverdraindelay"`
+
+	// TLS with a certificate and key on disk, or with certificates obtained
+	// from Let's Encrypt (ACME) for AutocertDomains. Plain HTTP if neither.
+	TLSCertFile      string   `mapstructure:"tlscertfile"`
+	TLSKeyFile       string   `mapstructure:"tlskeyfile"`
+	AutocertDomains  []string `mapstructure:"autocertdomains"`
+	AutocertEmail    string   `mapstructure:"autocertemail"`
+	AutocertCacheDir string   `mapstructure:"autocertcachedir"`
+	AutocertHTTPPort string   `mapstructure:"autocerthttpport"`
+
+	// How long page data computed on a cache miss is kept, e.g. "24h"
+	CacheTTL time.Duration `mapstructure:"cachettl"`
+
+	// How long the rendered HTML of listing pages is kept, e.g. "10m"
+	PageCacheTTL time.Duration `mapstructure:"pagecachettl"`
+
+	// Dev mode: read the *.gohtml templates from this directory, e.g.
+	// ./templates, on every request instead of the embedded copies, and
+	// don't cache rendered pages
+	TemplatesDir string `mapstructure:"templatesdir"`
 }
 
 type IndexModeConfig struct {
@@ -46,26 +110,62 @@ type IndexModeConfig struct {
 	CompanyFacts               string `mapstructure:"companyfacts"`
 }
 
+// OCRConfig is the command run on image exhibits (JPG, GIF, PNG, ...) to
+// index their text, e.g. tesseract with args ["{file}", "stdout"]. {file}
+// is replaced with the path of the image. Images are skipped if no command
+// is set.
+type OCRConfig struct {
+	Command string        `mapstructure:"command"`
+	Args    []string      `mapstructure:"args"`
+	Timeout time.Duration `mapstructure:"timeout"`
+}
+
 type ProxiesConfig struct {
 	Addresses []string `mapstructure:"addresses"`
 }
 
-func LoadConfig(path string) (config Config, err error) {
-	viper.AddConfigPath(path)
-	viper.SetConfigName("config")
-	viper.SetConfigType("yaml")
+// LoadConfig reads config.yaml from path, overridden by the keys of
+// profiles.<profile> if profile isn't empty, and then by the SEC_*
+// environment variables of EnvVars.
+func LoadConfig(path string, profile string) (config Config, err error) {
+	return loadConfig(viper.GetViper(), path, profile)
+}
 
-	viper.AutomaticEnv()
+func loadConfig(v *viper.Viper, path string, profile string) (config Config, err error) {
+	v.AddConfigPath(path)
+	v.SetConfigName("config")
+	v.SetConfigType("yaml")
 
-	err = viper.ReadInConfig()
+	err = BindEnv(v)
 	if err != nil {
 		return
 	}
 
-	err = viper.Unmarshal(&config)
+	err = v.ReadInConfig()
+	if err != nil {
+		return
+	}
+
+	if profile != "" {
+		p := v.Sub("profiles." + profile)
+		if p == nil {
+			return config, fmt.Errorf("profile %q not found in %v", profile, v.ConfigFileUsed())
+		}
+		err = v.MergeConfigMap(p.AllSettings())
+		if err != nil {
+			return
+		}
+	}
+
+	err = v.Unmarshal(&config)
 	return
 }
 
+// RateLimit is the time to wait between requests to the SEC.
+func (mc MainConfig) RateLimit() time.Duration {
+	return time.Duration(mc.RateLimitMs) * time.Millisecond
+}
+
 func (c *Config) DBGetURL() string {
 	return fmt.Sprintf("%v://%v:%v@%v:%d/%v?sslmode=disable",
 		c.Database.Driver,
@@ -0,0 +1,212 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+func validConfig() Config {
+	return Config{
+		Database: DatabaseConfig{Driver: "postgres", Host: "localhost", Port: 5432, Name: "sec", User: "sec"},
+		Main: MainConfig{
+			BaseURL:          "https://www.sec.gov",
+			WebsiteURL:       "https://equres.com/",
+			CacheDir:         "./cache",
+			CacheDirUnpacked: "./unzipped_cache",
+			RateLimitMs:      100,
+			RetryLimit:       3,
+			ServerPort:       ":8000",
+		},
+		Redis:     RedisConfig{Host: "localhost", Port: 6379},
+		IndexMode: IndexModeConfig{FinancialStatementDataSets: "enabled"},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	if err := validConfig().Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil", err)
+	}
+
+	c := validConfig()
+	c.Database.Port = 0
+	c.Cache.Backend = "disk"
+	c.Main.WebsiteURL = "equres.com"
+	c.Main.RetryLimit = 0
+	c.Main.PageCacheTTL = -time.Minute
+	c.Main.TLSCertFile = "cert.pem"
+	c.Proxies.Addresses = []string{"10.0.0.1:3128", "10.0.0.2"}
+
+	err := c.Validate()
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatalf("Validate() = %v, want ValidationErrors", err)
+	}
+
+	want := []string{"database.port", "cache.backend", "main.websiteurl", "main.retrylimit", "main.tlscertfile", "main.pagecachettl", "proxies.addresses"}
+	if len(errs) != len(want) {
+		t.Fatalf("Validate() = %v, want errors for %v", err, want)
+	}
+	for i, key := range want {
+		if errs[i].Key != key {
+			t.Errorf("Validate()[%d] is for %v, want %v", i, errs[i].Key, key)
+		}
+	}
+	if !strings.Contains(err.Error(), "(SEC_DATABASE_PORT)") {
+		t.Errorf("Validate() = %v, want the environment variables of the keys", err)
+	}
+}
+
+func TestValidateRequired(t *testing.T) {
+	c := validConfig()
+	c.Main.WebsiteURL = ""
+	c.Main.CacheDirUnpacked = ""
+	if err := c.Validate(); err != nil {
+		t.Fatalf("Validate() = %v, want nil without main.websiteurl and main.cachedirunpacked", err)
+	}
+
+	err := c.Validate("main.websiteurl", "main.cachedirunpacked")
+	errs, ok := err.(ValidationErrors)
+	if !ok || len(errs) != 2 || errs[0].Key != "main.websiteurl" || errs[1].Key != "main.cachedirunpacked" {
+		t.Errorf("Validate(main.websiteurl, main.cachedirunpacked) = %v, want both required", err)
+	}
+}
+
+func TestEnvVars(t *testing.T) {
+	vars := make(map[string]EnvVar)
+	for _, env := range EnvVars() {
+		vars[env.Key] = env
+	}
+
+	tests := map[string]EnvVar{
+		"database.password":      {Name: "SEC_DATABASE_PASSWORD", Type: "string"},
+		"redis.port":             {Name: "SEC_REDIS_PORT", Type: "int"},
+		"main.ratelimitms":       {Name: "SEC_MAIN_RATELIMITMS", Type: "int"},
+		"main.serverreadtimeout": {Name: "SEC_MAIN_SERVERREADTIMEOUT", Type: "duration"},
+		"proxies.addresses":      {Name: "SEC_PROXIES_ADDRESSES", Type: "list"},
+		"ocr.timeout":            {Name: "SEC_OCR_TIMEOUT", Type: "duration"},
+	}
+	for key, want := range tests {
+		got, ok := vars[key]
+		if !ok {
+			t.Errorf("EnvVars() has no %v", key)
+			continue
+		}
+		if got.Name != want.Name || got.Type != want.Type {
+			t.Errorf("EnvVars()[%v] = %+v, want %+v", key, got, want)
+		}
+	}
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	dir := t.TempDir()
+	err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(`
+database:
+  host: localhost
+  port: 5432
+main:
+  ratelimitms: "100"
+redis:
+  port: "6379"
+`), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("SEC_DATABASE_PASSWORD", "secret")
+	os.Setenv("SEC_MAIN_SERVERREADTIMEOUT", "15s")
+	os.Setenv("SEC_REDIS_PORT", "6380")
+	defer os.Unsetenv("SEC_DATABASE_PASSWORD")
+	defer os.Unsetenv("SEC_MAIN_SERVERREADTIMEOUT")
+	defer os.Unsetenv("SEC_REDIS_PORT")
+
+	c, err := LoadConfig(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if c.Database.Password != "secret" || c.Database.Host != "localhost" {
+		t.Errorf("Database = %+v, want the password from SEC_DATABASE_PASSWORD", c.Database)
+	}
+	if c.Main.ServerReadTimeout != 15*time.Second || c.Main.RateLimit() != 100*time.Millisecond {
+		t.Errorf("Main = %+v, want a 15s read timeout and a 100ms rate limit", c.Main)
+	}
+	if c.Redis.Port != 6380 {
+		t.Errorf("Redis.Port = %v, want 6380 from SEC_REDIS_PORT", c.Redis.Port)
+	}
+}
+
+func TestInitProfiles(t *testing.T) {
+	dir := t.TempDir()
+	defaults := Defaults("sec")
+
+	_, err := Init(dir, "", map[string]string{"database.password": "secret", "redis.port": "6380"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Init(dir, "ci", map[string]string{"database.host": "postgres", "main.cachettl": "1h"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = Init(dir, "", map[string]string{"main.retrylimit": "5"}, defaults)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = Init(dir, "prod", map[string]string{"redis.port": "http"}, defaults)
+	if err == nil {
+		t.Errorf("Init() with an invalid port succeeded")
+	}
+	_, err = Init(dir, "prod", map[string]string{"database.hots": "db"}, defaults)
+	if err == nil {
+		t.Errorf("Init() with an unknown key succeeded")
+	}
+
+	base, err := loadConfig(viper.New(), dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if base.Database.Host != "localhost" || base.Database.Password != "secret" || base.Redis.Port != 6380 || base.Main.RetryLimit != 5 {
+		t.Errorf("loadConfig() = %+v, want the top level values", base)
+	}
+	if err := base.Validate(); err != nil {
+		t.Errorf("Validate() = %v", err)
+	}
+
+	ci, err := loadConfig(viper.New(), dir, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ci.Database.Host != "postgres" || ci.Database.Password != "secret" || ci.Main.CacheTTL != time.Hour || ci.Main.RetryLimit != 5 {
+		t.Errorf("loadConfig() = %+v, want the ci profile over the top level", ci)
+	}
+
+	_, err = loadConfig(viper.New(), dir, "prod")
+	if err == nil {
+		t.Errorf("loadConfig() with a profile that failed to init succeeded")
+	}
+}
+
+func TestRedact(t *testing.T) {
+	c := validConfig()
+	c.Database.Password = "secret"
+
+	data, err := c.Redact().YAML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || !strings.Contains(string(data), "password: "+Redacted) {
+		t.Errorf("YAML() = %s, want the database password redacted", data)
+	}
+	if !strings.Contains(string(data), "pagecachettl: 0s") {
+		t.Errorf("YAML() = %s, want durations written like 0s", data)
+	}
+	if c.Database.Password != "secret" {
+		t.Errorf("Redact() changed the config")
+	}
+}
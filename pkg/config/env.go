@@ -0,0 +1,75 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package config
+
+import (
+	"reflect"
+	"strings"
+
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix prefixes the environment variables overriding the config
+const EnvPrefix = "SEC"
+
+// EnvVar is an environment variable overriding the config key Key, e.g.
+// SEC_DATABASE_PASSWORD for database.password.
+type EnvVar struct {
+	Key  string
+	Name string
+	Type string
+}
+
+// EnvName returns the environment variable of a config key.
+func EnvName(key string) string {
+	return EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, ".", "_"))
+}
+
+// EnvVars returns an environment variable for every key of Config. Lists
+// are comma separated and durations written like "15s".
+func EnvVars() []EnvVar {
+	var vars []EnvVar
+	collectEnvVars(reflect.TypeOf(Config{}), "", &vars)
+	return vars
+}
+
+func collectEnvVars(t reflect.Type, prefix string, vars *[]EnvVar) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		if field.Type.Kind() == reflect.Struct {
+			collectEnvVars(field.Type, key, vars)
+			continue
+		}
+
+		typ := field.Type.String()
+		switch typ {
+		case "time.Duration":
+			typ = "duration"
+		case "[]string":
+			typ = "list"
+		}
+		*vars = append(*vars, EnvVar{Key: key, Name: EnvName(key), Type: typ})
+	}
+}
+
+// BindEnv lets the environment variables of EnvVars override v. Viper's
+// AutomaticEnv only reaches keys that are in the config file already, and
+// none of the nested ones.
+func BindEnv(v *viper.Viper) error {
+	for _, env := range EnvVars() {
+		err := v.BindEnv(env.Key, env.Name)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,262 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// Redacted replaces non-empty secrets
+const Redacted = "REDACTED"
+
+// Defaults returns the values written by "sec config init" for the keys it
+// isn't given. The database and its user are named after username.
+func Defaults(username string) map[string]interface{} {
+	return map[string]interface{}{
+		"main.baseurl":                         "https://www.sec.gov",
+		"main.websiteurl":                      "https://equres.com/",
+		"main.cachedir":                        "./cache",
+		"main.cachedirunpacked":                "./unzipped_cache",
+		"main.ratelimitms":                     100,
+		"main.retrylimit":                      3,
+		"main.serverport":                      ":8000",
+		"database.driver":                      "postgres",
+		"database.host":                        "localhost",
+		"database.port":                        5432,
+		"database.name":                        username,
+		"database.user":                        username,
+		"indexmode.financialstatementdatasets": "enabled",
+		"indexmode.companyfacts":               "enabled",
+		"cache.backend":                        "redis",
+		"redis.host":                           "localhost",
+		"redis.port":                           6379,
+	}
+}
+
+// Init writes config.yaml in dir with values, keys like "database.host"
+// mapped to values written as in the SEC_* environment variables. Without
+// a profile, values are written to the top level of the file and the keys
+// left out keep their value in the file, or get the one of defaults. With
+// a profile, values are added to profiles.<profile>, overriding the top
+// level when the profile is selected. Other profiles are kept.
+//
+// The resulting config is validated before anything is written, and the
+// file is replaced atomically, so a failed init leaves the old one intact.
+func Init(dir, profile string, values map[string]string, defaults map[string]interface{}) (config Config, err error) {
+	known := make(map[string]bool)
+	for _, env := range EnvVars() {
+		known[env.Key] = true
+	}
+	for key := range values {
+		if !known[key] {
+			return config, fmt.Errorf("unknown config key %q", key)
+		}
+	}
+
+	file := filepath.Join(dir, "config.yaml")
+	existing := viper.New()
+	existing.SetConfigFile(file)
+	existing.SetConfigType("yaml")
+	if _, err = os.Stat(file); err == nil {
+		err = existing.ReadInConfig()
+		if err != nil {
+			return config, fmt.Errorf("could not read %v: %v", file, err)
+		}
+	}
+
+	base := viper.New()
+	for key, value := range defaults {
+		base.SetDefault(key, value)
+	}
+	for _, key := range existing.AllKeys() {
+		if known[key] {
+			base.Set(key, existing.Get(key))
+		}
+	}
+
+	profiles := existing.GetStringMap("profiles")
+	profileValues := make(map[string]interface{})
+	if profile == "" {
+		for key, value := range values {
+			base.Set(key, value)
+		}
+	} else {
+		if p := existing.Sub("profiles." + profile); p != nil {
+			for _, key := range p.AllKeys() {
+				profileValues[key] = p.Get(key)
+			}
+		}
+		for key, value := range values {
+			profileValues[key] = value
+		}
+	}
+
+	var baseConfig Config
+	err = base.Unmarshal(&baseConfig)
+	if err != nil {
+		return config, err
+	}
+
+	effective := viper.New()
+	for key, value := range Settings(baseConfig) {
+		effective.Set(key, value)
+	}
+	for key, value := range profileValues {
+		effective.Set(key, value)
+	}
+	err = effective.Unmarshal(&config)
+	if err != nil {
+		return config, err
+	}
+
+	err = config.Validate()
+	if err != nil {
+		return config, err
+	}
+
+	out := Nest(Settings(baseConfig))
+	if profile != "" {
+		settings := Settings(config)
+		section := make(map[string]interface{})
+		for key := range profileValues {
+			section[key] = settings[key]
+		}
+		if profiles == nil {
+			profiles = make(map[string]interface{})
+		}
+		profiles[profile] = Nest(section)
+	}
+	if len(profiles) > 0 {
+		out["profiles"] = profiles
+	}
+
+	data, err := yaml.Marshal(out)
+	if err != nil {
+		return config, err
+	}
+
+	err = WriteFileAtomic(file, data, 0600)
+	return config, err
+}
+
+// WriteFileAtomic writes data to a temporary file next to filename and
+// renames it over filename, so readers see the old or the new file but
+// never a partial one. The directory is created if needed.
+func WriteFileAtomic(filename string, data []byte, perm os.FileMode) error {
+	dir := filepath.Dir(filename)
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, "."+filepath.Base(filename)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+
+	err = os.Chmod(tmp.Name(), perm)
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), filename)
+}
+
+// Settings returns the value of every key of EnvVars in c. Durations are
+// strings like "15s", as they're written in the config file.
+func Settings(c Config) map[string]interface{} {
+	settings := make(map[string]interface{})
+	collectSettings(reflect.ValueOf(c), "", settings)
+	return settings
+}
+
+func collectSettings(v reflect.Value, prefix string, settings map[string]interface{}) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := field.Tag.Get("mapstructure")
+		if name == "" {
+			name = strings.ToLower(field.Name)
+		}
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+
+		value := v.Field(i)
+		switch {
+		case field.Type.Kind() == reflect.Struct:
+			collectSettings(value, key, settings)
+		case field.Type == reflect.TypeOf(time.Duration(0)):
+			settings[key] = time.Duration(value.Int()).String()
+		case field.Type.Kind() == reflect.Slice && value.IsNil():
+			settings[key] = []string{}
+		default:
+			settings[key] = value.Interface()
+		}
+	}
+}
+
+// Nest turns keys like "database.host" into nested maps, the way they're
+// written in the config file.
+func Nest(settings map[string]interface{}) map[string]interface{} {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	nested := make(map[string]interface{})
+	for _, key := range keys {
+		parts := strings.Split(key, ".")
+		m := nested
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := m[part].(map[string]interface{})
+			if !ok {
+				child = make(map[string]interface{})
+				m[part] = child
+			}
+			m = child
+		}
+		m[parts[len(parts)-1]] = settings[key]
+	}
+	return nested
+}
+
+// Redact returns c with its passwords replaced by Redacted, to print it.
+func (c Config) Redact() Config {
+	if c.Database.Password != "" {
+		c.Database.Password = Redacted
+	}
+	if c.Redis.Password != "" {
+		c.Redis.Password = Redacted
+	}
+	return c
+}
+
+// YAML returns c the way it's written in the config file.
+func (c Config) YAML() ([]byte, error) {
+	return yaml.Marshal(Nest(Settings(c)))
+}
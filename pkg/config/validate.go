@@ -0,0 +1,193 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package config
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ValidationError is an invalid value of a config key.
+type ValidationError struct {
+	Key     string
+	Message string
+}
+
+func (e ValidationError) Error() string {
+	return fmt.Sprintf("%v (%v): %v", e.Key, EnvName(e.Key), e.Message)
+}
+
+// ValidationErrors are all the invalid values of a config.
+type ValidationErrors []ValidationError
+
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = err.Error()
+	}
+	return strings.Join(lines, "\n")
+}
+
+var indexModes = map[string]bool{
+	"":         true,
+	"enabled":  true,
+	"disabled": true,
+	"true":     true,
+	"false":    true,
+}
+
+// Validate checks the config before anything runs with it, so a bad value
+// doesn't fail deep inside a download. main.websiteurl and
+// main.cachedirunpacked are only read by some commands, they're required
+// if listed in required and otherwise only checked when set. It returns
+// ValidationErrors listing every invalid key.
+func (c Config) Validate(required ...string) error {
+	var errs ValidationErrors
+	add := func(key string, format string, args ...interface{}) {
+		errs = append(errs, ValidationError{Key: key, Message: fmt.Sprintf(format, args...)})
+	}
+	isRequired := func(key string) bool {
+		for _, k := range required {
+			if k == key {
+				return true
+			}
+		}
+		return false
+	}
+
+	switch c.Database.Driver {
+	case "":
+		add("database.driver", "is required, e.g. postgres or sqlite3")
+	case "sqlite3":
+		if c.Database.Path == "" {
+			add("database.path", "is required with sqlite3")
+		}
+	default:
+		if c.Database.Host == "" {
+			add("database.host", "is required")
+		}
+		if c.Database.Port < 1 || c.Database.Port > 65535 {
+			add("database.port", "%d is not a port", c.Database.Port)
+		}
+		if c.Database.Name == "" {
+			add("database.name", "is required")
+		}
+		if c.Database.User == "" {
+			add("database.user", "is required")
+		}
+	}
+
+	switch c.Cache.Backend {
+	case "", "redis", "memory":
+	default:
+		add("cache.backend", "%q should be redis or memory", c.Cache.Backend)
+	}
+	if c.CacheBackend() == "redis" {
+		if c.Redis.Host == "" {
+			add("redis.host", "is required with cache.backend redis")
+		}
+		if c.Redis.Port < 1 || c.Redis.Port > 65535 {
+			add("redis.port", "%d is not a port", c.Redis.Port)
+		}
+	}
+	if c.Cache.MaxSizeMB < 0 {
+		add("cache.maxsizemb", "%d is negative", c.Cache.MaxSizeMB)
+	}
+
+	if err := validateURL(c.Main.BaseURL); err != nil {
+		add("main.baseurl", "%v", err)
+	}
+	if c.Main.WebsiteURL != "" || isRequired("main.websiteurl") {
+		if err := validateURL(c.Main.WebsiteURL); err != nil {
+			add("main.websiteurl", "%v", err)
+		} else if !strings.HasSuffix(c.Main.WebsiteURL, "/") {
+			add("main.websiteurl", "%q should end with /, page URLs are appended to it", c.Main.WebsiteURL)
+		}
+	}
+	if c.Main.CacheDir == "" {
+		add("main.cachedir", "is required")
+	}
+	if c.Main.CacheDirUnpacked == "" && isRequired("main.cachedirunpacked") {
+		add("main.cachedirunpacked", "is required")
+	}
+	if c.Main.RateLimitMs < 0 {
+		add("main.ratelimitms", "%d is negative", c.Main.RateLimitMs)
+	}
+	if c.Main.RetryLimit < 1 {
+		add("main.retrylimit", "%d should be at least 1", c.Main.RetryLimit)
+	}
+	if c.Main.ServerPort != "" {
+		if _, _, err := net.SplitHostPort(c.Main.ServerPort); err != nil {
+			add("main.serverport", "%q is not an address, e.g. :8000", c.Main.ServerPort)
+		}
+	}
+	if c.Main.AutocertHTTPPort != "" {
+		if _, _, err := net.SplitHostPort(c.Main.AutocertHTTPPort); err != nil {
+			add("main.autocerthttpport", "%q is not an address, e.g. :80", c.Main.AutocertHTTPPort)
+		}
+	}
+	if (c.Main.TLSCertFile == "") != (c.Main.TLSKeyFile == "") {
+		add("main.tlscertfile", "the certificate and main.tlskeyfile are set together")
+	}
+	if c.Main.TLSCertFile != "" && len(c.Main.AutocertDomains) > 0 {
+		add("main.autocertdomains", "can't be used with main.tlscertfile")
+	}
+
+	durations := []struct {
+		key   string
+		value time.Duration
+	}{
+		{"main.serverreadheadertimeout", c.Main.ServerReadHeaderTimeout},
+		{"main.serverreadtimeout", c.Main.ServerReadTimeout},
+		{"main.serverwritetimeout", c.Main.ServerWriteTimeout},
+		{"main.serveridletimeout", c.Main.ServerIdleTimeout},
+		{"main.servershutdowntimeout", c.Main.ServerShutdownTimeout},
+		{"main.cachettl", c.Main.CacheTTL},
+		{"main.pagecachettl", c.Main.PageCacheTTL},
+		{"ocr.timeout", c.OCR.Timeout},
+	}
+	for _, d := range durations {
+		if d.value < 0 {
+			add(d.key, "%v is negative", d.value)
+		}
+	}
+
+	if !indexModes[c.IndexMode.FinancialStatementDataSets] {
+		add("indexmode.financialstatementdatasets", "%q should be enabled or disabled", c.IndexMode.FinancialStatementDataSets)
+	}
+	if !indexModes[c.IndexMode.CompanyFacts] {
+		add("indexmode.companyfacts", "%q should be enabled or disabled", c.IndexMode.CompanyFacts)
+	}
+
+	if c.OCR.Command == "" && len(c.OCR.Args) > 0 {
+		add("ocr.command", "is required with ocr.args")
+	}
+
+	for _, address := range c.Proxies.Addresses {
+		if _, _, err := net.SplitHostPort(address); err != nil {
+			add("proxies.addresses", "%q is not a host:port address", address)
+		}
+	}
+
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateURL(s string) error {
+	if s == "" {
+		return fmt.Errorf("is required")
+	}
+	u, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+	if (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%q is not an http(s) URL", s)
+	}
+	return nil
+}
@@ -3,7 +3,7 @@
 package database
 
 import (
-	"embed"
+	"io/fs"
 
 	log "github.com/sirupsen/logrus"
 
@@ -15,6 +15,10 @@ import (
 )
 
 func ConnectDB(config config.Config) (*sqlx.DB, error) {
+	if config.Database.Driver == SQLite {
+		return connectSQLite(config.Database.Path)
+	}
+
 	// Connect to DB
 	db, err := sqlx.Open(config.Database.Driver, config.DBGetURL())
 	if err != nil {
@@ -24,21 +28,42 @@ func ConnectDB(config config.Config) (*sqlx.DB, error) {
 	return db, nil
 }
 
-func MigrateUp(db *sqlx.DB, fs embed.FS, config config.Config) error {
-	err := db.Ping()
-	if err != nil {
-		return err
+// newMigrate returns the migrations of db from fsys: the migrations
+// directory for Postgres and migrations_sqlite for SQLite.
+func newMigrate(db *sqlx.DB, fsys fs.FS, config config.Config) (*migrate.Migrate, error) {
+	dir := "migrations"
+	if config.Database.Driver == SQLite {
+		dir = "migrations_sqlite"
 	}
 
-	d, err := iofs.New(fs, "migrations")
+	d, err := iofs.New(fsys, dir)
 	if err != nil {
 		log.Info("failed to make migrations")
-		return err
+		return nil, err
 	}
 
-	m, err := migrate.NewWithSourceInstance("iofs", d, config.DBGetURL())
+	var m *migrate.Migrate
+	if config.Database.Driver == SQLite {
+		m, err = newSQLiteMigrate(db, d)
+	} else {
+		m, err = migrate.NewWithSourceInstance("iofs", d, config.DBGetURL())
+	}
 	if err != nil {
 		log.Info("failed make iofs source")
+		return nil, err
+	}
+
+	return m, nil
+}
+
+func MigrateUp(db *sqlx.DB, fsys fs.FS, config config.Config) error {
+	err := db.Ping()
+	if err != nil {
+		return err
+	}
+
+	m, err := newMigrate(db, fsys, config)
+	if err != nil {
 		return err
 	}
 
@@ -51,21 +76,14 @@ func MigrateUp(db *sqlx.DB, fs embed.FS, config config.Config) error {
 	return nil
 }
 
-func MigrateDown(db *sqlx.DB, fs embed.FS, config config.Config) error {
+func MigrateDown(db *sqlx.DB, fsys fs.FS, config config.Config) error {
 	err := db.Ping()
 	if err != nil {
 		return err
 	}
 
-	d, err := iofs.New(fs, "migrations")
+	m, err := newMigrate(db, fsys, config)
 	if err != nil {
-		log.Info("failed to make migrations")
-		return err
-	}
-
-	m, err := migrate.NewWithSourceInstance("iofs", d, config.DBGetURL())
-	if err != nil {
-		log.Info("failed make iofs source")
 		return err
 	}
 
@@ -85,7 +103,11 @@ func CheckMigration(config config.Config) error {
 	}
 
 	// Check if migrated
-	_, err = db.Exec("SELECT 'sec.tickers'::regclass")
+	query := "SELECT 'sec.tickers'::regclass"
+	if IsSQLite(db) {
+		query = "SELECT 1 FROM sec.tickers LIMIT 1"
+	}
+	_, err = db.Exec(query)
 	if err != nil {
 		log.Info("looks like you're running sec for the first time. Please initialize the database with sec migrate up")
 		return err
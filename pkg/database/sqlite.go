@@ -0,0 +1,61 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package database
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLite is the database.driver of the embedded SQLite database, for a
+// single user or offline use without a Postgres server. Its migrations are
+// in migrations_sqlite and cover every command but "sec xbrl".
+// The SQLite driver is written in C, so binaries built without cgo only
+// connect to Postgres, see SQLiteEnabled.
+const SQLite = "sqlite3"
+
+// sqliteDriver is SQLite with the schemas attached, and NOW(), json_text(json,
+// key) and the similarity and word_similarity functions of pg_trgm defined
+// on every connection
+const sqliteDriver = "sec_sqlite3"
+
+// SQLiteSchemas are the schemas of the tables in SQLite. SQLite has no
+// schemas, so each is a database file next to database.path attached
+// under the name of the schema, and queries like SELECT ... FROM sec.ciks
+// run unchanged on both databases.
+var SQLiteSchemas = []string{"sec", "fsds", "mfd"}
+
+// SQLiteSchemaFile returns the database file of schema for the database at
+// path, e.g. sec-fsds.db for sec.db.
+func SQLiteSchemaFile(path, schema string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "-" + schema + ext
+}
+
+// jsonText is the text of key in the JSON object doc, like doc->>'key' in
+// Postgres. It's "" if doc isn't an object or key is missing.
+func jsonText(doc string, key string) string {
+	var object map[string]interface{}
+	err := json.Unmarshal([]byte(doc), &object)
+	if err != nil {
+		return ""
+	}
+
+	switch value := object[key].(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	default:
+		text, _ := json.Marshal(value)
+		return string(text)
+	}
+}
+
+// IsSQLite reports whether db is the embedded SQLite database
+func IsSQLite(db *sqlx.DB) bool {
+	return db.DriverName() == sqliteDriver
+}
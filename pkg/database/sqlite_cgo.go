@@ -0,0 +1,104 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+//go:build cgo
+// +build cgo
+
+package database
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	migrate "github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/database/sqlite3"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/jmoiron/sqlx"
+	gosqlite3 "github.com/mattn/go-sqlite3"
+)
+
+// SQLiteEnabled reports whether sec is built with SQLite, which needs cgo
+const SQLiteEnabled = true
+
+func init() {
+	sql.Register(sqliteDriver, &gosqlite3.SQLiteDriver{
+		ConnectHook: func(conn *gosqlite3.SQLiteConn) error {
+			path, err := sqliteMainFile(conn)
+			if err != nil {
+				return err
+			}
+
+			for _, schema := range SQLiteSchemas {
+				file := strings.ReplaceAll(SQLiteSchemaFile(path, schema), "'", "''")
+				_, err = conn.Exec(fmt.Sprintf("ATTACH DATABASE '%v' AS %v", file, schema), nil)
+				if err != nil {
+					return err
+				}
+			}
+
+			err = conn.RegisterFunc("now", func() string {
+				return time.Now().UTC().Format(gosqlite3.SQLiteTimestampFormats[0])
+			}, false)
+			if err != nil {
+				return err
+			}
+
+			err = conn.RegisterFunc("similarity", similarity, true)
+			if err != nil {
+				return err
+			}
+
+			err = conn.RegisterFunc("word_similarity", wordSimilarity, true)
+			if err != nil {
+				return err
+			}
+
+			return conn.RegisterFunc("json_text", jsonText, true)
+		},
+	})
+	sqlx.BindDriver(sqliteDriver, sqlx.QUESTION)
+}
+
+func sqliteMainFile(conn *gosqlite3.SQLiteConn) (string, error) {
+	rows, err := conn.Query("PRAGMA database_list", nil)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	values := make([]driver.Value, len(rows.Columns()))
+	for {
+		err = rows.Next(values)
+		if err == io.EOF {
+			return "", fmt.Errorf("no main database")
+		}
+		if err != nil {
+			return "", err
+		}
+		if values[1] == "main" {
+			path, _ := values[2].(string)
+			return path, nil
+		}
+	}
+}
+
+func connectSQLite(path string) (*sqlx.DB, error) {
+	err := os.MkdirAll(filepath.Dir(path), 0755)
+	if err != nil {
+		return nil, err
+	}
+	return sqlx.Open(sqliteDriver, fmt.Sprintf("file:%v?_busy_timeout=10000", path))
+}
+
+func newSQLiteMigrate(db *sqlx.DB, d source.Driver) (*migrate.Migrate, error) {
+	driver, err := sqlite3.WithInstance(db.DB, &sqlite3.Config{})
+	if err != nil {
+		return nil, err
+	}
+	return migrate.NewWithInstance("iofs", d, SQLite, driver)
+}
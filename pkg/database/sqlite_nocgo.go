@@ -0,0 +1,27 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+//go:build !cgo
+// +build !cgo
+
+package database
+
+import (
+	"errors"
+
+	migrate "github.com/golang-migrate/migrate/v4"
+	"github.com/golang-migrate/migrate/v4/source"
+	"github.com/jmoiron/sqlx"
+)
+
+// SQLiteEnabled reports whether sec is built with SQLite, which needs cgo
+const SQLiteEnabled = false
+
+var errNoSQLite = errors.New("this sec binary is built without cgo and can't use SQLite: rebuild it with CGO_ENABLED=1 or use Postgres")
+
+func connectSQLite(path string) (*sqlx.DB, error) {
+	return nil, errNoSQLite
+}
+
+func newSQLiteMigrate(db *sqlx.DB, d source.Driver) (*migrate.Migrate, error) {
+	return nil, errNoSQLite
+}
@@ -0,0 +1,69 @@
+//go:build cgo
+// +build cgo
+
+package database
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+)
+
+func TestSQLiteMigrations(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Config{Database: config.DatabaseConfig{Driver: SQLite, Path: filepath.Join(dir, "sec.db")}}
+
+	db, err := ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	if !IsSQLite(db) {
+		t.Errorf("IsSQLite() = false for %v", cfg.Database.Path)
+	}
+	if CheckMigration(cfg) == nil {
+		t.Errorf("CheckMigration() = nil before migrating")
+	}
+
+	err = MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := CheckMigration(cfg); err != nil {
+		t.Errorf("CheckMigration() = %v after migrating", err)
+	}
+
+	for _, schema := range SQLiteSchemas {
+		if _, err := os.Stat(SQLiteSchemaFile(cfg.Database.Path, schema)); err != nil {
+			t.Errorf("no database file for schema %v: %v", schema, err)
+		}
+	}
+
+	url := "https://www.sec.gov/Archives/edgar/monthly/xbrlrss-2022-02.xml"
+	for i := 0; i < 2; i++ {
+		if err := SkipFileInsert(db, url); err != nil {
+			t.Fatal(err)
+		}
+	}
+	skipped, err := IsSkippedFile(db, url)
+	if err != nil || !skipped {
+		t.Errorf("IsSkippedFile() = %v, %v, want true", skipped, err)
+	}
+
+	var now []string
+	err = db.Select(&now, "SELECT NOW()")
+	if err != nil || len(now) != 1 || now[0] == "" {
+		t.Errorf("NOW() = %v, %v, want the current time", now, err)
+	}
+
+	err = MigrateDown(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if CheckMigration(cfg) == nil {
+		t.Errorf("CheckMigration() = nil after migrating down")
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package database
+
+import (
+	"strings"
+	"unicode"
+)
+
+// trigrams returns the trigrams of s in order, like pg_trgm: every word of
+// letters and digits is lower cased and padded with two spaces in front and
+// one behind, so "Word" has "  w", " wo", "wor", "ord" and "rd ".
+func trigrams(s string) []string {
+	var trgms []string
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			trgms = append(trgms, string(padded[i:i+3]))
+		}
+	}
+	return trgms
+}
+
+func trigramSet(s string) map[string]bool {
+	set := make(map[string]bool)
+	for _, trgm := range trigrams(s) {
+		set[trgm] = true
+	}
+	return set
+}
+
+// similarity is pg_trgm's similarity(a, b): the trigrams a and b share out
+// of all their trigrams, from 0 to 1.
+func similarity(a string, b string) float64 {
+	setA, setB := trigramSet(a), trigramSet(b)
+
+	shared := 0
+	for trgm := range setA {
+		if setB[trgm] {
+			shared++
+		}
+	}
+	if len(setA)+len(setB) == 0 {
+		return 0
+	}
+	return float64(shared) / float64(len(setA)+len(setB)-shared)
+}
+
+// wordSimilarity is pg_trgm's word_similarity(a, b): the greatest
+// similarity of the trigrams of a with a continuous extent of the trigrams
+// of b, so a word of b close to a scores high however long b is.
+func wordSimilarity(a string, b string) float64 {
+	setA := trigramSet(a)
+	trgmsB := trigrams(b)
+
+	best := 0.0
+	for start := range trgmsB {
+		extent := make(map[string]bool)
+		shared := 0
+		for _, trgm := range trgmsB[start:] {
+			if extent[trgm] {
+				continue
+			}
+			extent[trgm] = true
+			if setA[trgm] {
+				shared++
+			}
+
+			score := float64(shared) / float64(len(setA)+len(extent)-shared)
+			if score > best {
+				best = score
+			}
+		}
+	}
+	return best
+}
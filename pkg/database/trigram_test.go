@@ -0,0 +1,32 @@
+package database
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTrigrams(t *testing.T) {
+	// The examples of the pg_trgm documentation
+	tests := []struct {
+		name string
+		f    func(string, string) float64
+		a, b string
+		want float64
+	}{
+		{"similarity", similarity, "word", "two words", 4.0 / 11},
+		{"word_similarity", wordSimilarity, "word", "two words", 0.8},
+		{"similarity", similarity, "Apple Inc.", "APPLE INC", 1},
+		{"similarity", similarity, "", "", 0},
+		{"word_similarity", wordSimilarity, "tesla", "", 0},
+	}
+
+	for _, tt := range tests {
+		if got := tt.f(tt.a, tt.b); math.Abs(got-tt.want) > 1e-6 {
+			t.Errorf("%v(%q, %q) = %v, want %v", tt.name, tt.a, tt.b, got, tt.want)
+		}
+	}
+
+	if got := trigrams("Word"); len(got) != 5 || got[0] != "  w" || got[4] != "rd " {
+		t.Errorf("trigrams(%q) = %q, want the padded trigrams of word", "Word", got)
+	}
+}
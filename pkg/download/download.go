@@ -11,7 +11,6 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
-	"strconv"
 	"strings"
 	"time"
 
@@ -96,17 +95,7 @@ func (d Downloader) GetFileETag(db *sqlx.DB, fullURL string) (string, error) {
 	req.IsEtag = true
 	req.IsContentLength = false
 
-	retryLimit, err := strconv.Atoi(d.Config.Main.RetryLimit)
-	if err != nil {
-		return "", err
-	}
-
-	rateLimit, err := strconv.Atoi(d.Config.Main.RateLimitMs)
-	if err != nil {
-		return "", err
-	}
-
-	resp, err := req.SendRequest(retryLimit, time.Duration(rateLimit), fullURL)
+	resp, err := req.SendRequest(d.Config.Main.RetryLimit, d.Config.Main.RateLimit(), fullURL)
 	if err != nil {
 		err := database.SkipFileInsert(db, fullURL)
 		if err != nil {
@@ -173,27 +162,18 @@ func (d Downloader) DownloadFile(db *sqlx.DB, fullurl string) error {
 
 	log.Info(fmt.Sprintf("File %v progress [%d/%d/%f%%] currently_downloading", fullurl, d.CurrentDownloadCount, d.TotalDownloadsCount, d.GetDownloadPercentage()))
 
-	retryLimit, err := strconv.Atoi(d.Config.Main.RetryLimit)
-	if err != nil {
-		return err
-	}
-
 	fileUrl, err := url.Parse(fullurl)
 	if err != nil {
 		return err
 	}
 	cachePath := filepath.Join(d.Config.Main.CacheDir, fileUrl.Path)
 
-	rateLimit, err := time.ParseDuration(d.Config.Main.RateLimitMs + "ms")
-	if err != nil {
-		return err
-	}
-
 	req := secreq.NewSECReqGET(d.Config)
 	req.IsEtag = d.IsEtag
 	req.IsContentLength = d.IsContentLength
 
-	resp, err := req.SendRequest(retryLimit, rateLimit, fullurl)
+	rateLimit := d.Config.Main.RateLimit()
+	resp, err := req.SendRequest(d.Config.Main.RetryLimit, rateLimit, fullurl)
 	if err != nil {
 		secevent.CreateDownloadEvent(db, cachePath, fullurl, "failed", err.Error())
 
@@ -254,8 +234,8 @@ func (d Downloader) DownloadFile(db *sqlx.DB, fullurl string) error {
 	timeTaken := time.Since(fileDowStartTime)
 	timeToWait := rateLimit - timeTaken
 
-	if timeToWait.Milliseconds() > 0 {
-		time.Sleep(time.Duration(timeToWait.Milliseconds()))
+	if timeToWait > 0 {
+		time.Sleep(timeToWait)
 	}
 
 	return nil
@@ -187,13 +187,14 @@ func GetCompanyFilingsFromCIK(db *sqlx.DB, cik int) (map[string][]SECItemFile, e
 	var secItemFiles []SECItemFile
 
 	err := db.Select(&secItemFiles, `
-	SELECT * FROM (
-		SELECT DISTINCT ON (accessionnumber) 
-			companyname, ciknumber, formtype, fillingdate 
+	SELECT companyname, ciknumber, formtype, fillingdate FROM (
+		SELECT companyname, ciknumber, formtype, fillingdate,
+			ROW_NUMBER() OVER (PARTITION BY accessionnumber) AS row_number
 		FROM sec.secItemFile 
 		WHERE ciknumber = $1
 			AND companyname IS NOT NULL
 		) filings 
+	WHERE row_number = 1
 	ORDER BY fillingdate desc;
 	`, cik)
 	if err != nil {
@@ -0,0 +1,335 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package seccache
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/equres/sec/pkg/cache"
+	"github.com/equres/sec/pkg/secutil"
+	"github.com/jmoiron/sqlx"
+)
+
+// Targets of `sec regen` that keep their own high-water mark
+const (
+	RegenPages   = "pages"
+	RegenSitemap = "sitemap"
+)
+
+// RegenMark is the latest updated_at of sec.secItemFile and fsds.sub that a
+// regen target already covers. Rows updated after it are regenerated.
+type RegenMark struct {
+	Name                 string    `db:"name"`
+	SECItemFileUpdatedAt time.Time `db:"secitemfile_updated_at"`
+	SubUpdatedAt         time.Time `db:"sub_updated_at"`
+}
+
+// Day of the /filings/{year}/{month}/{day} pages
+type Day struct {
+	Year  int
+	Month int
+	Day   int
+}
+
+type Month struct {
+	Year  int
+	Month int
+}
+
+// Changes lists the pages touched by the filings updated since a RegenMark.
+type Changes struct {
+	Months    []Month
+	Days      []Day
+	CIKsByDay map[Day][]int
+	CIKs      []int
+	SICs      []string
+}
+
+// GetRegenMark returns the mark saved by the last run of name. A target
+// that never ran gets a zero mark, so everything is regenerated.
+func GetRegenMark(db *sqlx.DB, name string) (RegenMark, error) {
+	var saved struct {
+		SECItemFileUpdatedAt sql.NullTime `db:"secitemfile_updated_at"`
+		SubUpdatedAt         sql.NullTime `db:"sub_updated_at"`
+	}
+	err := db.Get(&saved, `
+		SELECT secitemfile_updated_at, sub_updated_at
+		FROM sec.regen_marks
+		WHERE name = $1;`, name)
+	if err != nil && err != sql.ErrNoRows {
+		return RegenMark{}, err
+	}
+	return RegenMark{Name: name, SECItemFileUpdatedAt: orEpoch(saved.SECItemFileUpdatedAt), SubUpdatedAt: orEpoch(saved.SubUpdatedAt)}, nil
+}
+
+// CurrentRegenMark reads the mark to save once name is regenerated. It has
+// to be taken before looking for changes, so filings indexed during the run
+// are picked up by the next one.
+func CurrentRegenMark(db *sqlx.DB, name string) (RegenMark, error) {
+	mark := RegenMark{Name: name}
+
+	// The latest row rather than MAX(updated_at), which SQLite returns as
+	// text
+	for _, latest := range []struct {
+		updatedAt *time.Time
+		query     string
+	}{
+		{&mark.SECItemFileUpdatedAt, `SELECT updated_at FROM sec.secItemFile WHERE updated_at IS NOT NULL ORDER BY updated_at DESC LIMIT 1;`},
+		{&mark.SubUpdatedAt, `SELECT updated_at FROM fsds.sub WHERE updated_at IS NOT NULL ORDER BY updated_at DESC LIMIT 1;`},
+	} {
+		var updatedAt sql.NullTime
+		err := db.Get(&updatedAt, latest.query)
+		if err != nil && err != sql.ErrNoRows {
+			return RegenMark{}, err
+		}
+		*latest.updatedAt = orEpoch(updatedAt)
+	}
+
+	return mark, nil
+}
+
+// orEpoch is t, or the Unix epoch if it's NULL
+func orEpoch(t sql.NullTime) time.Time {
+	if !t.Valid {
+		return time.Unix(0, 0)
+	}
+	return t.Time
+}
+
+func SaveRegenMark(db *sqlx.DB, mark RegenMark) error {
+	_, err := db.NamedExec(`
+		INSERT INTO sec.regen_marks (name, secitemfile_updated_at, sub_updated_at, created_at, updated_at)
+		VALUES (:name, :secitemfile_updated_at, :sub_updated_at, NOW(), NOW())
+		ON CONFLICT (name)
+		DO UPDATE SET secitemfile_updated_at=EXCLUDED.secitemfile_updated_at, sub_updated_at=EXCLUDED.sub_updated_at, updated_at=NOW();`, mark)
+	return err
+}
+
+// GetChanges returns the days, CIKs and SICs of the filings updated after
+// mark. Companies with new financial statements in fsds.sub count as
+// changed CIKs too.
+func GetChanges(db *sqlx.DB, mark RegenMark) (Changes, error) {
+	type changedFiling struct {
+		FilingDate sql.NullTime `db:"fillingdate"`
+		CIK        int          `db:"ciknumber"`
+		SIC        int          `db:"assignedsic"`
+	}
+
+	var filings []changedFiling
+	err := db.Select(&filings, `
+		SELECT DISTINCT fillingdate, ciknumber, COALESCE(assignedsic, 0) AS assignedsic
+		FROM sec.secItemFile
+		WHERE updated_at > $1
+		AND ciknumber IS NOT NULL;`, mark.SECItemFileUpdatedAt)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	// A CIK is listed on the pages of every SIC it filed with, so when its
+	// SIC changes the page of the former one is rebuilt too
+	var cikSICs []int
+	err = db.Select(&cikSICs, `
+		SELECT DISTINCT assignedsic
+		FROM sec.secItemFile
+		WHERE assignedsic IS NOT NULL AND assignedsic <> 0
+		AND ciknumber IN (SELECT ciknumber FROM sec.secItemFile WHERE updated_at > $1);`, mark.SECItemFileUpdatedAt)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	var subCIKs []int
+	err = db.Select(&subCIKs, `SELECT DISTINCT cik FROM fsds.sub WHERE updated_at > $1 AND cik IS NOT NULL;`, mark.SubUpdatedAt)
+	if err != nil {
+		return Changes{}, err
+	}
+
+	changes := Changes{CIKsByDay: make(map[Day][]int)}
+	months := make(map[Month]bool)
+	ciks := make(map[int]bool)
+	sics := make(map[string]bool)
+
+	// A CIK can file with different SICs on the same day
+	type dayCIK struct {
+		day Day
+		cik int
+	}
+	dayCIKs := make(map[dayCIK]bool)
+
+	for _, filing := range filings {
+		ciks[filing.CIK] = true
+		if filing.SIC != 0 {
+			sics[strconv.Itoa(filing.SIC)] = true
+		}
+
+		// Files unpacked from ZIPs don't have a filing date
+		if !filing.FilingDate.Valid {
+			continue
+		}
+
+		date := filing.FilingDate.Time
+		months[Month{Year: date.Year(), Month: int(date.Month())}] = true
+
+		day := Day{Year: date.Year(), Month: int(date.Month()), Day: date.Day()}
+		if _, ok := changes.CIKsByDay[day]; !ok {
+			changes.Days = append(changes.Days, day)
+		}
+		if !dayCIKs[dayCIK{day, filing.CIK}] {
+			dayCIKs[dayCIK{day, filing.CIK}] = true
+			changes.CIKsByDay[day] = append(changes.CIKsByDay[day], filing.CIK)
+		}
+	}
+
+	for _, sic := range cikSICs {
+		sics[strconv.Itoa(sic)] = true
+	}
+
+	for _, cik := range subCIKs {
+		ciks[cik] = true
+	}
+
+	for month := range months {
+		changes.Months = append(changes.Months, month)
+	}
+	for cik := range ciks {
+		changes.CIKs = append(changes.CIKs, cik)
+	}
+	for sic := range sics {
+		changes.SICs = append(changes.SICs, sic)
+	}
+
+	sort.Slice(changes.Months, func(i, j int) bool {
+		if changes.Months[i].Year != changes.Months[j].Year {
+			return changes.Months[i].Year < changes.Months[j].Year
+		}
+		return changes.Months[i].Month < changes.Months[j].Month
+	})
+	sort.Ints(changes.CIKs)
+	sort.Strings(changes.SICs)
+
+	return changes, nil
+}
+
+func (sc *SECCache) setPageData(key string, generate func() (string, error)) error {
+	value, err := generate()
+	if err != nil {
+		return err
+	}
+	return sc.S.Cache.MustSet(key, value)
+}
+
+// GenerateChangedPagesDataCache regenerates only the page data that depends
+// on changes: the month, day and filings listings of the changed days, the
+// filings of the changed CIKs, the companies of the changed SICs and the
+// lists of all companies and SICs.
+func (sc *SECCache) GenerateChangedPagesDataCache(changes Changes) error {
+	years := make(map[int]bool)
+	for _, month := range changes.Months {
+		if !years[month.Year] {
+			years[month.Year] = true
+
+			year := month.Year
+			sc.S.Log(fmt.Sprintf("Regenerating months of %v...", year))
+			err := sc.setPageData(cache.MonthsInYearKey(year), func() (string, error) {
+				return sc.GenerateMonthsInYearJSON(year)
+			})
+			if err != nil {
+				return err
+			}
+		}
+
+		month := month
+		err := sc.setPageData(cache.DaysInMonthKey(month.Year, month.Month), func() (string, error) {
+			return sc.GenerateDaysInMonthJSON(month.Year, month.Month)
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, day := range changes.Days {
+		day := day
+		sc.S.Log(fmt.Sprintf("Regenerating filings of %v-%02d-%02d...", day.Year, day.Month, day.Day))
+
+		err := sc.setPageData(cache.CompaniesInDayKey(day.Year, day.Month, day.Day), func() (string, error) {
+			return sc.GenerateCompaniesInDayJSON(day.Year, day.Month, day.Day)
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, cik := range changes.CIKsByDay[day] {
+			cik := cik
+			err = sc.setPageData(cache.FilingsInDayKey(day.Year, day.Month, day.Day, cik), func() (string, error) {
+				return sc.GenerateFilingsInDayJSON(day.Year, day.Month, day.Day, cik)
+			})
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	for i, cik := range changes.CIKs {
+		sc.S.Log(fmt.Sprintf("[%d/%d] Regenerating filings of CIK %v...", i+1, len(changes.CIKs), cik))
+		err := sc.GenerateCompanyFilingsPageDataCache(cik)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changes.CIKs) > 0 {
+		err := sc.GenerateCompanySlugsDataCache()
+		if err != nil {
+			return err
+		}
+
+		err = sc.GenerateCompaniesDataCache()
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(changes.SICs) > 0 {
+		err := sc.setPageData(cache.SECSICs, sc.GenerateSICsJSON)
+		if err != nil {
+			return err
+		}
+
+		for _, sic := range changes.SICs {
+			err = sc.GenerateCompaniesWithSICPageDataCache(sic)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// GenerateMonthURLs returns the URLs of the filings pages of a month: the
+// month itself, its days and the filings of each CIK on those days.
+func (sc *SECCache) GenerateMonthURLs(baseURL string, year int, month int) ([]string, error) {
+	urls := []string{fmt.Sprintf("%vfilings/%v/%v", baseURL, year, month)}
+
+	days, err := secutil.GetFilingDaysFromMonthYear(sc.DB, year, month)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, day := range days {
+		urls = append(urls, fmt.Sprintf("%vfilings/%v/%v/%v", baseURL, year, month, day))
+
+		companies, err := secutil.GetFilingCompaniesFromYearMonthDay(sc.DB, year, month, day)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, company := range companies {
+			urls = append(urls, fmt.Sprintf("%vfilings/%v/%v/%v/%v", baseURL, year, month, day, company.CIKNumber))
+		}
+	}
+
+	return urls, nil
+}
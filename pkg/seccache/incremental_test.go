@@ -0,0 +1,169 @@
+package seccache
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestGetChanges(t *testing.T) {
+	sectest.ForEachDatabase(t, testGetChanges)
+}
+
+func testGetChanges(t *testing.T, dbConfig config.DatabaseConfig) {
+	cfg := config.Config{Database: dbConfig}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`INSERT INTO sec.ciks (cik) VALUES (320193), (789019), (1018724);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Each run of regen sees the rows inserted since the previous one and
+	// saves the mark taken before looking for them
+	tests := []struct {
+		name   string
+		insert string
+		want   Changes
+	}{
+		{
+			name: "first run",
+			insert: `INSERT INTO sec.secItemFile (ciknumber, accessionnumber, fillingdate, assignedsic, xbrlfile, updated_at) VALUES
+				(320193, '0000320193-22-000007', '2022-01-28', 3571, 'aapl-20211225.htm', '2022-01-28 18:00:00+00:00');`,
+			want: Changes{
+				Months:    []Month{{2022, 1}},
+				Days:      []Day{{2022, 1, 28}},
+				CIKsByDay: map[Day][]int{{2022, 1, 28}: {320193}},
+				CIKs:      []int{320193},
+				SICs:      []string{"3571"},
+			},
+		},
+		{
+			name: "no changes",
+			want: Changes{CIKsByDay: map[Day][]int{}},
+		},
+		{
+			name: "new filing",
+			insert: `INSERT INTO sec.secItemFile (ciknumber, accessionnumber, fillingdate, assignedsic, xbrlfile, updated_at) VALUES
+				(320193, '0000320193-22-000059', '2022-04-29', 3571, 'aapl-20220326.htm', '2022-04-29 18:00:00+00:00'),
+				(320193, '0000320193-22-000059', '2022-04-29', 3571, 'aapl-20220326_htm.xml', '2022-04-29 18:00:00+00:00');`,
+			want: Changes{
+				Months:    []Month{{2022, 4}},
+				Days:      []Day{{2022, 4, 29}},
+				CIKsByDay: map[Day][]int{{2022, 4, 29}: {320193}},
+				CIKs:      []int{320193},
+				SICs:      []string{"3571"},
+			},
+		},
+		{
+			name: "new CIK",
+			insert: `INSERT INTO sec.secItemFile (ciknumber, accessionnumber, fillingdate, assignedsic, xbrlfile, updated_at) VALUES
+				(789019, '0001564590-22-015675', '2022-04-26', 7372, 'msft-10q_20220331.htm', '2022-04-30 18:00:00+00:00');`,
+			want: Changes{
+				Months:    []Month{{2022, 4}},
+				Days:      []Day{{2022, 4, 26}},
+				CIKsByDay: map[Day][]int{{2022, 4, 26}: {789019}},
+				CIKs:      []int{789019},
+				SICs:      []string{"7372"},
+			},
+		},
+		{
+			// The page of the SIC the CIK filed with before is rebuilt too
+			name: "new SIC",
+			insert: `INSERT INTO sec.secItemFile (ciknumber, accessionnumber, fillingdate, assignedsic, xbrlfile, updated_at) VALUES
+				(320193, '0000320193-22-000070', '2022-07-29', 3572, 'aapl-20220625.htm', '2022-07-29 18:00:00+00:00');`,
+			want: Changes{
+				Months:    []Month{{2022, 7}},
+				Days:      []Day{{2022, 7, 29}},
+				CIKsByDay: map[Day][]int{{2022, 7, 29}: {320193}},
+				CIKs:      []int{320193},
+				SICs:      []string{"3571", "3572"},
+			},
+		},
+		{
+			name: "new financial statements",
+			insert: `INSERT INTO fsds.sub (adsh, cik, name, sic, updated_at) VALUES
+				('0001018724-22-000019', 1018724, 'AMAZON COM INC', '5961', '2022-08-01 18:00:00+00:00');`,
+			want: Changes{
+				CIKsByDay: map[Day][]int{},
+				CIKs:      []int{1018724},
+			},
+		},
+		{
+			name: "files unpacked from a ZIP",
+			insert: `INSERT INTO sec.secItemFile (ciknumber, accessionnumber, xbrlfile, updated_at) VALUES
+				(789019, '0001564590-22-015675', 'Financial_Report.xlsx', '2022-08-02 18:00:00+00:00');`,
+			want: Changes{
+				CIKsByDay: map[Day][]int{},
+				CIKs:      []int{789019},
+				SICs:      []string{"7372"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		if tt.insert != "" {
+			_, err = db.Exec(tt.insert)
+			if err != nil {
+				t.Fatalf("%v: %v", tt.name, err)
+			}
+		}
+
+		current, err := CurrentRegenMark(db, RegenPages)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		mark, err := GetRegenMark(db, RegenPages)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		changes, err := GetChanges(db, mark)
+		if err != nil {
+			t.Fatalf("%v: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(changes, tt.want) {
+			t.Errorf("%v: GetChanges() = %+v, want %+v", tt.name, changes, tt.want)
+		}
+
+		err = SaveRegenMark(db, current)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		saved, err := GetRegenMark(db, RegenPages)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !saved.SECItemFileUpdatedAt.Equal(current.SECItemFileUpdatedAt) || !saved.SubUpdatedAt.Equal(current.SubUpdatedAt) {
+			t.Errorf("%v: GetRegenMark() = %+v, want the saved %+v", tt.name, saved, current)
+		}
+	}
+
+	// Marks are kept per target: the sitemap never ran, so it sees everything
+	mark, err := GetRegenMark(db, RegenSitemap)
+	if err != nil {
+		t.Fatal(err)
+	}
+	changes, err := GetChanges(db, mark)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []int{320193, 789019, 1018724}; !reflect.DeepEqual(changes.CIKs, want) {
+		t.Errorf("GetChanges() of %v = %v, want CIKs %v", RegenSitemap, changes.CIKs, want)
+	}
+}
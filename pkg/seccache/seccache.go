@@ -13,9 +13,9 @@ import (
 	"github.com/equres/sec/pkg/secevent"
 	"github.com/equres/sec/pkg/secextra"
 	"github.com/equres/sec/pkg/secsic"
+	"github.com/equres/sec/pkg/secslug"
 	"github.com/equres/sec/pkg/secutil"
 	"github.com/equres/sec/pkg/secworklist"
-	"github.com/equres/sec/pkg/server"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -74,7 +74,11 @@ func (sc *SECCache) GenerateStatsJSON() (string, error) {
 
 	var eventStatsArrFormattedDates []secevent.EventStat
 	for _, eventStat := range eventStatsArr {
-		eventStat.Date = eventStat.DateTime.Format("2006-01-02")
+		eventDate, err := time.Parse(time.RFC3339, eventStat.Date)
+		if err != nil {
+			return "", err
+		}
+		eventStat.Date = eventDate.Format("2006-01-02")
 		eventStatsArrFormattedDates = append(eventStatsArrFormattedDates, eventStat)
 	}
 
@@ -94,7 +98,11 @@ func (sc *SECCache) GenerateBackupStatsJSON() (string, error) {
 
 	var eventStatsArrFormattedDates []secevent.BackupEventStat
 	for _, eventStat := range eventStatsArr {
-		eventStat.Date = eventStat.DateTime.Format("2006-01-02")
+		eventDate, err := time.Parse(time.RFC3339, eventStat.Date)
+		if err != nil {
+			return "", err
+		}
+		eventStat.Date = eventDate.Format("2006-01-02")
 		eventStatsArrFormattedDates = append(eventStatsArrFormattedDates, eventStat)
 	}
 
@@ -134,46 +142,6 @@ func (sc *SECCache) GenerateBackupStatsCache() error {
 	return nil
 }
 
-func (sc *SECCache) GenerateYearMonthDayCIKURLs(db *sqlx.DB, baseURL string) ([]string, error) {
-	uniqueYears, err := secworklist.UniqueYears(sc.DB)
-	if err != nil {
-		return nil, err
-	}
-
-	var urls []string
-	for _, year := range uniqueYears {
-		urls = append(urls, fmt.Sprintf("%vfilings/%v", baseURL, year))
-
-		months, err := secworklist.MonthsInYear(db, year)
-		if err != nil {
-			return nil, err
-		}
-
-		for _, month := range months {
-			urls = append(urls, fmt.Sprintf("%vfilings/%v/%v", baseURL, year, month))
-
-			days, err := secutil.GetFilingDaysFromMonthYear(sc.DB, year, month)
-			if err != nil {
-				return nil, err
-			}
-			for _, day := range days {
-				urls = append(urls, fmt.Sprintf("%vfilings/%v/%v/%v", baseURL, year, month, day))
-
-				companies, err := secutil.GetFilingCompaniesFromYearMonthDay(db, year, month, day)
-				if err != nil {
-					return nil, err
-				}
-
-				for _, company := range companies {
-					urls = append(urls, fmt.Sprintf("%vfilings/%v/%v/%v/%v", baseURL, year, month, day, company.CIKNumber))
-				}
-			}
-		}
-	}
-
-	return urls, nil
-}
-
 func (sc *SECCache) GenerateHomePageDataCache() error {
 	formattedFilingsJSON, err := sc.GenerateTopFiveRecentFilingsJSON()
 	if err != nil {
@@ -215,6 +183,20 @@ func (sc *SECCache) GenerateHomePageDataCache() error {
 	return nil
 }
 
+func (sc *SECCache) GenerateMonthsInYearJSON(year int) (string, error) {
+	months, err := secworklist.MonthsInYear(sc.DB, year)
+	if err != nil {
+		return "", err
+	}
+
+	monthsJSON, err := json.Marshal(months)
+	if err != nil {
+		return "", err
+	}
+
+	return string(monthsJSON), nil
+}
+
 func (sc *SECCache) GenerateMonthDayCIKDataCache() error {
 	years, err := secworklist.UniqueYears(sc.DB)
 	if err != nil {
@@ -232,7 +214,7 @@ func (sc *SECCache) GenerateMonthDayCIKDataCache() error {
 			return err
 		}
 
-		err = sc.S.Cache.MustSet(fmt.Sprintf("%v_%v", cache.SECMonthsInYear, year), string(monthsJSON))
+		err = sc.S.Cache.MustSet(cache.MonthsInYearKey(year), string(monthsJSON))
 		if err != nil {
 			return err
 		}
@@ -246,6 +228,20 @@ func (sc *SECCache) GenerateMonthDayCIKDataCache() error {
 	return nil
 }
 
+func (sc *SECCache) GenerateDaysInMonthJSON(year int, month int) (string, error) {
+	days, err := secutil.GetFilingDaysFromMonthYear(sc.DB, year, month)
+	if err != nil {
+		return "", err
+	}
+
+	daysJSON, err := json.Marshal(days)
+	if err != nil {
+		return "", err
+	}
+
+	return string(daysJSON), nil
+}
+
 func (sc *SECCache) GenerateDaysInMonthPageDataCache(year int, months []int) error {
 	for _, month := range months {
 		days, err := secutil.GetFilingDaysFromMonthYear(sc.DB, year, month)
@@ -258,7 +254,7 @@ func (sc *SECCache) GenerateDaysInMonthPageDataCache(year int, months []int) err
 			return err
 		}
 
-		err = sc.S.Cache.MustSet(fmt.Sprintf("%v_%v_%v", cache.SECDaysInMonth, year, month), string(daysJSON))
+		err = sc.S.Cache.MustSet(cache.DaysInMonthKey(year, month), string(daysJSON))
 		if err != nil {
 			return err
 		}
@@ -274,6 +270,20 @@ func (sc *SECCache) GenerateDaysInMonthPageDataCache(year int, months []int) err
 	return nil
 }
 
+func (sc *SECCache) GenerateCompaniesInDayJSON(year int, month int, day int) (string, error) {
+	companies, err := secutil.GetFilingCompaniesFromYearMonthDay(sc.DB, year, month, day)
+	if err != nil {
+		return "", err
+	}
+
+	companiesJSON, err := json.Marshal(companies)
+	if err != nil {
+		return "", err
+	}
+
+	return string(companiesJSON), nil
+}
+
 func (sc *SECCache) GenerateCompaniesInDayPageData(year int, month int, day int) error {
 	companies, err := secutil.GetFilingCompaniesFromYearMonthDay(sc.DB, year, month, day)
 	if err != nil {
@@ -285,7 +295,7 @@ func (sc *SECCache) GenerateCompaniesInDayPageData(year int, month int, day int)
 		return err
 	}
 
-	err = sc.S.Cache.MustSet(fmt.Sprintf("%v_%v_%v_%v", cache.SECCompaniesInDay, year, month, day), string(companiesJSON))
+	err = sc.S.Cache.MustSet(cache.CompaniesInDayKey(year, month, day), string(companiesJSON))
 	if err != nil {
 		return err
 	}
@@ -304,18 +314,27 @@ func (sc *SECCache) GenerateCompaniesInDayPageData(year int, month int, day int)
 	return nil
 }
 
-func (sc *SECCache) GenerateFilingsInDayPageDataCache(year int, month int, day int, cik int) error {
+func (sc *SECCache) GenerateFilingsInDayJSON(year int, month int, day int, cik int) (string, error) {
 	filings, err := secutil.SearchFilingsByYearMonthDayCIK(sc.DB, year, month, day, cik)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	filingsJSON, err := json.Marshal(filings)
+	if err != nil {
+		return "", err
+	}
+
+	return string(filingsJSON), nil
+}
+
+func (sc *SECCache) GenerateFilingsInDayPageDataCache(year int, month int, day int, cik int) error {
+	filingsJSON, err := sc.GenerateFilingsInDayJSON(year, month, day, cik)
 	if err != nil {
 		return err
 	}
 
-	err = sc.S.Cache.MustSet(fmt.Sprintf("%v_%v_%v_%v_%v", cache.SECFilingsInDay, year, month, day, cik), string(filingsJSON))
+	err = sc.S.Cache.MustSet(cache.FilingsInDayKey(year, month, day, cik), filingsJSON)
 	if err != nil {
 		return err
 	}
@@ -328,21 +347,31 @@ func (sc *SECCache) GenerateFilingsInDayPageDataCache(year int, month int, day i
 	return nil
 }
 
-func (sc *SECCache) GenerateCompanySlugsDataCache() error {
-	companies, err := sec.GetAllCompanies(sc.DB)
+// GenerateCompanySlugsHTML lists every company once, under the canonical
+// slug of its latest name.
+func (sc *SECCache) GenerateCompanySlugsHTML() (string, error) {
+	names, err := secslug.GetCompanyNames(sc.DB)
 	if err != nil {
-		return err
+		return "", err
 	}
 
-	companySlugs := server.GetCompanySlugs(companies)
-
 	var allCompanies []sec.Company
-	for slug, company := range companySlugs {
+	for _, s := range secslug.Slugs(names) {
+		if !s.Canonical {
+			continue
+		}
 		allCompanies = append(allCompanies, sec.Company{
-			CompanyName: company.CompanyName,
-			Slug:        slug,
+			CompanyName: s.Name,
+			CIKNumber:   strconv.Itoa(s.CIK),
+			Slug:        s.Slug,
 		})
 	}
+	sort.Slice(allCompanies, func(i, j int) bool {
+		if allCompanies[i].CompanyName != allCompanies[j].CompanyName {
+			return allCompanies[i].CompanyName < allCompanies[j].CompanyName
+		}
+		return allCompanies[i].Slug < allCompanies[j].Slug
+	})
 
 	var companiesHTML string
 	for index, company := range allCompanies {
@@ -353,7 +382,16 @@ func (sc *SECCache) GenerateCompanySlugsDataCache() error {
 		</tr>`, index+1, company.Slug, company.CompanyName)
 	}
 
-	err = sc.S.Cache.MustSet(cache.SECCompanySlugsHTML, string(companiesHTML))
+	return companiesHTML, nil
+}
+
+func (sc *SECCache) GenerateCompanySlugsDataCache() error {
+	companiesHTML, err := sc.GenerateCompanySlugsHTML()
+	if err != nil {
+		return err
+	}
+
+	err = sc.S.Cache.MustSet(cache.SECCompanySlugsHTML, companiesHTML)
 	if err != nil {
 		return err
 	}
@@ -361,12 +399,11 @@ func (sc *SECCache) GenerateCompanySlugsDataCache() error {
 	return nil
 }
 
-func (sc *SECCache) GenerateCompanyFilingsPageDataCache(cik int) error {
+func (sc *SECCache) GenerateCompanyFilingsHTML(cik int) (string, error) {
 	filings, err := sec.GetCompanyFilingsFromCIK(sc.DB, cik)
 	if err != nil {
-		return err
+		return "", err
 	}
-
 	type FormattedFiling struct {
 		CompanyName string
 		FillingDate string
@@ -418,7 +455,16 @@ func (sc *SECCache) GenerateCompanyFilingsPageDataCache(cik int) error {
 		}
 	}
 
-	err = sc.S.Cache.MustSet(fmt.Sprintf("%v_%v", cache.SECCompanyFilingsHTML, cik), HTMLList)
+	return HTMLList, nil
+}
+
+func (sc *SECCache) GenerateCompanyFilingsPageDataCache(cik int) error {
+	HTMLList, err := sc.GenerateCompanyFilingsHTML(cik)
+	if err != nil {
+		return err
+	}
+
+	err = sc.S.Cache.MustSet(cache.CompanyFilingsHTMLKey(cik), HTMLList)
 	if err != nil {
 		return err
 	}
@@ -426,6 +472,20 @@ func (sc *SECCache) GenerateCompanyFilingsPageDataCache(cik int) error {
 	return nil
 }
 
+func (sc *SECCache) GenerateSICsJSON() (string, error) {
+	sics, err := secsic.GetAllSICCodes(sc.DB)
+	if err != nil {
+		return "", err
+	}
+
+	sicsJSON, err := json.Marshal(sics)
+	if err != nil {
+		return "", err
+	}
+
+	return string(sicsJSON), nil
+}
+
 func (sc *SECCache) GenerateSICPageDataCache() error {
 	sics, err := secsic.GetAllSICCodes(sc.DB)
 	if err != nil {
@@ -442,6 +502,28 @@ func (sc *SECCache) GenerateSICPageDataCache() error {
 		return err
 	}
 
+	treeJSON, err := sc.GenerateSICTreeJSON()
+	if err != nil {
+		return err
+	}
+
+	err = sc.S.Cache.MustSet(cache.SECSICTree, treeJSON)
+	if err != nil {
+		return err
+	}
+
+	for name := range secsic.Presets {
+		companiesJSON, err := sc.GenerateSICPresetCompaniesJSON(name)
+		if err != nil {
+			return err
+		}
+
+		err = sc.S.Cache.MustSet(cache.SICPresetCompaniesKey(name), companiesJSON)
+		if err != nil {
+			return err
+		}
+	}
+
 	for _, sic := range sics {
 		err = sc.GenerateCompaniesWithSICPageDataCache(sic.SIC)
 		if err != nil {
@@ -452,18 +534,63 @@ func (sc *SECCache) GenerateSICPageDataCache() error {
 	return nil
 }
 
-func (sc *SECCache) GenerateCompaniesWithSICPageDataCache(sic string) error {
+// GenerateSICTreeJSON returns the SIC hierarchy with company counts.
+func (sc *SECCache) GenerateSICTreeJSON() (string, error) {
+	tree, err := secsic.GetTree(sc.DB)
+	if err != nil {
+		return "", err
+	}
+
+	treeJSON, err := json.Marshal(tree)
+	if err != nil {
+		return "", err
+	}
+
+	return string(treeJSON), nil
+}
+
+// GenerateSICPresetCompaniesJSON returns the companies of the SIC preset
+// named name.
+func (sc *SECCache) GenerateSICPresetCompaniesJSON(name string) (string, error) {
+	preset, ok := secsic.Presets[name]
+	if !ok {
+		return "", fmt.Errorf("unknown SIC preset: %v", name)
+	}
+
+	companies, err := secsic.GetPresetCompanies(sc.DB, preset)
+	if err != nil {
+		return "", err
+	}
+
+	companiesJSON, err := json.Marshal(companies)
+	if err != nil {
+		return "", err
+	}
+
+	return string(companiesJSON), nil
+}
+
+func (sc *SECCache) GenerateCompaniesWithSICJSON(sic string) (string, error) {
 	companies, err := secsic.GetAllCompaniesWithSIC(sc.DB, sic)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	companiesJSON, err := json.Marshal(companies)
+	if err != nil {
+		return "", err
+	}
+
+	return string(companiesJSON), nil
+}
+
+func (sc *SECCache) GenerateCompaniesWithSICPageDataCache(sic string) error {
+	companiesJSON, err := sc.GenerateCompaniesWithSICJSON(sic)
 	if err != nil {
 		return err
 	}
 
-	err = sc.S.Cache.MustSet(fmt.Sprintf("%v_%v", cache.SECCompaniesWithSIC, sic), string(companiesJSON))
+	err = sc.S.Cache.MustSet(cache.CompaniesWithSICKey(sic), companiesJSON)
 	if err != nil {
 		return err
 	}
@@ -545,18 +672,27 @@ func (sc *SECCache) GenerateHourlyDownloadStatsPageDataCache() error {
 	return nil
 }
 
-func (sc *SECCache) GenerateCompaniesDataCache() error {
+func (sc *SECCache) GenerateCompaniesJSON() (string, error) {
 	companies, err := sec.GetAllCompanies(sc.DB)
 	if err != nil {
-		return err
+		return "", err
 	}
 
 	companyJSON, err := json.Marshal(companies)
+	if err != nil {
+		return "", err
+	}
+
+	return string(companyJSON), nil
+}
+
+func (sc *SECCache) GenerateCompaniesDataCache() error {
+	companyJSON, err := sc.GenerateCompaniesJSON()
 	if err != nil {
 		return err
 	}
 
-	err = sc.S.Cache.MustSet(fmt.Sprintf("%v", cache.SECCompanies), string(companyJSON))
+	err = sc.S.Cache.MustSet(cache.SECCompanies, companyJSON)
 	if err != nil {
 		return err
 	}
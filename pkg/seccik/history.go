@@ -0,0 +1,135 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package seccik
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// TickerSpan is a ticker a CIK traded under from ValidFrom until ValidTo,
+// which isn't set while it still does.
+type TickerSpan struct {
+	CIK       int          `db:"cik"`
+	Ticker    string       `db:"ticker"`
+	Exchange  string       `db:"exchange"`
+	ValidFrom time.Time    `db:"valid_from"`
+	ValidTo   sql.NullTime `db:"valid_to"`
+}
+
+// NameSpan is a name a CIK filed under from ValidFrom until ValidTo, which
+// isn't set for the current name.
+type NameSpan struct {
+	CIK       int          `db:"cik"`
+	Name      string       `db:"name"`
+	ValidFrom time.Time    `db:"valid_from"`
+	ValidTo   sql.NullTime `db:"valid_to"`
+}
+
+// Current reports whether the ticker is still listed.
+func (t TickerSpan) Current() bool {
+	return !t.ValidTo.Valid
+}
+
+func (n NameSpan) Current() bool {
+	return !n.ValidTo.Valid
+}
+
+// GetTickerHistory returns the tickers of the CIK, the latest first.
+func GetTickerHistory(db *sqlx.DB, cik int) ([]TickerSpan, error) {
+	var spans []TickerSpan
+	err := db.Select(&spans, `
+		SELECT cik, ticker, exchange, valid_from, valid_to
+		FROM sec.ticker_history
+		WHERE cik = $1
+		ORDER BY valid_to DESC NULLS FIRST, valid_from DESC, ticker;`, cik)
+	if err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// GetNameHistory returns the names of the CIK, the current one first.
+func GetNameHistory(db *sqlx.DB, cik int) ([]NameSpan, error) {
+	var spans []NameSpan
+	err := db.Select(&spans, `
+		SELECT cik, name, valid_from, valid_to
+		FROM sec.name_history
+		WHERE cik = $1
+		ORDER BY valid_from DESC;`, cik)
+	if err != nil {
+		return nil, err
+	}
+	return spans, nil
+}
+
+// GetCompanyTickerOn returns the ticker of the CIK on date, or "" if it
+// had none then or its history doesn't go back that far.
+func GetCompanyTickerOn(db *sqlx.DB, cik int, date time.Time) (string, error) {
+	var tickers []string
+	err := db.Select(&tickers, `
+		SELECT ticker
+		FROM sec.ticker_history
+		WHERE cik = $1
+		AND valid_from <= $2
+		AND (valid_to IS NULL OR valid_to > $2)
+		ORDER BY valid_from DESC, ticker
+		LIMIT 1;`, cik, date)
+	if err != nil {
+		return "", err
+	}
+
+	if len(tickers) < 1 {
+		return "", nil
+	}
+
+	return tickers[0], nil
+}
+
+// GetCompanyNameOn returns the name of the CIK on date, or "" if its
+// history doesn't go back that far.
+func GetCompanyNameOn(db *sqlx.DB, cik int, date time.Time) (string, error) {
+	var names []string
+	err := db.Select(&names, `
+		SELECT name
+		FROM sec.name_history
+		WHERE cik = $1
+		AND valid_from <= $2
+		AND (valid_to IS NULL OR valid_to > $2)
+		ORDER BY valid_from DESC
+		LIMIT 1;`, cik, date)
+	if err != nil {
+		return "", err
+	}
+
+	if len(names) < 1 {
+		return "", nil
+	}
+
+	return names[0], nil
+}
+
+// GetCIKFromTicker resolves a ticker, current or former, to the CIK that
+// traded under it on date. With a zero date, the CIK that traded under it
+// last is returned. 0 if no CIK ever did.
+func GetCIKFromTicker(db *sqlx.DB, ticker string, date time.Time) (int, error) {
+	var ciks []int
+	err := db.Select(&ciks, `
+		SELECT cik
+		FROM sec.ticker_history
+		WHERE UPPER(ticker) = $1
+		AND ($2 OR (valid_from <= $3 AND (valid_to IS NULL OR valid_to > $3)))
+		ORDER BY valid_to DESC NULLS FIRST, valid_from DESC
+		LIMIT 1;`, strings.ToUpper(strings.TrimSpace(ticker)), date.IsZero(), date)
+	if err != nil {
+		return 0, err
+	}
+
+	if len(ciks) < 1 {
+		return 0, nil
+	}
+
+	return ciks[0], nil
+}
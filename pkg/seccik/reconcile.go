@@ -0,0 +1,505 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package seccik
+
+import (
+	"bufio"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// Sources of entity names
+const (
+	SourceLookup  = "cik-lookup"
+	SourceTickers = "tickers"
+	SourceFilings = "filings"
+)
+
+// Flags of entities known from one side only
+const (
+	FlagUnregistered = "unregistered"
+	FlagUnfiled      = "unfiled"
+)
+
+// EntityName is a name a CIK is known under in a source.
+type EntityName struct {
+	CIK       int          `db:"cik"`
+	Name      string       `db:"name"`
+	Source    string       `db:"source"`
+	FirstSeen sql.NullTime `db:"first_seen"`
+	LastSeen  sql.NullTime `db:"last_seen"`
+}
+
+// Entity is a CIK reconciled across the sources. InRegistry is set for
+// CIKs in the CIK lookup file or the tickers files, InFilings for CIKs
+// with indexed filings.
+type Entity struct {
+	CIK        int          `db:"cik"`
+	Name       string       `db:"name"`
+	FirstSeen  sql.NullTime `db:"first_seen"`
+	LastSeen   sql.NullTime `db:"last_seen"`
+	FilesXBRL  bool         `db:"files_xbrl"`
+	InRegistry bool         `db:"in_registry"`
+	InFilings  bool         `db:"in_filings"`
+}
+
+// Flag returns FlagUnregistered for CIKs that file but are in no registry,
+// FlagUnfiled for CIKs in a registry without filings, and "" otherwise.
+func (e Entity) Flag() string {
+	switch {
+	case e.InFilings && !e.InRegistry:
+		return FlagUnregistered
+	case e.InRegistry && !e.InFilings:
+		return FlagUnfiled
+	}
+	return ""
+}
+
+var lookupLine = regexp.MustCompile(`^(.*):([0-9]+):\s*$`)
+
+// ParseLookupLine parses a "NAME:CIK:" line of cik-lookup-data.txt. Names
+// may contain colons.
+func ParseLookupLine(line string) (string, int, bool) {
+	match := lookupLine.FindStringSubmatch(line)
+	if match == nil {
+		return "", 0, false
+	}
+	cik, err := strconv.Atoi(match[2])
+	if err != nil || cik == 0 {
+		return "", 0, false
+	}
+	name := strings.TrimSpace(match[1])
+	if name == "" {
+		return "", 0, false
+	}
+	return name, cik, true
+}
+
+// ReadLookupFile returns every name of cik-lookup-data.txt, seen on the
+// date the file was last modified.
+func ReadLookupFile(path string) ([]EntityName, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+	seen := sql.NullTime{Time: info.ModTime().UTC().Truncate(24 * time.Hour), Valid: true}
+
+	var names []EntityName
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		name, cik, ok := ParseLookupLine(scanner.Text())
+		if !ok {
+			continue
+		}
+		names = append(names, EntityName{CIK: cik, Name: name, Source: SourceLookup, FirstSeen: seen, LastSeen: seen})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return names, nil
+}
+
+var sourcePriority = map[string]int{
+	SourceFilings: 3,
+	SourceTickers: 2,
+	SourceLookup:  1,
+}
+
+// Reconcile returns one entity per CIK of names, ordered by CIK. An entity
+// is named after its latest filing, or after its latest listing in a
+// registry when it doesn't file.
+func Reconcile(names []EntityName, xbrl map[int]bool) []Entity {
+	entities := make(map[int]*Entity)
+	best := make(map[int]EntityName)
+	var ciks []int
+
+	for _, name := range names {
+		entity, ok := entities[name.CIK]
+		if !ok {
+			entity = &Entity{CIK: name.CIK, FilesXBRL: xbrl[name.CIK]}
+			entities[name.CIK] = entity
+			ciks = append(ciks, name.CIK)
+		}
+
+		if name.Source == SourceFilings {
+			entity.InFilings = true
+		} else {
+			entity.InRegistry = true
+		}
+		if name.FirstSeen.Valid && (!entity.FirstSeen.Valid || name.FirstSeen.Time.Before(entity.FirstSeen.Time)) {
+			entity.FirstSeen = name.FirstSeen
+		}
+		if name.LastSeen.Valid && (!entity.LastSeen.Valid || name.LastSeen.Time.After(entity.LastSeen.Time)) {
+			entity.LastSeen = name.LastSeen
+		}
+
+		current, ok := best[name.CIK]
+		if !ok || sourcePriority[name.Source] > sourcePriority[current.Source] ||
+			(sourcePriority[name.Source] == sourcePriority[current.Source] && name.LastSeen.Time.After(current.LastSeen.Time)) {
+			best[name.CIK] = name
+		}
+	}
+
+	sort.Ints(ciks)
+	reconciled := make([]Entity, len(ciks))
+	for i, cik := range ciks {
+		reconciled[i] = *entities[cik]
+		reconciled[i].Name = best[cik].Name
+	}
+	return reconciled
+}
+
+// GetFilingNames returns the names CIKs filed under, seen from their first
+// to their last filing under the name.
+func GetFilingNames(db *sqlx.DB) ([]EntityName, error) {
+	return getSeenNames(db, `
+		SELECT ciknumber AS cik, companyname AS name, 'filings' AS source,
+			DATE(MIN(fillingdate)) AS first_seen, DATE(MAX(fillingdate)) AS last_seen
+		FROM sec.secItemFile
+		WHERE ciknumber IS NOT NULL AND companyname IS NOT NULL AND companyname <> ''
+		GROUP BY ciknumber, companyname;`)
+}
+
+// GetTickerNames returns the names of the company tickers files, seen while
+// they were listed.
+func GetTickerNames(db *sqlx.DB) ([]EntityName, error) {
+	return getSeenNames(db, `
+		SELECT cik, title AS name, 'tickers' AS source,
+			DATE(MIN(created_at)) AS first_seen, DATE(MAX(COALESCE(updated_at, created_at))) AS last_seen
+		FROM sec.tickers
+		WHERE cik IS NOT NULL AND title IS NOT NULL AND title <> ''
+		GROUP BY cik, title;`)
+}
+
+// getSeenNames returns the names of query, whose first_seen and last_seen
+// are DATE()s: dates on Postgres, text on SQLite. Both start with the date
+// as 2006-01-02 when scanned into a string.
+func getSeenNames(db *sqlx.DB, query string) ([]EntityName, error) {
+	var rows []struct {
+		CIK       int            `db:"cik"`
+		Name      string         `db:"name"`
+		Source    string         `db:"source"`
+		FirstSeen sql.NullString `db:"first_seen"`
+		LastSeen  sql.NullString `db:"last_seen"`
+	}
+	err := db.Select(&rows, query)
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]EntityName, len(rows))
+	for i, row := range rows {
+		names[i] = EntityName{CIK: row.CIK, Name: row.Name, Source: row.Source, FirstSeen: parseSeen(row.FirstSeen), LastSeen: parseSeen(row.LastSeen)}
+	}
+	return names, nil
+}
+
+func parseSeen(date sql.NullString) sql.NullTime {
+	if !date.Valid || len(date.String) < len("2006-01-02") {
+		return sql.NullTime{}
+	}
+	t, err := time.Parse("2006-01-02", date.String[:len("2006-01-02")])
+	if err != nil {
+		return sql.NullTime{}
+	}
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+// GetXBRLFilers returns the CIKs with XBRL filings or financial statement
+// data.
+func GetXBRLFilers(db *sqlx.DB) (map[int]bool, error) {
+	var ciks []int
+	err := db.Select(&ciks, `
+		SELECT DISTINCT ciknumber
+		FROM sec.secItemFile
+		WHERE ciknumber IS NOT NULL AND (xbrltype = 'EX-101.INS' OR xbrlinlinexbrl = true)
+		UNION
+		SELECT DISTINCT cik
+		FROM fsds.sub
+		WHERE cik IS NOT NULL;`)
+	if err != nil {
+		return nil, err
+	}
+
+	filers := make(map[int]bool)
+	for _, cik := range ciks {
+		filers[cik] = true
+	}
+	return filers, nil
+}
+
+// SaveEntityNames adds the names to sec.entity_names, widening the dates
+// names already stored were seen.
+func SaveEntityNames(db *sqlx.DB, names []EntityName) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	// Dropped by the end of the transaction, SQLite has no ON COMMIT DROP
+	createTemp := `
+		CREATE TEMP TABLE entity_names_new (
+			cik integer, name text, source text, first_seen date, last_seen date
+		) ON COMMIT DROP;`
+	if database.IsSQLite(db) {
+		createTemp = strings.Replace(createTemp, " ON COMMIT DROP", "", 1)
+	}
+	_, err = tx.Exec(createTemp)
+	if err != nil {
+		return err
+	}
+
+	copier, err := newCopier(tx, database.IsSQLite(db), "", "entity_names_new", "cik", "name", "source", "first_seen", "last_seen")
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		err = copier.Copy(name.CIK, name.Name, name.Source, name.FirstSeen, name.LastSeen)
+		if err != nil {
+			copier.Close()
+			return err
+		}
+	}
+	err = copier.Close()
+	if err != nil {
+		return err
+	}
+
+	// SQLite needs the WHERE of an INSERT ... SELECT ... ON CONFLICT to tell
+	// ON CONFLICT from the ON of a join
+	_, err = tx.Exec(`
+		INSERT INTO sec.entity_names (cik, name, source, first_seen, last_seen, created_at, updated_at)
+		SELECT cik, name, source, MIN(first_seen), MAX(last_seen), NOW(), NOW()
+		FROM entity_names_new
+		WHERE true
+		GROUP BY cik, name, source
+		ON CONFLICT (cik, name, source)
+		DO UPDATE SET
+			first_seen = CASE WHEN EXCLUDED.first_seen < entity_names.first_seen THEN EXCLUDED.first_seen ELSE COALESCE(entity_names.first_seen, EXCLUDED.first_seen) END,
+			last_seen = CASE WHEN EXCLUDED.last_seen > entity_names.last_seen THEN EXCLUDED.last_seen ELSE COALESCE(entity_names.last_seen, EXCLUDED.last_seen) END,
+			updated_at = NOW();`)
+	if err != nil {
+		return err
+	}
+
+	if database.IsSQLite(db) {
+		_, err = tx.Exec(`DROP TABLE entity_names_new;`)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetEntityNames returns every name of sec.entity_names.
+func GetEntityNames(db *sqlx.DB) ([]EntityName, error) {
+	var names []EntityName
+	err := db.Select(&names, `
+		SELECT cik, name, source, first_seen, last_seen
+		FROM sec.entity_names
+		ORDER BY cik, last_seen, name;`)
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// SaveEntities replaces sec.entities and adds the CIKs missing from
+// sec.ciks.
+func SaveEntities(db *sqlx.DB, entities []Entity) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM sec.entities;`)
+	if err != nil {
+		return err
+	}
+
+	copier, err := newCopier(tx, database.IsSQLite(db), "sec", "entities", "cik", "name", "first_seen", "last_seen", "files_xbrl", "in_registry", "in_filings", "created_at", "updated_at")
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, e := range entities {
+		err = copier.Copy(e.CIK, e.Name, e.FirstSeen, e.LastSeen, e.FilesXBRL, e.InRegistry, e.InFilings, now, now)
+		if err != nil {
+			copier.Close()
+			return err
+		}
+	}
+	err = copier.Close()
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO sec.ciks (cik, created_at, updated_at)
+		SELECT cik, NOW(), NOW() FROM sec.entities
+		WHERE true
+		ON CONFLICT (cik) DO NOTHING;`)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// copier writes rows into a table: with COPY on Postgres and, as SQLite has
+// no COPY, with a prepared INSERT on SQLite.
+type copier struct {
+	stmt   *sql.Stmt
+	sqlite bool
+}
+
+// newCopier starts writing the columns of schema.table, or of the
+// temporary table if schema is "".
+func newCopier(tx *sqlx.Tx, sqlite bool, schema string, table string, columns ...string) (*copier, error) {
+	query := pq.CopyInSchema(schema, table, columns...)
+	if schema == "" {
+		query = pq.CopyIn(table, columns...)
+	}
+	if sqlite {
+		if schema != "" {
+			table = schema + "." + table
+		}
+		params := make([]string, len(columns))
+		for i := range columns {
+			params[i] = fmt.Sprintf("$%d", i+1)
+		}
+		query = fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v);", table, strings.Join(columns, ", "), strings.Join(params, ", "))
+	}
+
+	stmt, err := tx.Prepare(query)
+	if err != nil {
+		return nil, err
+	}
+	return &copier{stmt: stmt, sqlite: sqlite}, nil
+}
+
+// Copy writes a row, the values of the columns in order
+func (c *copier) Copy(values ...interface{}) error {
+	_, err := c.stmt.Exec(values...)
+	return err
+}
+
+// Close flushes the rows copied to Postgres and closes the statement
+func (c *copier) Close() error {
+	if !c.sqlite {
+		_, err := c.stmt.Exec()
+		if err != nil {
+			c.stmt.Close()
+			return err
+		}
+	}
+	return c.stmt.Close()
+}
+
+// ReconcileSummary counts the entities of a reconciliation.
+type ReconcileSummary struct {
+	Names        int
+	Entities     int
+	FilesXBRL    int
+	Unregistered int
+	Unfiled      int
+}
+
+// ReconcileCIKs gathers the names of the CIK lookup file at lookupPath, if
+// any, of the tickers files and of the filings, and rebuilds sec.entities
+// from every name stored so far.
+func ReconcileCIKs(db *sqlx.DB, lookupPath string) (ReconcileSummary, error) {
+	var names []EntityName
+	if lookupPath != "" {
+		lookupNames, err := ReadLookupFile(lookupPath)
+		if err != nil {
+			return ReconcileSummary{}, err
+		}
+		names = append(names, lookupNames...)
+	}
+
+	tickerNames, err := GetTickerNames(db)
+	if err != nil {
+		return ReconcileSummary{}, err
+	}
+	names = append(names, tickerNames...)
+
+	filingNames, err := GetFilingNames(db)
+	if err != nil {
+		return ReconcileSummary{}, err
+	}
+	names = append(names, filingNames...)
+
+	err = SaveEntityNames(db, names)
+	if err != nil {
+		return ReconcileSummary{}, err
+	}
+
+	allNames, err := GetEntityNames(db)
+	if err != nil {
+		return ReconcileSummary{}, err
+	}
+
+	xbrl, err := GetXBRLFilers(db)
+	if err != nil {
+		return ReconcileSummary{}, err
+	}
+
+	entities := Reconcile(allNames, xbrl)
+	err = SaveEntities(db, entities)
+	if err != nil {
+		return ReconcileSummary{}, err
+	}
+
+	summary := ReconcileSummary{Names: len(allNames), Entities: len(entities)}
+	for _, e := range entities {
+		if e.FilesXBRL {
+			summary.FilesXBRL++
+		}
+		switch e.Flag() {
+		case FlagUnregistered:
+			summary.Unregistered++
+		case FlagUnfiled:
+			summary.Unfiled++
+		}
+	}
+	return summary, nil
+}
+
+// GetFlaggedEntities returns the entities with the flag, ordered by CIK.
+func GetFlaggedEntities(db *sqlx.DB, flag string) ([]Entity, error) {
+	condition := "in_filings AND NOT in_registry"
+	if flag == FlagUnfiled {
+		condition = "in_registry AND NOT in_filings"
+	}
+
+	var entities []Entity
+	err := db.Select(&entities, `
+		SELECT cik, name, first_seen, last_seen, files_xbrl, in_registry, in_filings
+		FROM sec.entities
+		WHERE `+condition+`
+		ORDER BY cik;`)
+	if err != nil {
+		return nil, err
+	}
+	return entities, nil
+}
@@ -0,0 +1,72 @@
+package seccik
+
+import (
+	"database/sql"
+	"testing"
+	"time"
+)
+
+func seen(s string) sql.NullTime {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return sql.NullTime{Time: t, Valid: true}
+}
+
+func TestParseLookupLine(t *testing.T) {
+	tests := []struct {
+		line string
+		name string
+		cik  int
+		ok   bool
+	}{
+		{"APPLE INC:0000320193:", "APPLE INC", 320193, true},
+		{"ABC CORP: SERIES A:0001234567:", "ABC CORP: SERIES A", 1234567, true},
+		{"!J INC:0001438823:  ", "!J INC", 1438823, true},
+		{"NO CIK:", "", 0, false},
+		{":0000320193:", "", 0, false},
+	}
+
+	for _, test := range tests {
+		name, cik, ok := ParseLookupLine(test.line)
+		if name != test.name || cik != test.cik || ok != test.ok {
+			t.Errorf("ParseLookupLine(%q) = %q %v %v, want %q %v %v", test.line, name, cik, ok, test.name, test.cik, test.ok)
+		}
+	}
+}
+
+func TestReconcile(t *testing.T) {
+	names := []EntityName{
+		{CIK: 1326801, Name: "FACEBOOK INC", Source: SourceLookup, FirstSeen: seen("2022-01-01"), LastSeen: seen("2022-01-01")},
+		{CIK: 1326801, Name: "Meta Platforms, Inc.", Source: SourceTickers, FirstSeen: seen("2021-11-01"), LastSeen: seen("2022-03-01")},
+		{CIK: 1326801, Name: "FACEBOOK INC", Source: SourceFilings, FirstSeen: seen("2012-02-01"), LastSeen: seen("2021-10-29")},
+		{CIK: 1326801, Name: "Meta Platforms, Inc.", Source: SourceFilings, FirstSeen: seen("2021-11-01"), LastSeen: seen("2022-02-03")},
+		{CIK: 2, Name: "SHELL CO", Source: SourceLookup, FirstSeen: seen("2022-01-01"), LastSeen: seen("2022-01-01")},
+		{CIK: 1, Name: "UNLISTED FILER", Source: SourceFilings, FirstSeen: seen("2015-01-01"), LastSeen: seen("2016-01-01")},
+	}
+
+	entities := Reconcile(names, map[int]bool{1326801: true})
+
+	if len(entities) != 3 {
+		t.Fatalf("Reconcile() = %+v, want 3 entities", entities)
+	}
+
+	unlisted := entities[0]
+	if unlisted.CIK != 1 || unlisted.Name != "UNLISTED FILER" || unlisted.Flag() != FlagUnregistered {
+		t.Errorf("Reconcile()[0] = %+v, want CIK 1 flagged unregistered", unlisted)
+	}
+
+	shell := entities[1]
+	if shell.CIK != 2 || shell.Flag() != FlagUnfiled || shell.FilesXBRL {
+		t.Errorf("Reconcile()[1] = %+v, want CIK 2 flagged unfiled", shell)
+	}
+
+	meta := entities[2]
+	if meta.Name != "Meta Platforms, Inc." || meta.Flag() != "" || !meta.FilesXBRL {
+		t.Errorf("Reconcile()[2] = %+v, want Meta Platforms filing XBRL", meta)
+	}
+	if meta.FirstSeen != seen("2012-02-01") || meta.LastSeen != seen("2022-03-01") {
+		t.Errorf("Reconcile()[2] seen %v to %v, want 2012-02-01 to 2022-03-01", meta.FirstSeen.Time, meta.LastSeen.Time)
+	}
+}
@@ -26,13 +26,22 @@ func SaveCIK(db *sqlx.DB, cik int) error {
 	return nil
 }
 
+// GetCompanyNameFromCIK returns the current name of the CIK, falling back
+// to the names it's listed or filed under when it has no name history.
 func GetCompanyNameFromCIK(db *sqlx.DB, cik int) (string, error) {
 	var companyNames []string
-	err := db.Select(&companyNames, "SELECT title FROM sec.tickers WHERE cik = $1 AND title IS NOT NULL", cik)
+	err := db.Select(&companyNames, "SELECT name FROM sec.name_history WHERE cik = $1 AND valid_to IS NULL", cik)
 	if err != nil {
 		return "", err
 	}
 
+	if len(companyNames) < 1 {
+		err = db.Select(&companyNames, "SELECT title FROM sec.tickers WHERE cik = $1 AND title IS NOT NULL", cik)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	if len(companyNames) < 1 {
 		err = db.Select(&companyNames, "SELECT companyname FROM sec.secitemfile WHERE ciknumber = $1 AND companyname IS NOT NULL;", cik)
 		if err != nil {
@@ -47,13 +56,22 @@ func GetCompanyNameFromCIK(db *sqlx.DB, cik int) (string, error) {
 	return companyNames[0], nil
 }
 
+// GetCompanyTickerFromCIK returns the ticker the CIK currently trades
+// under, see GetCompanyTickerOn for earlier dates.
 func GetCompanyTickerFromCIK(db *sqlx.DB, cik int) (string, error) {
 	var companyTickers []string
-	err := db.Select(&companyTickers, "SELECT ticker FROM sec.tickers WHERE cik = $1", cik)
+	err := db.Select(&companyTickers, "SELECT ticker FROM sec.ticker_history WHERE cik = $1 AND valid_to IS NULL ORDER BY valid_from DESC, ticker", cik)
 	if err != nil {
 		return "", err
 	}
 
+	if len(companyTickers) < 1 {
+		err = db.Select(&companyTickers, "SELECT ticker FROM sec.tickers WHERE cik = $1", cik)
+		if err != nil {
+			return "", err
+		}
+	}
+
 	if len(companyTickers) < 1 {
 		return "", nil
 	}
@@ -56,10 +56,7 @@ func (sd *SECData) DownloadSECData(db *sqlx.DB, s *sec.SEC) error {
 	downloader.CurrentDownloadCount = 0
 	downloader.TotalDownloadsCount = len(worklist)
 
-	rateLimit, err := time.ParseDuration(fmt.Sprintf("%vms", s.Config.Main.RateLimitMs))
-	if err != nil {
-		return err
-	}
+	rateLimit := s.Config.Main.RateLimit()
 	for _, v := range worklist {
 		quarter := secutil.QuarterFromMonth(v.Month)
 
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"io"
 	"net/url"
+	"time"
 
 	"github.com/equres/sec/pkg/sec"
 	"github.com/gocarina/gocsv"
@@ -142,19 +143,8 @@ func FSDSSubDataUpsert(s *sec.SEC, db *sqlx.DB, reader io.ReadCloser) error {
 	}
 
 	for _, v := range subs {
-		var period sql.NullString
-		if v.Period != "" {
-			period = sql.NullString{
-				String: v.Period,
-			}
-		}
-
-		var filled sql.NullString
-		if v.Filled != "" {
-			filled = sql.NullString{
-				String: v.Filled,
-			}
-		}
+		period := subDate(v.Period)
+		filled := subDate(v.Filled)
 
 		_, err = db.Exec(`
 		INSERT INTO fsds.sub (adsh, cik, name, sic, countryba, stprba, cityba, zipba, bas1, bas2, baph, countryma, strpma, cityma, zipma, mas1, mas2, countryinc, stprinc, ein, former, changed, afs, wksi, fye, form, period, fy, fp, filled, accepted, prevrpt, detail, instance, nciks, aciks, created_at, updated_at) 
@@ -251,3 +241,16 @@ func FSDSPreDataUpsert(s *sec.SEC, db *sqlx.DB, reader io.ReadCloser) error {
 	}
 	return nil
 }
+
+// subDate is a 20060102 date of sub.txt as 2006-01-02, which SQLite
+// compares and scans as a date like Postgres does, or NULL if it's empty
+func subDate(date string) sql.NullString {
+	if date == "" {
+		return sql.NullString{}
+	}
+	t, err := time.Parse("20060102", date)
+	if err != nil {
+		return sql.NullString{String: date, Valid: true}
+	}
+	return sql.NullString{String: t.Format("2006-01-02"), Valid: true}
+}
@@ -0,0 +1,69 @@
+package secdata
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestFSDSUpsertSQLite(t *testing.T) {
+	cfg := config.Config{Database: sectest.SQLite(t)}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := NewSECData(NewSECDataOpsFSDS()).SECDataOps
+	files := []struct {
+		name string
+		data string
+	}{
+		{"sub.txt", "adsh\tcik\tname\tsic\tform\tperiod\tfy\tfp\tfiled\n0000320193-22-000007\t320193\tAPPLE INC\t3571\t10-Q\t20211231\t2022\tQ1\t20220128\n"},
+		{"tag.txt", "tag\tversion\tcustom\tabstract\tdatatype\tlord\tcrdr\ttlabel\tdoc\nRevenues\tus-gaap/2021\t0\t0\tmonetary\tD\tC\tRevenues\tAmount of revenue.\n"},
+		{"num.txt", "adsh\ttag\tversion\tcoreg\tddate\tqtrs\tuom\tvalue\tfootnote\n0000320193-22-000007\tRevenues\tus-gaap/2021\t\t20211231\t1\tUSD\t123945000000\t\n0000320193-22-000007\tUnknownTag\tus-gaap/2021\t\t20211231\t1\tUSD\t1\t\n"},
+		{"pre.txt", "adsh\treport\tline\tstmt\tinpth\trfile\ttag\tversion\tplabel\n0000320193-22-000007\t4\t1\tIS\t0\tH\tRevenues\tus-gaap/2021\tTotal net sales\n"},
+	}
+
+	// Upserting twice doesn't duplicate rows
+	for i := 0; i < 2; i++ {
+		for _, file := range files {
+			upsert := ops.GetDataTypeInsertFunc(file.name)
+			err = upsert(nil, db, ioutil.NopCloser(strings.NewReader(file.data)))
+			if err != nil {
+				t.Fatalf("upserting %v: %v", file.name, err)
+			}
+		}
+	}
+
+	counts := map[string]int{"fsds.sub": 1, "fsds.tag": 1, "fsds.num": 1, "fsds.pre": 1}
+	for table, want := range counts {
+		var count int
+		err = db.Get(&count, "SELECT COUNT(*) FROM "+table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("%v has %v rows, want %v", table, count, want)
+		}
+	}
+	var period time.Time
+	err = db.Get(&period, "SELECT period FROM fsds.sub")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2021, 12, 31, 0, 0, 0, 0, time.UTC); !period.Equal(want) {
+		t.Errorf("period = %v, want %v", period, want)
+	}
+}
@@ -0,0 +1,322 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package secdiff compares two filings, usually a 10-K with the prior one:
+// the text of sections such as Item 1A (Risk Factors) and Item 7 (MD&A)
+// from sec.filing_sections, and the values of the statement line items in
+// fsds.pre and fsds.num.
+package secdiff
+
+import (
+	"database/sql"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/equres/sec/pkg/secsection"
+	"github.com/jmoiron/sqlx"
+)
+
+// Lines of unchanged text shown around each change
+const DiffContext = 2
+
+// Sections compared by default: Item 1A and Item 7 of a 10-K, Part II,
+// Item 1A and Part I, Item 2 of a 10-Q
+var DefaultSections = []string{"risk_factors", "mdna"}
+
+// Statements compared, in display order
+var Statements = []string{"BS", "IS", "CI", "CF", "EQ"}
+
+var StatementNames = map[string]string{
+	"BS": "Balance Sheet",
+	"IS": "Income Statement",
+	"CI": "Comprehensive Income",
+	"CF": "Cash Flow",
+	"EQ": "Equity",
+}
+
+type SectionDiff struct {
+	// Key is the section as requested, see secsection.Find
+	Key   string
+	Label string
+	Edits []Edit
+	// Why there is nothing to compare, e.g. the section wasn't found
+	Note string
+}
+
+// LineItem is the value of a statement line item for the period of the
+// filing.
+type LineItem struct {
+	Stmt  string  `db:"stmt"`
+	Tag   string  `db:"tag"`
+	Label string  `db:"plabel"`
+	UOM   string  `db:"uom"`
+	Value float64 `db:"value"`
+}
+
+type LineItemDelta struct {
+	Stmt  string
+	Tag   string
+	Label string
+	UOM   string
+	A     sql.NullFloat64
+	B     sql.NullFloat64
+	Delta sql.NullFloat64
+	// Change relative to A
+	Change sql.NullFloat64
+}
+
+type Result struct {
+	A         secfiling.Filing
+	B         secfiling.Filing
+	Sections  []SectionDiff
+	LineItems []LineItemDelta
+}
+
+// CompareSections diffs the sections matching keys (names such as
+// risk_factors or items such as 1A) of two filings. Long runs of unchanged
+// lines are collapsed.
+func CompareSections(sectionsA []secsection.Section, sectionsB []secsection.Section, keys []string) []SectionDiff {
+	var diffs []SectionDiff
+	for _, key := range keys {
+		diff := SectionDiff{Key: key, Label: key}
+
+		sectionA, okA := secsection.Find(sectionsA, key)
+		sectionB, okB := secsection.Find(sectionsB, key)
+		switch {
+		case okB:
+			diff.Label = sectionB.Label()
+		case okA:
+			diff.Label = sectionA.Label()
+		}
+
+		switch {
+		case !okA && !okB:
+			diff.Note = "not found in either filing"
+		case !okA:
+			diff.Note = "not found in the earlier filing"
+		case !okB:
+			diff.Note = "not found in the later filing"
+		}
+
+		edits, err := Diff(Lines(sectionA.Body), Lines(sectionB.Body))
+		if err != nil {
+			diff.Note = err.Error()
+			diffs = append(diffs, diff)
+			continue
+		}
+		if diff.Note == "" && !Changed(edits) {
+			diff.Note = "no changes"
+		}
+		diff.Edits = Collapse(edits, DiffContext)
+
+		diffs = append(diffs, diff)
+	}
+	return diffs
+}
+
+// CompareLineItems matches the line items of two filings by statement, tag
+// and unit, in the order of the later filing, followed by the line items
+// only the earlier filing has.
+func CompareLineItems(a []LineItem, b []LineItem) []LineItemDelta {
+	key := func(item LineItem) string {
+		return item.Stmt + "|" + item.Tag + "|" + item.UOM
+	}
+
+	valuesA := make(map[string]LineItem)
+	for _, item := range a {
+		if _, ok := valuesA[key(item)]; !ok {
+			valuesA[key(item)] = item
+		}
+	}
+
+	var deltas []LineItemDelta
+	seen := make(map[string]bool)
+
+	add := func(item LineItem, valueA *LineItem, valueB *LineItem) {
+		delta := LineItemDelta{Stmt: item.Stmt, Tag: item.Tag, Label: item.Label, UOM: item.UOM}
+		if valueA != nil {
+			delta.A = sql.NullFloat64{Float64: valueA.Value, Valid: true}
+		}
+		if valueB != nil {
+			delta.B = sql.NullFloat64{Float64: valueB.Value, Valid: true}
+		}
+		if valueA != nil && valueB != nil {
+			delta.Delta = sql.NullFloat64{Float64: valueB.Value - valueA.Value, Valid: true}
+			if valueA.Value != 0 {
+				delta.Change = sql.NullFloat64{Float64: (valueB.Value - valueA.Value) / math.Abs(valueA.Value), Valid: true}
+			}
+		}
+		deltas = append(deltas, delta)
+	}
+
+	for _, item := range b {
+		k := key(item)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		item := item
+		if itemA, ok := valuesA[k]; ok {
+			add(item, &itemA, &item)
+		} else {
+			add(item, nil, &item)
+		}
+	}
+
+	for _, item := range a {
+		k := key(item)
+		if seen[k] {
+			continue
+		}
+		seen[k] = true
+
+		item := item
+		add(item, &item, nil)
+	}
+
+	return deltas
+}
+
+// GetLineItems returns the statement line items of a filing with their
+// values for its period: instants for the balance sheet, the fiscal year
+// for annual reports and the quarter (year to date for cash flows) for
+// quarterly reports.
+func GetLineItems(db *sqlx.DB, accession string) ([]LineItem, error) {
+	type row struct {
+		Stmt  string `db:"stmt"`
+		Tag   string `db:"tag"`
+		Label string `db:"plabel"`
+		UOM   string `db:"uom"`
+		Value string `db:"value"`
+	}
+
+	query := `
+		SELECT pre.stmt, pre.tag, COALESCE(pre.plabel, pre.tag) AS plabel, num.uom, num.value
+		FROM fsds.pre
+		JOIN fsds.sub ON sub.adsh = pre.adsh
+		JOIN fsds.num ON num.adsh = pre.adsh AND num.tag = pre.tag AND num.version = pre.version
+		WHERE pre.adsh = $1
+		AND pre.stmt IN ('BS', 'IS', 'CI', 'CF', 'EQ')
+		AND num.coreg = ''
+		AND num.value <> ''
+		AND num.ddate = to_char(sub.period, 'YYYYMMDD')
+		AND num.qtrs = CASE
+			WHEN pre.stmt = 'BS' THEN '0'
+			WHEN sub.fp = 'FY' THEN '4'
+			WHEN pre.stmt = 'CF' AND sub.fp = 'Q2' THEN '2'
+			WHEN pre.stmt = 'CF' AND sub.fp = 'Q3' THEN '3'
+			ELSE '1'
+		END
+		ORDER BY CAST(pre.report AS integer), CAST(pre.line AS integer);`
+	if database.IsSQLite(db) {
+		query = strings.Replace(query, "to_char(sub.period, 'YYYYMMDD')", "strftime('%Y%m%d', sub.period)", 1)
+	}
+
+	var rows []row
+	err := db.Select(&rows, query, accession)
+	if err != nil {
+		return nil, err
+	}
+
+	var items []LineItem
+	for _, r := range rows {
+		value, err := strconv.ParseFloat(r.Value, 64)
+		if err != nil {
+			continue
+		}
+		items = append(items, LineItem{Stmt: r.Stmt, Tag: r.Tag, Label: r.Label, UOM: r.UOM, Value: value})
+	}
+	return items, nil
+}
+
+// PreviousFiling returns the accession number of the latest filing of the
+// same form and company before filing, or "" if there is none.
+func PreviousFiling(db *sqlx.DB, filing secfiling.Filing) (string, error) {
+	var accessions []string
+	err := db.Select(&accessions, `
+		SELECT accessionnumber
+		FROM sec.secItemFile
+		WHERE ciknumber = $1
+		AND formtype = $2
+		AND fillingdate < $3
+		AND accessionnumber <> $4
+		ORDER BY fillingdate DESC
+		LIMIT 1;`, filing.CIK, filing.FormType, filing.FilingDate, filing.Accession)
+	if err != nil {
+		return "", err
+	}
+	if len(accessions) == 0 {
+		return "", nil
+	}
+	return accessions[0], nil
+}
+
+// Compare diffs the sections and line items of filings a (the earlier one)
+// and b. Without keys, DefaultSections are compared.
+func Compare(db *sqlx.DB, accessionA string, accessionB string, keys []string) (Result, error) {
+	filingA, err := secfiling.GetFiling(db, accessionA)
+	if err != nil {
+		return Result{}, fmt.Errorf("%v: %w", accessionA, err)
+	}
+	filingB, err := secfiling.GetFiling(db, accessionB)
+	if err != nil {
+		return Result{}, fmt.Errorf("%v: %w", accessionB, err)
+	}
+
+	if len(keys) == 0 {
+		keys = DefaultSections
+	}
+
+	sectionsA, err := secsection.Sections(db, filingA)
+	if err != nil {
+		return Result{}, err
+	}
+	sectionsB, err := secsection.Sections(db, filingB)
+	if err != nil {
+		return Result{}, err
+	}
+
+	lineItemsA, err := GetLineItems(db, accessionA)
+	if err != nil {
+		return Result{}, err
+	}
+	lineItemsB, err := GetLineItems(db, accessionB)
+	if err != nil {
+		return Result{}, err
+	}
+
+	return Result{
+		A:         filingA,
+		B:         filingB,
+		Sections:  CompareSections(sectionsA, sectionsB, keys),
+		LineItems: CompareLineItems(lineItemsA, lineItemsB),
+	}, nil
+}
+
+type StatementDeltas struct {
+	Stmt      string
+	Name      string
+	LineItems []LineItemDelta
+}
+
+// Statements groups the line item deltas by statement, in the order of
+// Statements, leaving out statements neither filing has.
+func (r Result) Statements() []StatementDeltas {
+	var statements []StatementDeltas
+	for _, stmt := range Statements {
+		statement := StatementDeltas{Stmt: stmt, Name: StatementNames[stmt]}
+		for _, delta := range r.LineItems {
+			if delta.Stmt == stmt {
+				statement.LineItems = append(statement.LineItems, delta)
+			}
+		}
+		if len(statement.LineItems) > 0 {
+			statements = append(statements, statement)
+		}
+	}
+	return statements
+}
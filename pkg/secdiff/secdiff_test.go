@@ -0,0 +1,185 @@
+package secdiff
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secdata"
+	"github.com/equres/sec/pkg/secsection"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestCompareSections(t *testing.T) {
+	a := secsection.Segment("Item 1A. Risk Factors\nCompetition is intense.\nItem 2. Properties\nCupertino.\n", "10-K")
+	b := secsection.Segment("Item 1A. Risk Factors\nCompetition is intense.\nSupply is constrained.\nItem 2. Properties\nCupertino.\n", "10-K")
+
+	diffs := CompareSections(a, b, []string{"risk_factors", "2", "7"})
+	if len(diffs) != 3 {
+		t.Fatalf("CompareSections() returned %d diffs, want 3", len(diffs))
+	}
+
+	if diffs[0].Label != "Item 1A. Risk Factors" || diffs[0].Note != "" || !Changed(diffs[0].Edits) {
+		t.Errorf("risk_factors diff = %+v", diffs[0])
+	}
+	if diffs[1].Note != "no changes" {
+		t.Errorf("item 2 note = %q, want %q", diffs[1].Note, "no changes")
+	}
+	if diffs[2].Note != "not found in either filing" {
+		t.Errorf("item 7 note = %q, want %q", diffs[2].Note, "not found in either filing")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := strings.Split("a b c d e", " ")
+	b := strings.Split("a c d x e", " ")
+
+	want := []Edit{
+		{OpEqual, "a"},
+		{OpDelete, "b"},
+		{OpEqual, "c"},
+		{OpEqual, "d"},
+		{OpInsert, "x"},
+		{OpEqual, "e"},
+	}
+	got, err := Diff(a, b)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %v, want %v", got, want)
+	}
+
+	same, err := Diff(a, a)
+	if err != nil || Changed(same) {
+		t.Errorf("Changed() = true for identical texts, err %v", err)
+	}
+}
+
+func TestDiffTooLarge(t *testing.T) {
+	var a, b []string
+	for i := 0; i < 1001; i++ {
+		a = append(a, fmt.Sprintf("a%d", i))
+		b = append(b, fmt.Sprintf("b%d", i))
+	}
+
+	_, err := Diff(a, b)
+	if err != ErrTooLarge {
+		t.Errorf("Diff() of 1001 changed lines = %v, want ErrTooLarge", err)
+	}
+
+	// Only the changed part counts
+	same := append(append([]string{}, a...), "x")
+	_, err = Diff(a, same)
+	if err != nil {
+		t.Errorf("Diff() of 1 changed line = %v", err)
+	}
+
+	diffs := CompareSections([]secsection.Section{{Name: "risk_factors", Body: strings.Join(a, "\n")}}, []secsection.Section{{Name: "risk_factors", Body: strings.Join(b, "\n")}}, []string{"risk_factors"})
+	if len(diffs) != 1 || diffs[0].Note != "too large to diff" || len(diffs[0].Edits) != 0 {
+		t.Errorf("CompareSections() = %+v, want a too large note", diffs)
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	edits, err := Diff(strings.Split("1 2 3 4 5 6 7 8", " "), strings.Split("1 2 3 4 5 6 7 x", " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []Edit{
+		{OpSkip, "5 unchanged lines"},
+		{OpEqual, "6"},
+		{OpEqual, "7"},
+		{OpDelete, "8"},
+		{OpInsert, "x"},
+	}
+	got := Collapse(edits, 2)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Collapse() = %v, want %v", got, want)
+	}
+}
+
+func TestCompareLineItems(t *testing.T) {
+	a := []LineItem{
+		{Stmt: "BS", Tag: "Assets", UOM: "USD", Value: 100},
+		{Stmt: "BS", Tag: "Goodwill", UOM: "USD", Value: 10},
+	}
+	b := []LineItem{
+		{Stmt: "BS", Tag: "Assets", UOM: "USD", Value: 150},
+		{Stmt: "IS", Tag: "Revenues", UOM: "USD", Value: 50},
+	}
+
+	deltas := CompareLineItems(a, b)
+	if len(deltas) != 3 {
+		t.Fatalf("CompareLineItems() returned %d deltas, want 3", len(deltas))
+	}
+
+	assets := deltas[0]
+	if assets.Tag != "Assets" || assets.Delta.Float64 != 50 || assets.Change.Float64 != 0.5 {
+		t.Errorf("Assets delta = %+v", assets)
+	}
+	if deltas[1].Tag != "Revenues" || deltas[1].A.Valid || deltas[1].Delta.Valid {
+		t.Errorf("Revenues delta = %+v", deltas[1])
+	}
+	if deltas[2].Tag != "Goodwill" || deltas[2].B.Valid {
+		t.Errorf("Goodwill delta = %+v", deltas[2])
+	}
+}
+
+func TestGetLineItems(t *testing.T) {
+	sectest.ForEachDatabase(t, testGetLineItems)
+}
+
+func testGetLineItems(t *testing.T, dbConfig config.DatabaseConfig) {
+	cfg := config.Config{Database: dbConfig}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("INSERT INTO sec.ciks (cik) VALUES (320193);")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Revenues of the quarter and of the prior year's quarter, only the
+	// former is of the period of the filing
+	ops := secdata.NewSECData(secdata.NewSECDataOpsFSDS()).SECDataOps
+	files := []struct {
+		name string
+		data string
+	}{
+		{"sub.txt", "adsh\tcik\tname\tsic\tform\tperiod\tfy\tfp\tfiled\n0000320193-22-000007\t320193\tAPPLE INC\t3571\t10-Q\t20211231\t2022\tQ1\t20220128\n"},
+		{"tag.txt", "tag\tversion\tcustom\tabstract\tdatatype\tlord\tcrdr\ttlabel\tdoc\nRevenues\tus-gaap/2021\t0\t0\tmonetary\tD\tC\tRevenues\tAmount of revenue.\n"},
+		{"num.txt", "adsh\ttag\tversion\tcoreg\tddate\tqtrs\tuom\tvalue\tfootnote\n0000320193-22-000007\tRevenues\tus-gaap/2021\t\t20211231\t1\tUSD\t123945000000\t\n0000320193-22-000007\tRevenues\tus-gaap/2021\t\t20201231\t1\tUSD\t111439000000\t\n"},
+		{"pre.txt", "adsh\treport\tline\tstmt\tinpth\trfile\ttag\tversion\tplabel\n0000320193-22-000007\t4\t1\tIS\t0\tH\tRevenues\tus-gaap/2021\tTotal net sales\n"},
+	}
+	for _, file := range files {
+		upsert := ops.GetDataTypeInsertFunc(file.name)
+		err = upsert(nil, db, ioutil.NopCloser(strings.NewReader(file.data)))
+		if err != nil {
+			t.Fatalf("upserting %v: %v", file.name, err)
+		}
+	}
+
+	items, err := GetLineItems(db, "0000320193-22-000007")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []LineItem{{Stmt: "IS", Tag: "Revenues", Label: "Total net sales", UOM: "USD", Value: 123945000000}}
+	if !reflect.DeepEqual(items, want) {
+		t.Errorf("GetLineItems() = %+v, want %+v", items, want)
+	}
+}
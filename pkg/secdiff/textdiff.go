@@ -0,0 +1,168 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secdiff
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+type Op string
+
+const (
+	OpEqual  Op = "="
+	OpDelete Op = "-"
+	OpInsert Op = "+"
+	// OpSkip stands for unchanged lines left out by Collapse
+	OpSkip Op = "~"
+)
+
+type Edit struct {
+	Op   Op
+	Text string
+}
+
+// Above this many cells (4 MB) the LCS table is not built, /diff is open to
+// anyone. Sections of a few thousand changed lines still fit.
+const maxDiffCells = 1000000
+
+// ErrTooLarge is returned by Diff for texts whose changed parts are too long
+// to be compared
+var ErrTooLarge = errors.New("too large to diff")
+
+// Lines splits text into trimmed, non-empty lines.
+func Lines(text string) []string {
+	var lines []string
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		if line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+// Diff returns the edits turning a into b, based on their longest common
+// subsequence of lines, or ErrTooLarge.
+func Diff(a []string, b []string) ([]Edit, error) {
+	// Common prefix and suffix keep the table small for similar texts
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	changedA, changedB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(changedA)*len(changedB) > maxDiffCells {
+		return nil, ErrTooLarge
+	}
+
+	var edits []Edit
+	for _, line := range a[:prefix] {
+		edits = append(edits, Edit{Op: OpEqual, Text: line})
+	}
+	edits = append(edits, diffLCS(changedA, changedB)...)
+	for _, line := range a[len(a)-suffix:] {
+		edits = append(edits, Edit{Op: OpEqual, Text: line})
+	}
+
+	return edits, nil
+}
+
+func diffLCS(a []string, b []string) []Edit {
+	var edits []Edit
+
+	// lcs[i][j] is the length of the LCS of a[i:] and b[j:]
+	lcs := make([][]int32, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int32, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] >= lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			edits = append(edits, Edit{Op: OpEqual, Text: a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			edits = append(edits, Edit{Op: OpDelete, Text: a[i]})
+			i++
+		default:
+			edits = append(edits, Edit{Op: OpInsert, Text: b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		edits = append(edits, Edit{Op: OpDelete, Text: a[i]})
+	}
+	for ; j < len(b); j++ {
+		edits = append(edits, Edit{Op: OpInsert, Text: b[j]})
+	}
+
+	return edits
+}
+
+// Collapse replaces runs of unchanged lines longer than 2*context with an
+// OpSkip edit, keeping context lines around each change.
+func Collapse(edits []Edit, context int) []Edit {
+	var collapsed []Edit
+
+	for start := 0; start < len(edits); {
+		if edits[start].Op != OpEqual {
+			collapsed = append(collapsed, edits[start])
+			start++
+			continue
+		}
+
+		end := start
+		for end < len(edits) && edits[end].Op == OpEqual {
+			end++
+		}
+
+		keepBefore, keepAfter := context, context
+		if start == 0 {
+			keepBefore = 0
+		}
+		if end == len(edits) {
+			keepAfter = 0
+		}
+
+		if end-start <= keepBefore+keepAfter {
+			collapsed = append(collapsed, edits[start:end]...)
+		} else {
+			collapsed = append(collapsed, edits[start:start+keepBefore]...)
+			collapsed = append(collapsed, Edit{Op: OpSkip, Text: fmt.Sprintf("%d unchanged lines", end-start-keepBefore-keepAfter)})
+			collapsed = append(collapsed, edits[end-keepAfter:end]...)
+		}
+
+		start = end
+	}
+
+	return collapsed
+}
+
+// Changed reports whether the edits contain anything but unchanged lines.
+func Changed(edits []Edit) bool {
+	for _, edit := range edits {
+		if edit.Op == OpDelete || edit.Op == OpInsert {
+			return true
+		}
+	}
+	return false
+}
@@ -2,8 +2,9 @@ package secevent
 
 import (
 	"encoding/json"
-	"time"
+	"strings"
 
+	"github.com/equres/sec/pkg/database"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -46,20 +47,18 @@ type IndexDayStat struct {
 }
 
 type EventStat struct {
-	DateTime        time.Time `db:"events_date"`
-	Date            string    `db:"date"`
-	FilesDownloaded int       `db:"files_downloaded"`
-	FilesBroken     int       `db:"files_broken"`
-	FilesIndexed    int       `db:"files_indexed"`
+	Date            string `db:"events_date"`
+	FilesDownloaded int    `db:"files_downloaded"`
+	FilesBroken     int    `db:"files_broken"`
+	FilesIndexed    int    `db:"files_indexed"`
 }
 
 type BackupEventStat struct {
-	DateTime             time.Time `db:"events_date"`
-	Date                 string    `db:"date"`
-	SuccessfulFileBackup int       `db:"successful_file_backup"`
-	FailedFileBackup     int       `db:"failed_file_backup"`
-	SuccessfulDBBackup   int       `db:"successful_db_backup"`
-	FailedDBBackup       int       `db:"failed_db_backup"`
+	Date                 string `db:"events_date"`
+	SuccessfulFileBackup int    `db:"successful_file_backup"`
+	FailedFileBackup     int    `db:"failed_file_backup"`
+	SuccessfulDBBackup   int    `db:"successful_db_backup"`
+	FailedDBBackup       int    `db:"failed_db_backup"`
 }
 
 type DownloadEventStatsByHour struct {
@@ -68,6 +67,26 @@ type DownloadEventStatsByHour struct {
 	FilesDownloaded int    `db:"files_downloaded"`
 }
 
+// sqliteEvents translates the Postgres expressions of the queries on
+// sec.events for SQLite
+var sqliteEvents = strings.NewReplacer(
+	"created_at::date", "strftime('%Y-%m-%dT00:00:00Z', created_at)",
+	"EXTRACT(HOUR FROM created_at)", "CAST(strftime('%H', created_at) AS integer)",
+	"(current_date - 7)", "strftime('%Y-%m-%dT00:00:00Z', 'now', '-7 days')",
+	"ev->>'event'", "json_text(ev, 'event')",
+	"ev->>'status'", "json_text(ev, 'status')",
+	"ev->>'job'", "json_text(ev, 'job')",
+)
+
+// Query returns q, a query on sec.events written for Postgres, for the
+// database of db
+func Query(db *sqlx.DB, q string) string {
+	if database.IsSQLite(db) {
+		return sqliteEvents.Replace(q)
+	}
+	return q
+}
+
 func CreateIndexEvent(db *sqlx.DB, file string, status string, reason string) {
 	event := IndexEvent{
 		Event:  "index",
@@ -79,7 +98,7 @@ func CreateIndexEvent(db *sqlx.DB, file string, status string, reason string) {
 	if err != nil {
 		panic(err)
 	}
-	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, eventJson)
+	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, string(eventJson))
 	if err != nil {
 		panic(err)
 	}
@@ -97,7 +116,7 @@ func CreateDownloadEvent(db *sqlx.DB, file string, url string, status string, re
 	if err != nil {
 		panic(err)
 	}
-	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, eventJson)
+	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, string(eventJson))
 	if err != nil {
 		panic(err)
 	}
@@ -114,7 +133,7 @@ func CreateUnzipEvent(db *sqlx.DB, file string, status string, reason string) {
 	if err != nil {
 		panic(err)
 	}
-	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, eventJson)
+	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, string(eventJson))
 	if err != nil {
 		panic(err)
 	}
@@ -130,7 +149,7 @@ func CreateOtherEvent(db *sqlx.DB, eventName string, job string, status string)
 	if err != nil {
 		panic(err)
 	}
-	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, eventJson)
+	_, err = db.Exec(`INSERT INTO sec.events (ev) VALUES ($1)`, string(eventJson))
 	if err != nil {
 		panic(err)
 	}
@@ -138,14 +157,14 @@ func CreateOtherEvent(db *sqlx.DB, eventName string, job string, status string)
 
 func GetEventStats(db *sqlx.DB) ([]EventStat, error) {
 	var allEventStats []EventStat
-	err := db.Select(&allEventStats, `
+	err := db.Select(&allEventStats, Query(db, `
 	SELECT 
 		created_at::date as events_date, 
 		COUNT(case when ev->>'event' = 'download' AND ev->>'status' = 'success' then 1 end) as files_downloaded,
 		COUNT(case when (ev->>'event' = 'download' OR ev->>'event' = 'unzip') AND ev->>'status' = 'failed' then 1 end) as files_broken,
 		COUNT(case when ev->>'event' = 'index' AND ev->>'status' = 'success' then 1 end) as files_indexed
 	FROM sec.events GROUP BY created_at::date;
-	`)
+	`))
 	if err != nil {
 		return nil, err
 	}
@@ -155,7 +174,7 @@ func GetEventStats(db *sqlx.DB) ([]EventStat, error) {
 
 func GetBackupEventStats(db *sqlx.DB) ([]BackupEventStat, error) {
 	var allEventStats []BackupEventStat
-	err := db.Select(&allEventStats, `
+	err := db.Select(&allEventStats, Query(db, `
 	SELECT 
 		created_at::date as events_date, 
 		COUNT(case when ev->>'job' = 'cache_compressed' AND ev->>'status' = 'success' then 1 end) as successful_file_backup,
@@ -163,7 +182,7 @@ func GetBackupEventStats(db *sqlx.DB) ([]BackupEventStat, error) {
 		COUNT(case when ev->>'job' = 'db_backup' AND ev->>'status' = 'success' then 1 end) as successful_db_backup,
 		COUNT(case when ev->>'job' = 'db_backup' AND ev->>'status' = 'failed' then 1 end) as failed_db_backup
 	FROM sec.events GROUP BY created_at::date;
-	`)
+	`))
 	if err != nil {
 		return nil, err
 	}
@@ -173,7 +192,7 @@ func GetBackupEventStats(db *sqlx.DB) ([]BackupEventStat, error) {
 
 func GetDownloadEventStatsByHour(db *sqlx.DB) ([]DownloadEventStatsByHour, error) {
 	var allDownloadEventStats []DownloadEventStatsByHour
-	err := db.Select(&allDownloadEventStats, `
+	err := db.Select(&allDownloadEventStats, Query(db, `
 	SELECT 
 		EXTRACT(HOUR FROM created_at) as hour,
 		created_at::date as date,
@@ -183,7 +202,7 @@ func GetDownloadEventStatsByHour(db *sqlx.DB) ([]DownloadEventStatsByHour, error
 		ev->>'event' = 'download' 
 		AND ev->>'status' = 'success' 
 	GROUP BY created_at::date, EXTRACT(HOUR FROM created_at);
-	`)
+	`))
 	if err != nil {
 		return nil, err
 	}
@@ -193,7 +212,7 @@ func GetDownloadEventStatsByHour(db *sqlx.DB) ([]DownloadEventStatsByHour, error
 
 func GetLastSevenDaysDownloads(db *sqlx.DB) ([]DownloadDayStat, error) {
 	var lastSevenDaysDownloadsCount []DownloadDayStat
-	err := db.Select(&lastSevenDaysDownloadsCount, `
+	err := db.Select(&lastSevenDaysDownloadsCount, Query(db, `
 	SELECT
 		COUNT(*) as count,
 		created_at::date as date
@@ -207,7 +226,7 @@ func GetLastSevenDaysDownloads(db *sqlx.DB) ([]DownloadDayStat, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 7;
-	`)
+	`))
 	if err != nil {
 		return nil, err
 	}
@@ -217,7 +236,7 @@ func GetLastSevenDaysDownloads(db *sqlx.DB) ([]DownloadDayStat, error) {
 
 func GetLastSevenDaysIndexes(db *sqlx.DB) ([]IndexDayStat, error) {
 	var lastSevenDaysIndexesCount []IndexDayStat
-	err := db.Select(&lastSevenDaysIndexesCount, `
+	err := db.Select(&lastSevenDaysIndexesCount, Query(db, `
 	SELECT
 		COUNT(*) as count,
 		created_at::date as date
@@ -231,7 +250,7 @@ func GetLastSevenDaysIndexes(db *sqlx.DB) ([]IndexDayStat, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 7;
-	`)
+	`))
 	if err != nil {
 		return nil, err
 	}
@@ -241,7 +260,7 @@ func GetLastSevenDaysIndexes(db *sqlx.DB) ([]IndexDayStat, error) {
 
 func GetLastSuccessfulBackUpToCa2(db *sqlx.DB) (string, error) {
 	var lastSuccessfulBackUpToCa2 []string
-	err := db.Select(&lastSuccessfulBackUpToCa2, `
+	err := db.Select(&lastSuccessfulBackUpToCa2, Query(db, `
 	SELECT
 		created_at::date as date
 	FROM sec.events
@@ -253,7 +272,7 @@ func GetLastSuccessfulBackUpToCa2(db *sqlx.DB) (string, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 1;
-	`)
+	`))
 	if err != nil {
 		return "", err
 	}
@@ -267,7 +286,7 @@ func GetLastSuccessfulBackUpToCa2(db *sqlx.DB) (string, error) {
 
 func GetLastSuccessfulBackUpToWaw1(db *sqlx.DB) (string, error) {
 	var lastSuccessfulBackUpToWaw1 []string
-	err := db.Select(&lastSuccessfulBackUpToWaw1, `
+	err := db.Select(&lastSuccessfulBackUpToWaw1, Query(db, `
 	SELECT
 		created_at::date as date
 	FROM sec.events
@@ -279,7 +298,7 @@ func GetLastSuccessfulBackUpToWaw1(db *sqlx.DB) (string, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 1;
-	`)
+	`))
 	if err != nil {
 		return "", err
 	}
@@ -293,7 +312,7 @@ func GetLastSuccessfulBackUpToWaw1(db *sqlx.DB) (string, error) {
 
 func GetLastSuccessfulDBBackup(db *sqlx.DB) (string, error) {
 	var lastSuccessfulDBBackup []string
-	err := db.Select(&lastSuccessfulDBBackup, `
+	err := db.Select(&lastSuccessfulDBBackup, Query(db, `
 	SELECT
 		created_at::date as date
 	FROM sec.events
@@ -305,7 +324,7 @@ func GetLastSuccessfulDBBackup(db *sqlx.DB) (string, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 1;
-	`)
+	`))
 	if err != nil {
 		return "", err
 	}
@@ -319,7 +338,7 @@ func GetLastSuccessfulDBBackup(db *sqlx.DB) (string, error) {
 
 func GetLastSuccessfulDBBackupToCa2(db *sqlx.DB) (string, error) {
 	var lastSuccessfulDBBackupToCa2 []string
-	err := db.Select(&lastSuccessfulDBBackupToCa2, `
+	err := db.Select(&lastSuccessfulDBBackupToCa2, Query(db, `
 	SELECT
 		created_at::date as date
 	FROM sec.events
@@ -331,7 +350,7 @@ func GetLastSuccessfulDBBackupToCa2(db *sqlx.DB) (string, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 1;
-	`)
+	`))
 	if err != nil {
 		return "", err
 	}
@@ -345,7 +364,7 @@ func GetLastSuccessfulDBBackupToCa2(db *sqlx.DB) (string, error) {
 
 func GetLastSuccessfulDBBackupToWaw1(db *sqlx.DB) (string, error) {
 	var lastSuccessfulDBBackupToWaw1 []string
-	err := db.Select(&lastSuccessfulDBBackupToWaw1, `
+	err := db.Select(&lastSuccessfulDBBackupToWaw1, Query(db, `
 	SELECT
 		created_at::date as date
 	FROM sec.events
@@ -357,7 +376,7 @@ func GetLastSuccessfulDBBackupToWaw1(db *sqlx.DB) (string, error) {
 	ORDER BY
 		created_at::date DESC
 	LIMIT 1;
-	`)
+	`))
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,75 @@
+package secevent
+
+import (
+	"os"
+	"testing"
+	"time"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestEventsSQLite(t *testing.T) {
+	cfg := config.Config{Database: sectest.SQLite(t)}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	CreateDownloadEvent(db, "a.zip", "https://www.sec.gov/a.zip", "success", "")
+	CreateDownloadEvent(db, "b.zip", "https://www.sec.gov/b.zip", "success", "")
+	CreateDownloadEvent(db, "c.zip", "https://www.sec.gov/c.zip", "failed", "not_found")
+	CreateUnzipEvent(db, "b.zip", "failed", "corrupt")
+	CreateIndexEvent(db, "a.zip", "success", "")
+	CreateOtherEvent(db, "other", "db_backup", "success")
+
+	today := time.Now().UTC().Format("2006-01-02") + "T00:00:00Z"
+
+	stats, err := GetEventStats(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(stats) != 1 {
+		t.Fatalf("GetEventStats() = %+v, want today", stats)
+	}
+	want := EventStat{Date: today, FilesDownloaded: 2, FilesBroken: 2, FilesIndexed: 1}
+	if stats[0] != want {
+		t.Errorf("GetEventStats() = %+v, want %+v", stats[0], want)
+	}
+
+	backups, err := GetBackupEventStats(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 1 || backups[0].SuccessfulDBBackup != 1 {
+		t.Errorf("GetBackupEventStats() = %+v, want a database backup", backups)
+	}
+
+	hourly, err := GetDownloadEventStatsByHour(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(hourly) != 1 || hourly[0].Date != today || hourly[0].FilesDownloaded != 2 {
+		t.Errorf("GetDownloadEventStatsByHour() = %+v, want 2 downloads today", hourly)
+	}
+
+	downloads, err := GetLastSevenDaysDownloads(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(downloads) != 1 || downloads[0].Count != 2 {
+		t.Errorf("GetLastSevenDaysDownloads() = %+v, want 2 downloads today", downloads)
+	}
+
+	lastBackup, err := GetLastSuccessfulDBBackup(db)
+	if err != nil || lastBackup != today {
+		t.Errorf("GetLastSuccessfulDBBackup() = %q, %v, want %v", lastBackup, err, today)
+	}
+}
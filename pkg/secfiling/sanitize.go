@@ -0,0 +1,240 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secfiling
+
+import (
+	"bytes"
+	"io"
+	"net/url"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Elements kept when rendering a document. Other elements are unwrapped:
+// their children are kept, the element itself isn't.
+var allowedElements = map[string]bool{
+	"a": true, "abbr": true, "b": true, "big": true, "blockquote": true, "br": true,
+	"caption": true, "center": true, "code": true, "col": true, "colgroup": true,
+	"dd": true, "div": true, "dl": true, "dt": true, "em": true, "font": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true,
+	"hr": true, "i": true, "img": true, "li": true, "ol": true, "p": true,
+	"pre": true, "s": true, "small": true, "span": true, "strike": true,
+	"strong": true, "sub": true, "sup": true, "table": true, "tbody": true,
+	"td": true, "tfoot": true, "th": true, "thead": true, "tr": true, "tt": true,
+	"u": true, "ul": true,
+}
+
+// Elements dropped together with their content. ix:header holds the hidden
+// facts of inline XBRL documents.
+var droppedElements = map[string]bool{
+	"script": true, "style": true, "iframe": true, "frame": true, "frameset": true,
+	"object": true, "embed": true, "applet": true, "form": true, "input": true,
+	"button": true, "select": true, "textarea": true, "link": true, "meta": true,
+	"base": true, "title": true, "head": true, "svg": true, "math": true,
+	"template": true, "noscript": true, "ix:header": true,
+}
+
+var allowedAttributes = map[string]bool{
+	"align": true, "alt": true, "border": true, "cellpadding": true,
+	"cellspacing": true, "color": true, "colspan": true, "face": true,
+	"height": true, "href": true, "id": true, "name": true, "rowspan": true,
+	"size": true, "src": true, "style": true, "title": true, "valign": true,
+	"width": true,
+}
+
+// Inline style properties kept, as filers use them for fonts, borders and
+// alignment. Others are removed: with position, z-index and the like a
+// document could cover the page it's shown in.
+var allowedStyleProperties = map[string]bool{
+	"background-color": true, "color": true, "height": true,
+	"letter-spacing": true, "line-height": true, "list-style-type": true,
+	"max-width": true, "min-width": true, "page-break-after": true,
+	"page-break-before": true, "vertical-align": true, "white-space": true,
+	"width": true, "word-spacing": true,
+}
+
+// Prefixes of the style properties kept, e.g. border-bottom or font-size
+var allowedStylePrefixes = []string{"border", "font", "margin", "padding", "text-"}
+
+// idPrefix is put in front of the ids and names of documents, so they
+// can't clobber the ones of the page, e.g. document.getElementById
+const idPrefix = "doc-"
+
+// Sanitize parses an HTML document, as filed with EDGAR, and returns the
+// content of its body with only presentational elements and attributes.
+// Scripts, forms, event handlers and javascript: URLs are removed, styles
+// are reduced to presentational properties, ids and names are prefixed and
+// relative links and images are resolved against base.
+func Sanitize(r io.Reader, base string) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+
+	baseURL, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+
+	body := findElement(doc, "body")
+	if body == nil {
+		body = doc
+	}
+
+	var buf bytes.Buffer
+	for child := body.FirstChild; child != nil; child = child.NextSibling {
+		for _, node := range sanitizeNode(child, baseURL) {
+			err = html.Render(&buf, node)
+			if err != nil {
+				return "", err
+			}
+		}
+	}
+
+	return buf.String(), nil
+}
+
+func findElement(n *html.Node, name string) *html.Node {
+	if n.Type == html.ElementNode && n.Data == name {
+		return n
+	}
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		found := findElement(child, name)
+		if found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// sanitizeNode returns a sanitized copy of n: a single node, the sanitized
+// children of an unwrapped element, or nothing for dropped elements.
+func sanitizeNode(n *html.Node, base *url.URL) []*html.Node {
+	switch n.Type {
+	case html.TextNode:
+		return []*html.Node{{Type: html.TextNode, Data: n.Data}}
+	case html.ElementNode:
+	default:
+		// Comments, doctypes
+		return nil
+	}
+
+	name := strings.ToLower(n.Data)
+	if droppedElements[name] {
+		return nil
+	}
+
+	var children []*html.Node
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		children = append(children, sanitizeNode(child, base)...)
+	}
+
+	if !allowedElements[name] {
+		return children
+	}
+
+	clean := &html.Node{Type: html.ElementNode, Data: name, DataAtom: n.DataAtom}
+	for _, attr := range n.Attr {
+		key := strings.ToLower(attr.Key)
+		if attr.Namespace != "" || !allowedAttributes[key] {
+			continue
+		}
+
+		value := attr.Val
+		switch key {
+		case "href", "src":
+			var ok bool
+			value, ok = safeURL(value, base)
+			if !ok {
+				continue
+			}
+		case "style":
+			value = sanitizeStyle(value)
+			if value == "" {
+				continue
+			}
+		case "id", "name":
+			value = idPrefix + value
+		}
+
+		clean.Attr = append(clean.Attr, html.Attribute{Key: key, Val: value})
+	}
+
+	for _, child := range children {
+		clean.AppendChild(child)
+	}
+
+	return []*html.Node{clean}
+}
+
+// safeURL resolves a link against base and only accepts http(s) URLs,
+// in-page anchors and mailto: links.
+func safeURL(value string, base *url.URL) (string, bool) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "#") {
+		// The ids of the document are prefixed
+		return "#" + idPrefix + value[1:], true
+	}
+
+	u, err := url.Parse(value)
+	if err != nil {
+		return "", false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "":
+		return base.ResolveReference(u).String(), true
+	case "http", "https", "mailto":
+		return u.String(), true
+	}
+	return "", false
+}
+
+// sanitizeStyle keeps the declarations of an inline style whose property
+// is allowed and whose value can't load content, run scripts or pull the
+// text over the page with negative margins. It returns "" if none is left.
+func sanitizeStyle(value string) string {
+	var kept []string
+	for _, declaration := range strings.Split(value, ";") {
+		parts := strings.SplitN(declaration, ":", 2)
+		if len(parts) != 2 {
+			continue
+		}
+		property := strings.ToLower(strings.TrimSpace(parts[0]))
+		value := strings.TrimSpace(parts[1])
+
+		if !allowedStyleProperty(property) || !safeStyleValue(value) {
+			continue
+		}
+		if strings.HasPrefix(property, "margin") && strings.Contains(value, "-") {
+			continue
+		}
+
+		kept = append(kept, property+":"+value)
+	}
+	return strings.Join(kept, ";")
+}
+
+func allowedStyleProperty(property string) bool {
+	if allowedStyleProperties[property] {
+		return true
+	}
+	for _, prefix := range allowedStylePrefixes {
+		if strings.HasPrefix(property, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// safeStyleValue rejects values that can load content or run scripts, and
+// CSS escapes that could hide them.
+func safeStyleValue(value string) bool {
+	value = strings.ToLower(value)
+	for _, unsafe := range []string{"url(", "expression(", "javascript:", "behavior:", "@import", "-moz-binding", "\\", "<"} {
+		if strings.Contains(value, unsafe) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,76 @@
+package secfiling
+
+import (
+	"strings"
+	"testing"
+)
+
+const document = `<html><head><title>10-K</title><style>p { color: red }</style><script>alert(1)</script></head>
+<body onload="steal()">
+<div style="display:none"><ix:header><ix:hidden><ix:nonNumeric name="dei:DocumentType" contextRef="c1">10-K</ix:nonNumeric></ix:hidden></ix:header></div>
+<p style="font-weight:bold" onclick="steal()">Revenues were <ix:nonFraction name="us-gaap:Revenues" contextRef="c1">1,234</ix:nonFraction> million.</p>
+<a href="javascript:steal()">bad</a> <a href="ex21.htm">Exhibit 21</a> <a href="https://www.sec.gov/">SEC</a> <a href="#toc">TOC</a>
+<img src="logo.jpg" style="background:url(https://evil.example/x)">
+<form action="/post"><input name="x"></form>
+<table><tr><td colspan="2" class="x">cell</td></tr></table>
+<div style="position:fixed; top:0; z-index:9999; Color: red" id="login">overlay</div>
+<p style="margin-left:-500px;font-size:10pt"><a name="toc">Contents</a></p>
+<!-- comment -->
+</body></html>`
+
+func TestSanitize(t *testing.T) {
+	got, err := Sanitize(strings.NewReader(document), "/Archives/edgar/data/1/000000000121000001/")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unwanted := range []string{"script", "alert", "steal", "<style", "<title", "dei:DocumentType", "ix:", "<form", "<input", "evil.example", "comment", "class=", "position", "z-index", "-500px"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("%q not removed from %v", unwanted, got)
+		}
+	}
+
+	for _, wanted := range []string{
+		`<p style="font-weight:bold">Revenues were 1,234 million.</p>`,
+		`<a>bad</a>`,
+		`<a href="/Archives/edgar/data/1/000000000121000001/ex21.htm">Exhibit 21</a>`,
+		`<a href="https://www.sec.gov/">SEC</a>`,
+		`<a href="#doc-toc">TOC</a>`,
+		`<img src="/Archives/edgar/data/1/000000000121000001/logo.jpg"/>`,
+		`<td colspan="2">cell</td>`,
+		`<div style="color:red" id="doc-login">overlay</div>`,
+		`<p style="font-size:10pt"><a name="doc-toc">Contents</a></p>`,
+	} {
+		if !strings.Contains(got, wanted) {
+			t.Errorf("%q missing from %v", wanted, got)
+		}
+	}
+}
+
+func TestPrimaryDocumentAndExhibits(t *testing.T) {
+	filing := Filing{
+		FormType: "10-K",
+		Files: []File{
+			{Name: "aapl-20210925.xsd", Type: "EX-101.SCH", Sequence: 7},
+			{Name: "Financial_Report.xlsx"},
+			{Name: "ex211.htm", Type: "EX-21.1", Sequence: 3},
+			{Name: "aapl-20210925.htm", Type: "10-K", Sequence: 1},
+			{Name: "ex231.htm", Type: "EX-23.1", Sequence: 4},
+		},
+	}
+	SortFiles(filing.Files)
+
+	if filing.Files[0].Name != "aapl-20210925.htm" || filing.Files[len(filing.Files)-1].Name != "Financial_Report.xlsx" {
+		t.Errorf("files not sorted by sequence: %+v", filing.Files)
+	}
+
+	primary, ok := filing.PrimaryDocument()
+	if !ok || primary.Name != "aapl-20210925.htm" {
+		t.Errorf("primary document: got %+v", primary)
+	}
+
+	exhibits := filing.Exhibits()
+	if len(exhibits) != 2 || exhibits[0].Name != "ex211.htm" || exhibits[1].Name != "ex231.htm" {
+		t.Errorf("exhibits: got %+v", exhibits)
+	}
+}
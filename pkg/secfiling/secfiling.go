@@ -0,0 +1,273 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package secfiling gathers everything known about one filing (accession
+// number): the filer, the form and the documents listed in secItemFile.
+package secfiling
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/equres/sec/pkg/sec"
+	"github.com/jmoiron/sqlx"
+)
+
+// Documents larger than this are linked instead of rendered inline
+const MaxInlineDocumentSize = 20 * 1024 * 1024
+
+var ErrFilingNotFound = errors.New("filing not found")
+
+var accessionNumber = regexp.MustCompile(`^\d{10}-\d{2}-\d{6}$`)
+
+// IsAccessionNumber reports whether s looks like 0000320193-21-000105
+func IsAccessionNumber(s string) bool {
+	return accessionNumber.MatchString(s)
+}
+
+// File is one document of a filing. Files unpacked from the filing ZIP
+// only have a name and a size.
+type File struct {
+	Sequence    int    `db:"xbrlsequence"`
+	Name        string `db:"xbrlfile"`
+	Type        string `db:"xbrltype"`
+	Size        int    `db:"xbrlsize"`
+	Description string `db:"xbrldescription"`
+	InlineXBRL  bool   `db:"xbrlinlinexbrl"`
+	URL         string `db:"xbrlurl"`
+}
+
+type Filing struct {
+	Accession          string    `db:"accessionnumber"`
+	CIK                int       `db:"ciknumber"`
+	CompanyName        string    `db:"companyname"`
+	FormType           string    `db:"formtype"`
+	FilingDate         time.Time `db:"fillingdate"`
+	Period             string    `db:"period"`
+	FileNumber         string    `db:"filenumber"`
+	AcceptanceDatetime string    `db:"acceptancedatetime"`
+	AssignedSIC        int       `db:"assignedsic"`
+	FiscalYearEnd      int       `db:"fiscalyearend"`
+	Files              []File
+}
+
+// GetFiling returns the filing with the given accession number and its
+// documents ordered by sequence, followed by the files from the ZIP.
+func GetFiling(db *sqlx.DB, accession string) (Filing, error) {
+	var filings []Filing
+	err := db.Select(&filings, `
+		SELECT accessionnumber, ciknumber, companyname, COALESCE(formtype, '') AS formtype, fillingdate,
+			COALESCE(period, '') AS period, COALESCE(filenumber, '') AS filenumber, COALESCE(acceptancedatetime, '') AS acceptancedatetime,
+			COALESCE(assignedsic, 0) AS assignedsic, COALESCE(fiscalyearend, 0) AS fiscalyearend
+		FROM sec.secItemFile
+		WHERE accessionnumber = $1
+		AND companyname IS NOT NULL
+		LIMIT 1;`, accession)
+	if err != nil {
+		return Filing{}, err
+	}
+	if len(filings) == 0 {
+		return Filing{}, ErrFilingNotFound
+	}
+	filing := filings[0]
+
+	err = db.Select(&filing.Files, `
+		SELECT xbrlsequence, xbrlfile, xbrltype, xbrlsize, xbrldescription, xbrlinlinexbrl, xbrlurl FROM (
+			SELECT COALESCE(xbrlsequence, 0) AS xbrlsequence, xbrlfile, COALESCE(xbrltype, '') AS xbrltype, COALESCE(xbrlsize, 0) AS xbrlsize,
+				COALESCE(xbrldescription, '') AS xbrldescription, COALESCE(xbrlinlinexbrl, false) AS xbrlinlinexbrl, COALESCE(xbrlurl, '') AS xbrlurl,
+				ROW_NUMBER() OVER (PARTITION BY xbrlfile ORDER BY xbrlsequence NULLS LAST) AS row_number
+			FROM sec.secItemFile
+			WHERE accessionnumber = $1
+			AND xbrlfile IS NOT NULL
+		) files
+		WHERE row_number = 1;`, accession)
+	if err != nil {
+		return Filing{}, err
+	}
+
+	SortFiles(filing.Files)
+
+	return filing, nil
+}
+
+// SortFiles orders documents by sequence, the ZIP files without one last.
+func SortFiles(files []File) {
+	sort.SliceStable(files, func(i, j int) bool {
+		a, b := files[i], files[j]
+		if (a.Sequence == 0) != (b.Sequence == 0) {
+			return a.Sequence != 0
+		}
+		if a.Sequence != b.Sequence {
+			return a.Sequence < b.Sequence
+		}
+		return a.Name < b.Name
+	})
+}
+
+// ArchivePath is where EDGAR keeps the documents of the filing, e.g.
+// /Archives/edgar/data/320193/000032019321000105. The download and unzip
+// caches mirror it.
+func (f Filing) ArchivePath() string {
+	return fmt.Sprintf("/Archives/edgar/data/%v/%v", f.CIK, strings.ReplaceAll(f.Accession, "-", ""))
+}
+
+// SECURL links to the filing index on sec.gov.
+func (f Filing) SECURL() string {
+	return fmt.Sprintf("https://www.sec.gov%v/%v-index.htm", f.ArchivePath(), f.Accession)
+}
+
+// IsHTML reports whether the document can be rendered inline.
+func (file File) IsHTML() bool {
+	switch strings.ToLower(filepath.Ext(file.Name)) {
+	case ".htm", ".html":
+		return true
+	}
+	return false
+}
+
+// IsExhibit reports whether the document is an exhibit (EX-*) other than
+// the XBRL files (EX-101.*), which aren't meant to be read.
+func (file File) IsExhibit() bool {
+	return strings.HasPrefix(file.Type, "EX-") && !strings.HasPrefix(file.Type, "EX-101")
+}
+
+// PrimaryDocument is the main document of the filing: the one whose type
+// is the form type, or else the first one in sequence.
+func (f Filing) PrimaryDocument() (File, bool) {
+	for _, file := range f.Files {
+		if file.Type != "" && file.Type == f.FormType {
+			return file, true
+		}
+	}
+	for _, file := range f.Files {
+		if file.Sequence == 1 {
+			return file, true
+		}
+	}
+	return File{}, false
+}
+
+// Exhibits returns the exhibits of the filing in sequence.
+func (f Filing) Exhibits() []File {
+	var exhibits []File
+	for _, file := range f.Files {
+		if file.IsExhibit() {
+			exhibits = append(exhibits, file)
+		}
+	}
+	return exhibits
+}
+
+// DocumentText returns the text of the primary document of the filing as
+// stored in xbrlbody when it was indexed, or "" if it has none.
+func DocumentText(db *sqlx.DB, filing Filing) (string, error) {
+	primary, ok := filing.PrimaryDocument()
+	if !ok {
+		return "", fmt.Errorf("filing %v has no primary document", filing.Accession)
+	}
+
+	var bodies []string
+	err := db.Select(&bodies, `
+		SELECT xbrlbody
+		FROM sec.secItemFile
+		WHERE accessionnumber = $1
+		AND xbrlfile = $2
+		AND xbrlbody IS NOT NULL
+		AND xbrlbody <> ''
+		LIMIT 1;`, filing.Accession, primary.Name)
+	if err != nil {
+		return "", err
+	}
+	if len(bodies) == 0 {
+		return "", nil
+	}
+	return bodies[0], nil
+}
+
+// DocumentPath returns where the document is cached on disk, either
+// downloaded as is or unpacked from the filing ZIP.
+func DocumentPath(s *sec.SEC, filing Filing, file File) (string, error) {
+	for _, dir := range []string{s.Config.Main.CacheDir, s.Config.Main.CacheDirUnpacked} {
+		filePath := filepath.Join(dir, filing.ArchivePath(), file.Name)
+		_, err := os.Stat(filePath)
+		if err == nil {
+			return filePath, nil
+		}
+	}
+
+	return "", fmt.Errorf("file %v is not in the cache", file.Name)
+}
+
+// RenderDocument returns the sanitized HTML of a document from the cache,
+// with relative links and images pointing to the cached copies.
+func RenderDocument(s *sec.SEC, filing Filing, file File) (string, error) {
+	filePath, err := DocumentPath(s, filing, file)
+	if err != nil {
+		return "", err
+	}
+
+	info, err := os.Stat(filePath)
+	if err != nil {
+		return "", err
+	}
+	if info.Size() > MaxInlineDocumentSize {
+		return "", fmt.Errorf("file %v is too large to be shown (%d bytes)", file.Name, info.Size())
+	}
+
+	document, err := os.Open(filePath)
+	if err != nil {
+		return "", err
+	}
+	defer document.Close()
+
+	return Sanitize(document, filing.ArchivePath()+"/")
+}
+
+// Document is a document of the filing rendered for the filing page. Error
+// explains why it couldn't be shown, e.g. because it isn't downloaded yet.
+type Document struct {
+	File
+	HTML  template.HTML
+	Error string
+}
+
+// RenderDocuments renders the primary document and the HTML exhibits.
+func RenderDocuments(s *sec.SEC, filing Filing) []Document {
+	var files []File
+	primary, ok := filing.PrimaryDocument()
+	if ok {
+		files = append(files, primary)
+	}
+	for _, exhibit := range filing.Exhibits() {
+		if exhibit.Name != primary.Name {
+			files = append(files, exhibit)
+		}
+	}
+
+	var documents []Document
+	for _, file := range files {
+		document := Document{File: file}
+		if !file.IsHTML() {
+			document.Error = "this document can't be shown inline"
+			documents = append(documents, document)
+			continue
+		}
+
+		rendered, err := RenderDocument(s, filing, file)
+		if err != nil {
+			document.Error = err.Error()
+		}
+		// Sanitized, so it is safe to include as is
+		document.HTML = template.HTML(rendered)
+
+		documents = append(documents, document)
+	}
+
+	return documents
+}
@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secgeo
+
+import (
+	"fmt"
+	"html"
+	"html/template"
+	"math"
+	"sort"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/jmoiron/sqlx"
+)
+
+type State struct {
+	Code string
+	Name string
+	// Position in the tile grid map
+	Col int
+	Row int
+}
+
+// States are the US states, DC and Puerto Rico laid out as an equal-area
+// tile grid, roughly following their geographic position.
+var States = []State{
+	{"AK", "Alaska", 0, 0}, {"ME", "Maine", 11, 0},
+	{"WI", "Wisconsin", 6, 1}, {"VT", "Vermont", 10, 1}, {"NH", "New Hampshire", 11, 1},
+	{"WA", "Washington", 1, 2}, {"ID", "Idaho", 2, 2}, {"MT", "Montana", 3, 2}, {"ND", "North Dakota", 4, 2}, {"MN", "Minnesota", 5, 2}, {"IL", "Illinois", 6, 2}, {"MI", "Michigan", 7, 2}, {"NY", "New York", 9, 2}, {"MA", "Massachusetts", 10, 2},
+	{"OR", "Oregon", 1, 3}, {"NV", "Nevada", 2, 3}, {"WY", "Wyoming", 3, 3}, {"SD", "South Dakota", 4, 3}, {"IA", "Iowa", 5, 3}, {"IN", "Indiana", 6, 3}, {"OH", "Ohio", 7, 3}, {"PA", "Pennsylvania", 8, 3}, {"NJ", "New Jersey", 9, 3}, {"CT", "Connecticut", 10, 3}, {"RI", "Rhode Island", 11, 3},
+	{"CA", "California", 1, 4}, {"UT", "Utah", 2, 4}, {"CO", "Colorado", 3, 4}, {"NE", "Nebraska", 4, 4}, {"MO", "Missouri", 5, 4}, {"KY", "Kentucky", 6, 4}, {"WV", "West Virginia", 7, 4}, {"VA", "Virginia", 8, 4}, {"MD", "Maryland", 9, 4}, {"DE", "Delaware", 10, 4},
+	{"AZ", "Arizona", 2, 5}, {"NM", "New Mexico", 3, 5}, {"KS", "Kansas", 4, 5}, {"AR", "Arkansas", 5, 5}, {"TN", "Tennessee", 6, 5}, {"NC", "North Carolina", 7, 5}, {"SC", "South Carolina", 8, 5}, {"DC", "District of Columbia", 9, 5},
+	{"OK", "Oklahoma", 4, 6}, {"LA", "Louisiana", 5, 6}, {"MS", "Mississippi", 6, 6}, {"AL", "Alabama", 7, 6}, {"GA", "Georgia", 8, 6},
+	{"HI", "Hawaii", 0, 7}, {"TX", "Texas", 4, 7}, {"FL", "Florida", 9, 7}, {"PR", "Puerto Rico", 11, 7},
+}
+
+type StateCount struct {
+	State     string `db:"state"`
+	Name      string
+	Companies int `db:"companies"`
+	Funds     int `db:"funds"`
+}
+
+type StateCompany struct {
+	CIK        int    `db:"cik"`
+	Name       string `db:"name"`
+	SIC        string `db:"sic"`
+	CityBA     string `db:"cityba"`
+	ZIPBA      string `db:"zipba"`
+	CountryInc string `db:"countryinc"`
+	StprInc    string `db:"stprinc"`
+	Slug       string `db:"slug"`
+}
+
+type SICCount struct {
+	SIC       string `db:"sic"`
+	Title     string `db:"title"`
+	Companies int    `db:"companies"`
+}
+
+// GetState returns the state with the given two letter code.
+func GetState(code string) (State, bool) {
+	code = strings.ToUpper(code)
+	for _, state := range States {
+		if state.Code == code {
+			return state, true
+		}
+	}
+	return State{}, false
+}
+
+// GetStateCounts returns the number of companies (fsds.sub) and fund filers
+// (mfd.sub) with a business address in each state, using the address of the
+// most recent submission of every CIK so companies that moved count once,
+// and companies that moved abroad don't count.
+func GetStateCounts(db *sqlx.DB) ([]StateCount, error) {
+	var counts []StateCount
+	err := db.Select(&counts, `
+		WITH companies AS (
+			SELECT cik, stprba, countryba, ROW_NUMBER() OVER (PARTITION BY cik ORDER BY filled DESC) AS row_number FROM fsds.sub
+		), funds AS (
+			SELECT cik, stprba, countryba, ROW_NUMBER() OVER (PARTITION BY cik ORDER BY filed DESC) AS row_number FROM mfd.sub
+		), company_counts AS (
+			SELECT stprba, COUNT(*) AS companies FROM companies WHERE row_number = 1 AND countryba = 'US' GROUP BY stprba
+		), fund_counts AS (
+			SELECT stprba, COUNT(*) AS funds FROM funds WHERE row_number = 1 AND countryba = 'US' GROUP BY stprba
+		), states AS (
+			SELECT stprba FROM company_counts UNION SELECT stprba FROM fund_counts
+		)
+		SELECT states.stprba AS state, COALESCE(c.companies, 0) AS companies, COALESCE(f.funds, 0) AS funds
+		FROM states
+		LEFT JOIN company_counts c ON c.stprba = states.stprba
+		LEFT JOIN fund_counts f ON f.stprba = states.stprba
+		WHERE states.stprba <> '';`)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range counts {
+		state, ok := GetState(counts[i].State)
+		if ok {
+			counts[i].Name = state.Name
+		}
+	}
+
+	sort.Slice(counts, func(i, j int) bool {
+		return counts[i].Companies > counts[j].Companies
+	})
+
+	return counts, nil
+}
+
+// GetStateCompanies returns the companies whose latest submission has a
+// business address in state, optionally restricted to one SIC code.
+func GetStateCompanies(db *sqlx.DB, state string, sic string) ([]StateCompany, error) {
+	var companies []StateCompany
+	err := db.Select(&companies, `
+		SELECT latest.cik, COALESCE(latest.name, '') AS name, COALESCE(latest.sic, '') AS sic, COALESCE(latest.cityba, '') AS cityba, COALESCE(latest.zipba, '') AS zipba,
+			COALESCE(latest.countryinc, '') AS countryinc, COALESCE(latest.stprinc, '') AS stprinc, COALESCE(company_slugs.slug, '') AS slug
+		FROM (
+			SELECT cik, name, sic, cityba, zipba, countryinc, stprinc, stprba, countryba,
+				ROW_NUMBER() OVER (PARTITION BY cik ORDER BY filled DESC) AS row_number
+			FROM fsds.sub
+		) latest
+		LEFT JOIN sec.company_slugs ON company_slugs.cik = latest.cik AND company_slugs.canonical
+		WHERE latest.row_number = 1 AND latest.countryba = 'US' AND latest.stprba = $1 AND ($2 = '' OR latest.sic = $2)
+		ORDER BY latest.name;`, strings.ToUpper(state), sic)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range companies {
+		companies[i].Slug = secslug.Canonical(companies[i].Slug, companies[i].Name, companies[i].CIK)
+	}
+	return companies, nil
+}
+
+// GetStateSICCounts returns the number of companies in state per SIC code.
+func GetStateSICCounts(db *sqlx.DB, state string) ([]SICCount, error) {
+	var counts []SICCount
+	err := db.Select(&counts, `
+		SELECT latest.sic, COALESCE(sics.title, '') AS title, COUNT(*) AS companies
+		FROM (
+			SELECT cik, sic, stprba, countryba,
+				ROW_NUMBER() OVER (PARTITION BY cik ORDER BY filled DESC) AS row_number
+			FROM fsds.sub
+		) latest
+		LEFT JOIN sec.sics ON sics.sic = CAST(NULLIF(latest.sic, '') AS integer)
+		WHERE latest.row_number = 1 AND latest.countryba = 'US' AND latest.stprba = $1 AND latest.sic <> ''
+		GROUP BY latest.sic, sics.title
+		ORDER BY companies DESC, latest.sic;`, strings.ToUpper(state))
+	if err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+const (
+	tileSize = 48
+	tileGap  = 4
+)
+
+// Colors of the choropleth buckets, from fewest to most companies.
+var choroplethColors = []string{"#eff3ff", "#c6dbef", "#9ecae1", "#6baed6", "#3182bd", "#08519c"}
+
+// RenderChoropleth draws the states as a tile grid SVG shaded by company
+// count. Counts span several orders of magnitude (Wyoming vs. New York), so
+// the buckets are on a log scale. Every tile links to its /states/{st} page.
+func RenderChoropleth(counts []StateCount) template.HTML {
+	byState := make(map[string]int)
+	maxCount := 0
+	for _, count := range counts {
+		byState[count.State] = count.Companies
+		if count.Companies > maxCount {
+			maxCount = count.Companies
+		}
+	}
+
+	width := 12*(tileSize+tileGap) - tileGap
+	height := 8*(tileSize+tileGap) - tileGap
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 %d %d" width="100%%" role="img" aria-label="Companies by state">`, width, height)
+	for _, state := range States {
+		count := byState[state.Code]
+		x := state.Col * (tileSize + tileGap)
+		y := state.Row * (tileSize + tileGap)
+
+		color := choroplethColors[bucket(count, maxCount, len(choroplethColors))]
+		textColor := "#000"
+		if bucket(count, maxCount, len(choroplethColors)) >= len(choroplethColors)-2 {
+			textColor = "#fff"
+		}
+
+		fmt.Fprintf(&b, `<a href="/states/%s">`, state.Code)
+		fmt.Fprintf(&b, `<title>%s: %s companies</title>`, html.EscapeString(state.Name), humanize.Comma(int64(count)))
+		fmt.Fprintf(&b, `<rect x="%d" y="%d" width="%d" height="%d" fill="%s" stroke="#999"/>`, x, y, tileSize, tileSize, color)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-size="14" font-family="sans-serif" fill="%s">%s</text>`, x+tileSize/2, y+tileSize/2, textColor, state.Code)
+		fmt.Fprintf(&b, `<text x="%d" y="%d" text-anchor="middle" font-size="10" font-family="sans-serif" fill="%s">%s</text>`, x+tileSize/2, y+tileSize/2+14, textColor, humanize.Comma(int64(count)))
+		b.WriteString(`</a>`)
+	}
+	b.WriteString(`</svg>`)
+
+	return template.HTML(b.String())
+}
+
+func bucket(count int, maxCount int, buckets int) int {
+	if count <= 0 || maxCount <= 1 {
+		return 0
+	}
+	i := int(math.Log(float64(count)) / math.Log(float64(maxCount)) * float64(buckets-1))
+	if i >= buckets {
+		i = buckets - 1
+	}
+	if i < 1 {
+		i = 1
+	}
+	return i
+}
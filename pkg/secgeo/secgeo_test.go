@@ -0,0 +1,75 @@
+package secgeo
+
+import (
+	"os"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestStateQueries(t *testing.T) {
+	sectest.ForEachDatabase(t, testStateQueries)
+}
+
+func testStateQueries(t *testing.T, dbConfig config.DatabaseConfig) {
+	cfg := config.Config{Database: dbConfig}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 1 moved from New York to California, 2 moved from California to the
+	// UK and 3 has no SIC code
+	_, err = db.Exec(`
+		INSERT INTO sec.ciks (cik) VALUES (1), (2), (3), (4);
+		INSERT INTO sec.sics (sic, title) VALUES (3571, 'ELECTRONIC COMPUTERS');
+		INSERT INTO fsds.sub (adsh, cik, name, sic, countryba, stprba, filled) VALUES
+			('a1', 1, 'ONE', '3571', 'US', 'NY', '2019-01-01'),
+			('a2', 1, 'ONE', '3571', 'US', 'CA', '2021-01-01'),
+			('b1', 2, 'TWO', '3571', 'US', 'CA', '2019-01-01'),
+			('b2', 2, 'TWO', '3571', 'GB', '', '2021-01-01'),
+			('c1', 3, 'THREE', '', 'US', 'CA', '2021-01-01');
+		INSERT INTO mfd.sub (adsh, cik, name, countryba, stprba, filed) VALUES
+			('d1', 4, 'FOUR', 'US', 'CA', '2021-06-01');
+		INSERT INTO sec.company_slugs (slug, cik, name, canonical) VALUES
+			('one-1', 1, 'ONE', false),
+			('one-inc-1', 1, 'ONE INC', true);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	counts, err := GetStateCounts(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(counts) != 1 || counts[0].State != "CA" || counts[0].Name != "California" || counts[0].Companies != 2 || counts[0].Funds != 1 {
+		t.Errorf("GetStateCounts = %+v, want 2 companies and 1 fund in California", counts)
+	}
+
+	companies, err := GetStateCompanies(db, "ca", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(companies) != 2 || companies[0].CIK != 1 || companies[1].CIK != 3 {
+		t.Errorf("GetStateCompanies = %+v, want 1 and 3", companies)
+	}
+	if len(companies) == 2 && (companies[0].Slug != "one-inc-1" || companies[1].Slug != "three-3") {
+		t.Errorf("GetStateCompanies slugs = %v and %v, want the canonical one-inc-1 and three-3", companies[0].Slug, companies[1].Slug)
+	}
+
+	sics, err := GetStateSICCounts(db, "CA")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(sics) != 1 || sics[0].SIC != "3571" || sics[0].Title != "ELECTRONIC COMPUTERS" || sics[0].Companies != 1 {
+		t.Errorf("GetStateSICCounts = %+v, want 1 company in 3571", sics)
+	}
+}
@@ -2,31 +2,41 @@ package secindex
 
 import (
 	"archive/zip"
-	"bytes"
 	"fmt"
-	"io"
 	"io/ioutil"
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/equres/sec/pkg/cache"
 	"github.com/equres/sec/pkg/sec"
 	"github.com/equres/sec/pkg/secevent"
+	"github.com/equres/sec/pkg/secsection"
+	"github.com/equres/sec/pkg/secsic"
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/equres/sec/pkg/sectext"
 	"github.com/equres/sec/pkg/secutil"
 	"github.com/equres/sec/pkg/secworklist"
 	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
 	log "github.com/sirupsen/logrus"
-	"jaytaylor.com/html2text"
 )
 
 func InsertAllSecItemFile(db *sqlx.DB, s *sec.SEC, rssFiles []sec.RSSFile, worklistMap map[string]sec.Entry, totalCount int) error {
+	invalidation, err := NewCacheInvalidation(db)
+	if err != nil {
+		return err
+	}
+	defer invalidation.Flush(db, s)
+
 	currentCount := 0
 	for _, rssFile := range rssFiles {
 		for _, v1 := range rssFile.Channel.Item {
-			err := SecItemFileUpsert(db, s, v1, worklistMap, &currentCount, totalCount)
+			err := SecItemFileUpsert(db, s, v1, worklistMap, &currentCount, totalCount, invalidation)
 			if err != nil {
 				return err
 			}
@@ -35,7 +45,9 @@ func InsertAllSecItemFile(db *sqlx.DB, s *sec.SEC, rssFiles []sec.RSSFile, workl
 	return nil
 }
 
-func SecItemFileUpsert(db *sqlx.DB, s *sec.SEC, item sec.Item, worklist map[string]sec.Entry, currentCount *int, totalCount int) error {
+// SecItemFileUpsert stores the files of item in sec.secItemFile and adds
+// the page data it's listed in to invalidation.
+func SecItemFileUpsert(db *sqlx.DB, s *sec.SEC, item sec.Item, worklist map[string]sec.Entry, currentCount *int, totalCount int, invalidation *CacheInvalidation) error {
 	var err error
 
 	var enclosureLength int
@@ -68,6 +80,9 @@ func SecItemFileUpsert(db *sqlx.DB, s *sec.SEC, item sec.Item, worklist map[stri
 		}
 	}
 
+	extractors := Extractors(s)
+
+	inserted := false
 	for _, v := range item.XbrlFiling.XbrlFiles.XbrlFile {
 		if _, ok := worklist[v.URL]; ok {
 			continue
@@ -109,7 +124,7 @@ func SecItemFileUpsert(db *sqlx.DB, s *sec.SEC, item sec.Item, worklist map[stri
 			return err
 		}
 
-		var fileBody string
+		var fileBody sectext.Text
 		filePath := filepath.Join(s.Config.Main.CacheDir, fileUrl.Path)
 		_, err = os.Stat(filePath)
 		if err != nil {
@@ -125,112 +140,251 @@ func SecItemFileUpsert(db *sqlx.DB, s *sec.SEC, item sec.Item, worklist map[stri
 			}
 		}
 
+		var extractErr error
 		if filePath != "" {
-			fileBody, err = GetXbrlFileBody(filePath)
-			if err != nil {
-				return err
+			fileBody, extractErr = GetXbrlFileBody(extractors, filePath)
+			if extractErr != nil {
+				// Broken PDFs or a failing OCR command don't stop indexing,
+				// the file is stored without text
+				secevent.CreateIndexEvent(db, filePath, "failed", "could_not_extract_text")
+				s.Log(fmt.Sprintf("Could not extract the text of %v: %v", filePath, extractErr))
 			}
 		}
 
-		if fileBody == "" && IsFileIndexable(filePath) {
+		if filePath == "" && extractors.CanExtract(fileUrl.Path) {
 			secevent.CreateIndexEvent(db, v.URL, "failed", "could_not_find_file")
+		} else if filePath != "" && extractErr == nil && fileBody.Text == "" && extractors.CanExtract(filePath) {
+			// Scanned PDFs without OCR, empty documents
+			secevent.CreateIndexEvent(db, filePath, "failed", "no_text")
 		}
 
 		_, err = db.Exec(`
-		INSERT INTO sec.secItemFile (title, link, guid, enclosure_url, enclosure_length, enclosure_type, description, pubdate, companyname, formtype, fillingdate, ciknumber, accessionnumber, filenumber, acceptancedatetime, period, assistantdirector, assignedsic, fiscalyearend, xbrlsequence, xbrlfile, xbrltype, xbrlsize, xbrldescription, xbrlinlinexbrl, xbrlurl, xbrlbody, XbrlFilePath, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, NOW(), NOW()) 
+		INSERT INTO sec.secItemFile (title, link, guid, enclosure_url, enclosure_length, enclosure_type, description, pubdate, companyname, formtype, fillingdate, ciknumber, accessionnumber, filenumber, acceptancedatetime, period, assistantdirector, assignedsic, fiscalyearend, xbrlsequence, xbrlfile, xbrltype, xbrlsize, xbrldescription, xbrlinlinexbrl, xbrlurl, xbrlbody, XbrlFilePath, xbrlbodyoffsets, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25, $26, $27, $28, $29, NOW(), NOW()) 
 
 		ON CONFLICT (xbrlsequence, xbrlfile, xbrltype, xbrlsize, xbrldescription, xbrlinlinexbrl, xbrlurl)
-		DO UPDATE SET title=EXCLUDED.title, link=EXCLUDED.link, guid=EXCLUDED.guid, enclosure_url=EXCLUDED.enclosure_url, enclosure_length=EXCLUDED.enclosure_length, enclosure_type=EXCLUDED.enclosure_type, description=EXCLUDED.description, pubdate=EXCLUDED.pubdate, companyname=EXCLUDED.companyname, formtype=EXCLUDED.formtype, fillingdate=EXCLUDED.fillingdate, ciknumber=EXCLUDED.ciknumber, accessionnumber=EXCLUDED.accessionnumber, filenumber=EXCLUDED.filenumber, acceptancedatetime=EXCLUDED.acceptancedatetime, period=EXCLUDED.period, assistantdirector=EXCLUDED.assistantdirector, assignedsic=EXCLUDED.assignedsic, fiscalyearend=EXCLUDED.fiscalyearend, xbrlsequence=EXCLUDED.xbrlsequence, xbrlfile=EXCLUDED.xbrlfile, xbrltype=EXCLUDED.xbrltype, xbrlsize=EXCLUDED.xbrlsize, xbrldescription=EXCLUDED.xbrldescription, xbrlinlinexbrl=EXCLUDED.xbrlinlinexbrl, xbrlurl=EXCLUDED.xbrlurl, xbrlfilepath=EXCLUDED.xbrlfilepath, updated_at=NOW()
-		WHERE secItemFile.xbrlsequence=EXCLUDED.xbrlsequence AND secItemFile.xbrlfile=EXCLUDED.xbrlfile AND secItemFile.xbrltype=EXCLUDED.xbrltype AND secItemFile.xbrlsize=EXCLUDED.xbrlsize AND secItemFile.xbrldescription=EXCLUDED.xbrldescription AND secItemFile.xbrlinlinexbrl=EXCLUDED.xbrlinlinexbrl AND secItemFile.xbrlurl=EXCLUDED.xbrlurl AND secItemFile.xbrlbody=EXCLUDED.xbrlbody;`,
-			item.Title, item.Link, item.Guid, item.Enclosure.URL, enclosureLength, item.Enclosure.Type, item.Description, item.PubDate, item.XbrlFiling.CompanyName, item.XbrlFiling.FormType, item.XbrlFiling.FilingDate, cikNumber, item.XbrlFiling.AccessionNumber, item.XbrlFiling.FileNumber, item.XbrlFiling.AcceptanceDatetime, item.XbrlFiling.Period, item.XbrlFiling.AssistantDirector, assignedSic, fiscalYearEnd, xbrlSequence, v.File, v.Type, xbrlSize, v.Description, xbrlInline, v.URL, fileBody, filePath)
+		DO UPDATE SET title=EXCLUDED.title, link=EXCLUDED.link, guid=EXCLUDED.guid, enclosure_url=EXCLUDED.enclosure_url, enclosure_length=EXCLUDED.enclosure_length, enclosure_type=EXCLUDED.enclosure_type, description=EXCLUDED.description, pubdate=EXCLUDED.pubdate, companyname=EXCLUDED.companyname, formtype=EXCLUDED.formtype, fillingdate=EXCLUDED.fillingdate, ciknumber=EXCLUDED.ciknumber, accessionnumber=EXCLUDED.accessionnumber, filenumber=EXCLUDED.filenumber, acceptancedatetime=EXCLUDED.acceptancedatetime, period=EXCLUDED.period, assistantdirector=EXCLUDED.assistantdirector, assignedsic=EXCLUDED.assignedsic, fiscalyearend=EXCLUDED.fiscalyearend, xbrlsequence=EXCLUDED.xbrlsequence, xbrlfile=EXCLUDED.xbrlfile, xbrltype=EXCLUDED.xbrltype, xbrlsize=EXCLUDED.xbrlsize, xbrldescription=EXCLUDED.xbrldescription, xbrlinlinexbrl=EXCLUDED.xbrlinlinexbrl, xbrlurl=EXCLUDED.xbrlurl, xbrlfilepath=EXCLUDED.xbrlfilepath, xbrlbody=EXCLUDED.xbrlbody, xbrlbodyoffsets=EXCLUDED.xbrlbodyoffsets, updated_at=NOW()
+		WHERE secItemFile.xbrlsequence=EXCLUDED.xbrlsequence AND secItemFile.xbrlfile=EXCLUDED.xbrlfile AND secItemFile.xbrltype=EXCLUDED.xbrltype AND secItemFile.xbrlsize=EXCLUDED.xbrlsize AND secItemFile.xbrldescription=EXCLUDED.xbrldescription AND secItemFile.xbrlinlinexbrl=EXCLUDED.xbrlinlinexbrl AND secItemFile.xbrlurl=EXCLUDED.xbrlurl;`,
+			item.Title, item.Link, item.Guid, item.Enclosure.URL, enclosureLength, item.Enclosure.Type, item.Description, item.PubDate, item.XbrlFiling.CompanyName, item.XbrlFiling.FormType, filingDate(item.XbrlFiling.FilingDate), cikNumber, item.XbrlFiling.AccessionNumber, item.XbrlFiling.FileNumber, item.XbrlFiling.AcceptanceDatetime, item.XbrlFiling.Period, item.XbrlFiling.AssistantDirector, assignedSic, fiscalYearEnd, xbrlSequence, v.File, v.Type, xbrlSize, v.Description, xbrlInline, v.URL, fileBody.Text, filePath, pq.Array(fileBody.Flatten()))
 		if err != nil {
 			secevent.CreateIndexEvent(db, filePath, "failed", "error_inserting_in_database")
 			return err
 		}
 
 		*currentCount++
+		inserted = true
 
 		secevent.CreateIndexEvent(db, filePath, "success", "")
+
+		if fileBody.Text != "" && v.Type == item.XbrlFiling.FormType && secsection.FormKind(v.Type) != "" {
+			SaveSections(db, item, v.File, fileBody.Text)
+		}
 	}
+
+	if inserted {
+		invalidation.AddItem(item)
+	}
+
 	return nil
 }
 
-func IsFileIndexable(filename string) bool {
-	fileExtension := strings.ToLower(filepath.Ext(filename))
-
-	if fileExtension == ".html" || fileExtension == ".htm" || fileExtension == ".xml" {
-		return true
+// filingDate is the 01/02/2006 filing date of an RSS item as 2006-01-02,
+// which SQLite compares and scans as a date like Postgres does
+func filingDate(date string) string {
+	t, err := time.Parse("01/02/2006", date)
+	if err != nil {
+		return date
 	}
-	return false
+	return t.Format("2006-01-02")
 }
 
-func IsFileTypeHTML(filename string) bool {
-	fileExtension := strings.ToLower(filepath.Ext(filename))
+// SaveSections segments the primary document of a 10-K or 10-Q into its
+// Items. Failing to do so doesn't stop indexing, "sec sections" retries.
+func SaveSections(db *sqlx.DB, item sec.Item, file string, fileBody string) {
+	sections := secsection.Segment(fileBody, item.XbrlFiling.FormType)
 
-	if fileExtension == ".html" || fileExtension == ".htm" {
-		return true
+	err := secsection.Save(db, item.XbrlFiling.AccessionNumber, file, item.XbrlFiling.FormType, sections)
+	if err != nil {
+		log.Error("could not save the sections of ", item.XbrlFiling.AccessionNumber, ": ", err)
 	}
-	return false
 }
 
-func GetXbrlFileBody(filePath string) (string, error) {
-	xbrlFile, err := os.Open(filePath)
+// CacheInvalidation collects the cached page data changed by the filings
+// of an indexing run, see cache.Invalidation. Flush deletes it at the end of
+// the run, so it's recomputed on the next request instead of waiting for
+// "sec regen".
+type CacheInvalidation struct {
+	keys *cache.Invalidation
+
+	// Rows of sec.secItemFile with a higher id were added during the run
+	lastID int
+
+	// Names the CIKs of the run filed under, their company pages are keyed
+	// by the slugs made from them
+	names map[int]map[string]bool
+}
+
+// NewCacheInvalidation starts collecting the page data changed by an
+// indexing run.
+func NewCacheInvalidation(db *sqlx.DB) (*CacheInvalidation, error) {
+	var lastID int
+	err := db.Get(&lastID, "SELECT COALESCE(MAX(id), 0) FROM sec.secItemFile;")
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer xbrlFile.Close()
 
-	data, err := ioutil.ReadAll(xbrlFile)
+	return &CacheInvalidation{
+		keys:   cache.NewInvalidation(),
+		lastID: lastID,
+		names:  make(map[int]map[string]bool),
+	}, nil
+}
+
+// AddItem adds the page data the filing of item is listed in.
+func (c *CacheInvalidation) AddItem(item sec.Item) {
+	cik, err := strconv.Atoi(item.XbrlFiling.CikNumber)
 	if err != nil {
-		return "", err
+		return
 	}
 
-	var fileBody string
+	// Without a filing date only the CIK keys are added
+	filingDate, _ := time.Parse("01/02/2006", item.XbrlFiling.FilingDate)
+	c.keys.AddFiling(filingDate, cik)
+	c.keys.AddPages("/filing/" + item.XbrlFiling.AccessionNumber)
 
-	if IsFileIndexable(filePath) {
-		fileBody = string(data)
-		if IsFileTypeHTML(filePath) {
-			fileBody, err = html2text.FromString(string(data))
-			if err != nil {
-				return "", err
+	if c.names[cik] == nil {
+		c.names[cik] = make(map[string]bool)
+	}
+	if item.XbrlFiling.CompanyName != "" {
+		c.names[cik][item.XbrlFiling.CompanyName] = true
+	}
+}
+
+// cacheInvalidationBatch is how many CIKs Flush looks up per query
+const cacheInvalidationBatch = 1000
+
+// Flush deletes the page data collected since the last flush. A CIK is
+// listed on the pages of all the SICs it filed under, e.g. before its SIC
+// changed, and on its company page under the canonical slug and the slugs
+// of the names it filed under. The lists of all companies and SICs are only
+// deleted when a company filed under a SIC for the first time in the run.
+// Failing to reach the database or the cache doesn't stop indexing.
+func (c *CacheInvalidation) Flush(db *sqlx.DB, s *sec.SEC) {
+	var ciks []int
+	for cik, names := range c.names {
+		ciks = append(ciks, cik)
+		for name := range names {
+			c.keys.AddPages("/company/" + secslug.Make(name, cik))
+		}
+	}
+	sort.Ints(ciks)
+
+	newSIC := false
+	for start := 0; start < len(ciks); start += cacheInvalidationBatch {
+		end := start + cacheInvalidationBatch
+		if end > len(ciks) {
+			end = len(ciks)
+		}
+
+		query, args, err := sqlx.In(`
+			SELECT CAST(assignedsic AS TEXT) AS sic, MIN(id) AS id
+			FROM sec.secItemFile
+			WHERE ciknumber IN (?) AND assignedsic IS NOT NULL
+			GROUP BY ciknumber, assignedsic;`, ciks[start:end])
+		if err != nil {
+			log.Error("could not get the SICs of the indexed CIKs: ", err)
+			continue
+		}
+		var sics []struct {
+			SIC string `db:"sic"`
+			ID  int    `db:"id"`
+		}
+		err = db.Select(&sics, db.Rebind(query), args...)
+		if err != nil {
+			log.Error("could not get the SICs of the indexed CIKs: ", err)
+		}
+		for _, sic := range sics {
+			c.keys.AddSIC(sic.SIC)
+			if sic.ID > c.lastID {
+				newSIC = true
 			}
 		}
+
+		query, args, err = sqlx.In(`SELECT slug FROM sec.company_slugs WHERE canonical AND cik IN (?);`, ciks[start:end])
+		if err != nil {
+			log.Error("could not get the slugs of the indexed CIKs: ", err)
+			continue
+		}
+		var slugs []string
+		err = db.Select(&slugs, db.Rebind(query), args...)
+		if err != nil {
+			log.Error("could not get the slugs of the indexed CIKs: ", err)
+		}
+		for _, slug := range slugs {
+			c.keys.AddPages("/company/" + slug)
+		}
+	}
+
+	if newSIC {
+		var presets []string
+		for name := range secsic.Presets {
+			presets = append(presets, name)
+		}
+		c.keys.AddLists(presets...)
+	}
+
+	err := c.keys.Flush(s.Cache)
+	if err != nil {
+		log.Error("could not invalidate the cache: ", err)
 	}
-	return fileBody, nil
+	c.names = make(map[int]map[string]bool)
 }
 
-func GetXbrlFileBodyFromZIPFile(currentFile *zip.File, filePath string) (string, error) {
-	if currentFile == nil {
-		return "", nil
+// Extractors returns the text extractors for indexing: documents, PDFs and,
+// if an OCR command is configured, images.
+func Extractors(s *sec.SEC) sectext.Extractors {
+	return sectext.NewExtractors(sectext.OCRExtractor{
+		Command: s.Config.OCR.Command,
+		Args:    s.Config.OCR.Args,
+		Timeout: s.Config.OCR.Timeout,
+	})
+}
+
+// GetXbrlFileBody extracts the text stored in xbrlbody from a document,
+// with the offsets mapping it back into the file.
+func GetXbrlFileBody(extractors sectext.Extractors, filePath string) (sectext.Text, error) {
+	if !extractors.CanExtract(filePath) {
+		return sectext.Text{}, nil
 	}
 
-	fileReader, err := currentFile.Open()
+	content, err := ioutil.ReadFile(filePath)
 	if err != nil {
-		return "", err
+		return sectext.Text{}, err
+	}
+
+	return extractors.Extract(filePath, content)
+}
+
+func GetXbrlFileBodyFromZIPFile(extractors sectext.Extractors, currentFile *zip.File) (sectext.Text, error) {
+	if currentFile == nil || !extractors.CanExtract(currentFile.Name) {
+		return sectext.Text{}, nil
 	}
 
-	stringBuilder := new(strings.Builder)
-	_, err = io.Copy(stringBuilder, fileReader)
+	fileReader, err := currentFile.Open()
 	if err != nil {
-		return "", err
+		return sectext.Text{}, err
 	}
+	defer fileReader.Close()
 
-	var fileBody string
-	if IsFileIndexable(currentFile.Name) {
-		fileBody = stringBuilder.String()
-		if IsFileTypeHTML(filePath) {
-			fileBody, err = html2text.FromString(stringBuilder.String())
-			if err != nil {
-				return "", err
-			}
-		}
+	content, err := ioutil.ReadAll(fileReader)
+	if err != nil {
+		return sectext.Text{}, err
 	}
 
-	return fileBody, nil
+	return extractors.Extract(currentFile.Name, content)
 }
 
-func ZIPContentUpsert(db *sqlx.DB, pathname string, files []*zip.File) error {
+func ZIPContentUpsert(db *sqlx.DB, extractors sectext.Extractors, pathname string, files []*zip.File) error {
 	// Keeping only directories
 	dirsPath := filepath.Dir(pathname)
 
@@ -247,30 +401,17 @@ func ZIPContentUpsert(db *sqlx.DB, pathname string, files []*zip.File) error {
 	accession := dirs[1]
 
 	for _, file := range files {
-		reader, err := file.Open()
-		if err != nil {
-			return err
-		}
-
-		buf := bytes.Buffer{}
-		_, err = buf.ReadFrom(reader)
+		xbrlBody, err := GetXbrlFileBodyFromZIPFile(extractors, file)
 		if err != nil {
-			return err
-		}
-
-		reader.Close()
-
-		var xbrlBody string
-
-		if IsFileIndexable(file.FileInfo().Name()) {
-			xbrlBody = buf.String()
+			secevent.CreateIndexEvent(db, filepath.Join(pathname, file.Name), "failed", "indexz_could_not_extract_text")
+			log.Error("could not extract the text of ", file.Name, ": ", err)
 		}
 
 		_, err = db.Exec(`
-			INSERT INTO sec.secItemFile (ciknumber, accessionnumber, xbrlfile, xbrlsize, xbrlbody, created_at, updated_at)
-			VALUES ($1, $2, $3, $4, $5, NOW(), NOW()) 
+			INSERT INTO sec.secItemFile (ciknumber, accessionnumber, xbrlfile, xbrlsize, xbrlbody, xbrlbodyoffsets, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW()) 
 			ON CONFLICT (cikNumber, accessionNumber, xbrlFile, xbrlSize)
-			DO NOTHING;`, cik, accession, file.Name, int(file.FileInfo().Size()), xbrlBody)
+			DO NOTHING;`, cik, accession, file.Name, int(file.FileInfo().Size()), xbrlBody.Text, pq.Array(xbrlBody.Flatten()))
 		if err != nil {
 			secevent.CreateIndexEvent(db, pathname, "failed", "indexz_error_inserting_in_database")
 		}
@@ -281,7 +422,7 @@ func ZIPContentUpsert(db *sqlx.DB, pathname string, files []*zip.File) error {
 	return nil
 }
 
-func IndexZIPFileContent(db *sqlx.DB, s *sec.SEC, rssFile sec.RSSFile, worklist []secworklist.Worklist) error {
+func IndexZIPFileContent(db *sqlx.DB, s *sec.SEC, rssFile sec.RSSFile, worklist []secworklist.Worklist, invalidation *CacheInvalidation) error {
 	totalCount := len(rssFile.Channel.Item)
 	currentCount := 0
 	for _, v1 := range rssFile.Channel.Item {
@@ -309,13 +450,14 @@ func IndexZIPFileContent(db *sqlx.DB, s *sec.SEC, rssFile sec.RSSFile, worklist
 			continue
 		}
 
-		err = ZIPContentUpsert(db, zipPath, reader.File)
+		err = ZIPContentUpsert(db, Extractors(s), zipPath, reader.File)
 		if err != nil {
 			secevent.CreateIndexEvent(db, zipCachePath, "failed", "indexz_error_inserting_in_database")
 			return err
 		}
 
 		secevent.CreateIndexEvent(db, zipCachePath, "success", "")
+		invalidation.AddItem(v1)
 
 		reader.Close()
 		currentCount++
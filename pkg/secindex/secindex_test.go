@@ -0,0 +1,244 @@
+package secindex
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equres/sec/pkg/cache"
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/secevent"
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/equres/sec/pkg/sectest"
+	"github.com/equres/sec/pkg/sectext"
+	"github.com/lib/pq"
+)
+
+func TestSecItemFileUpsertSQLite(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Config{
+		Database: sectest.SQLite(t),
+		Main:     config.MainConfig{CacheDir: filepath.Join(dir, "cache"), CacheDirUnpacked: filepath.Join(dir, "unzipped_cache")},
+	}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sec.NewSEC(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	path := "/Archives/edgar/data/320193/000032019322000007/ex21.htm"
+	err = os.MkdirAll(filepath.Join(cfg.Main.CacheDir, filepath.Dir(path)), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(cfg.Main.CacheDir, path), []byte("<html><body><p>Subsidiaries of Apple Inc.</p></body></html>"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := sec.Item{
+		Title:   "APPLE INC (0000320193) (Filer)",
+		PubDate: "Fri, 28 Jan 2022 18:02:46 EST",
+		XbrlFiling: sec.XbrlFiling{
+			CompanyName:     "APPLE INC",
+			FormType:        "10-Q",
+			FilingDate:      "01/28/2022",
+			CikNumber:       "0000320193",
+			AccessionNumber: "0000320193-22-000007",
+			AssignedSic:     "3571",
+			XbrlFiles: sec.XbrlFiles{XbrlFile: []sec.XbrlFile{
+				{Sequence: "2", File: "ex21.htm", Type: "EX-21", Size: "59", Description: "EXHIBIT 21", InlineXBRL: "false", URL: "https://www.sec.gov" + path},
+			}},
+		},
+	}
+
+	companyPage := cache.PageKey("/company/" + secslug.Make("APPLE INC", 320193))
+	dayKey := cache.FilingsInDayKey(2022, 1, 28, 320193)
+	otherDayKey := cache.FilingsInDayKey(2022, 1, 27, 320193)
+
+	// Indexing twice updates the row. The lists of all companies and SICs
+	// are only invalidated the first time, when the company is new.
+	for i, wantListsDeleted := range []bool{true, false} {
+		for _, k := range []string{cache.SECCompanies, cache.SICPresetCompaniesKey("tech"), cache.CompaniesWithSICKey("3571"), companyPage, dayKey, otherDayKey} {
+			s.Cache.Set(k, "cached")
+		}
+
+		invalidation, err := NewCacheInvalidation(db)
+		if err != nil {
+			t.Fatal(err)
+		}
+		count := 0
+		err = SecItemFileUpsert(db, s, item, map[string]sec.Entry{}, &count, 1, invalidation)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		// Nothing is deleted before the end of the run
+		if _, err := s.Cache.Get(dayKey); err != nil {
+			t.Errorf("run %d: %v was deleted before Flush: %v", i, dayKey, err)
+		}
+		invalidation.Flush(db, s)
+
+		want := map[string]bool{
+			cache.SECCompanies:                  wantListsDeleted,
+			cache.SICPresetCompaniesKey("tech"): wantListsDeleted,
+			cache.CompaniesWithSICKey("3571"):   true,
+			companyPage:                         true,
+			dayKey:                              true,
+			otherDayKey:                         false,
+		}
+		for k, deleted := range want {
+			_, err := s.Cache.Get(k)
+			if (err == cache.ErrMiss) != deleted {
+				t.Errorf("run %d: Get(%v) = %v, want deleted %v", i, k, err, deleted)
+			}
+		}
+	}
+
+	var files []struct {
+		Body    string        `db:"xbrlbody"`
+		Offsets pq.Int64Array `db:"xbrlbodyoffsets"`
+	}
+	err = db.Select(&files, "SELECT xbrlbody, xbrlbodyoffsets FROM sec.secItemFile WHERE accessionnumber = $1", item.XbrlFiling.AccessionNumber)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 1 || files[0].Body != "Subsidiaries of Apple Inc." || len(files[0].Offsets) == 0 {
+		t.Errorf("sec.secItemFile = %+v, want the text of ex21.htm with its offsets", files)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	f, err := w.Create("R1.htm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.Write([]byte("<p>Cover page</p>"))
+	w.Close()
+
+	r, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	zipPath := filepath.Join(cfg.Main.CacheDir, "Archives/edgar/data/320193/0000320193-22-000007/0000320193-22-000007-xbrl.zip")
+	err = ZIPContentUpsert(db, sectext.NewExtractors(sectext.OCRExtractor{}), zipPath, r.File)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var count int
+	err = db.Get(&count, "SELECT COUNT(*) FROM sec.secItemFile WHERE xbrlfile = 'R1.htm' AND xbrlbody = 'Cover page'")
+	if err != nil || count != 1 {
+		t.Errorf("R1.htm is in sec.secItemFile %v times, %v, want once", count, err)
+	}
+}
+
+func TestIndexEventsSQLite(t *testing.T) {
+	dir := t.TempDir()
+	cfg := config.Config{
+		Database: sectest.SQLite(t),
+		Main:     config.MainConfig{CacheDir: filepath.Join(dir, "cache"), CacheDirUnpacked: filepath.Join(dir, "unzipped_cache")},
+	}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sec.NewSEC(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// ex21.htm is downloaded but has no text, the others weren't downloaded
+	dataDir := "/Archives/edgar/data/320193/000032019322000007"
+	err = os.MkdirAll(filepath.Join(cfg.Main.CacheDir, dataDir), 0755)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = ioutil.WriteFile(filepath.Join(cfg.Main.CacheDir, dataDir, "ex21.htm"), []byte("<html><body></body></html>"), 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	item := sec.Item{
+		Title:   "APPLE INC (0000320193) (Filer)",
+		PubDate: "Fri, 28 Jan 2022 18:02:46 EST",
+		XbrlFiling: sec.XbrlFiling{
+			CompanyName:     "APPLE INC",
+			FormType:        "10-Q",
+			FilingDate:      "01/28/2022",
+			CikNumber:       "0000320193",
+			AccessionNumber: "0000320193-22-000007",
+			AssignedSic:     "3571",
+			XbrlFiles: sec.XbrlFiles{XbrlFile: []sec.XbrlFile{
+				{Sequence: "1", File: "aapl-20211225.htm", Type: "10-Q", Size: "1000", URL: "https://www.sec.gov" + dataDir + "/aapl-20211225.htm"},
+				{Sequence: "2", File: "ex21.htm", Type: "EX-21", Size: "26", URL: "https://www.sec.gov" + dataDir + "/ex21.htm"},
+				{Sequence: "3", File: "image01.jpg", Type: "GRAPHIC", Size: "2000", URL: "https://www.sec.gov" + dataDir + "/image01.jpg"},
+			}},
+		},
+	}
+
+	invalidation, err := NewCacheInvalidation(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	count := 0
+	err = SecItemFileUpsert(db, s, item, map[string]sec.Entry{}, &count, 3, invalidation)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []string
+	err = db.Select(&events, "SELECT ev FROM sec.events ORDER BY id")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	failed := make(map[string]string)
+	for _, ev := range events {
+		var event secevent.IndexEvent
+		err = json.Unmarshal([]byte(ev), &event)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Status == "failed" {
+			failed[filepath.Base(event.File)] = event.Reason
+		}
+	}
+
+	want := map[string]string{
+		"aapl-20211225.htm": "could_not_find_file",
+		"ex21.htm":          "no_text",
+	}
+	if len(failed) != len(want) {
+		t.Errorf("failed index events = %v, want %v", failed, want)
+	}
+	for file, reason := range want {
+		if failed[file] != reason {
+			t.Errorf("reason of %v = %q, want %q", file, failed[file], reason)
+		}
+	}
+}
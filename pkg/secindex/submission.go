@@ -0,0 +1,163 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secindex
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/secevent"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/equres/sec/pkg/sgml"
+	"github.com/jmoiron/sqlx"
+)
+
+// SubmissionItem describes a full submission the way the monthly RSS feeds
+// describe XBRL filings, so it's indexed the same way. Each document is a
+// file under the EDGAR archive path of the filing.
+func SubmissionItem(submission sgml.Submission) (sec.Item, error) {
+	header := submission.Header
+	filer := header.Filer()
+	if header.AccessionNumber == "" {
+		return sec.Item{}, fmt.Errorf("submission has no accession number")
+	}
+	if filer.CIK == 0 {
+		return sec.Item{}, fmt.Errorf("submission %v has no filer CIK", header.AccessionNumber)
+	}
+
+	archivePath := fmt.Sprintf("https://www.sec.gov/Archives/edgar/data/%v/%v", filer.CIK, strings.ReplaceAll(header.AccessionNumber, "-", ""))
+
+	var item sec.Item
+	item.Link = fmt.Sprintf("%v/%v-index.htm", archivePath, header.AccessionNumber)
+	item.Guid = fmt.Sprintf("%v/%v.txt", archivePath, header.AccessionNumber)
+	item.XbrlFiling = sec.XbrlFiling{
+		CompanyName:     filer.CompanyName,
+		FormType:        header.SubmissionType,
+		CikNumber:       strconv.Itoa(filer.CIK),
+		AccessionNumber: header.AccessionNumber,
+		FileNumber:      filer.FileNumber,
+		Period:          header.PeriodOfReport,
+		FiscalYearEnd:   filer.FiscalYearEnd,
+	}
+	if !header.FiledAsOfDate.IsZero() {
+		item.XbrlFiling.FilingDate = header.FiledAsOfDate.Format("01/02/2006")
+	}
+	if filer.SIC != 0 {
+		item.XbrlFiling.AssignedSic = strconv.Itoa(filer.SIC)
+	}
+	item.Title = fmt.Sprintf("%v (%010d) (Filer)", filer.CompanyName, filer.CIK)
+
+	for _, document := range submission.Documents {
+		item.XbrlFiling.XbrlFiles.XbrlFile = append(item.XbrlFiling.XbrlFiles.XbrlFile, sec.XbrlFile{
+			Sequence:    strconv.Itoa(document.Sequence),
+			File:        document.Name(),
+			Type:        document.Type,
+			Size:        strconv.Itoa(len(document.Content)),
+			Description: document.Description,
+			URL:         fmt.Sprintf("%v/%v", archivePath, document.Name()),
+		})
+	}
+
+	return item, nil
+}
+
+// UnpackSubmission writes the documents of the submission into the unpacked
+// cache, decoded, where the documents of filings unpacked from ZIPs are.
+func UnpackSubmission(s *sec.SEC, submission sgml.Submission) error {
+	filing := secfiling.Filing{CIK: submission.Header.Filer().CIK, Accession: submission.Header.AccessionNumber}
+	dir := filepath.Join(s.Config.Main.CacheDirUnpacked, filing.ArchivePath())
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+
+	for _, document := range submission.Documents {
+		err = ioutil.WriteFile(filepath.Join(dir, document.Name()), document.Content, 0644)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// IndexSubmission indexes a full submission .txt into sec.secItemFile, one
+// row per document. Filings without XBRL, which includes every filing
+// before 2009, have no RSS feed entry and are indexed from these.
+func IndexSubmission(db *sqlx.DB, s *sec.SEC, path string, invalidation *CacheInvalidation) error {
+	submission, err := sgml.ParseFile(path)
+	if err != nil {
+		secevent.CreateIndexEvent(db, path, "failed", "could_not_parse_submission")
+		return err
+	}
+
+	// Headers of some older submissions lack the accession number, the
+	// file is named after it
+	if submission.Header.AccessionNumber == "" {
+		name := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+		if secfiling.IsAccessionNumber(name) {
+			submission.Header.AccessionNumber = name
+		}
+	}
+
+	item, err := SubmissionItem(submission)
+	if err != nil {
+		secevent.CreateIndexEvent(db, path, "failed", "could_not_parse_submission")
+		return err
+	}
+
+	err = UnpackSubmission(s, submission)
+	if err != nil {
+		secevent.CreateIndexEvent(db, path, "failed", "could_not_unpack_submission")
+		return err
+	}
+
+	currentCount := 0
+	return SecItemFileUpsert(db, s, item, map[string]sec.Entry{}, &currentCount, len(submission.Documents), invalidation)
+}
+
+// IndexSubmissions indexes the full submissions among paths, walking
+// directories. Submissions that can't be indexed are logged and skipped.
+func IndexSubmissions(db *sqlx.DB, s *sec.SEC, paths []string) error {
+	var files []string
+	for _, path := range paths {
+		err := filepath.Walk(path, func(filePath string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			name := strings.TrimSuffix(info.Name(), ".txt")
+			if filePath == path || (strings.HasSuffix(info.Name(), ".txt") && secfiling.IsAccessionNumber(name)) {
+				files = append(files, filePath)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	invalidation, err := NewCacheInvalidation(db)
+	if err != nil {
+		return err
+	}
+	defer invalidation.Flush(db, s)
+
+	for i, file := range files {
+		err = IndexSubmission(db, s, file, invalidation)
+		if err != nil {
+			s.Log(fmt.Sprintf("Could not index %v: %v", file, err))
+			continue
+		}
+		s.Log(fmt.Sprintf("[%d/%d] Indexed %v", i+1, len(files), file))
+	}
+
+	return nil
+}
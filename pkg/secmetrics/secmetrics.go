@@ -0,0 +1,451 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secmetrics
+
+import (
+	"database/sql"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/secevent"
+	"github.com/jmoiron/sqlx"
+)
+
+const (
+	KindAnnual = "annual"
+	KindTTM    = "ttm"
+
+	ddateLayout = "20060102"
+)
+
+// Forms we derive metrics from. Only periodic reports carry a full set of
+// statements that can be rolled up into annual and trailing values.
+var Forms = []string{"10-K", "10-K/A", "10-KT", "10-KT/A", "10-Q", "10-Q/A", "20-F", "20-F/A", "40-F", "40-F/A"}
+
+// Tags used for every metric, in order of preference. Filers use different
+// us-gaap elements for the same concept, so the first tag with a value wins.
+var (
+	RevenueTags           = []string{"Revenues", "RevenueFromContractWithCustomerExcludingAssessedTax", "RevenueFromContractWithCustomerIncludingAssessedTax", "SalesRevenueNet"}
+	GrossProfitTags       = []string{"GrossProfit"}
+	OperatingIncomeTags   = []string{"OperatingIncomeLoss"}
+	NetIncomeTags         = []string{"NetIncomeLoss", "ProfitLoss", "NetIncomeLossAvailableToCommonStockholdersBasic"}
+	EPSDilutedTags        = []string{"EarningsPerShareDiluted", "EarningsPerShareBasicAndDiluted"}
+	AssetsTags            = []string{"Assets"}
+	LiabilitiesTags       = []string{"Liabilities"}
+	EquityTags            = []string{"StockholdersEquity", "StockholdersEquityIncludingPortionAttributableToNoncontrollingInterest"}
+	CurrentAssetsTags     = []string{"AssetsCurrent"}
+	CurrentLiabilityTags  = []string{"LiabilitiesCurrent"}
+	LongTermDebtTags      = []string{"LongTermDebt", "LongTermDebtNoncurrent"}
+	SharesOutstandingTags = []string{"CommonStockSharesOutstanding", "EntityCommonStockSharesOutstanding"}
+)
+
+type Metric struct {
+	CIK                int             `db:"cik"`
+	Adsh               string          `db:"adsh"`
+	DDate              string          `db:"ddate"`
+	Fy                 string          `db:"fy"`
+	Fp                 string          `db:"fp"`
+	Form               string          `db:"form"`
+	Kind               string          `db:"kind"`
+	Revenues           sql.NullFloat64 `db:"revenues"`
+	GrossProfit        sql.NullFloat64 `db:"gross_profit"`
+	OperatingIncome    sql.NullFloat64 `db:"operating_income"`
+	NetIncome          sql.NullFloat64 `db:"net_income"`
+	EPSDiluted         sql.NullFloat64 `db:"eps_diluted"`
+	Assets             sql.NullFloat64 `db:"assets"`
+	Liabilities        sql.NullFloat64 `db:"liabilities"`
+	Equity             sql.NullFloat64 `db:"equity"`
+	CurrentAssets      sql.NullFloat64 `db:"current_assets"`
+	CurrentLiabilities sql.NullFloat64 `db:"current_liabilities"`
+	LongTermDebt       sql.NullFloat64 `db:"long_term_debt"`
+	SharesOutstanding  sql.NullFloat64 `db:"shares_outstanding"`
+	GrossMargin        sql.NullFloat64 `db:"gross_margin"`
+	OperatingMargin    sql.NullFloat64 `db:"operating_margin"`
+	NetMargin          sql.NullFloat64 `db:"net_margin"`
+	ROE                sql.NullFloat64 `db:"roe"`
+	CurrentRatio       sql.NullFloat64 `db:"current_ratio"`
+	DebtToEquity       sql.NullFloat64 `db:"debt_to_equity"`
+	SubUpdatedAt       time.Time       `db:"sub_updated_at"`
+}
+
+// Submission is one fsds.sub row a set of metrics is derived for.
+type Submission struct {
+	Adsh      string       `db:"adsh"`
+	CIK       int          `db:"cik"`
+	Form      string       `db:"form"`
+	Period    sql.NullTime `db:"period"`
+	Fy        string       `db:"fy"`
+	Fp        string       `db:"fp"`
+	Prevrpt   string       `db:"prevrpt"`
+	UpdatedAt time.Time    `db:"updated_at"`
+}
+
+// Fact is one fsds.num value joined with the submission it was reported in.
+type Fact struct {
+	Tag     string       `db:"tag"`
+	DDate   string       `db:"ddate"`
+	Qtrs    string       `db:"qtrs"`
+	Value   string       `db:"value"`
+	Prevrpt string       `db:"prevrpt"`
+	Filled  sql.NullTime `db:"filled"`
+}
+
+type factKey struct {
+	Tag   string
+	DDate string
+	Qtrs  int
+}
+
+type factValue struct {
+	Value   float64
+	Amended bool
+	Filled  time.Time
+}
+
+// Facts holds the values of a single CIK after restatements are resolved:
+// for every (tag, ddate, qtrs) only the latest reported value is kept.
+type Facts struct {
+	values map[factKey]factValue
+	dates  map[string][]time.Time
+}
+
+func NewFacts() *Facts {
+	return &Facts{
+		values: make(map[factKey]factValue),
+		dates:  make(map[string][]time.Time),
+	}
+}
+
+// Add records a value. A value from a submission that was later amended
+// (prevrpt = 1) never replaces one from a current submission, and among
+// current submissions the most recently filed one wins, so restated
+// comparatives in newer reports override the originally reported numbers.
+func (f *Facts) Add(tag string, ddate string, qtrs int, value float64, amended bool, filled time.Time) {
+	key := factKey{Tag: tag, DDate: ddate, Qtrs: qtrs}
+
+	current, ok := f.values[key]
+	if ok {
+		if amended && !current.Amended {
+			return
+		}
+		if amended == current.Amended && !filled.After(current.Filled) {
+			return
+		}
+	} else {
+		date, err := time.Parse(ddateLayout, ddate)
+		if err == nil {
+			f.dates[tag] = append(f.dates[tag], date)
+		}
+	}
+
+	f.values[key] = factValue{Value: value, Amended: amended, Filled: filled}
+}
+
+// Get returns the value of tag for the period of qtrs quarters ending
+// around date. Fiscal periods of 52/53-week filers don't end on the same
+// day every year, so any ddate within a week of date matches.
+func (f *Facts) Get(tag string, date time.Time, qtrs int) (float64, bool) {
+	for _, d := range f.dates[tag] {
+		diff := d.Sub(date)
+		if diff < -7*24*time.Hour || diff > 7*24*time.Hour {
+			continue
+		}
+		v, ok := f.values[factKey{Tag: tag, DDate: d.Format(ddateLayout), Qtrs: qtrs}]
+		if ok {
+			return v.Value, true
+		}
+	}
+	return 0, false
+}
+
+// Annual returns the value for the fiscal year ending at date.
+func (f *Facts) Annual(tags []string, date time.Time) sql.NullFloat64 {
+	for _, tag := range tags {
+		v, ok := f.Get(tag, date, 4)
+		if ok {
+			return sql.NullFloat64{Float64: v, Valid: true}
+		}
+	}
+	return sql.NullFloat64{}
+}
+
+// TTM returns the trailing-twelve-month value for the period ending at date.
+// A 4-quarter value is used as is. Otherwise the year-to-date value is rolled
+// forward with the prior fiscal year: YTD + prior annual - prior YTD. As a
+// last resort the four most recent single quarters are summed.
+func (f *Facts) TTM(tags []string, date time.Time) sql.NullFloat64 {
+	for _, tag := range tags {
+		v, ok := f.ttm(tag, date)
+		if ok {
+			return sql.NullFloat64{Float64: v, Valid: true}
+		}
+	}
+	return sql.NullFloat64{}
+}
+
+func (f *Facts) ttm(tag string, date time.Time) (float64, bool) {
+	v, ok := f.Get(tag, date, 4)
+	if ok {
+		return v, true
+	}
+
+	for qtrs := 3; qtrs >= 1; qtrs-- {
+		ytd, ok := f.Get(tag, date, qtrs)
+		if !ok {
+			continue
+		}
+		priorYTD, ok := f.Get(tag, addQuarters(date, -4), qtrs)
+		if !ok {
+			continue
+		}
+		priorAnnual, ok := f.Get(tag, addQuarters(date, -qtrs), 4)
+		if !ok {
+			continue
+		}
+		return ytd + priorAnnual - priorYTD, true
+	}
+
+	sum := 0.0
+	for i := 0; i < 4; i++ {
+		q, ok := f.Get(tag, addQuarters(date, -i), 1)
+		if !ok {
+			return 0, false
+		}
+		sum += q
+	}
+	return sum, true
+}
+
+// Instant returns a point-in-time (qtrs = 0) value at date.
+func (f *Facts) Instant(tags []string, date time.Time) sql.NullFloat64 {
+	for _, tag := range tags {
+		v, ok := f.Get(tag, date, 0)
+		if ok {
+			return sql.NullFloat64{Float64: v, Valid: true}
+		}
+	}
+	return sql.NullFloat64{}
+}
+
+// addQuarters moves a period end date by n quarters, keeping month-end
+// dates on the month end (e.g. 20210630 - 1 quarter = 20210331).
+func addQuarters(date time.Time, n int) time.Time {
+	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	lastOfMonth := firstOfMonth.AddDate(0, 1, -1)
+	if date.Day() == lastOfMonth.Day() {
+		return firstOfMonth.AddDate(0, 3*n+1, -1)
+	}
+	return date.AddDate(0, 3*n, 0)
+}
+
+func ratio(a sql.NullFloat64, b sql.NullFloat64) sql.NullFloat64 {
+	if !a.Valid || !b.Valid || b.Float64 == 0 {
+		return sql.NullFloat64{}
+	}
+	return sql.NullFloat64{Float64: a.Float64 / b.Float64, Valid: true}
+}
+
+// Compute derives the metrics of one submission from the CIK's facts. Flow
+// values (income statement) are annual or TTM depending on kind, balance
+// sheet values are taken at the period end.
+func Compute(facts *Facts, sub Submission, kind string) Metric {
+	date := sub.Period.Time
+
+	flow := facts.TTM
+	if kind == KindAnnual {
+		flow = facts.Annual
+	}
+
+	m := Metric{
+		CIK:                sub.CIK,
+		Adsh:               sub.Adsh,
+		DDate:              date.Format(ddateLayout),
+		Fy:                 sub.Fy,
+		Fp:                 sub.Fp,
+		Form:               sub.Form,
+		Kind:               kind,
+		Revenues:           flow(RevenueTags, date),
+		GrossProfit:        flow(GrossProfitTags, date),
+		OperatingIncome:    flow(OperatingIncomeTags, date),
+		NetIncome:          flow(NetIncomeTags, date),
+		EPSDiluted:         flow(EPSDilutedTags, date),
+		Assets:             facts.Instant(AssetsTags, date),
+		Liabilities:        facts.Instant(LiabilitiesTags, date),
+		Equity:             facts.Instant(EquityTags, date),
+		CurrentAssets:      facts.Instant(CurrentAssetsTags, date),
+		CurrentLiabilities: facts.Instant(CurrentLiabilityTags, date),
+		LongTermDebt:       facts.Instant(LongTermDebtTags, date),
+		SharesOutstanding:  facts.Instant(SharesOutstandingTags, date),
+		SubUpdatedAt:       sub.UpdatedAt,
+	}
+
+	m.GrossMargin = ratio(m.GrossProfit, m.Revenues)
+	m.OperatingMargin = ratio(m.OperatingIncome, m.Revenues)
+	m.NetMargin = ratio(m.NetIncome, m.Revenues)
+	m.ROE = ratio(m.NetIncome, m.Equity)
+	m.CurrentRatio = ratio(m.CurrentAssets, m.CurrentLiabilities)
+
+	debt := m.LongTermDebt
+	if !debt.Valid {
+		debt = m.Liabilities
+	}
+	m.DebtToEquity = ratio(debt, m.Equity)
+
+	return m
+}
+
+func allTags() []string {
+	var tags []string
+	for _, list := range [][]string{RevenueTags, GrossProfitTags, OperatingIncomeTags, NetIncomeTags, EPSDilutedTags, AssetsTags, LiabilitiesTags, EquityTags, CurrentAssetsTags, CurrentLiabilityTags, LongTermDebtTags, SharesOutstandingTags} {
+		tags = append(tags, list...)
+	}
+	return tags
+}
+
+// GetCIKsToDerive returns the CIKs with periodic submissions newer than the
+// metrics already derived for them. With full set, every such CIK is returned.
+func GetCIKsToDerive(db *sqlx.DB, full bool) ([]int, error) {
+	var ciks []int
+
+	query := `
+		SELECT DISTINCT sub.cik
+		FROM fsds.sub
+		LEFT JOIN (
+			SELECT cik, MAX(sub_updated_at) AS sub_updated_at FROM fsds.metrics GROUP BY cik
+		) derived ON derived.cik = sub.cik
+		WHERE sub.form IN (?)
+		AND (derived.sub_updated_at IS NULL OR sub.updated_at > derived.sub_updated_at)
+		ORDER BY sub.cik;`
+	if full {
+		query = `SELECT DISTINCT cik FROM fsds.sub WHERE form IN (?) ORDER BY cik;`
+	}
+
+	// IN (?) is expanded by sqlx.In, unlike ANY($1) it also runs on SQLite
+	query, args, err := sqlx.In(query, Forms)
+	if err != nil {
+		return nil, err
+	}
+	err = db.Select(&ciks, db.Rebind(query), args...)
+	return ciks, err
+}
+
+func GetFacts(db *sqlx.DB, cik int) (*Facts, error) {
+	query, args, err := sqlx.In(`
+		SELECT num.tag, num.ddate, num.qtrs, num.value, sub.prevrpt, sub.filled
+		FROM fsds.num
+		JOIN fsds.sub ON sub.adsh = num.adsh
+		WHERE sub.cik = ?
+		AND sub.form IN (?)
+		AND num.tag IN (?)
+		AND num.coreg = ''
+		AND num.value <> ''
+		AND num.qtrs IN ('0', '1', '2', '3', '4');`, cik, Forms, allTags())
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []Fact
+	err = db.Select(&rows, db.Rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+
+	facts := NewFacts()
+	for _, row := range rows {
+		value, err := strconv.ParseFloat(row.Value, 64)
+		if err != nil {
+			continue
+		}
+		qtrs, err := strconv.Atoi(row.Qtrs)
+		if err != nil {
+			continue
+		}
+		facts.Add(row.Tag, row.DDate, qtrs, value, row.Prevrpt == "1", row.Filled.Time)
+	}
+	return facts, nil
+}
+
+func GetSubmissions(db *sqlx.DB, cik int) ([]Submission, error) {
+	query, args, err := sqlx.In(`
+		SELECT adsh, cik, form, period, fy, fp, prevrpt, updated_at
+		FROM fsds.sub
+		WHERE cik = ?
+		AND form IN (?)
+		AND period IS NOT NULL
+		ORDER BY period, filled;`, cik, Forms)
+	if err != nil {
+		return nil, err
+	}
+
+	var subs []Submission
+	err = db.Select(&subs, db.Rebind(query), args...)
+	return subs, err
+}
+
+func MetricUpsert(db *sqlx.DB, m Metric) error {
+	_, err := db.NamedExec(`
+		INSERT INTO fsds.metrics (cik, adsh, ddate, fy, fp, form, kind, revenues, gross_profit, operating_income, net_income, eps_diluted, assets, liabilities, equity, current_assets, current_liabilities, long_term_debt, shares_outstanding, gross_margin, operating_margin, net_margin, roe, current_ratio, debt_to_equity, sub_updated_at, created_at, updated_at)
+		VALUES (:cik, :adsh, :ddate, :fy, :fp, :form, :kind, :revenues, :gross_profit, :operating_income, :net_income, :eps_diluted, :assets, :liabilities, :equity, :current_assets, :current_liabilities, :long_term_debt, :shares_outstanding, :gross_margin, :operating_margin, :net_margin, :roe, :current_ratio, :debt_to_equity, :sub_updated_at, NOW(), NOW())
+		ON CONFLICT (cik, ddate, kind)
+		DO UPDATE SET adsh = EXCLUDED.adsh, fy = EXCLUDED.fy, fp = EXCLUDED.fp, form = EXCLUDED.form, revenues = EXCLUDED.revenues, gross_profit = EXCLUDED.gross_profit, operating_income = EXCLUDED.operating_income, net_income = EXCLUDED.net_income, eps_diluted = EXCLUDED.eps_diluted, assets = EXCLUDED.assets, liabilities = EXCLUDED.liabilities, equity = EXCLUDED.equity, current_assets = EXCLUDED.current_assets, current_liabilities = EXCLUDED.current_liabilities, long_term_debt = EXCLUDED.long_term_debt, shares_outstanding = EXCLUDED.shares_outstanding, gross_margin = EXCLUDED.gross_margin, operating_margin = EXCLUDED.operating_margin, net_margin = EXCLUDED.net_margin, roe = EXCLUDED.roe, current_ratio = EXCLUDED.current_ratio, debt_to_equity = EXCLUDED.debt_to_equity, sub_updated_at = EXCLUDED.sub_updated_at, updated_at = NOW();`, m)
+	return err
+}
+
+// DeriveCIK recomputes every annual and TTM metric row of one CIK. Subs are
+// ordered by period and filing date, so for a period reported more than once
+// (an amendment, or a 10-K/A) the latest submission owns the row.
+func DeriveCIK(db *sqlx.DB, cik int) error {
+	subs, err := GetSubmissions(db, cik)
+	if err != nil {
+		return err
+	}
+
+	facts, err := GetFacts(db, cik)
+	if err != nil {
+		return err
+	}
+
+	for _, sub := range subs {
+		if sub.Prevrpt == "1" {
+			continue
+		}
+
+		err = MetricUpsert(db, Compute(facts, sub, KindTTM))
+		if err != nil {
+			return err
+		}
+
+		if sub.Fp != "FY" {
+			continue
+		}
+
+		err = MetricUpsert(db, Compute(facts, sub, KindAnnual))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Derive computes the metrics of every CIK with new FSDS submissions, or of
+// every CIK when full is set.
+func Derive(s *sec.SEC, db *sqlx.DB, full bool) error {
+	ciks, err := GetCIKsToDerive(db, full)
+	if err != nil {
+		return err
+	}
+
+	s.Log(fmt.Sprintf("Deriving metrics for %v companies...", len(ciks)))
+
+	for _, cik := range ciks {
+		err = DeriveCIK(db, cik)
+		if err != nil {
+			secevent.CreateOtherEvent(db, "derive", fmt.Sprint(cik), "failed")
+			return err
+		}
+	}
+
+	secevent.CreateOtherEvent(db, "derive", "metrics", "success")
+	return nil
+}
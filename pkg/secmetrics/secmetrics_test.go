@@ -0,0 +1,99 @@
+package secmetrics
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	d, err := time.Parse(ddateLayout, s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestTTM(t *testing.T) {
+	filled := date("20210801")
+
+	facts := NewFacts()
+	facts.Add("Revenues", "20201231", 4, 400, false, filled)
+	facts.Add("Revenues", "20200630", 2, 180, false, filled)
+	facts.Add("Revenues", "20210630", 2, 220, false, filled)
+
+	ttm := facts.TTM(RevenueTags, date("20210630"))
+	if !ttm.Valid || ttm.Float64 != 440 {
+		t.Errorf("YTD roll-forward: got %v, want 440", ttm)
+	}
+
+	facts.Add("NetIncomeLoss", "20210331", 1, 10, false, filled)
+	facts.Add("NetIncomeLoss", "20201231", 1, 20, false, filled)
+	facts.Add("NetIncomeLoss", "20200930", 1, 30, false, filled)
+	facts.Add("NetIncomeLoss", "20200630", 1, 40, false, filled)
+
+	ttm = facts.TTM(NetIncomeTags, date("20210331"))
+	if !ttm.Valid || ttm.Float64 != 100 {
+		t.Errorf("quarter sum: got %v, want 100", ttm)
+	}
+
+	// 52/53-week fiscal years end on slightly different days.
+	annual := facts.Annual(RevenueTags, date("20201226"))
+	if !annual.Valid || annual.Float64 != 400 {
+		t.Errorf("near period end: got %v, want 400", annual)
+	}
+}
+
+func TestRestatement(t *testing.T) {
+	facts := NewFacts()
+	facts.Add("Assets", "20201231", 0, 100, false, date("20210215"))
+	facts.Add("Assets", "20201231", 0, 110, false, date("20220215"))
+	facts.Add("Assets", "20201231", 0, 999, true, date("20230215"))
+
+	assets := facts.Instant(AssetsTags, date("20201231"))
+	if !assets.Valid || assets.Float64 != 110 {
+		t.Errorf("got %v, want restated 110", assets)
+	}
+}
+
+func TestCompute(t *testing.T) {
+	filled := date("20210215")
+
+	facts := NewFacts()
+	facts.Add("Revenues", "20201231", 4, 1000, false, filled)
+	facts.Add("GrossProfit", "20201231", 4, 400, false, filled)
+	facts.Add("NetIncomeLoss", "20201231", 4, 100, false, filled)
+	facts.Add("StockholdersEquity", "20201231", 0, 500, false, filled)
+	facts.Add("AssetsCurrent", "20201231", 0, 300, false, filled)
+	facts.Add("LiabilitiesCurrent", "20201231", 0, 150, false, filled)
+	facts.Add("Liabilities", "20201231", 0, 250, false, filled)
+
+	sub := Submission{CIK: 1, Adsh: "0000000001-21-000001", Fp: "FY"}
+	sub.Period.Time = date("20201231")
+	sub.Period.Valid = true
+
+	m := Compute(facts, sub, KindAnnual)
+
+	checks := map[string]float64{
+		"gross_margin":   m.GrossMargin.Float64,
+		"net_margin":     m.NetMargin.Float64,
+		"roe":            m.ROE.Float64,
+		"current_ratio":  m.CurrentRatio.Float64,
+		"debt_to_equity": m.DebtToEquity.Float64,
+	}
+	want := map[string]float64{
+		"gross_margin":   0.4,
+		"net_margin":     0.1,
+		"roe":            0.2,
+		"current_ratio":  2,
+		"debt_to_equity": 0.5,
+	}
+	for k, v := range want {
+		if checks[k] != v {
+			t.Errorf("%v: got %v, want %v", k, checks[k], v)
+		}
+	}
+
+	if m.OperatingMargin.Valid {
+		t.Errorf("operating_margin: got %v, want NULL", m.OperatingMargin)
+	}
+}
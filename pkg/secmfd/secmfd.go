@@ -0,0 +1,246 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secmfd
+
+import (
+	"database/sql"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"jaytaylor.com/html2text"
+)
+
+const PageSize = 100
+
+// Fee levels the fund list can be filtered by, on the net expense ratio.
+var FeeLevels = map[string][2]float64{
+	"low":    {0, 0.005},
+	"medium": {0.005, 0.01},
+	"high":   {0.01, 1000},
+}
+
+// Text blocks of mfd.txt shown on the fund page, in display order.
+var NarrativeTags = []string{"ObjectivePrimaryTextBlock", "StrategyNarrativeTextBlock", "RiskNarrativeTextBlock", "BarChartAndPerformanceTableHeading", "PerformanceNarrativeTextBlock"}
+
+type Fund struct {
+	Series             string          `db:"series"`
+	Class              string          `db:"class"`
+	Adsh               string          `db:"adsh"`
+	CIK                int             `db:"cik"`
+	Name               string          `db:"name"`
+	Filed              string          `db:"filed"`
+	NetExpenses        sql.NullFloat64 `db:"net_expenses"`
+	Expenses           sql.NullFloat64 `db:"expenses"`
+	ManagementFees     sql.NullFloat64 `db:"management_fees"`
+	DistributionFees   sql.NullFloat64 `db:"distribution_fees"`
+	MaximumSalesCharge sql.NullFloat64 `db:"maximum_sales_charge"`
+	ReturnYear01       sql.NullFloat64 `db:"return_year01"`
+	ReturnYear05       sql.NullFloat64 `db:"return_year05"`
+	ReturnYear10       sql.NullFloat64 `db:"return_year10"`
+}
+
+// ExpenseRatio is the net expense ratio, or the gross one for funds that
+// have no fee waiver and only report the latter.
+func (f Fund) ExpenseRatio() sql.NullFloat64 {
+	if f.NetExpenses.Valid {
+		return f.NetExpenses
+	}
+	return f.Expenses
+}
+
+type Fact struct {
+	Tag     string `db:"tag"`
+	Label   string `db:"label"`
+	Measure string `db:"measure"`
+	DDate   string `db:"ddate"`
+	UOM     string `db:"uom"`
+	Value   string `db:"value"`
+}
+
+// Display formats the value for the fund page. Ratios (uom "pure") are
+// shown as percentages, everything else as reported.
+func (f Fact) Display() string {
+	if f.UOM != "pure" {
+		return f.Value
+	}
+	v, err := strconv.ParseFloat(f.Value, 64)
+	if err != nil {
+		return f.Value
+	}
+	return fmt.Sprintf("%.2f%%", v*100)
+}
+
+type Narrative struct {
+	Tag     string `db:"tag"`
+	Label   string `db:"label"`
+	Text    string `db:"value"`
+	Escaped string `db:"escaped"`
+}
+
+// FundDetails holds everything shown on the page of a single share class.
+type FundDetails struct {
+	Fund
+	Fees       []Fact
+	Returns    []Fact
+	Other      []Fact
+	Narratives []Narrative
+}
+
+// fundsQuery selects the latest risk/return summary of every share class.
+// A prospectus covers many series and classes, so facts are keyed by
+// (series, class) and only the most recently filed submission is used.
+const fundsQuery = `
+	WITH latest AS (
+		SELECT series, class, adsh, cik, name, filed
+		FROM (
+			SELECT num.series, num.class, num.adsh, sub.cik, sub.name, sub.filed,
+				ROW_NUMBER() OVER (PARTITION BY num.series, num.class ORDER BY sub.filed DESC) AS row_number
+			FROM mfd.num
+			JOIN mfd.sub ON sub.adsh = num.adsh
+			WHERE num.class <> ''
+			AND num.tag IN ('NetExpensesOverAssets', 'ExpensesOverAssets')
+			%v
+		) submissions
+		WHERE row_number = 1
+	), funds AS (
+		SELECT latest.series, latest.class, latest.adsh, latest.cik, latest.name, latest.filed,
+			MAX(CASE WHEN num.tag = 'NetExpensesOverAssets' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS net_expenses,
+			MAX(CASE WHEN num.tag = 'ExpensesOverAssets' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS expenses,
+			MAX(CASE WHEN num.tag = 'ManagementFeesOverAssets' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS management_fees,
+			MAX(CASE WHEN num.tag = 'DistributionAndService12b1FeesOverAssets' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS distribution_fees,
+			MAX(CASE WHEN num.tag = 'MaximumSalesChargeImposedOnPurchasesOverOfferingPrice' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS maximum_sales_charge,
+			MAX(CASE WHEN num.tag = 'AverageAnnualReturnYear01' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS return_year01,
+			MAX(CASE WHEN num.tag = 'AverageAnnualReturnYear05' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS return_year05,
+			MAX(CASE WHEN num.tag = 'AverageAnnualReturnYear10' THEN CAST(NULLIF(num.value, '') AS numeric) END) AS return_year10
+		FROM latest
+		JOIN mfd.num ON num.adsh = latest.adsh AND num.series = latest.series AND num.class = latest.class
+		WHERE num.measure = '' AND num.otherdims = ''
+		GROUP BY latest.series, latest.class, latest.adsh, latest.cik, latest.name, latest.filed
+	)
+	SELECT * FROM funds
+`
+
+// GetFunds returns one page of share classes ordered by expense ratio,
+// optionally restricted to one of FeeLevels.
+func GetFunds(db *sqlx.DB, fee string, page int) ([]Fund, error) {
+	if page < 1 {
+		page = 1
+	}
+
+	// The parameters are numbered in the order they appear, SQLite binds
+	// them by position
+	where := ""
+	var args []interface{}
+	if fee != "" {
+		level, ok := FeeLevels[fee]
+		if !ok {
+			return nil, fmt.Errorf("unknown fee level %v, expected low, medium or high", fee)
+		}
+		where = "WHERE COALESCE(net_expenses, expenses) >= $1 AND COALESCE(net_expenses, expenses) < $2"
+		args = append(args, level[0], level[1])
+	}
+	args = append(args, PageSize, (page-1)*PageSize)
+
+	var funds []Fund
+	err := db.Select(&funds, fmt.Sprintf(fundsQuery, "")+where+fmt.Sprintf(`
+	ORDER BY COALESCE(net_expenses, expenses) ASC NULLS LAST, name, series, class
+	LIMIT $%d OFFSET $%d;`, len(args)-1, len(args)), args...)
+	if err != nil {
+		return nil, err
+	}
+	return funds, nil
+}
+
+// GetFund returns the latest summary of one share class.
+func GetFund(db *sqlx.DB, series string, class string) (Fund, error) {
+	var funds []Fund
+	err := db.Select(&funds, fmt.Sprintf(fundsQuery, "AND num.series = $1 AND num.class = $2")+";", series, class)
+	if err != nil {
+		return Fund{}, err
+	}
+	if len(funds) == 0 {
+		return Fund{}, fmt.Errorf("could not find fund series %v class %v", series, class)
+	}
+	return funds[0], nil
+}
+
+// GetFundDetails returns the summary, all numeric facts and the narrative
+// text blocks of one share class from its latest submission.
+func GetFundDetails(db *sqlx.DB, series string, class string) (FundDetails, error) {
+	fund, err := GetFund(db, series, class)
+	if err != nil {
+		return FundDetails{}, err
+	}
+
+	var facts []Fact
+	err = db.Select(&facts, `
+		SELECT num.tag, COALESCE(NULLIF(lab.std, ''), tag.tlabel, num.tag) AS label, num.measure, num.ddate, num.uom, num.value
+		FROM mfd.num
+		LEFT JOIN mfd.lab ON lab.adsh = num.adsh AND lab.tag = num.tag AND lab.version = num.version
+		LEFT JOIN mfd.tag ON tag.tag = num.tag AND tag.version = num.version
+		WHERE num.adsh = $1 AND num.series = $2 AND num.class = $3 AND num.otherdims = ''
+		ORDER BY num.tag, num.measure, num.ddate;`, fund.Adsh, series, class)
+	if err != nil {
+		return FundDetails{}, err
+	}
+
+	// Narratives are usually tagged per series, shared by all of its classes.
+	// IN (?) is expanded by sqlx.In, unlike ANY($1) it also runs on SQLite
+	query, args, err := sqlx.In(`
+		SELECT txt.tag, COALESCE(NULLIF(lab.std, ''), tag.tlabel, txt.tag) AS label, txt.value, txt.escaped
+		FROM mfd.txt
+		LEFT JOIN mfd.lab ON lab.adsh = txt.adsh AND lab.tag = txt.tag AND lab.version = txt.version
+		LEFT JOIN mfd.tag ON tag.tag = txt.tag AND tag.version = txt.version
+		WHERE txt.adsh = ? AND txt.series = ? AND txt.class IN (?, '') AND txt.tag IN (?) AND txt.otherdims = ''
+		ORDER BY txt.class DESC;`, fund.Adsh, series, class, NarrativeTags)
+	if err != nil {
+		return FundDetails{}, err
+	}
+	var narratives []Narrative
+	err = db.Select(&narratives, db.Rebind(query), args...)
+	if err != nil {
+		return FundDetails{}, err
+	}
+
+	// In the order of NarrativeTags, the class' own text first
+	position := make(map[string]int)
+	for i, tag := range NarrativeTags {
+		position[tag] = i
+	}
+	sort.SliceStable(narratives, func(i, j int) bool {
+		return position[narratives[i].Tag] < position[narratives[j].Tag]
+	})
+
+	details := FundDetails{Fund: fund}
+	for _, fact := range facts {
+		switch {
+		case strings.HasSuffix(fact.Tag, "OverAssets") || strings.HasPrefix(fact.Tag, "ExpenseExample") || strings.Contains(fact.Tag, "SalesCharge") || strings.Contains(fact.Tag, "Fee"):
+			details.Fees = append(details.Fees, fact)
+		case strings.Contains(fact.Tag, "Return"):
+			details.Returns = append(details.Returns, fact)
+		default:
+			details.Other = append(details.Other, fact)
+		}
+	}
+
+	seen := make(map[string]bool)
+	for _, narrative := range narratives {
+		if seen[narrative.Tag] {
+			continue
+		}
+		seen[narrative.Tag] = true
+
+		// Text blocks with escaped = 1 hold the HTML of the prospectus
+		if narrative.Escaped == "1" {
+			text, err := html2text.FromString(narrative.Text)
+			if err == nil {
+				narrative.Text = text
+			}
+		}
+		details.Narratives = append(details.Narratives, narrative)
+	}
+
+	return details, nil
+}
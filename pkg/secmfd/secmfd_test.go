@@ -0,0 +1,229 @@
+package secmfd
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secdata"
+	"github.com/equres/sec/pkg/sectest"
+	"github.com/jmoiron/sqlx"
+)
+
+// mfdFiles are the tables of a risk/return summary data set. Series
+// S000001 has three share classes: C000001 with a fee waiver, C000002 with
+// only a gross expense ratio and C000003 with high fees. C000001 was also
+// in an older prospectus, with other fees.
+var mfdFiles = []struct {
+	name string
+	data string
+}{
+	{"sub.txt", "adsh\tcik\tname\tform\tfiled\taccepted\n" +
+		"0000000001-20-000001\t1\tFUND TRUST\t485BPOS\t20200301\t2020-03-01 12:00:00.0\n" +
+		"0000000001-21-000001\t1\tFUND TRUST\t485BPOS\t20210301\t2021-03-01 12:00:00.0\n"},
+	{"tag.txt", "tag\tversion\tcustom\tabstract\tdatatype\tlord\ttlabel\tdoc\n" +
+		"NetExpensesOverAssets\trr/2020\t0\t0\tpure\t\tNet Expenses over Assets\t\n" +
+		"ExpensesOverAssets\trr/2020\t0\t0\tpure\t\tExpenses over Assets\t\n" +
+		"ManagementFeesOverAssets\trr/2020\t0\t0\tpure\t\tManagement Fees over Assets\t\n" +
+		"AverageAnnualReturnYear01\trr/2020\t0\t0\tpure\t\tAverage Annual Return, 1 Year\t\n" +
+		"RiskNarrativeTextBlock\trr/2020\t0\t0\ttextBlock\t\tRisk Narrative\t\n"},
+	{"lab.txt", "adsh\ttag\tversion\tstd\tterse\n" +
+		"0000000001-21-000001\tManagementFeesOverAssets\trr/2020\tManagement fees\t\n"},
+	{"num.txt", "adsh\ttag\tversion\tddate\tuom\tseries\tclass\tmeasure\tdocument\totherdims\tiprx\tvalue\n" +
+		"0000000001-20-000001\tNetExpensesOverAssets\trr/2020\t20200301\tpure\tS000001\tC000001\t\t\t\t0\t0.02\n" +
+		"0000000001-21-000001\tNetExpensesOverAssets\trr/2020\t20210301\tpure\tS000001\tC000001\t\t\t\t0\t0.003\n" +
+		"0000000001-21-000001\tExpensesOverAssets\trr/2020\t20210301\tpure\tS000001\tC000001\t\t\t\t0\t0.004\n" +
+		"0000000001-21-000001\tManagementFeesOverAssets\trr/2020\t20210301\tpure\tS000001\tC000001\t\t\t\t0\t0.0025\n" +
+		"0000000001-21-000001\tAverageAnnualReturnYear01\trr/2020\t20201231\tpure\tS000001\tC000001\t\t\t\t0\t0.12\n" +
+		"0000000001-21-000001\tExpensesOverAssets\trr/2020\t20210301\tpure\tS000001\tC000002\t\t\t\t0\t0.005\n" +
+		"0000000001-21-000001\tNetExpensesOverAssets\trr/2020\t20210301\tpure\tS000001\tC000003\t\t\t\t0\t0.012\n" +
+		"0000000001-21-000001\tUnknownTag\trr/2020\t20210301\tpure\tS000001\tC000003\t\t\t\t0\t1\n"},
+	{"txt.txt", "adsh\ttag\tversion\tddate\tlang\tseries\tclass\tmeasure\tdocument\totherdims\tiprx\tescaped\tvalue\n" +
+		"0000000001-21-000001\tRiskNarrativeTextBlock\trr/2020\t20210301\ten-US\tS000001\t\t\t\t\t0\t1\t<p>You could <b>lose money</b>.</p>\n"},
+}
+
+func loadFunds(t *testing.T, db *sqlx.DB) {
+	_, err := db.Exec("INSERT INTO sec.ciks (cik) VALUES (1);")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ops := secdata.NewSECData(secdata.NewSECDataOpsMFD()).SECDataOps
+	for _, file := range mfdFiles {
+		upsert := ops.GetDataTypeInsertFunc(file.name)
+		err = upsert(nil, db, ioutil.NopCloser(strings.NewReader(file.data)))
+		if err != nil {
+			t.Fatalf("upserting %v: %v", file.name, err)
+		}
+	}
+}
+
+func migratedDB(t *testing.T, dbConfig config.DatabaseConfig) *sqlx.DB {
+	cfg := config.Config{Database: dbConfig}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestFeeLevels(t *testing.T) {
+	tests := []struct {
+		ratio float64
+		want  string
+	}{
+		{0, "low"},
+		{0.0049, "low"},
+		{0.005, "medium"},
+		{0.0099, "medium"},
+		{0.01, "high"},
+		{0.05, "high"},
+	}
+
+	for _, tt := range tests {
+		var levels []string
+		for name, level := range FeeLevels {
+			if tt.ratio >= level[0] && tt.ratio < level[1] {
+				levels = append(levels, name)
+			}
+		}
+		if len(levels) != 1 || levels[0] != tt.want {
+			t.Errorf("expense ratio %v is in fee levels %v, want only %v", tt.ratio, levels, tt.want)
+		}
+	}
+}
+
+func TestExpenseRatio(t *testing.T) {
+	tests := []struct {
+		fund Fund
+		want sql.NullFloat64
+	}{
+		{Fund{NetExpenses: sql.NullFloat64{Float64: 0.003, Valid: true}, Expenses: sql.NullFloat64{Float64: 0.004, Valid: true}}, sql.NullFloat64{Float64: 0.003, Valid: true}},
+		{Fund{Expenses: sql.NullFloat64{Float64: 0.004, Valid: true}}, sql.NullFloat64{Float64: 0.004, Valid: true}},
+		{Fund{}, sql.NullFloat64{}},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fund.ExpenseRatio(); got != tt.want {
+			t.Errorf("ExpenseRatio() of %+v = %v, want %v", tt.fund, got, tt.want)
+		}
+	}
+}
+
+func TestFactDisplay(t *testing.T) {
+	tests := []struct {
+		fact Fact
+		want string
+	}{
+		{Fact{UOM: "pure", Value: "0.0123"}, "1.23%"},
+		{Fact{UOM: "pure", Value: "n/a"}, "n/a"},
+		{Fact{UOM: "USD", Value: "1000"}, "1000"},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fact.Display(); got != tt.want {
+			t.Errorf("Display() of %+v = %q, want %q", tt.fact, got, tt.want)
+		}
+	}
+}
+
+func TestParseTables(t *testing.T) {
+	db := migratedDB(t, sectest.SQLite(t))
+	loadFunds(t, db)
+
+	// num rows of tags missing from mfd.tag are skipped
+	counts := map[string]int{"mfd.sub": 2, "mfd.tag": 5, "mfd.lab": 1, "mfd.num": 7, "mfd.txt": 1}
+	for table, want := range counts {
+		var count int
+		err := db.Get(&count, "SELECT COUNT(*) FROM "+table)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if count != want {
+			t.Errorf("%v has %v rows, want %v", table, count, want)
+		}
+	}
+
+	var value string
+	err := db.Get(&value, "SELECT value FROM mfd.num WHERE class = 'C000002' AND tag = 'ExpensesOverAssets'")
+	if err != nil || value != "0.005" {
+		t.Errorf("ExpensesOverAssets of C000002 = %q, %v, want 0.005", value, err)
+	}
+
+	var narrative Narrative
+	err = db.Get(&narrative, "SELECT tag, value, escaped FROM mfd.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if narrative.Text != "<p>You could <b>lose money</b>.</p>" || narrative.Escaped != "1" {
+		t.Errorf("mfd.txt = %+v, want the escaped HTML of the narrative", narrative)
+	}
+}
+
+func TestGetFunds(t *testing.T) {
+	sectest.ForEachDatabase(t, testGetFunds)
+}
+
+func testGetFunds(t *testing.T, dbConfig config.DatabaseConfig) {
+	db := migratedDB(t, dbConfig)
+	loadFunds(t, db)
+
+	tests := []struct {
+		fee     string
+		classes []string
+	}{
+		{"", []string{"C000001", "C000002", "C000003"}},
+		{"low", []string{"C000001"}},
+		{"medium", []string{"C000002"}},
+		{"high", []string{"C000003"}},
+	}
+
+	for _, tt := range tests {
+		funds, err := GetFunds(db, tt.fee, 1)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var classes []string
+		for _, fund := range funds {
+			classes = append(classes, fund.Class)
+		}
+		if strings.Join(classes, ",") != strings.Join(tt.classes, ",") {
+			t.Errorf("GetFunds(%q) = %v, want %v", tt.fee, classes, tt.classes)
+		}
+	}
+
+	_, err := GetFunds(db, "free", 1)
+	if err == nil {
+		t.Error("expected an error for an unknown fee level")
+	}
+
+	details, err := GetFundDetails(db, "S000001", "C000001")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if details.Adsh != "0000000001-21-000001" || details.NetExpenses.Float64 != 0.003 || details.ManagementFees.Float64 != 0.0025 {
+		t.Errorf("GetFundDetails = %+v, want the fees of the latest prospectus", details.Fund)
+	}
+	if len(details.Fees) != 3 || len(details.Returns) != 1 || len(details.Other) != 0 {
+		t.Errorf("GetFundDetails has %v fees, %v returns and %v other facts, want 3, 1 and 0", len(details.Fees), len(details.Returns), len(details.Other))
+	}
+	for _, fee := range details.Fees {
+		if fee.Tag == "ManagementFeesOverAssets" && fee.Label != "Management fees" {
+			t.Errorf("label of %v = %q, want the one of mfd.lab", fee.Tag, fee.Label)
+		}
+	}
+	if len(details.Narratives) != 1 || strings.Contains(details.Narratives[0].Text, "<p>") || !strings.Contains(details.Narratives[0].Text, "lose money") {
+		t.Errorf("GetFundDetails narratives = %+v, want the series' risk narrative as text", details.Narratives)
+	}
+}
@@ -0,0 +1,640 @@
+package secscreener
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/jmoiron/sqlx"
+)
+
+const DefaultLimit = 500
+
+// Every distinct tag in a filter adds a subquery over fsds.num, so
+// filters are capped in length and in the number of tags they use
+const (
+	MaxFilterLength = 500
+	MaxTags         = 10
+)
+
+// Timeout is how long a screen of the public /screener page may run
+const Timeout = 10 * time.Second
+
+// Columns of fsds.sub that can be used in a filter next to XBRL tags
+var SubColumns = map[string]bool{
+	"cik":        true,
+	"name":       true,
+	"sic":        true,
+	"countryba":  true,
+	"stprba":     true,
+	"cityba":     true,
+	"zipba":      true,
+	"countryinc": true,
+	"stprinc":    true,
+	"form":       true,
+	"fy":         true,
+	"fp":         true,
+}
+
+// NumericSubColumns are the text columns of fsds.sub holding numbers, which
+// are compared as numbers with numbers, e.g. "fy >= 2020"
+var NumericSubColumns = map[string]bool{
+	"fy":  true,
+	"sic": true,
+}
+
+type Result struct {
+	CIK    int
+	Name   string
+	StprBA string
+	SIC    string
+	Period string
+	Values []sql.NullFloat64
+}
+
+type Query struct {
+	Filter string
+	Tags   []string
+	SQL    string
+	Args   []interface{}
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenOp
+	tokenLParen
+	tokenRParen
+	tokenAnd
+	tokenOr
+	tokenNot
+)
+
+type token struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+func tokenize(filter string) ([]token, error) {
+	var tokens []token
+
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		r := runes[i]
+
+		switch {
+		case unicode.IsSpace(r):
+			i++
+		case unicode.IsLetter(r) || r == '_':
+			start := i
+			for i < len(runes) && (unicode.IsLetter(runes[i]) || unicode.IsDigit(runes[i]) || runes[i] == '_') {
+				i++
+			}
+			word := string(runes[start:i])
+			switch strings.ToUpper(word) {
+			case "AND":
+				tokens = append(tokens, token{kind: tokenAnd, text: word, pos: start})
+			case "OR":
+				tokens = append(tokens, token{kind: tokenOr, text: word, pos: start})
+			case "NOT":
+				tokens = append(tokens, token{kind: tokenNot, text: word, pos: start})
+			default:
+				tokens = append(tokens, token{kind: tokenIdent, text: word, pos: start})
+			}
+		case unicode.IsDigit(r) || (r == '.' && i+1 < len(runes) && unicode.IsDigit(runes[i+1])):
+			start := i
+			for i < len(runes) && (unicode.IsDigit(runes[i]) || runes[i] == '.') {
+				i++
+			}
+			if i < len(runes) && (runes[i] == 'e' || runes[i] == 'E') {
+				i++
+				if i < len(runes) && (runes[i] == '+' || runes[i] == '-') {
+					i++
+				}
+				for i < len(runes) && unicode.IsDigit(runes[i]) {
+					i++
+				}
+			}
+			text := string(runes[start:i])
+			value, err := strconv.ParseFloat(text, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", text, start)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, text: text, value: value, pos: start})
+		case r == '\'':
+			start := i
+			i++
+			var sb strings.Builder
+			closed := false
+			for i < len(runes) {
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						sb.WriteRune('\'')
+						i += 2
+						continue
+					}
+					closed = true
+					i++
+					break
+				}
+				sb.WriteRune(runes[i])
+				i++
+			}
+			if !closed {
+				return nil, fmt.Errorf("unterminated string at position %d", start)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: sb.String(), pos: start})
+		case r == '(':
+			tokens = append(tokens, token{kind: tokenLParen, text: "(", pos: i})
+			i++
+		case r == ')':
+			tokens = append(tokens, token{kind: tokenRParen, text: ")", pos: i})
+			i++
+		case strings.ContainsRune("+-*/", r):
+			tokens = append(tokens, token{kind: tokenOp, text: string(r), pos: i})
+			i++
+		case strings.ContainsRune("<>=!", r):
+			start := i
+			i++
+			if i < len(runes) && (runes[i] == '=' || (r == '<' && runes[i] == '>')) {
+				i++
+			}
+			op := string(runes[start:i])
+			if op == "!" {
+				return nil, fmt.Errorf("unexpected '!' at position %d", start)
+			}
+			if op == "==" {
+				op = "="
+			}
+			if op == "<>" {
+				op = "!="
+			}
+			tokens = append(tokens, token{kind: tokenOp, text: op, pos: start})
+		default:
+			return nil, fmt.Errorf("unexpected character %q at position %d", r, i)
+		}
+	}
+
+	tokens = append(tokens, token{kind: tokenEOF, pos: len(runes)})
+	return tokens, nil
+}
+
+type compiler struct {
+	tokens []token
+	pos    int
+	args   []interface{}
+	tags   []string
+	tagIdx map[string]int
+}
+
+func (c *compiler) peek() token {
+	return c.tokens[c.pos]
+}
+
+func (c *compiler) next() token {
+	t := c.tokens[c.pos]
+	if t.kind != tokenEOF {
+		c.pos++
+	}
+	return t
+}
+
+func (c *compiler) param(v interface{}) string {
+	c.args = append(c.args, v)
+	return fmt.Sprintf("$%d", len(c.args))
+}
+
+func (c *compiler) parseOr() (string, error) {
+	left, err := c.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for c.peek().kind == tokenOr {
+		c.next()
+		right, err := c.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%v OR %v)", left, right)
+	}
+	return left, nil
+}
+
+func (c *compiler) parseAnd() (string, error) {
+	left, err := c.parseNot()
+	if err != nil {
+		return "", err
+	}
+	for c.peek().kind == tokenAnd {
+		c.next()
+		right, err := c.parseNot()
+		if err != nil {
+			return "", err
+		}
+		left = fmt.Sprintf("(%v AND %v)", left, right)
+	}
+	return left, nil
+}
+
+func (c *compiler) parseNot() (string, error) {
+	if c.peek().kind == tokenNot {
+		c.next()
+		operand, err := c.parseNot()
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("(NOT %v)", operand), nil
+	}
+	return c.parseComparison()
+}
+
+func (c *compiler) parseComparison() (string, error) {
+	if c.peek().kind == tokenLParen && c.isGroupedCondition() {
+		c.next()
+		inner, err := c.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if c.next().kind != tokenRParen {
+			return "", fmt.Errorf("expected ')' at position %d", c.peek().pos)
+		}
+		return inner, nil
+	}
+
+	left, err := c.parseSum()
+	if err != nil {
+		return "", err
+	}
+
+	t := c.peek()
+	if t.kind != tokenOp || !isComparison(t.text) {
+		return "", fmt.Errorf("expected a comparison (>, <, >=, <=, =, !=) at position %d", t.pos)
+	}
+	c.next()
+
+	right, err := c.parseSum()
+	if err != nil {
+		return "", err
+	}
+
+	// Text is compared with text, e.g. stprba = 'CA' or fy = '2021', and as
+	// a number with numbers, e.g. fy >= 2020
+	leftSQL, rightSQL := left.sql, right.sql
+	if left.text != right.text {
+		leftSQL, err = left.numeric()
+		if err != nil {
+			return "", err
+		}
+		rightSQL, err = right.numeric()
+		if err != nil {
+			return "", err
+		}
+	}
+
+	op := t.text
+	if op == "!=" {
+		op = "<>"
+	}
+	return fmt.Sprintf("(%v %v %v)", leftSQL, op, rightSQL), nil
+}
+
+// isGroupedCondition reports whether the parenthesis at the current position
+// wraps a boolean condition, e.g. "(a > 1 OR b < 2)", rather than an
+// arithmetic expression, e.g. "(a + b) > 1".
+func (c *compiler) isGroupedCondition() bool {
+	depth := 0
+	for i := c.pos; i < len(c.tokens); i++ {
+		t := c.tokens[i]
+		switch t.kind {
+		case tokenLParen:
+			depth++
+		case tokenRParen:
+			depth--
+			if depth == 0 {
+				next := c.tokens[i+1]
+				return !(next.kind == tokenOp)
+			}
+		case tokenAnd, tokenOr, tokenNot:
+			if depth == 1 {
+				return true
+			}
+		case tokenOp:
+			if depth == 1 && isComparison(t.text) {
+				return true
+			}
+		case tokenEOF:
+			return false
+		}
+	}
+	return false
+}
+
+// operand is a compiled arithmetic expression, with the sub column or the
+// string literal it is when it's text
+type operand struct {
+	sql    string
+	text   bool
+	column string
+	pos    int
+}
+
+// numeric returns the SQL of o as a number. Text is only a number in the
+// sub columns of NumericSubColumns.
+func (o operand) numeric() (string, error) {
+	if !o.text {
+		return o.sql, nil
+	}
+	if NumericSubColumns[o.column] {
+		return fmt.Sprintf("CAST(NULLIF(%v, '') AS numeric)", o.sql), nil
+	}
+	if o.column == "" {
+		return "", fmt.Errorf("string at position %d can't be used as a number", o.pos)
+	}
+	return "", fmt.Errorf("%v at position %d is text and can't be used as a number", o.column, o.pos)
+}
+
+func (c *compiler) parseSum() (operand, error) {
+	left, err := c.parseTerm()
+	if err != nil {
+		return operand{}, err
+	}
+	for c.peek().kind == tokenOp && (c.peek().text == "+" || c.peek().text == "-") {
+		op := c.next().text
+		right, err := c.parseTerm()
+		if err != nil {
+			return operand{}, err
+		}
+		left, err = arithmetic(left, right, "(%v "+op+" %v)")
+		if err != nil {
+			return operand{}, err
+		}
+	}
+	return left, nil
+}
+
+func (c *compiler) parseTerm() (operand, error) {
+	left, err := c.parseUnary()
+	if err != nil {
+		return operand{}, err
+	}
+	for c.peek().kind == tokenOp && (c.peek().text == "*" || c.peek().text == "/") {
+		op := c.next().text
+		right, err := c.parseUnary()
+		if err != nil {
+			return operand{}, err
+		}
+		format := "(%v * %v)"
+		if op == "/" {
+			format = "(%v / NULLIF(%v, 0))"
+		}
+		left, err = arithmetic(left, right, format)
+		if err != nil {
+			return operand{}, err
+		}
+	}
+	return left, nil
+}
+
+func arithmetic(left, right operand, format string) (operand, error) {
+	leftSQL, err := left.numeric()
+	if err != nil {
+		return operand{}, err
+	}
+	rightSQL, err := right.numeric()
+	if err != nil {
+		return operand{}, err
+	}
+	return operand{sql: fmt.Sprintf(format, leftSQL, rightSQL), pos: left.pos}, nil
+}
+
+func (c *compiler) parseUnary() (operand, error) {
+	if c.peek().kind == tokenOp && c.peek().text == "-" {
+		pos := c.next().pos
+		o, err := c.parseUnary()
+		if err != nil {
+			return operand{}, err
+		}
+		sql, err := o.numeric()
+		if err != nil {
+			return operand{}, err
+		}
+		return operand{sql: fmt.Sprintf("(-%v)", sql), pos: pos}, nil
+	}
+	return c.parsePrimary()
+}
+
+func (c *compiler) parsePrimary() (operand, error) {
+	t := c.next()
+	switch t.kind {
+	case tokenNumber:
+		return operand{sql: fmt.Sprintf("CAST(%v AS numeric)", c.param(t.value)), pos: t.pos}, nil
+	case tokenString:
+		return operand{sql: fmt.Sprintf("CAST(%v AS text)", c.param(t.text)), text: true, pos: t.pos}, nil
+	case tokenIdent:
+		column := strings.ToLower(t.text)
+		if SubColumns[column] {
+			// cik is the only integer column
+			return operand{sql: "latest." + column, text: column != "cik", column: column, pos: t.pos}, nil
+		}
+		return operand{sql: c.tagColumn(t.text), pos: t.pos}, nil
+	case tokenLParen:
+		inner, err := c.parseSum()
+		if err != nil {
+			return operand{}, err
+		}
+		if c.next().kind != tokenRParen {
+			return operand{}, fmt.Errorf("expected ')' at position %d", t.pos)
+		}
+		return inner, nil
+	case tokenEOF:
+		return operand{}, fmt.Errorf("unexpected end of filter")
+	default:
+		return operand{}, fmt.Errorf("unexpected %q at position %d", t.text, t.pos)
+	}
+}
+
+func (c *compiler) tagColumn(tag string) string {
+	idx, ok := c.tagIdx[tag]
+	if !ok {
+		idx = len(c.tags)
+		c.tagIdx[tag] = idx
+		c.tags = append(c.tags, tag)
+	}
+	return fmt.Sprintf("latest.t%d", idx)
+}
+
+func isComparison(op string) bool {
+	switch op {
+	case ">", "<", ">=", "<=", "=", "!=":
+		return true
+	}
+	return false
+}
+
+// Compile turns a filter such as
+// "Revenues > 1e9 AND NetIncomeLoss/Revenues > 0.1 AND stprba = 'CA'"
+// into a parameterized query over the latest annual values of each company.
+// Identifiers that are fsds.sub columns (see SubColumns) refer to the
+// submission itself; every other identifier is an XBRL tag from fsds.num.
+func Compile(filter string, limit int) (Query, error) {
+	if len(filter) > MaxFilterLength {
+		return Query{}, fmt.Errorf("filter is longer than %d characters", MaxFilterLength)
+	}
+
+	tokens, err := tokenize(filter)
+	if err != nil {
+		return Query{}, err
+	}
+
+	c := &compiler{
+		tokens: tokens,
+		tagIdx: make(map[string]int),
+	}
+
+	where, err := c.parseOr()
+	if err != nil {
+		return Query{}, err
+	}
+	if c.peek().kind != tokenEOF {
+		return Query{}, fmt.Errorf("unexpected %q at position %d", c.peek().text, c.peek().pos)
+	}
+	if len(c.tags) > MaxTags {
+		return Query{}, fmt.Errorf("filter uses %d tags, at most %d are allowed", len(c.tags), MaxTags)
+	}
+
+	var columns, values strings.Builder
+	for i, tag := range c.tags {
+		columns.WriteString(fmt.Sprintf(", latest.t%d", i))
+		values.WriteString(fmt.Sprintf(`, (
+			SELECT CAST(NULLIF(num.value, '') AS numeric)
+			FROM fsds.num num
+			WHERE num.adsh = submissions.adsh
+				AND num.tag = %v
+				AND num.ddate = to_char(submissions.period, 'YYYYMMDD')
+				AND num.qtrs IN ('0', '4')
+				AND COALESCE(num.coreg, '') = ''
+			ORDER BY (num.uom = 'USD') DESC
+			LIMIT 1
+		) AS t%d`, c.param(tag), i))
+	}
+
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+
+	query := fmt.Sprintf(`
+	WITH submissions AS (
+		SELECT adsh, cik, name, sic, countryba, stprba, cityba, zipba, countryinc, stprinc, form, fy, fp, period,
+			ROW_NUMBER() OVER (PARTITION BY cik ORDER BY period DESC, filled DESC) AS row_number
+		FROM fsds.sub
+		WHERE form IN ('10-K', '10-K/A', '20-F', '20-F/A', '40-F', '40-F/A')
+			AND period IS NOT NULL
+	), latest AS (
+		SELECT adsh, cik, name, sic, countryba, stprba, cityba, zipba, countryinc, stprinc, form, fy, fp, period%v
+		FROM submissions
+		WHERE row_number = 1
+	)
+	SELECT latest.cik, latest.name, COALESCE(latest.stprba, ''), COALESCE(latest.sic, ''), to_char(latest.period, 'YYYY-MM-DD')%v
+	FROM latest
+	WHERE %v
+	ORDER BY latest.name
+	LIMIT %v;`, values.String(), columns.String(), where, c.param(limit))
+
+	return Query{
+		Filter: filter,
+		Tags:   c.tags,
+		SQL:    query,
+		Args:   c.args,
+	}, nil
+}
+
+// sqliteScreens translates the Postgres expressions of compiled queries for
+// SQLite. Numbers are real, SQLite's numeric is an integer when it can be
+// and divides like one.
+var sqliteScreens = strings.NewReplacer(
+	"to_char(submissions.period, 'YYYYMMDD')", "strftime('%Y%m%d', submissions.period)",
+	"to_char(latest.period, 'YYYY-MM-DD')", "strftime('%Y-%m-%d', latest.period)",
+	" AS numeric)", " AS real)",
+)
+
+// placeholders are the parameters of compiled queries. The ones of the tags
+// come before those of the filter in the query, SQLite binds $1 by position
+// and ?1 by number.
+var placeholders = regexp.MustCompile(`\$([0-9]+)`)
+
+// Run runs the query, cancelling it on the database once ctx is done
+func Run(ctx context.Context, db *sqlx.DB, q Query) ([]Result, error) {
+	query := q.SQL
+	if database.IsSQLite(db) {
+		query = placeholders.ReplaceAllString(sqliteScreens.Replace(query), "?$1")
+	}
+
+	rows, err := db.QueryContext(ctx, query, q.Args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []Result
+	for rows.Next() {
+		result := Result{
+			Values: make([]sql.NullFloat64, len(q.Tags)),
+		}
+
+		dest := []interface{}{&result.CIK, &result.Name, &result.StprBA, &result.SIC, &result.Period}
+		for i := range result.Values {
+			dest = append(dest, &result.Values[i])
+		}
+
+		err = rows.Scan(dest...)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, rows.Err()
+}
+
+func Screen(ctx context.Context, db *sqlx.DB, filter string, limit int) (Query, []Result, error) {
+	q, err := Compile(filter, limit)
+	if err != nil {
+		return Query{}, nil, err
+	}
+
+	results, err := Run(ctx, db, q)
+	if err != nil {
+		return Query{}, nil, err
+	}
+
+	return q, results, nil
+}
+
+// Row returns the result formatted for CSV output, in the same order as Header
+func (r Result) Row() []string {
+	row := []string{strconv.Itoa(r.CIK), r.Name, r.StprBA, r.SIC, r.Period}
+	for _, v := range r.Values {
+		if !v.Valid {
+			row = append(row, "")
+			continue
+		}
+		row = append(row, strconv.FormatFloat(v.Float64, 'f', -1, 64))
+	}
+	return row
+}
+
+func (q Query) Header() []string {
+	return append([]string{"cik", "name", "stprba", "sic", "period"}, q.Tags...)
+}
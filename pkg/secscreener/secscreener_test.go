@@ -0,0 +1,176 @@
+package secscreener
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secdata"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestCompile(t *testing.T) {
+	q, err := Compile("Revenues > 1e9 AND NetIncomeLoss/Revenues > 0.1 AND stprba = 'CA'", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(q.Tags) != 2 || q.Tags[0] != "Revenues" || q.Tags[1] != "NetIncomeLoss" {
+		t.Errorf("unexpected tags %v", q.Tags)
+	}
+
+	if strings.Contains(q.SQL, "'CA'") || strings.Contains(q.SQL, "'Revenues'") {
+		t.Errorf("literals must be passed as parameters:\n%v", q.SQL)
+	}
+
+	if !strings.Contains(q.SQL, "NULLIF(latest.t0, 0)") {
+		t.Errorf("division must guard against zero:\n%v", q.SQL)
+	}
+
+	if len(q.Args) != 6 {
+		t.Errorf("expected 6 args, got %v", q.Args)
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	filters := []string{
+		"",
+		"Revenues",
+		"Revenues >",
+		"stprba = 'CA",
+		"Revenues > 1; DROP TABLE fsds.sub",
+		"(Revenues > 1",
+	}
+
+	for _, filter := range filters {
+		_, err := Compile(filter, 10)
+		if err == nil {
+			t.Errorf("expected an error for %q", filter)
+		}
+	}
+}
+
+func TestCompileSubColumnTypes(t *testing.T) {
+	q, err := Compile("fy >= 2020 AND fp = 'FY' AND sic + 0 = 3571", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(q.SQL, "(CAST(NULLIF(latest.fy, '') AS numeric) >= CAST($") {
+		t.Errorf("fy must be compared as a number with a number:\n%v", q.SQL)
+	}
+	if !strings.Contains(q.SQL, "(latest.fp = CAST($") {
+		t.Errorf("fp must be compared as text with text:\n%v", q.SQL)
+	}
+	if !strings.Contains(q.SQL, "(CAST(NULLIF(latest.sic, '') AS numeric) + CAST($") {
+		t.Errorf("sic must be a number in arithmetic:\n%v", q.SQL)
+	}
+
+	filters := []string{
+		"stprba > 1",
+		"Revenues > 'CA'",
+		"name * 2 > 1",
+		"-countryba < 0",
+	}
+	for _, filter := range filters {
+		_, err := Compile(filter, 10)
+		if err == nil || !strings.Contains(err.Error(), "can't be used as a number") {
+			t.Errorf("Compile(%q) = %v, want a text error", filter, err)
+		}
+	}
+}
+
+func TestCompileLimits(t *testing.T) {
+	var tags []string
+	for i := 0; i <= MaxTags; i++ {
+		tags = append(tags, fmt.Sprintf("Tag%d > 0", i))
+	}
+
+	filters := map[string]string{
+		strings.Join(tags[:MaxTags], " AND "): "",
+		strings.Join(tags, " AND "):           "tags",
+		"Revenues > 0 OR " + strings.Repeat("stprba = 'CA' OR ", MaxFilterLength/17) + "stprba = 'NY'": "longer than",
+	}
+
+	for filter, want := range filters {
+		_, err := Compile(filter, 10)
+		if want == "" && err != nil {
+			t.Errorf("Compile(%.40q...) = %v, want no error", filter, err)
+		}
+		if want != "" && (err == nil || !strings.Contains(err.Error(), want)) {
+			t.Errorf("Compile(%.40q...) = %v, want an error with %q", filter, err, want)
+		}
+	}
+}
+
+func TestScreen(t *testing.T) {
+	sectest.ForEachDatabase(t, testScreen)
+}
+
+func testScreen(t *testing.T, dbConfig config.DatabaseConfig) {
+	cfg := config.Config{Database: dbConfig}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec("INSERT INTO sec.ciks (cik) VALUES (320193), (789019);")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The annual reports of a Californian company with a 25% margin, one of
+	// them older, and of a company in Washington
+	ops := secdata.NewSECData(secdata.NewSECDataOpsFSDS()).SECDataOps
+	files := []struct {
+		name string
+		data string
+	}{
+		{"sub.txt", "adsh\tcik\tname\tsic\tstprba\tform\tperiod\tfy\tfp\tfiled\n" +
+			"0000320193-20-000096\t320193\tAPPLE INC\t3571\tCA\t10-K\t20200930\t2020\tFY\t20201030\n" +
+			"0000320193-21-000105\t320193\tAPPLE INC\t3571\tCA\t10-K\t20210930\t2021\tFY\t20211029\n" +
+			"0000789019-21-000039\t789019\tMICROSOFT CORP\t7372\tWA\t10-K\t20210630\t2021\tFY\t20210729\n"},
+		{"tag.txt", "tag\tversion\tcustom\tabstract\tdatatype\tlord\tcrdr\ttlabel\tdoc\n" +
+			"Revenues\tus-gaap/2020\t0\t0\tmonetary\tD\tC\tRevenues\t\n" +
+			"NetIncomeLoss\tus-gaap/2020\t0\t0\tmonetary\tD\tC\tNet Income (Loss)\t\n" +
+			"Revenues\tus-gaap/2021\t0\t0\tmonetary\tD\tC\tRevenues\t\n" +
+			"NetIncomeLoss\tus-gaap/2021\t0\t0\tmonetary\tD\tC\tNet Income (Loss)\t\n"},
+		{"num.txt", "adsh\ttag\tversion\tcoreg\tddate\tqtrs\tuom\tvalue\tfootnote\n" +
+			"0000320193-20-000096\tRevenues\tus-gaap/2020\t\t20200930\t4\tUSD\t274515000000\t\n" +
+			"0000320193-20-000096\tNetIncomeLoss\tus-gaap/2020\t\t20200930\t4\tUSD\t57411000000\t\n" +
+			"0000320193-21-000105\tRevenues\tus-gaap/2021\t\t20210930\t4\tUSD\t365817000000\t\n" +
+			"0000320193-21-000105\tRevenues\tus-gaap/2021\t\t20200930\t4\tUSD\t274515000000\t\n" +
+			"0000320193-21-000105\tNetIncomeLoss\tus-gaap/2021\t\t20210930\t4\tUSD\t94680000000\t\n" +
+			"0000789019-21-000039\tRevenues\tus-gaap/2021\t\t20210630\t4\tUSD\t168088000000\t\n" +
+			"0000789019-21-000039\tNetIncomeLoss\tus-gaap/2021\t\t20210630\t4\tUSD\t61271000000\t\n"},
+	}
+	for _, file := range files {
+		upsert := ops.GetDataTypeInsertFunc(file.name)
+		err = upsert(nil, db, ioutil.NopCloser(strings.NewReader(file.data)))
+		if err != nil {
+			t.Fatalf("upserting %v: %v", file.name, err)
+		}
+	}
+
+	_, results, err := Screen(context.Background(), db, "Revenues > 1e9 AND NetIncomeLoss/Revenues > 0.25 AND stprba = 'CA'", 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 {
+		t.Fatalf("Screen() = %+v, want Apple", results)
+	}
+	apple := results[0]
+	if apple.CIK != 320193 || apple.Period != "2021-09-30" || apple.Values[0].Float64 != 365817000000 || apple.Values[1].Float64 != 94680000000 {
+		t.Errorf("Screen() = %+v, want Apple's values of 2021", apple)
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package secsearch finds companies by name, ticker, former name or CIK,
+// tolerating typos. Searched terms are kept in sec.company_search with a
+// pg_trgm index and rebuilt on regen. SQLite compares them with the same
+// trigram functions, without an index.
+package secsearch
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/jmoiron/sqlx"
+)
+
+// Kinds of terms
+const (
+	KindName       = "name"
+	KindFormerName = "former_name"
+	KindTicker     = "ticker"
+	KindCIK        = "cik"
+)
+
+// DefaultLimit is the number of suggestions returned when none is asked
+const DefaultLimit = 10
+
+// MaxLimit caps the number of suggestions asked for
+const MaxLimit = 50
+
+// Suggestion is a company matching a query. Match is the name or ticker
+// that matched and Kind says which it is.
+type Suggestion struct {
+	CIK    int     `db:"cik" json:"cik"`
+	Name   string  `db:"name" json:"name"`
+	Ticker string  `db:"ticker" json:"ticker,omitempty"`
+	Slug   string  `db:"slug" json:"slug"`
+	Match  string  `db:"term" json:"match"`
+	Kind   string  `db:"kind" json:"kind"`
+	Score  float64 `db:"score" json:"score"`
+}
+
+// Term is a row of sec.company_search.
+type Term struct {
+	CIK    int
+	Term   string
+	Kind   string
+	Name   string
+	Ticker string
+	Slug   string
+}
+
+var nonAlphanumeric = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// Normalize reduces names, tickers and queries to upper case letters and
+// digits separated by single spaces, so "Apple Inc." matches "apple inc"
+// and "BRK.B" matches "brk b".
+func Normalize(s string) string {
+	return strings.TrimSpace(nonAlphanumeric.ReplaceAllString(strings.ToUpper(s), " "))
+}
+
+// Company is what's known about a company with filings: its current name
+// and slug, and the names and tickers it had.
+type Company struct {
+	CIK         int
+	Name        string
+	Slug        string
+	Ticker      string
+	FormerNames []string
+	Tickers     []string
+}
+
+// Terms returns the rows to search the company by, without duplicates.
+func (c Company) Terms() []Term {
+	base := Term{CIK: c.CIK, Name: c.Name, Ticker: c.Ticker, Slug: secslug.Canonical(c.Slug, c.Name, c.CIK)}
+
+	var terms []Term
+	seen := make(map[string]bool)
+	add := func(value string, kind string) {
+		term := Normalize(value)
+		if term == "" || seen[term] {
+			return
+		}
+		seen[term] = true
+
+		t := base
+		t.Term, t.Kind = term, kind
+		terms = append(terms, t)
+	}
+
+	add(c.Name, KindName)
+	for _, ticker := range append([]string{c.Ticker}, c.Tickers...) {
+		add(ticker, KindTicker)
+	}
+	for _, name := range c.FormerNames {
+		add(name, KindFormerName)
+	}
+
+	return terms
+}
+
+// GetCompanies returns the companies with filings, named after their
+// latest filing, with their canonical slug, the names they filed under
+// before and their tickers.
+func GetCompanies(db *sqlx.DB) ([]Company, error) {
+	var current []struct {
+		CIK    int    `db:"cik"`
+		Name   string `db:"name"`
+		Slug   string `db:"slug"`
+		Ticker string `db:"ticker"`
+	}
+	err := db.Select(&current, `
+		SELECT latest.cik, latest.name, COALESCE(company_slugs.slug, '') AS slug, COALESCE((
+			SELECT ticker FROM sec.ticker_history
+			WHERE ticker_history.cik = latest.cik AND valid_to IS NULL
+			ORDER BY valid_from DESC, ticker LIMIT 1
+		), (
+			SELECT ticker FROM sec.tickers WHERE tickers.cik = latest.cik AND ticker IS NOT NULL LIMIT 1
+		), '') AS ticker
+		FROM (
+			SELECT ciknumber AS cik, companyname AS name,
+				ROW_NUMBER() OVER (PARTITION BY ciknumber ORDER BY fillingdate DESC NULLS LAST) AS row_number
+			FROM sec.secItemFile
+			WHERE companyname IS NOT NULL AND ciknumber IS NOT NULL
+		) latest
+		LEFT JOIN sec.company_slugs ON company_slugs.cik = latest.cik AND company_slugs.canonical
+		WHERE latest.row_number = 1
+		ORDER BY latest.cik;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var others []struct {
+		CIK   int    `db:"cik"`
+		Value string `db:"value"`
+		Kind  string `db:"kind"`
+	}
+	err = db.Select(&others, `
+		SELECT DISTINCT ciknumber AS cik, companyname AS value, 'name' AS kind
+		FROM sec.secItemFile
+		WHERE companyname IS NOT NULL AND ciknumber IS NOT NULL
+		UNION
+		SELECT cik, name AS value, 'name' AS kind
+		FROM sec.name_history
+		UNION
+		SELECT cik, ticker AS value, 'ticker' AS kind
+		FROM sec.ticker_history
+		UNION
+		SELECT cik, ticker AS value, 'ticker' AS kind
+		FROM sec.tickers
+		WHERE cik IS NOT NULL AND ticker IS NOT NULL;`)
+	if err != nil {
+		return nil, err
+	}
+
+	companies := make([]Company, len(current))
+	index := make(map[int]int)
+	for i, c := range current {
+		companies[i] = Company{CIK: c.CIK, Name: c.Name, Slug: c.Slug, Ticker: c.Ticker}
+		index[c.CIK] = i
+	}
+
+	for _, other := range others {
+		// Companies without filings have no page to suggest
+		i, ok := index[other.CIK]
+		if !ok {
+			continue
+		}
+		if other.Kind == KindTicker {
+			companies[i].Tickers = append(companies[i].Tickers, other.Value)
+		} else {
+			companies[i].FormerNames = append(companies[i].FormerNames, other.Value)
+		}
+	}
+
+	return companies, nil
+}
+
+// Rebuild replaces the searched terms with those of the companies with
+// filings.
+func Rebuild(db *sqlx.DB) error {
+	companies, err := GetCompanies(db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM sec.company_search;`)
+	if err != nil {
+		return err
+	}
+
+	for _, company := range companies {
+		for _, term := range company.Terms() {
+			_, err = tx.Exec(`
+				INSERT INTO sec.company_search (cik, term, kind, name, ticker, slug, created_at, updated_at)
+				VALUES ($1, $2, $3, $4, $5, $6, NOW(), NOW());`,
+				term.CIK, term.Term, term.Kind, term.Name, term.Ticker, term.Slug)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Suggest returns the companies best matching the query, one suggestion
+// per company. Exact matches of a ticker or name come first, then names
+// starting with the query, then names close to it.
+func Suggest(db *sqlx.DB, query string, limit int) ([]Suggestion, error) {
+	term := Normalize(query)
+	if term == "" {
+		return nil, nil
+	}
+	if limit <= 0 {
+		limit = DefaultLimit
+	}
+	if limit > MaxLimit {
+		limit = MaxLimit
+	}
+
+	// A number is searched as a CIK too
+	cik, err := strconv.Atoi(strings.TrimSpace(query))
+	if err != nil {
+		cik = -1
+	}
+
+	// The parameters are numbered in the order they appear, SQLite binds
+	// them by position
+	q := `
+		SELECT cik, name, ticker, slug, term, kind, score
+		FROM (
+			SELECT cik, name, ticker, slug, term, kind, score,
+				ROW_NUMBER() OVER (PARTITION BY cik ORDER BY score DESC) AS row_number
+			FROM (
+				SELECT cik, name, ticker, slug,
+					CASE WHEN cik = $1 THEN $2 ELSE term END AS term,
+					CASE WHEN cik = $1 THEN 'cik' ELSE kind END AS kind,
+					CASE
+						WHEN cik = $1 THEN 4
+						WHEN term = $3 AND kind = 'ticker' THEN 3.5
+						WHEN term = $3 THEN 3
+						WHEN term LIKE $3 || '%' THEN 2 + similarity(term, $3)
+						ELSE word_similarity($3, term)
+					END AS score
+				FROM sec.company_search
+				WHERE cik = $1 OR term = $3 OR term LIKE $3 || '%' OR $3 <% term
+			) scored
+		) matches
+		WHERE row_number = 1
+		ORDER BY score DESC, name
+		LIMIT $4;`
+	if database.IsSQLite(db) {
+		// SQLite has the pg_trgm functions, see database.SQLite, but not its
+		// operators. <% is word_similarity above
+		// pg_trgm.word_similarity_threshold, 0.6 by default.
+		q = strings.Replace(q, "$3 <% term", "word_similarity($3, term) >= 0.6", 1)
+	}
+
+	suggestions := []Suggestion{}
+	err = db.Select(&suggestions, q, cik, strings.TrimSpace(query), term, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return suggestions, nil
+}
@@ -0,0 +1,119 @@
+package secsearch
+
+import (
+	"os"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := map[string]string{
+		"Apple Inc.":          "APPLE INC",
+		"  brk.b ":            "BRK B",
+		"AT&T INC":            "AT T INC",
+		"Johnson & Johnson":   "JOHNSON JOHNSON",
+		"3M CO /MN/":          "3M CO MN",
+		"---":                 "",
+		"Procter & Gamble Co": "PROCTER GAMBLE CO",
+	}
+
+	for s, want := range tests {
+		if got := Normalize(s); got != want {
+			t.Errorf("Normalize(%q) = %q, want %q", s, got, want)
+		}
+	}
+}
+
+func TestTerms(t *testing.T) {
+	company := Company{
+		CIK:         1326801,
+		Name:        "Meta Platforms, Inc.",
+		Ticker:      "META",
+		FormerNames: []string{"META PLATFORMS INC", "Facebook Inc"},
+		Tickers:     []string{"FB", "META"},
+	}
+
+	terms := company.Terms()
+
+	want := []struct {
+		term string
+		kind string
+	}{
+		{"META PLATFORMS INC", KindName},
+		{"META", KindTicker},
+		{"FB", KindTicker},
+		{"FACEBOOK INC", KindFormerName},
+	}
+
+	if len(terms) != len(want) {
+		t.Fatalf("Terms() = %v, want %v", terms, want)
+	}
+	for i, w := range want {
+		if terms[i].Term != w.term || terms[i].Kind != w.kind {
+			t.Errorf("Terms()[%d] = %v %v, want %v %v", i, terms[i].Term, terms[i].Kind, w.term, w.kind)
+		}
+		if terms[i].Slug != "meta-platforms-inc-1326801" || terms[i].Name != company.Name || terms[i].Ticker != "META" {
+			t.Errorf("Terms()[%d] = %+v, want the company's name, ticker and slug", i, terms[i])
+		}
+	}
+}
+
+func TestSuggest(t *testing.T) {
+	sectest.ForEachDatabase(t, testSuggest)
+}
+
+func testSuggest(t *testing.T, dbConfig config.DatabaseConfig) {
+	cfg := config.Config{Database: dbConfig}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = db.Exec(`
+		INSERT INTO sec.ciks (cik) VALUES (320193), (1326801);
+		INSERT INTO sec.tickers (cik, ticker, title) VALUES (320193, 'AAPL', 'Apple Inc.'), (1326801, 'META', 'Meta Platforms, Inc.');
+		INSERT INTO sec.secItemFile (ciknumber, companyname, accessionnumber, fillingdate, xbrlfile) VALUES
+			(320193, 'Apple Inc.', '0000320193-22-000007', '2022-01-28', 'aapl-20211225.htm'),
+			(1326801, 'FACEBOOK INC', '0001326801-21-000014', '2021-07-29', 'fb-20210630.htm'),
+			(1326801, 'Meta Platforms, Inc.', '0001326801-22-000018', '2022-02-03', 'fb-20211231.htm');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = Rebuild(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		query string
+		cik   int
+		match string
+		kind  string
+	}{
+		{"aapl", 320193, "AAPL", KindTicker},
+		{"320193", 320193, "320193", KindCIK},
+		{"meta plat", 1326801, "META PLATFORMS INC", KindName},
+		// A typo of the former name
+		{"facebok", 1326801, "FACEBOOK INC", KindFormerName},
+	}
+
+	for _, tt := range tests {
+		suggestions, err := Suggest(db, tt.query, DefaultLimit)
+		if err != nil {
+			t.Fatalf("Suggest(%q): %v", tt.query, err)
+		}
+		if len(suggestions) != 1 || suggestions[0].CIK != tt.cik || suggestions[0].Match != tt.match || suggestions[0].Kind != tt.kind {
+			t.Errorf("Suggest(%q) = %+v, want %v matching %v %q", tt.query, suggestions, tt.cik, tt.kind, tt.match)
+		}
+	}
+}
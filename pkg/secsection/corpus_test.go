@@ -0,0 +1,296 @@
+package secsection
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/html"
+)
+
+var fetch = flag.Bool("fetch", false, "download the filings of testdata/corpus.txt from EDGAR")
+
+// Sections are trimmed to their first keptBlocks blocks of text and their
+// last one
+const keptBlocks = 3
+
+// TestFetchCorpus downloads the primary documents of the real filings
+// listed in testdata/corpus.txt and writes them trimmed to testdata, see
+// trimFiling. EDGAR wants a User-Agent with a contact:
+//
+//	SEC_USER_AGENT="Name name@example.com" go test ./pkg/secsection -run TestFetchCorpus -fetch
+//	go test ./pkg/secsection -run TestSegmentCorpus -update
+//
+// Check the sections of the new .golden files against the filings before
+// committing them.
+func TestFetchCorpus(t *testing.T) {
+	if !*fetch {
+		t.Skip("run with -fetch to download the corpus")
+	}
+	userAgent := os.Getenv("SEC_USER_AGENT")
+	if userAgent == "" {
+		t.Fatal("set SEC_USER_AGENT, e.g. \"Name name@example.com\"")
+	}
+
+	corpus, err := os.Open("testdata/corpus.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer corpus.Close()
+
+	scanner := bufio.NewScanner(corpus)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		if len(fields) != 2 {
+			t.Fatalf("corpus.txt: expected a file name and a URL in %q", scanner.Text())
+		}
+		name, url := fields[0], fields[1]
+
+		req, err := http.NewRequest(http.MethodGet, url, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		req.Header.Set("User-Agent", userAgent)
+
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resp.StatusCode != http.StatusOK {
+			resp.Body.Close()
+			t.Fatalf("%v: %v", url, resp.Status)
+		}
+		trimmed, err := trimFiling(resp.Body)
+		resp.Body.Close()
+		if err != nil {
+			t.Fatalf("%v: %v", url, err)
+		}
+
+		err = os.WriteFile(filepath.Join("testdata", name), []byte(trimmed), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+		t.Logf("%v: %d bytes", name, len(trimmed))
+	}
+	if err := scanner.Err(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// trimFiling keeps what segmenting a filing depends on and drops the rest
+// so the corpus stays small. Headings, the table of contents and the text
+// that is kept stay whole: every section keeps its first keptBlocks
+// paragraphs or tables and its last one, so it's still followed by more
+// text than its line in the table of contents, and ends as it did. The ones
+// in between, e.g. the tables of numbers of the financial statements, are
+// dropped with the inline XBRL header, hidden elements, styles, images and
+// attributes.
+func trimFiling(r io.Reader) (string, error) {
+	doc, err := html.Parse(r)
+	if err != nil {
+		return "", err
+	}
+	trimNode(doc)
+	trimSections(doc)
+
+	var b strings.Builder
+	err = html.Render(&b, doc)
+	if err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+func trimNode(n *html.Node) {
+	for child := n.FirstChild; child != nil; {
+		next := child.NextSibling
+
+		switch {
+		case child.Type == html.CommentNode:
+			n.RemoveChild(child)
+		case child.Type != html.ElementNode:
+		case child.Data == "ix:header" || child.Data == "script" || child.Data == "style" || child.Data == "img" || isHidden(child):
+			n.RemoveChild(child)
+		default:
+			child.Attr = nil
+			trimNode(child)
+		}
+
+		child = next
+	}
+}
+
+func isHidden(n *html.Node) bool {
+	for _, attr := range n.Attr {
+		if attr.Key == "style" && strings.Contains(strings.ReplaceAll(strings.ToLower(attr.Val), " ", ""), "display:none") {
+			return true
+		}
+	}
+	return false
+}
+
+// Elements holding a paragraph of text, the innermost ones are the blocks
+// sections are trimmed by. Tables are a single block.
+var textBlocks = map[string]bool{
+	"p": true, "div": true, "li": true, "center": true, "pre": true, "blockquote": true,
+	"h1": true, "h2": true, "h3": true, "h4": true, "h5": true, "h6": true, "table": true,
+}
+
+// trimSections drops the blocks of every section but its first keptBlocks
+// and its last one. Sections start at headings and at tables mentioning
+// items: the table of contents and headings laid out in tables.
+func trimSections(doc *html.Node) {
+	var blocks []*html.Node
+	collectBlocks(doc, &blocks)
+
+	var section []*html.Node
+	trim := func() {
+		if len(section) > keptBlocks+1 {
+			for _, block := range section[keptBlocks : len(section)-1] {
+				block.Parent.RemoveChild(block)
+			}
+		}
+		section = nil
+	}
+
+	for _, block := range blocks {
+		if (block.Data == "table" && mentionsItems(block)) || isHeading(textOf(block)) {
+			trim()
+			continue
+		}
+		section = append(section, block)
+	}
+	trim()
+}
+
+func collectBlocks(n *html.Node, blocks *[]*html.Node) {
+	isBlock := n.Type == html.ElementNode && textBlocks[n.Data]
+	if isBlock && n.Data == "table" {
+		*blocks = append(*blocks, n)
+		return
+	}
+
+	inner := len(*blocks)
+	for child := n.FirstChild; child != nil; child = child.NextSibling {
+		collectBlocks(child, blocks)
+	}
+
+	if isBlock && len(*blocks) == inner && strings.TrimSpace(textOf(n)) != "" {
+		*blocks = append(*blocks, n)
+	}
+}
+
+// isHeading reports whether text could be found as a heading by
+// findHeadings
+func isHeading(text string) bool {
+	text = normalizeLine(text)
+	if len(text) > maxHeadingLength {
+		return false
+	}
+	if itemHeading.MatchString(text) || partHeading.MatchString(text) || signatures.MatchString(text) {
+		return true
+	}
+	for _, items := range [][]itemDefinition{annualItems, quarterlyItems} {
+		for _, item := range items {
+			if normalizeTitle(item.Title) == normalizeTitle(text) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func textOf(n *html.Node) string {
+	var b strings.Builder
+	var collect func(*html.Node)
+	collect = func(n *html.Node) {
+		if n.Type == html.TextNode {
+			b.WriteString(n.Data)
+		}
+		for child := n.FirstChild; child != nil; child = child.NextSibling {
+			collect(child)
+		}
+	}
+	collect(n)
+	return b.String()
+}
+
+// mentionsItems reports whether a table has headings in it, e.g. the table
+// of contents or "Item 7." in a layout table
+func mentionsItems(n *html.Node) bool {
+	text := strings.ToLower(textOf(n))
+	return strings.Contains(text, "item") || strings.Contains(text, "part i")
+}
+
+func TestTrimFiling(t *testing.T) {
+	// The paragraphs are longer than the lines of the table of contents
+	paragraph := func(section string, i int) string {
+		return fmt.Sprintf("<p id=\"%v%d\">%v paragraph %d. %v</p>\n", section, i, section, i, strings.Repeat("Lorem ipsum dolor sit amet. ", 10))
+	}
+	var b strings.Builder
+	b.WriteString(`<html><head><style>p {}</style></head><body>
+<div style="display:none"><ix:header>hidden</ix:header></div>
+<table><tr><td>Item 1.</td><td style="x">Business</td><td>3</td></tr>
+<tr><td>Item 1A.</td><td>Risk Factors</td><td>9</td></tr>
+<tr><td>Item 1B.</td><td>Unresolved Staff Comments</td><td>20</td></tr>
+<tr><td>Item 7.</td><td>Management's Discussion and Analysis of Financial Condition and Results of Operations</td><td>21</td></tr></table>
+<img src="logo.jpg"><!-- comment -->
+<p>PART I</p>
+<div><span>Item 1. Business</span></div>
+`)
+	for i := 1; i <= 6; i++ {
+		b.WriteString(paragraph("Business", i))
+	}
+	b.WriteString("<div><div>Item 1A. Risk Factors</div>\n")
+	for i := 1; i <= 5; i++ {
+		b.WriteString(paragraph("Risks", i))
+	}
+	b.WriteString("</div>\n<p>Item 1B. Unresolved Staff Comments</p>\n<p>None.</p>\n<p>PART II</p>\n<p><b>Item 7. Management's Discussion and Analysis of Financial Condition and Results of Operations</b></p>\n")
+	for i := 1; i <= 5; i++ {
+		b.WriteString(paragraph("MD&amp;A", i))
+		b.WriteString("<table><tr><td>Net sales</td><td>365,817</td></tr></table>\n")
+	}
+	b.WriteString("<p>SIGNATURES</p>\n<p>Pursuant to the requirements of the Securities Exchange Act of 1934.</p>\n</body></html>")
+	document := b.String()
+
+	got, err := trimFiling(strings.NewReader(document))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, unwanted := range []string{"<style", "hidden", "style=", "id=", "<img", "comment", "Business paragraph 4", "Business paragraph 5", "Risks paragraph 4", "MD&amp;A paragraph 3"} {
+		if strings.Contains(got, unwanted) {
+			t.Errorf("%.20q not removed from %v", unwanted, got)
+		}
+	}
+	for _, wanted := range []string{"<td>Item 1.</td><td>Business</td>", "<p>Business paragraph 1. " + strings.Repeat("Lorem ipsum dolor sit amet. ", 10) + "</p>", "Business paragraph 3", "Business paragraph 6", "Risks paragraph 5", "None.", "MD&amp;A paragraph 2", "365,817"} {
+		if !strings.Contains(got, wanted) {
+			t.Errorf("%.20q missing from %v", wanted, got)
+		}
+	}
+
+	// Segmenting the trimmed filing finds the same sections
+	var summaries []string
+	for _, filing := range []string{document, got} {
+		sections, err := SegmentHTML(strings.NewReader(filing), FormAnnual)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(sections) != 4 {
+			t.Errorf("%d sections, want 4:\n%v", len(sections), summarize(sections))
+		}
+		summaries = append(summaries, summarize(sections))
+	}
+	if summaries[0] != summaries[1] {
+		t.Errorf("sections of the trimmed filing differ\ngot:\n%v\nwant:\n%v", summaries[1], summaries[0])
+	}
+}
@@ -0,0 +1,401 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package secsection splits the primary document of a 10-K or 10-Q into
+// its standard Items (Business, Risk Factors, MD&A, ...), so they can be
+// searched and compared on their own. It works on the text xbrlbody holds,
+// as extracted by sectext when the filing is indexed.
+package secsection
+
+import (
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/equres/sec/pkg/sectext"
+)
+
+// Forms are segmented as either FormAnnual or FormQuarterly
+const (
+	FormAnnual    = "10-K"
+	FormQuarterly = "10-Q"
+)
+
+// Headings longer than this are sentences referring to an item, e.g.
+// "Item 7 of this report discusses ...", not the item itself.
+const maxHeadingLength = 200
+
+type Section struct {
+	// Part of a 10-Q ("I" or "II"), empty for a 10-K
+	Part string `db:"part"`
+	Item string `db:"item"`
+	// Name identifies the section across forms, e.g. risk_factors is Item
+	// 1A of a 10-K and Part II, Item 1A of a 10-Q
+	Name string `db:"name"`
+	// Title is the heading as found in the document
+	Title    string `db:"title"`
+	Body     string `db:"body"`
+	Position int    `db:"position"`
+}
+
+type itemDefinition struct {
+	Part  string
+	Item  string
+	Name  string
+	Title string
+}
+
+var annualItems = []itemDefinition{
+	{"", "1", "business", "Business"},
+	{"", "1A", "risk_factors", "Risk Factors"},
+	{"", "1B", "unresolved_staff_comments", "Unresolved Staff Comments"},
+	{"", "1C", "cybersecurity", "Cybersecurity"},
+	{"", "2", "properties", "Properties"},
+	{"", "3", "legal_proceedings", "Legal Proceedings"},
+	{"", "4", "mine_safety_disclosures", "Mine Safety Disclosures"},
+	{"", "5", "market_for_equity", "Market for Registrant's Common Equity, Related Stockholder Matters and Issuer Purchases of Equity Securities"},
+	{"", "6", "selected_financial_data", "Selected Financial Data"},
+	{"", "7", "mdna", "Management's Discussion and Analysis of Financial Condition and Results of Operations"},
+	{"", "7A", "market_risk", "Quantitative and Qualitative Disclosures About Market Risk"},
+	{"", "8", "financial_statements", "Financial Statements and Supplementary Data"},
+	{"", "9", "changes_in_accountants", "Changes in and Disagreements with Accountants on Accounting and Financial Disclosure"},
+	{"", "9A", "controls_and_procedures", "Controls and Procedures"},
+	{"", "9B", "other_information", "Other Information"},
+	{"", "9C", "foreign_jurisdictions", "Disclosure Regarding Foreign Jurisdictions that Prevent Inspections"},
+	{"", "10", "directors_and_governance", "Directors, Executive Officers and Corporate Governance"},
+	{"", "11", "executive_compensation", "Executive Compensation"},
+	{"", "12", "security_ownership", "Security Ownership of Certain Beneficial Owners and Management and Related Stockholder Matters"},
+	{"", "13", "relationships_and_independence", "Certain Relationships and Related Transactions, and Director Independence"},
+	{"", "14", "accountant_fees", "Principal Accountant Fees and Services"},
+	{"", "15", "exhibits", "Exhibits and Financial Statement Schedules"},
+	{"", "16", "form_summary", "Form 10-K Summary"},
+}
+
+var quarterlyItems = []itemDefinition{
+	{"I", "1", "financial_statements", "Financial Statements"},
+	{"I", "2", "mdna", "Management's Discussion and Analysis of Financial Condition and Results of Operations"},
+	{"I", "3", "market_risk", "Quantitative and Qualitative Disclosures About Market Risk"},
+	{"I", "4", "controls_and_procedures", "Controls and Procedures"},
+	{"II", "1", "legal_proceedings", "Legal Proceedings"},
+	{"II", "1A", "risk_factors", "Risk Factors"},
+	{"II", "2", "unregistered_sales", "Unregistered Sales of Equity Securities and Use of Proceeds"},
+	{"II", "3", "senior_securities_defaults", "Defaults Upon Senior Securities"},
+	{"II", "4", "mine_safety_disclosures", "Mine Safety Disclosures"},
+	{"II", "5", "other_information", "Other Information"},
+	{"II", "6", "exhibits", "Exhibits"},
+}
+
+var (
+	itemHeading = regexp.MustCompile(`(?i)^items?\s*(\d{1,2})\s*([a-c])?\b\s*[.:\-–—]*\s*(.*)$`)
+	signatures  = regexp.MustCompile(`(?i)^signatures?$`)
+	partHeading = regexp.MustCompile(`(?i)^part\s+(iv|i{1,3}|[1-4])\b\s*[.:\-–—]*\s*(.*)$`)
+	linkRef     = regexp.MustCompile(`\(\s*[^()\s]*\s*\)`)
+	nonWord     = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// FormKind returns FormAnnual or FormQuarterly for the forms that can be
+// segmented, including amendments and transition reports, or "".
+func FormKind(formType string) string {
+	form := strings.ToUpper(strings.TrimSpace(formType))
+	form = strings.TrimSuffix(form, "/A")
+	switch form {
+	case "10-K", "10-K405", "10-KT", "10-KSB":
+		return FormAnnual
+	case "10-Q", "10-QT", "10-QSB":
+		return FormQuarterly
+	}
+	return ""
+}
+
+func itemDefinitions(formType string) []itemDefinition {
+	if FormKind(formType) == FormQuarterly {
+		return quarterlyItems
+	}
+	return annualItems
+}
+
+// TitleOf returns the standard title of a section name, e.g. "Risk
+// Factors" for risk_factors.
+func TitleOf(name string) string {
+	for _, items := range [][]itemDefinition{annualItems, quarterlyItems} {
+		for _, item := range items {
+			if item.Name == name {
+				return item.Title
+			}
+		}
+	}
+	return ""
+}
+
+// Label is how the section is referred to, e.g. "Part II, Item 1A. Risk
+// Factors".
+func (s Section) Label() string {
+	label := "Item " + s.Item
+	if s.Part != "" {
+		label = "Part " + s.Part + ", " + label
+	}
+	if title := TitleOf(s.Name); title != "" {
+		label += ". " + title
+	}
+	return label
+}
+
+// Find returns the section matching key: a name (risk_factors), an item
+// (1A) or a part and item (II-1A).
+func Find(sections []Section, key string) (Section, bool) {
+	key = strings.TrimSpace(key)
+	for _, section := range sections {
+		if section.Name == strings.ToLower(key) {
+			return section, true
+		}
+	}
+	for _, section := range sections {
+		if strings.EqualFold(section.Part+"-"+section.Item, key) {
+			return section, true
+		}
+	}
+	for _, section := range sections {
+		if strings.EqualFold(section.Item, key) {
+			return section, true
+		}
+	}
+	return Section{}, false
+}
+
+// normalizeLine strips non-breaking spaces and what html2text added around
+// headings (bold markers, underlines, link targets) in bodies indexed before
+// sectext.
+func normalizeLine(line string) string {
+	line = strings.ReplaceAll(line, "\u00a0", " ")
+	line = linkRef.ReplaceAllString(line, " ")
+	line = strings.ReplaceAll(line, "*", "")
+	line = strings.Trim(line, " \t\r#|-_=")
+	return strings.Join(strings.Fields(line), " ")
+}
+
+// normalizeTitle reduces a title to lower case letters and digits, so
+// "MANAGEMENT’S DISCUSSION AND ANALYSIS ..." matches the standard title.
+func normalizeTitle(title string) string {
+	title = strings.ToLower(title)
+	title = strings.ReplaceAll(title, "\u2019", "'")
+	title = strings.ReplaceAll(title, "'", "")
+	title = strings.ReplaceAll(title, "&", " and ")
+	return strings.TrimSpace(nonWord.ReplaceAllString(title, " "))
+}
+
+var romanParts = map[string]string{"1": "I", "2": "II", "3": "III", "4": "IV"}
+
+type line struct {
+	Text   string
+	Offset int
+}
+
+func splitLines(text string) []line {
+	var lines []line
+	offset := 0
+	for _, text := range strings.SplitAfter(text, "\n") {
+		lines = append(lines, line{Text: text, Offset: offset})
+		offset += len(text)
+	}
+	return lines
+}
+
+// heading is the start of an item, or with an empty def, a heading that
+// only ends the previous item (PART II, SIGNATURES)
+type heading struct {
+	def    itemDefinition
+	title  string
+	offset int
+	// Offset of the next heading of another item
+	end int
+}
+
+// findHeadings returns every line that looks like the heading of an item,
+// including the ones in the table of contents.
+func findHeadings(text string, formType string) []heading {
+	defs := itemDefinitions(formType)
+	quarterly := FormKind(formType) == FormQuarterly
+
+	byItem := make(map[string]itemDefinition)
+	byTitle := make(map[string]itemDefinition)
+	for _, def := range defs {
+		byItem[def.Part+"-"+def.Item] = def
+		byTitle[normalizeTitle(def.Title)] = def
+	}
+
+	var headings []heading
+	haveItemHeading := make(map[string]bool)
+	var titleHeadings []heading
+
+	part := "I"
+	lastItem := 0
+	for _, l := range splitLines(text) {
+		normalized := normalizeLine(l.Text)
+		if normalized == "" || len(normalized) > maxHeadingLength {
+			continue
+		}
+
+		if signatures.MatchString(normalized) {
+			headings = append(headings, heading{offset: l.Offset})
+			continue
+		}
+
+		if match := partHeading.FindStringSubmatch(normalized); match != nil {
+			part = strings.ToUpper(match[1])
+			if roman, ok := romanParts[part]; ok {
+				part = roman
+			}
+			lastItem = 0
+
+			// Part and item on the same line: "PART I - ITEM 2. ..". Other
+			// part headings only end the previous section.
+			normalized = match[2]
+			if !itemHeading.MatchString(normalized) {
+				headings = append(headings, heading{offset: l.Offset})
+				continue
+			}
+		}
+
+		match := itemHeading.FindStringSubmatch(normalized)
+		if match == nil {
+			// Headings without the item number, e.g. "RISK FACTORS"
+			if def, ok := byTitle[normalizeTitle(normalized)]; ok {
+				titleHeadings = append(titleHeadings, heading{def: def, title: normalized, offset: l.Offset})
+			}
+			continue
+		}
+
+		rest := match[3]
+		first, _ := utf8.DecodeRuneInString(rest)
+		if unicode.IsLower(first) && !strings.HasPrefix(rest, "and ") {
+			// "Item 7 of this report", a reference in the text. "Items 1
+			// and 2. Business and Properties" is a heading.
+			continue
+		}
+
+		number, _ := strconv.Atoi(match[1])
+		item := strings.ToUpper(match[1] + match[2])
+
+		if quarterly {
+			// Quarterly reports without PART headings: the numbering
+			// starting over switches parts, and 1A only exists in Part II
+			if number < lastItem {
+				if part == "I" {
+					part = "II"
+				} else {
+					part = "I"
+				}
+			}
+			if item == "1A" {
+				part = "II"
+			}
+			lastItem = number
+		}
+
+		itemPart := ""
+		if quarterly {
+			itemPart = part
+		}
+
+		def, ok := byItem[itemPart+"-"+item]
+		if !ok {
+			continue
+		}
+
+		title := normalized
+		if rest == "" {
+			title = "Item " + item
+		}
+
+		headings = append(headings, heading{def: def, title: title, offset: l.Offset})
+		haveItemHeading[def.Part+"-"+def.Item] = true
+	}
+
+	for _, h := range titleHeadings {
+		if !haveItemHeading[h.def.Part+"-"+h.def.Item] {
+			headings = append(headings, h)
+		}
+	}
+
+	sortHeadings(headings)
+
+	for i := range headings {
+		headings[i].end = len(text)
+		for _, next := range headings[i+1:] {
+			if next.def != headings[i].def {
+				headings[i].end = next.offset
+				break
+			}
+		}
+	}
+
+	return headings
+}
+
+func sortHeadings(headings []heading) {
+	sort.SliceStable(headings, func(i, j int) bool {
+		return headings[i].offset < headings[j].offset
+	})
+}
+
+// Segment splits the text of a 10-K or 10-Q into its items. The table of
+// contents lists the items too, so for each item the heading followed by
+// the most text is taken, later headings winning ties. A section ends where
+// the next section starts.
+func Segment(text string, formType string) []Section {
+	headings := findHeadings(text, formType)
+
+	best := make(map[itemDefinition]heading)
+	for _, h := range headings {
+		if h.def.Item == "" {
+			continue
+		}
+		current, ok := best[h.def]
+		if !ok || h.end-h.offset >= current.end-current.offset {
+			best[h.def] = h
+		}
+	}
+
+	var chosen []heading
+	for _, h := range best {
+		chosen = append(chosen, h)
+	}
+	sortHeadings(chosen)
+
+	var sections []Section
+	for i, h := range chosen {
+		end := h.end
+		if i+1 < len(chosen) && chosen[i+1].offset < end {
+			end = chosen[i+1].offset
+		}
+
+		body := strings.TrimSpace(text[h.offset:end])
+		if body == "" {
+			continue
+		}
+
+		sections = append(sections, Section{
+			Part:     h.def.Part,
+			Item:     h.def.Item,
+			Name:     h.def.Name,
+			Title:    h.title,
+			Body:     body,
+			Position: len(sections) + 1,
+		})
+	}
+
+	return sections
+}
+
+// SegmentHTML extracts the text of an HTML document the way indexing does
+// and segments it.
+func SegmentHTML(r io.Reader, formType string) ([]Section, error) {
+	text, err := sectext.FromHTML(r)
+	if err != nil {
+		return nil, err
+	}
+	return Segment(text.Text, formType), nil
+}
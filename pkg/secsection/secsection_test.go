@@ -0,0 +1,119 @@
+package secsection
+
+import (
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the .golden files in testdata")
+
+// summarize lists each section with its heading and the last line of its
+// body, which shows both where it starts and where it ends.
+func summarize(sections []Section) string {
+	var b strings.Builder
+	for _, section := range sections {
+		lines := strings.Split(section.Body, "\n")
+		last := strings.TrimSpace(lines[len(lines)-1])
+		fmt.Fprintf(&b, "%v\t%v\t%v\t%v\t%v\n", section.Part, section.Item, section.Name, section.Title, last)
+	}
+	return b.String()
+}
+
+// TestSegmentCorpus segments the documents in testdata and compares the
+// sections with the .golden files next to them. The file name says the
+// form: 10k_*.htm or 10q_*.htm. Run with -update after adding a document.
+func TestSegmentCorpus(t *testing.T) {
+	documents, err := filepath.Glob("testdata/*.htm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(documents) == 0 {
+		t.Fatal("no documents in testdata")
+	}
+
+	for _, document := range documents {
+		form := FormAnnual
+		if strings.HasPrefix(filepath.Base(document), "10q") {
+			form = FormQuarterly
+		}
+
+		file, err := os.Open(document)
+		if err != nil {
+			t.Fatal(err)
+		}
+		sections, err := SegmentHTML(file, form)
+		file.Close()
+		if err != nil {
+			t.Errorf("%v: %v", document, err)
+			continue
+		}
+
+		got := summarize(sections)
+		golden := strings.TrimSuffix(document, ".htm") + ".golden"
+
+		if *update {
+			err = ioutil.WriteFile(golden, []byte(got), 0644)
+			if err != nil {
+				t.Fatal(err)
+			}
+			continue
+		}
+
+		want, err := ioutil.ReadFile(golden)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got != string(want) {
+			t.Errorf("%v: sections differ from %v\ngot:\n%v\nwant:\n%v", document, golden, got, string(want))
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	sections := Segment("Item 1. Financial Statements\nAssets.\nItem 2. Management's Discussion\nSales.\nPART II\nItem 1A. Risk Factors\nNo changes.\n", "10-Q")
+
+	tests := []struct {
+		key  string
+		part string
+		item string
+	}{
+		{"risk_factors", "II", "1A"},
+		{"mdna", "I", "2"},
+		{"1a", "II", "1A"},
+		{"I-1", "I", "1"},
+	}
+
+	for _, tt := range tests {
+		section, ok := Find(sections, tt.key)
+		if !ok || section.Part != tt.part || section.Item != tt.item {
+			t.Errorf("Find(%q) = %v %v, %v, want %v %v", tt.key, section.Part, section.Item, ok, tt.part, tt.item)
+		}
+	}
+
+	if _, ok := Find(sections, "7A"); ok {
+		t.Errorf("Find(%q) found a section", "7A")
+	}
+}
+
+func TestFormKind(t *testing.T) {
+	tests := map[string]string{
+		"10-K":   FormAnnual,
+		"10-K/A": FormAnnual,
+		"10-KT":  FormAnnual,
+		"10-Q":   FormQuarterly,
+		"10-Q/A": FormQuarterly,
+		"8-K":    "",
+		"S-1":    "",
+	}
+
+	for form, want := range tests {
+		if got := FormKind(form); got != want {
+			t.Errorf("FormKind(%q) = %q, want %q", form, got, want)
+		}
+	}
+}
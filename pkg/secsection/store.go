@@ -0,0 +1,132 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secsection
+
+import (
+	"fmt"
+
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/jmoiron/sqlx"
+)
+
+// GetFilingsToSegment returns the accession numbers of the 10-K and 10-Q
+// filings without sections, or of all of them if all is set.
+func GetFilingsToSegment(db *sqlx.DB, all bool) ([]string, error) {
+	var accessions []string
+	err := db.Select(&accessions, `
+		SELECT accessionnumber
+		FROM sec.secItemFile
+		WHERE formtype IN ('10-K', '10-K/A', '10-K405', '10-KT', '10-KSB', '10-Q', '10-Q/A', '10-QT', '10-QSB')
+		AND xbrltype = formtype
+		AND ($1 OR NOT EXISTS (SELECT 1 FROM sec.filing_sections WHERE filing_sections.accessionnumber = secItemFile.accessionnumber))
+		GROUP BY accessionnumber
+		ORDER BY MIN(fillingdate), accessionnumber;`, all)
+	if err != nil {
+		return nil, err
+	}
+	return accessions, nil
+}
+
+// Save replaces the sections stored for the filing.
+func Save(db *sqlx.DB, accession string, file string, formType string, sections []Section) error {
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM sec.filing_sections WHERE accessionnumber = $1;`, accession)
+	if err != nil {
+		return err
+	}
+
+	for _, section := range sections {
+		_, err = tx.Exec(`
+			INSERT INTO sec.filing_sections (accessionnumber, xbrlfile, formtype, part, item, name, title, body, position, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, NOW(), NOW());`,
+			accession, file, formType, section.Part, section.Item, section.Name, section.Title, section.Body, section.Position)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Get returns the stored sections of a filing in document order.
+func Get(db *sqlx.DB, accession string) ([]Section, error) {
+	var sections []Section
+	err := db.Select(&sections, `
+		SELECT part, item, COALESCE(name, '') AS name, COALESCE(title, '') AS title, COALESCE(body, '') AS body, COALESCE(position, 0) AS position
+		FROM sec.filing_sections
+		WHERE accessionnumber = $1
+		ORDER BY position;`, accession)
+	if err != nil {
+		return nil, err
+	}
+	return sections, nil
+}
+
+// SegmentFiling segments the primary document of a 10-K or 10-Q from its
+// xbrlbody and stores the sections found.
+func SegmentFiling(db *sqlx.DB, filing secfiling.Filing) ([]Section, error) {
+	if FormKind(filing.FormType) == "" {
+		return nil, fmt.Errorf("filing %v is a %v, only 10-K and 10-Q filings can be segmented", filing.Accession, filing.FormType)
+	}
+
+	text, err := secfiling.DocumentText(db, filing)
+	if err != nil {
+		return nil, err
+	}
+
+	sections := Segment(text, filing.FormType)
+
+	primary, _ := filing.PrimaryDocument()
+	err = Save(db, filing.Accession, primary.Name, filing.FormType, sections)
+	if err != nil {
+		return nil, err
+	}
+
+	return sections, nil
+}
+
+// SegmentFilings segments every 10-K and 10-Q without sections, or all of
+// them if all is set.
+func SegmentFilings(s *sec.SEC, db *sqlx.DB, all bool) error {
+	accessions, err := GetFilingsToSegment(db, all)
+	if err != nil {
+		return err
+	}
+
+	for i, accession := range accessions {
+		filing, err := secfiling.GetFiling(db, accession)
+		if err != nil {
+			return err
+		}
+
+		sections, err := SegmentFiling(db, filing)
+		if err != nil {
+			s.Log(fmt.Sprintf("Could not segment %v: %v", accession, err))
+			continue
+		}
+
+		s.Log(fmt.Sprintf("[%d/%d] Found %d sections in %v %v", i+1, len(accessions), len(sections), filing.FormType, accession))
+	}
+
+	return nil
+}
+
+// Sections returns the stored sections of the filing, segmenting its text
+// on the fly if it has none yet.
+func Sections(db *sqlx.DB, filing secfiling.Filing) ([]Section, error) {
+	sections, err := Get(db, filing.Accession)
+	if err != nil || len(sections) > 0 {
+		return sections, err
+	}
+
+	text, err := secfiling.DocumentText(db, filing)
+	if err != nil {
+		return nil, err
+	}
+	return Segment(text, filing.FormType), nil
+}
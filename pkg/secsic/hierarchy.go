@@ -0,0 +1,220 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secsic
+
+import (
+	"sort"
+	"strconv"
+
+	"github.com/equres/sec/pkg/sec"
+	"github.com/jmoiron/sqlx"
+)
+
+// Division is the top level of the SIC hierarchy, e.g. D for
+// Manufacturing. Companies counts the companies of all its SICs.
+type Division struct {
+	Division    string `db:"division"`
+	Title       string `db:"title"`
+	Companies   int
+	MajorGroups []MajorGroup
+}
+
+// MajorGroup is a group of SICs sharing their first two digits.
+type MajorGroup struct {
+	MajorGroup     int    `db:"major_group"`
+	Division       string `db:"division"`
+	Title          string `db:"title"`
+	Companies      int
+	IndustryGroups []IndustryGroup
+}
+
+// IndustryGroup is a group of SICs sharing their first three digits.
+type IndustryGroup struct {
+	IndustryGroup int    `db:"industry_group"`
+	MajorGroup    int    `db:"major_group"`
+	Title         string `db:"title"`
+	Companies     int
+	SICs          []SICCount
+}
+
+// SICCount is a SIC with the number of companies filing under it.
+type SICCount struct {
+	SIC       string
+	Title     string
+	Companies int
+}
+
+// IndustryGroups returns the industry groups of the SICs with their titles
+// from named. The SEC also assigns codes of no industry group of the SIC
+// manual, e.g. 6770, their group takes the title of its SIC ending with 0
+// if there's one.
+func IndustryGroups(named []IndustryGroup, sics []sec.SIC) []IndustryGroup {
+	titles := make(map[int]string)
+	for _, group := range named {
+		titles[group.IndustryGroup] = group.Title
+	}
+
+	found := make(map[int]bool)
+	var groups []int
+	for _, sic := range sics {
+		code, err := strconv.Atoi(sic.SIC)
+		if err != nil {
+			continue
+		}
+		group := code / 10
+		if !found[group] {
+			found[group] = true
+			groups = append(groups, group)
+		}
+		if _, ok := titles[group]; !ok && code%10 == 0 {
+			titles[group] = sic.Title
+		}
+	}
+	sort.Ints(groups)
+
+	industryGroups := make([]IndustryGroup, len(groups))
+	for i, group := range groups {
+		industryGroups[i] = IndustryGroup{IndustryGroup: group, MajorGroup: group / 10, Title: titles[group]}
+	}
+	return industryGroups
+}
+
+// BuildTree puts the SICs with their company counts under their industry
+// group, major group and division. Groups without SICs are left out, SICs
+// of no known major group end up in a division without a code.
+func BuildTree(divisions []Division, majorGroups []MajorGroup, industryGroups []IndustryGroup, sics []sec.SIC, counts map[int]int) []Division {
+	sorted := make([]sec.SIC, len(sics))
+	copy(sorted, sics)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, _ := strconv.Atoi(sorted[i].SIC)
+		b, _ := strconv.Atoi(sorted[j].SIC)
+		return a < b
+	})
+
+	bySIC := make(map[int][]SICCount)
+	for _, sic := range sorted {
+		code, err := strconv.Atoi(sic.SIC)
+		if err != nil {
+			continue
+		}
+		bySIC[code/10] = append(bySIC[code/10], SICCount{SIC: sic.SIC, Title: sic.Title, Companies: counts[code]})
+	}
+
+	byMajorGroup := make(map[int][]IndustryGroup)
+	for _, group := range IndustryGroups(industryGroups, sorted) {
+		group.SICs = bySIC[group.IndustryGroup]
+		for _, sic := range group.SICs {
+			group.Companies += sic.Companies
+		}
+		byMajorGroup[group.MajorGroup] = append(byMajorGroup[group.MajorGroup], group)
+	}
+
+	known := make(map[int]bool)
+	byDivision := make(map[string][]MajorGroup)
+	for _, majorGroup := range majorGroups {
+		known[majorGroup.MajorGroup] = true
+		majorGroup.IndustryGroups = byMajorGroup[majorGroup.MajorGroup]
+		if len(majorGroup.IndustryGroups) == 0 {
+			continue
+		}
+		for _, group := range majorGroup.IndustryGroups {
+			majorGroup.Companies += group.Companies
+		}
+		byDivision[majorGroup.Division] = append(byDivision[majorGroup.Division], majorGroup)
+	}
+
+	var tree []Division
+	for _, division := range divisions {
+		division.MajorGroups = byDivision[division.Division]
+		if len(division.MajorGroups) == 0 {
+			continue
+		}
+		for _, majorGroup := range division.MajorGroups {
+			division.Companies += majorGroup.Companies
+		}
+		tree = append(tree, division)
+	}
+
+	other := Division{Title: "Other"}
+	var unknown []int
+	for majorGroup := range byMajorGroup {
+		if !known[majorGroup] {
+			unknown = append(unknown, majorGroup)
+		}
+	}
+	sort.Ints(unknown)
+	for _, majorGroup := range unknown {
+		group := MajorGroup{MajorGroup: majorGroup, IndustryGroups: byMajorGroup[majorGroup]}
+		for _, industryGroup := range group.IndustryGroups {
+			group.Companies += industryGroup.Companies
+		}
+		other.Companies += group.Companies
+		other.MajorGroups = append(other.MajorGroups, group)
+	}
+	if len(other.MajorGroups) > 0 {
+		tree = append(tree, other)
+	}
+
+	return tree
+}
+
+// GetSICCompanyCounts returns the number of companies by the SIC of their
+// latest filing.
+func GetSICCompanyCounts(db *sqlx.DB) (map[int]int, error) {
+	var rows []struct {
+		SIC       int `db:"sic"`
+		Companies int `db:"companies"`
+	}
+	err := db.Select(&rows, `
+		SELECT assignedsic AS sic, COUNT(*) AS companies
+		FROM (
+			SELECT assignedsic,
+				ROW_NUMBER() OVER (PARTITION BY ciknumber ORDER BY fillingdate DESC NULLS LAST) AS row_number
+			FROM sec.secitemfile
+			WHERE assignedsic IS NOT NULL AND ciknumber IS NOT NULL
+		) latest
+		WHERE row_number = 1
+		GROUP BY assignedsic;`)
+	if err != nil {
+		return nil, err
+	}
+
+	counts := make(map[int]int)
+	for _, row := range rows {
+		counts[row.SIC] = row.Companies
+	}
+	return counts, nil
+}
+
+// GetTree returns the SIC hierarchy with the number of companies at each
+// level.
+func GetTree(db *sqlx.DB) ([]Division, error) {
+	var divisions []Division
+	err := db.Select(&divisions, "SELECT division, title FROM sec.sic_divisions ORDER BY division;")
+	if err != nil {
+		return nil, err
+	}
+
+	var majorGroups []MajorGroup
+	err = db.Select(&majorGroups, "SELECT major_group, division, title FROM sec.sic_major_groups ORDER BY major_group;")
+	if err != nil {
+		return nil, err
+	}
+
+	var industryGroups []IndustryGroup
+	err = db.Select(&industryGroups, "SELECT industry_group, major_group, title FROM sec.sic_industry_groups ORDER BY industry_group;")
+	if err != nil {
+		return nil, err
+	}
+
+	sics, err := GetAllSICCodes(db)
+	if err != nil {
+		return nil, err
+	}
+
+	counts, err := GetSICCompanyCounts(db)
+	if err != nil {
+		return nil, err
+	}
+
+	return BuildTree(divisions, majorGroups, industryGroups, sics, counts), nil
+}
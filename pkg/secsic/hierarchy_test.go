@@ -0,0 +1,142 @@
+package secsic
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/equres/sec/pkg/sec"
+)
+
+var testSICs = []sec.SIC{
+	{SIC: "7372", Title: "SERVICES-PREPACKAGED SOFTWARE"},
+	{SIC: "7370", Title: "SERVICES-COMPUTER PROGRAMMING, DATA PROCESSING, ETC."},
+	{SIC: "2834", Title: "PHARMACEUTICAL PREPARATIONS"},
+	{SIC: "2836", Title: "BIOLOGICAL PRODUCTS, (NO DIAGNOSTIC SUBSTANCES)"},
+	{SIC: "8888", Title: "FOREIGN GOVERNMENTS"},
+	{SIC: "9995", Title: "NON-OPERATING ESTABLISHMENTS"},
+}
+
+var testIndustryGroups = []IndustryGroup{
+	{IndustryGroup: 283, MajorGroup: 28, Title: "Drugs"},
+	{IndustryGroup: 737, MajorGroup: 73, Title: "Computer Programming, Data Processing, And Other Computer Related Services"},
+	{IndustryGroup: 999, MajorGroup: 99, Title: "Nonclassifiable Establishments"},
+}
+
+func TestIndustryGroups(t *testing.T) {
+	sics := append(testSICs, sec.SIC{SIC: "6770", Title: "BLANK CHECKS"})
+	got := IndustryGroups(testIndustryGroups, sics)
+
+	want := []IndustryGroup{
+		{IndustryGroup: 283, MajorGroup: 28, Title: "Drugs"},
+		{IndustryGroup: 677, MajorGroup: 67, Title: "BLANK CHECKS"},
+		{IndustryGroup: 737, MajorGroup: 73, Title: "Computer Programming, Data Processing, And Other Computer Related Services"},
+		{IndustryGroup: 888, MajorGroup: 88, Title: ""},
+		{IndustryGroup: 999, MajorGroup: 99, Title: "Nonclassifiable Establishments"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("IndustryGroups() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i].IndustryGroup != want[i].IndustryGroup || got[i].MajorGroup != want[i].MajorGroup || got[i].Title != want[i].Title {
+			t.Errorf("IndustryGroups()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestBuildTree(t *testing.T) {
+	divisions := []Division{
+		{Division: "D", Title: "Manufacturing"},
+		{Division: "I", Title: "Services"},
+		{Division: "J", Title: "Public Administration"},
+	}
+	majorGroups := []MajorGroup{
+		{MajorGroup: 28, Division: "D", Title: "Chemicals And Allied Products"},
+		{MajorGroup: 35, Division: "D", Title: "Industrial And Commercial Machinery And Computer Equipment"},
+		{MajorGroup: 73, Division: "I", Title: "Business Services"},
+		{MajorGroup: 99, Division: "J", Title: "Nonclassifiable Establishments"},
+	}
+	counts := map[int]int{7372: 10, 7370: 2, 2834: 5, 8888: 1}
+
+	tree := BuildTree(divisions, majorGroups, testIndustryGroups, testSICs, counts)
+
+	if len(tree) != 4 {
+		t.Fatalf("BuildTree() has %d divisions, want D, I, J and Other: %+v", len(tree), tree)
+	}
+	if tree[0].Division != "D" || tree[0].Companies != 5 || len(tree[0].MajorGroups) != 1 {
+		t.Errorf("BuildTree()[0] = %+v, want division D with 5 companies in major group 28", tree[0])
+	}
+	if tree[1].Division != "I" || tree[1].Companies != 12 {
+		t.Errorf("BuildTree()[1] = %+v, want division I with 12 companies", tree[1])
+	}
+	sics := tree[1].MajorGroups[0].IndustryGroups[0].SICs
+	if len(sics) != 2 || sics[0].SIC != "7370" || sics[1].Companies != 10 {
+		t.Errorf("SICs of industry group 737 = %+v, want 7370 and 7372 with 10 companies", sics)
+	}
+	if tree[2].Division != "J" || tree[2].Companies != 0 {
+		t.Errorf("BuildTree()[2] = %+v, want division J without companies", tree[2])
+	}
+	if tree[3].Division != "" || tree[3].Companies != 1 || tree[3].MajorGroups[0].MajorGroup != 88 {
+		t.Errorf("BuildTree()[3] = %+v, want major group 88 under Other", tree[3])
+	}
+}
+
+func TestTechPreset(t *testing.T) {
+	tests := map[int]bool{
+		7372: true,
+		3674: true,
+		2834: true,
+		8731: true,
+		6022: false,
+		3711: false,
+		2837: false,
+	}
+
+	for sic, want := range tests {
+		if got := TechPreset.Contains(sic); got != want {
+			t.Errorf("TechPreset.Contains(%d) = %v, want %v", sic, got, want)
+		}
+	}
+
+	condition := TechPreset.Condition("sic")
+	if !strings.HasPrefix(condition, "(sic BETWEEN 2833 AND 2836 OR ") || !strings.Contains(condition, " OR sic = 8731)") {
+		t.Errorf("TechPreset.Condition() = %v", condition)
+	}
+}
+
+func TestParseNAICS(t *testing.T) {
+	crosswalk, err := ParseNAICS(bytes.NewReader(BundledNAICS))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crosswalk) == 0 {
+		t.Fatal("the bundled crosswalk is empty")
+	}
+
+	found := false
+	for _, row := range crosswalk {
+		if row.SIC == 7372 && row.NAICS == "511210" && row.Title == "Software Publishers" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("7372 Software Publishers not in the bundled crosswalk")
+	}
+
+	// SICs and group codes of the SEC's list from every division
+	sics := make(map[int]bool)
+	for _, row := range crosswalk {
+		sics[row.SIC] = true
+	}
+	for _, sic := range []int{100, 1311, 2834, 3674, 4911, 5961, 6189, 6770, 6798, 7370, 8731, 9721} {
+		if !sics[sic] {
+			t.Errorf("%d not in the bundled crosswalk", sic)
+		}
+	}
+
+	_, err = ParseNAICS(strings.NewReader("sic,naics,title\nabc,511210,Software Publishers\n"))
+	if err == nil {
+		t.Errorf("ParseNAICS() accepted an invalid SIC")
+	}
+}
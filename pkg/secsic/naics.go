@@ -0,0 +1,109 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secsic
+
+import (
+	"bytes"
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// BundledNAICS is a SIC to 2017 NAICS crosswalk of all SICs EDGAR assigns,
+// after the Census Bureau's concordance. The SEC's group codes, e.g. 7370,
+// get the NAICS group covering them, 8880, 8888 and 9995 have no NAICS.
+// Another crosswalk can be loaded instead, as a CSV with the same
+// sic,naics,title columns.
+//
+//go:embed sic_naics.csv
+var BundledNAICS []byte
+
+// NAICS is a NAICS code a SIC corresponds to.
+type NAICS struct {
+	SIC   int    `db:"sic"`
+	NAICS string `db:"naics"`
+	Title string `db:"title"`
+}
+
+// ParseNAICS reads a crosswalk CSV with a header and sic, naics and title
+// columns.
+func ParseNAICS(r io.Reader) ([]NAICS, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = 3
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, fmt.Errorf("empty crosswalk")
+	}
+
+	var crosswalk []NAICS
+	for i, record := range records[1:] {
+		sic, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid SIC %q", i+2, record[0])
+		}
+		naics := strings.TrimSpace(record[1])
+		if _, err := strconv.Atoi(naics); err != nil {
+			return nil, fmt.Errorf("line %d: invalid NAICS %q", i+2, record[1])
+		}
+		crosswalk = append(crosswalk, NAICS{SIC: sic, NAICS: naics, Title: strings.TrimSpace(record[2])})
+	}
+
+	return crosswalk, nil
+}
+
+// IndexNAICS replaces the crosswalk with the one read from r.
+func IndexNAICS(db *sqlx.DB, r io.Reader) (int, error) {
+	crosswalk, err := ParseNAICS(r)
+	if err != nil {
+		return 0, err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM sec.sic_naics;`)
+	if err != nil {
+		return 0, err
+	}
+
+	for _, row := range crosswalk {
+		_, err = tx.Exec(`
+		INSERT INTO sec.sic_naics (sic, naics, title, created_at, updated_at)
+		VALUES ($1, $2, $3, NOW(), NOW())
+		ON CONFLICT (sic, naics)
+		DO UPDATE SET title=EXCLUDED.title, updated_at=NOW();`,
+			row.SIC, row.NAICS, row.Title)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(crosswalk), tx.Commit()
+}
+
+// IndexBundledNAICS loads the bundled crosswalk.
+func IndexBundledNAICS(db *sqlx.DB) (int, error) {
+	return IndexNAICS(db, bytes.NewReader(BundledNAICS))
+}
+
+// GetNAICSFromSIC returns the NAICS codes of a SIC, none if the crosswalk
+// wasn't loaded.
+func GetNAICSFromSIC(db *sqlx.DB, sic string) ([]NAICS, error) {
+	var crosswalk []NAICS
+	err := db.Select(&crosswalk, "SELECT sic, naics, title FROM sec.sic_naics WHERE sic = $1 ORDER BY naics;", sic)
+	if err != nil {
+		return nil, err
+	}
+	return crosswalk, nil
+}
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secsic
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/jmoiron/sqlx"
+)
+
+// SICRange is an inclusive range of SICs.
+type SICRange struct {
+	From int
+	To   int
+	Name string
+}
+
+// Preset classifies companies by the SICs they file under.
+type Preset struct {
+	Name   string
+	Title  string
+	Ranges []SICRange
+}
+
+// TechPreset are the high-tech industries: drugs and biotech, computers,
+// communications equipment, electronics, aerospace, instruments,
+// telecommunications, software and commercial research.
+var TechPreset = Preset{
+	Name:  "tech",
+	Title: "Technology Companies",
+	Ranges: []SICRange{
+		{2833, 2836, "Drugs and biological products"},
+		{3570, 3579, "Computer and office equipment"},
+		{3660, 3669, "Communications equipment"},
+		{3670, 3679, "Electronic components and semiconductors"},
+		{3720, 3729, "Aircraft and parts"},
+		{3760, 3769, "Guided missiles and space vehicles"},
+		{3812, 3812, "Search, detection and navigation equipment"},
+		{3820, 3829, "Measuring and controlling instruments"},
+		{3840, 3845, "Medical instruments and apparatus"},
+		{4812, 4813, "Telephone communications"},
+		{4899, 4899, "Communications services"},
+		{7370, 7379, "Computer programming, software and data processing"},
+		{8731, 8731, "Commercial physical and biological research"},
+	},
+}
+
+// Presets by name
+var Presets = map[string]Preset{
+	TechPreset.Name: TechPreset,
+}
+
+// Contains reports whether companies filing under the SIC are in the preset.
+func (p Preset) Contains(sic int) bool {
+	for _, r := range p.Ranges {
+		if sic >= r.From && sic <= r.To {
+			return true
+		}
+	}
+	return false
+}
+
+// Condition returns an SQL condition on column matching the SICs of the
+// preset.
+func (p Preset) Condition(column string) string {
+	var conditions []string
+	for _, r := range p.Ranges {
+		if r.From == r.To {
+			conditions = append(conditions, fmt.Sprintf("%v = %d", column, r.From))
+			continue
+		}
+		conditions = append(conditions, fmt.Sprintf("%v BETWEEN %d AND %d", column, r.From, r.To))
+	}
+	return "(" + strings.Join(conditions, " OR ") + ")"
+}
+
+// PresetCompany is a company of a preset with the SIC of its latest filing.
+type PresetCompany struct {
+	CIK      int    `db:"cik"`
+	Name     string `db:"name"`
+	SIC      int    `db:"sic"`
+	SICTitle string `db:"sictitle"`
+	Slug     string `db:"slug"`
+}
+
+// GetPresetCompanies returns the companies whose latest filing is under a
+// SIC of the preset, ordered by name.
+func GetPresetCompanies(db *sqlx.DB, p Preset) ([]PresetCompany, error) {
+	var companies []PresetCompany
+	err := db.Select(&companies, fmt.Sprintf(`
+		SELECT latest.cik, latest.name, latest.sic, COALESCE(sics.title, '') AS sictitle, COALESCE(company_slugs.slug, '') AS slug
+		FROM (
+			SELECT ciknumber AS cik, companyname AS name, assignedsic AS sic,
+				ROW_NUMBER() OVER (PARTITION BY ciknumber ORDER BY fillingdate DESC NULLS LAST) AS row_number
+			FROM sec.secitemfile
+			WHERE companyname IS NOT NULL AND ciknumber IS NOT NULL AND assignedsic IS NOT NULL
+		) latest
+		LEFT JOIN sec.sics ON sics.sic = latest.sic
+		LEFT JOIN sec.company_slugs ON company_slugs.cik = latest.cik AND company_slugs.canonical
+		WHERE latest.row_number = 1 AND %v
+		ORDER BY latest.name, latest.cik;`, p.Condition("latest.sic")))
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range companies {
+		companies[i].Slug = secslug.Canonical(companies[i].Slug, companies[i].Name, companies[i].CIK)
+	}
+
+	return companies, nil
+}
@@ -2,8 +2,10 @@ package secsic
 
 import (
 	"fmt"
+	"strconv"
 
 	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/secslug"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -17,13 +19,43 @@ func GetAllSICCodes(db *sqlx.DB) ([]sec.SIC, error) {
 	return sics, nil
 }
 
+// GetAllCompaniesWithSIC returns the companies that filed under sic, once
+// each, with the name and slug of their canonical sec.company_slugs row,
+// ordered by name. Companies without one are named after their latest
+// filing.
 func GetAllCompaniesWithSIC(db *sqlx.DB, sic string) ([]sec.Company, error) {
-	var companies []sec.Company
-	err := db.Select(&companies, "SELECT DISTINCT companyname, ciknumber FROM sec.secitemfile WHERE assignedsic = $1 AND companyname IS NOT NULL;", sic)
+	var rows []struct {
+		CIK  int    `db:"cik"`
+		Name string `db:"name"`
+		Slug string `db:"slug"`
+	}
+	err := db.Select(&rows, `
+		SELECT ciks.cik, COALESCE(company_slugs.name, (
+			SELECT companyname FROM sec.secitemfile
+			WHERE secitemfile.ciknumber = ciks.cik AND companyname IS NOT NULL
+			ORDER BY fillingdate DESC NULLS LAST
+			LIMIT 1
+		)) AS name, COALESCE(company_slugs.slug, '') AS slug
+		FROM (
+			SELECT DISTINCT ciknumber AS cik
+			FROM sec.secitemfile
+			WHERE assignedsic = $1 AND companyname IS NOT NULL AND ciknumber IS NOT NULL
+		) ciks
+		LEFT JOIN sec.company_slugs ON company_slugs.cik = ciks.cik AND company_slugs.canonical
+		ORDER BY name, ciks.cik;`, sic)
 	if err != nil {
 		return nil, err
 	}
 
+	companies := make([]sec.Company, len(rows))
+	for i, row := range rows {
+		companies[i] = sec.Company{
+			CompanyName: row.Name,
+			CIKNumber:   strconv.Itoa(row.CIK),
+			Slug:        secslug.Canonical(row.Slug, row.Name, row.CIK),
+		}
+	}
+
 	return companies, nil
 }
 
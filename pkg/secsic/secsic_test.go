@@ -0,0 +1,84 @@
+package secsic
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestGetAllCompaniesWithSIC(t *testing.T) {
+	cfg := config.Config{Database: sectest.SQLite(t)}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Facebook renamed itself Meta, Zoom has no slug synced yet and Oracle
+	// filed under another SIC
+	_, err = db.Exec(`
+		INSERT INTO sec.ciks (cik) VALUES (1326801), (1585521), (1341439);
+		INSERT INTO sec.secItemFile (ciknumber, companyname, accessionnumber, fillingdate, assignedsic, xbrlfile) VALUES
+			(1326801, 'FACEBOOK INC', '0001326801-21-000014', '2021-07-29', 7370, 'fb-20210630.htm'),
+			(1326801, 'FACEBOOK INC', '0001326801-21-000014', '2021-07-29', 7370, 'fb-20210630_htm.xml'),
+			(1326801, 'Meta Platforms, Inc.', '0001326801-22-000018', '2022-02-03', 7370, 'fb-20211231.htm'),
+			(1585521, 'Zoom Video Communications, Inc.', '0001585521-21-000012', '2021-03-19', 7370, 'zm-20210131.htm'),
+			(1341439, 'ORACLE CORP', '0001564590-21-033681', '2021-06-21', 7372, 'orcl-10k_20210531.htm');
+		INSERT INTO sec.company_slugs (slug, cik, name, canonical) VALUES
+			('facebook-inc', 1326801, 'FACEBOOK INC', false),
+			('facebook-inc-1326801', 1326801, 'FACEBOOK INC', false),
+			('meta-platforms-inc-1326801', 1326801, 'Meta Platforms, Inc.', true);`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	companies, err := GetAllCompaniesWithSIC(db, "7370")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []sec.Company{
+		{CompanyName: "Meta Platforms, Inc.", CIKNumber: "1326801", Slug: "meta-platforms-inc-1326801"},
+		{CompanyName: "Zoom Video Communications, Inc.", CIKNumber: "1585521", Slug: "zoom-video-communications-inc-1585521"},
+	}
+	if len(companies) != len(want) {
+		t.Fatalf("GetAllCompaniesWithSIC() = %+v, want %+v", companies, want)
+	}
+	for i := range want {
+		if companies[i] != want[i] {
+			t.Errorf("GetAllCompaniesWithSIC()[%d] = %+v, want %+v", i, companies[i], want[i])
+		}
+	}
+
+	// Companies are counted and listed under the SIC of their latest filing
+	counts, err := GetSICCompanyCounts(db)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[int]int{7370: 2, 7372: 1}; !reflect.DeepEqual(counts, want) {
+		t.Errorf("GetSICCompanyCounts() = %v, want %v", counts, want)
+	}
+
+	presetCompanies, err := GetPresetCompanies(db, TechPreset)
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantPreset := []PresetCompany{
+		{CIK: 1326801, Name: "Meta Platforms, Inc.", SIC: 7370, Slug: "meta-platforms-inc-1326801"},
+		{CIK: 1341439, Name: "ORACLE CORP", SIC: 7372, Slug: "oracle-corp-1341439"},
+		{CIK: 1585521, Name: "Zoom Video Communications, Inc.", SIC: 7370, Slug: "zoom-video-communications-inc-1585521"},
+	}
+	if !reflect.DeepEqual(presetCompanies, wantPreset) {
+		t.Errorf("GetPresetCompanies() = %+v, want %+v", presetCompanies, wantPreset)
+	}
+}
@@ -0,0 +1,263 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package secslug makes the URL slugs of company pages. A company's
+// canonical slug is its latest name followed by its CIK, so two companies
+// with the same name don't collide. Slugs are kept in sec.company_slugs
+// with the slugs of former names and the name-only slugs used before,
+// which redirect to the canonical one.
+package secslug
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/gosimple/slug"
+	"github.com/jmoiron/sqlx"
+)
+
+// ErrSlugNotFound is returned for slugs of no known company
+var ErrSlugNotFound = errors.New("company not found")
+
+// CompanySlug is a row of sec.company_slugs. Non-canonical slugs redirect
+// to the canonical slug of their CIK.
+type CompanySlug struct {
+	Slug      string `db:"slug"`
+	CIK       int    `db:"cik"`
+	Name      string `db:"name"`
+	Canonical bool   `db:"canonical"`
+}
+
+// Make returns the canonical slug of a company named name.
+func Make(name string, cik int) string {
+	base := slug.Make(name)
+	if base == "" {
+		return strconv.Itoa(cik)
+	}
+	return base + "-" + strconv.Itoa(cik)
+}
+
+// Canonical returns slug, the canonical slug stored for a company, or for
+// companies without one yet, i.e. indexed since slugs were last synced, the
+// slug made from name.
+func Canonical(slug string, name string, cik int) string {
+	if slug != "" {
+		return slug
+	}
+	return Make(name, cik)
+}
+
+var trailingCIK = regexp.MustCompile(`(?:^|-)([0-9]+)$`)
+
+// CIKFromSlug returns the CIK a slug made by Make ends with.
+func CIKFromSlug(s string) (int, bool) {
+	match := trailingCIK.FindStringSubmatch(s)
+	if match == nil {
+		return 0, false
+	}
+	cik, err := strconv.Atoi(match[1])
+	if err != nil || cik == 0 {
+		return 0, false
+	}
+	return cik, true
+}
+
+// CompanyName is a name a CIK filed under, last on LastFiled.
+type CompanyName struct {
+	CIK       int       `db:"cik"`
+	Name      string    `db:"name"`
+	LastFiled time.Time `db:"lastfiled"`
+}
+
+// Slugs returns the slugs of the companies from the names they filed under:
+// the canonical slug made from the latest name, the slugs of the former
+// names, and the name-only slugs, each going to the CIK that filed under
+// the name last.
+func Slugs(names []CompanyName) []CompanySlug {
+	latest := make(map[int]CompanyName)
+	var ciks []int
+	for _, name := range names {
+		current, ok := latest[name.CIK]
+		if !ok {
+			ciks = append(ciks, name.CIK)
+		}
+		if !ok || name.LastFiled.After(current.LastFiled) {
+			latest[name.CIK] = name
+		}
+	}
+
+	var slugs []CompanySlug
+	taken := make(map[string]bool)
+	add := func(s CompanySlug) {
+		if s.Slug == "" || taken[s.Slug] {
+			return
+		}
+		taken[s.Slug] = true
+		slugs = append(slugs, s)
+	}
+
+	for _, cik := range ciks {
+		name := latest[cik]
+		add(CompanySlug{Slug: Make(name.Name, cik), CIK: cik, Name: name.Name, Canonical: true})
+	}
+
+	for _, name := range names {
+		add(CompanySlug{Slug: Make(name.Name, name.CIK), CIK: name.CIK, Name: name.Name})
+	}
+
+	// Name-only slugs of names two companies filed under go to the one that
+	// filed under it last
+	lastFiler := make(map[string]CompanyName)
+	var legacy []string
+	for _, name := range names {
+		s := slug.Make(name.Name)
+		current, ok := lastFiler[s]
+		if !ok {
+			legacy = append(legacy, s)
+		}
+		if !ok || name.LastFiled.After(current.LastFiled) {
+			lastFiler[s] = name
+		}
+	}
+	for _, s := range legacy {
+		name := lastFiler[s]
+		add(CompanySlug{Slug: s, CIK: name.CIK, Name: name.Name})
+	}
+
+	return slugs
+}
+
+// GetCompanyNames returns every name CIKs filed under, with the last
+// filing date under it. It's taken from the latest row rather than with
+// MAX(fillingdate), which SQLite returns as text.
+func GetCompanyNames(db *sqlx.DB) ([]CompanyName, error) {
+	var names []CompanyName
+	err := db.Select(&names, `
+		SELECT cik, name, lastfiled
+		FROM (
+			SELECT ciknumber AS cik, companyname AS name, fillingdate AS lastfiled,
+				ROW_NUMBER() OVER (PARTITION BY ciknumber, companyname ORDER BY fillingdate DESC) AS row_number
+			FROM sec.secItemFile
+			WHERE companyname IS NOT NULL AND ciknumber IS NOT NULL AND fillingdate IS NOT NULL
+		) names
+		WHERE row_number = 1
+		ORDER BY cik, lastfiled;`)
+	if err != nil {
+		return nil, err
+	}
+	return names, nil
+}
+
+// Sync stores the slugs of every company. Slugs already stored keep their
+// CIK, so URLs stay stable, and stop being canonical when the company is
+// renamed. Canonical slugs always go to their CIK, links are made from
+// them.
+func Sync(db *sqlx.DB) error {
+	names, err := GetCompanyNames(db)
+	if err != nil {
+		return err
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE sec.company_slugs SET canonical = false WHERE canonical;`)
+	if err != nil {
+		return err
+	}
+
+	for _, s := range Slugs(names) {
+		_, err = tx.Exec(`
+			INSERT INTO sec.company_slugs (slug, cik, name, canonical, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW())
+			ON CONFLICT (slug)
+			DO UPDATE SET
+				cik = CASE WHEN EXCLUDED.canonical THEN EXCLUDED.cik ELSE company_slugs.cik END,
+				name = CASE WHEN EXCLUDED.canonical THEN EXCLUDED.name ELSE company_slugs.name END,
+				canonical = EXCLUDED.canonical,
+				updated_at = NOW();`,
+			s.Slug, s.CIK, s.Name, s.Canonical)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCanonicalSlug returns the canonical slug of the CIK, made from its
+// latest filing if it's not stored yet.
+func GetCanonicalSlug(db *sqlx.DB, cik int) (CompanySlug, error) {
+	var slugs []CompanySlug
+	err := db.Select(&slugs, `
+		SELECT slug, cik, name, canonical
+		FROM sec.company_slugs
+		WHERE cik = $1 AND canonical;`, cik)
+	if err != nil {
+		return CompanySlug{}, err
+	}
+	if len(slugs) > 0 {
+		return slugs[0], nil
+	}
+
+	var names []string
+	err = db.Select(&names, `
+		SELECT companyname
+		FROM sec.secItemFile
+		WHERE ciknumber = $1 AND companyname IS NOT NULL
+		ORDER BY fillingdate DESC NULLS LAST
+		LIMIT 1;`, cik)
+	if err != nil {
+		return CompanySlug{}, err
+	}
+	if len(names) == 0 {
+		return CompanySlug{}, ErrSlugNotFound
+	}
+
+	return CompanySlug{Slug: Make(names[0], cik), CIK: cik, Name: names[0], Canonical: true}, nil
+}
+
+// Resolve returns the canonical slug of the company a slug is of. Slugs
+// not stored yet resolve by the CIK they end with.
+func Resolve(db *sqlx.DB, s string) (CompanySlug, error) {
+	var slugs []CompanySlug
+	err := db.Select(&slugs, `
+		SELECT slug, cik, name, canonical
+		FROM sec.company_slugs
+		WHERE slug = $1;`, s)
+	if err != nil {
+		return CompanySlug{}, err
+	}
+
+	if len(slugs) > 0 {
+		if slugs[0].Canonical {
+			return slugs[0], nil
+		}
+		return GetCanonicalSlug(db, slugs[0].CIK)
+	}
+
+	cik, ok := CIKFromSlug(s)
+	if !ok {
+		return CompanySlug{}, ErrSlugNotFound
+	}
+	return GetCanonicalSlug(db, cik)
+}
+
+// GetCanonicalSlugs returns the canonical slug of every company, ordered
+// by name.
+func GetCanonicalSlugs(db *sqlx.DB) ([]CompanySlug, error) {
+	var slugs []CompanySlug
+	err := db.Select(&slugs, `
+		SELECT slug, cik, name, canonical
+		FROM sec.company_slugs
+		WHERE canonical
+		ORDER BY name, cik;`)
+	if err != nil {
+		return nil, err
+	}
+	return slugs, nil
+}
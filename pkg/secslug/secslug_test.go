@@ -0,0 +1,70 @@
+package secslug
+
+import (
+	"testing"
+	"time"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestMake(t *testing.T) {
+	if got := Make("Meta Platforms, Inc.", 1326801); got != "meta-platforms-inc-1326801" {
+		t.Errorf("Make() = %q, want meta-platforms-inc-1326801", got)
+	}
+	if got := Make("---", 42); got != "42" {
+		t.Errorf("Make() = %q, want 42", got)
+	}
+}
+
+func TestCIKFromSlug(t *testing.T) {
+	tests := map[string]int{
+		"meta-platforms-inc-1326801": 1326801,
+		"42":                         42,
+		"apple-inc":                  0,
+		"3m-co":                      0,
+	}
+
+	for s, want := range tests {
+		cik, ok := CIKFromSlug(s)
+		if cik != want || ok != (want != 0) {
+			t.Errorf("CIKFromSlug(%q) = %v %v, want %v", s, cik, ok, want)
+		}
+	}
+}
+
+func TestSlugs(t *testing.T) {
+	names := []CompanyName{
+		{CIK: 1326801, Name: "FACEBOOK INC", LastFiled: date("2021-10-01")},
+		{CIK: 1326801, Name: "Meta Platforms, Inc.", LastFiled: date("2022-02-03")},
+		// Two companies named the same
+		{CIK: 1, Name: "ACME CORP", LastFiled: date("2010-01-01")},
+		{CIK: 2, Name: "ACME CORP", LastFiled: date("2015-01-01")},
+	}
+
+	got := Slugs(names)
+
+	want := []CompanySlug{
+		{Slug: "meta-platforms-inc-1326801", CIK: 1326801, Name: "Meta Platforms, Inc.", Canonical: true},
+		{Slug: "acme-corp-1", CIK: 1, Name: "ACME CORP", Canonical: true},
+		{Slug: "acme-corp-2", CIK: 2, Name: "ACME CORP", Canonical: true},
+		{Slug: "facebook-inc-1326801", CIK: 1326801, Name: "FACEBOOK INC"},
+		{Slug: "facebook-inc", CIK: 1326801, Name: "FACEBOOK INC"},
+		{Slug: "meta-platforms-inc", CIK: 1326801, Name: "Meta Platforms, Inc."},
+		{Slug: "acme-corp", CIK: 2, Name: "ACME CORP"},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("Slugs() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Slugs()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,42 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package sectest
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+)
+
+// Config writes the config.yaml of sec downloading from e into db, with
+// its caches in a temporary directory and the cache of pages in memory. It
+// returns the directory to pass with --config, and the config.
+func Config(t *testing.T, e *EDGAR, db config.DatabaseConfig) (string, config.Config) {
+	dir := t.TempDir()
+
+	values := map[string]string{
+		"main.baseurl":                         e.URL,
+		"main.cachedir":                        filepath.Join(dir, "cache"),
+		"main.cachedirunpacked":                filepath.Join(dir, "unzipped_cache"),
+		"main.ratelimitms":                     "0",
+		"main.retrylimit":                      "2",
+		"database.driver":                      db.Driver,
+		"database.host":                        db.Host,
+		"database.port":                        fmt.Sprint(db.Port),
+		"database.name":                        db.Name,
+		"database.user":                        db.User,
+		"database.password":                    db.Password,
+		"database.path":                        db.Path,
+		"indexmode.financialstatementdatasets": "disabled",
+		"indexmode.companyfacts":               "disabled",
+		"cache.backend":                        "memory",
+	}
+
+	cfg, err := config.Init(dir, "", values, config.Defaults("sec"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return dir, cfg
+}
@@ -0,0 +1,231 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+package sectest
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/jmoiron/sqlx"
+	_ "github.com/lib/pq"
+)
+
+// ConfigEnv names a config directory, e.g. ./ci, whose Postgres server
+// the tests create their databases on, like the one of the CI service
+const ConfigEnv = "SEC_TEST_CONFIG"
+
+// postgresImage is the image of the containers started by Postgres
+const postgresImage = "postgres:13"
+
+// Postgres returns a new, empty Postgres database, dropped at the end of
+// the test. It's created on the server of the config in $SEC_TEST_CONFIG,
+// or on a server of its own: an embedded one run with initdb and pg_ctl,
+// or a container run with docker. The test is skipped if there's none.
+func Postgres(t *testing.T) config.DatabaseConfig {
+	server, err := postgresServer(t)
+	if err != nil {
+		t.Skipf("no Postgres server: %v", err)
+	}
+
+	db, err := sqlx.Connect("postgres", dbURL(server))
+	if err != nil {
+		t.Skipf("could not connect to Postgres at %v:%v: %v", server.Host, server.Port, err)
+	}
+	defer db.Close()
+
+	name := fmt.Sprintf("sec_test_%d", time.Now().UnixNano())
+	_, err = db.Exec("CREATE DATABASE " + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() {
+		db, err := sqlx.Connect("postgres", dbURL(server))
+		if err != nil {
+			t.Log(err)
+			return
+		}
+		defer db.Close()
+
+		_, err = db.Exec(`SELECT pg_terminate_backend(pid) FROM pg_stat_activity WHERE datname = $1 AND pid <> pg_backend_pid()`, name)
+		if err == nil {
+			_, err = db.Exec("DROP DATABASE " + name)
+		}
+		if err != nil {
+			t.Log("could not drop ", name, ": ", err)
+		}
+	})
+
+	server.Name = name
+	return server
+}
+
+// SQLite returns a new SQLite database in a temporary directory. The test
+// is skipped if sec is built without SQLite.
+func SQLite(t *testing.T) config.DatabaseConfig {
+	if !database.SQLiteEnabled {
+		t.Skip("built without cgo, no SQLite")
+	}
+	return config.DatabaseConfig{
+		Driver: database.SQLite,
+		Path:   filepath.Join(t.TempDir(), "sec.db"),
+	}
+}
+
+// ForEachDatabase runs test on a Postgres and on a SQLite database, as
+// subtests named after the driver
+func ForEachDatabase(t *testing.T, test func(*testing.T, config.DatabaseConfig)) {
+	t.Run("postgres", func(t *testing.T) {
+		test(t, Postgres(t))
+	})
+	t.Run(database.SQLite, func(t *testing.T) {
+		test(t, SQLite(t))
+	})
+}
+
+func postgresServer(t *testing.T) (config.DatabaseConfig, error) {
+	if dir := os.Getenv(ConfigEnv); dir != "" {
+		cfg, err := config.LoadConfig(dir, "")
+		if err != nil {
+			return config.DatabaseConfig{}, err
+		}
+		return cfg.Database, nil
+	}
+
+	if initdb, err := findPostgresBin("initdb"); err == nil {
+		return embeddedPostgres(t, initdb)
+	}
+	if _, err := exec.LookPath("docker"); err == nil {
+		return containerPostgres(t)
+	}
+	return config.DatabaseConfig{}, fmt.Errorf("set %v, or install Postgres or docker", ConfigEnv)
+}
+
+// embeddedPostgres runs a server of its own in a temporary directory,
+// stopped at the end of the test
+func embeddedPostgres(t *testing.T, initdb string) (config.DatabaseConfig, error) {
+	dir := t.TempDir()
+	data := filepath.Join(dir, "data")
+
+	out, err := exec.Command(initdb, "-D", data, "-U", "sec", "--auth=trust", "--no-sync").CombinedOutput()
+	if err != nil {
+		return config.DatabaseConfig{}, fmt.Errorf("initdb: %v: %s", err, out)
+	}
+
+	port, err := freePort()
+	if err != nil {
+		return config.DatabaseConfig{}, err
+	}
+
+	pgCtl := filepath.Join(filepath.Dir(initdb), "pg_ctl")
+	options := fmt.Sprintf("-p %d -k %s -c listen_addresses=127.0.0.1 -c fsync=off", port, dir)
+	out, err = exec.Command(pgCtl, "-D", data, "-o", options, "-l", filepath.Join(dir, "postgres.log"), "-w", "start").CombinedOutput()
+	if err != nil {
+		return config.DatabaseConfig{}, fmt.Errorf("pg_ctl start: %v: %s", err, out)
+	}
+	t.Cleanup(func() {
+		exec.Command(pgCtl, "-D", data, "-m", "immediate", "stop").Run()
+	})
+
+	return config.DatabaseConfig{
+		Driver: "postgres",
+		Host:   "127.0.0.1",
+		Port:   port,
+		Name:   "postgres",
+		User:   "sec",
+	}, nil
+}
+
+// containerPostgres runs a server in a container, removed at the end of
+// the test
+func containerPostgres(t *testing.T) (config.DatabaseConfig, error) {
+	out, err := exec.Command("docker", "run", "--detach", "--rm",
+		"--publish", "127.0.0.1::5432",
+		"--env", "POSTGRES_USER=sec",
+		"--env", "POSTGRES_PASSWORD=sec",
+		postgresImage).Output()
+	if err != nil {
+		return config.DatabaseConfig{}, fmt.Errorf("docker run: %v", err)
+	}
+	id := strings.TrimSpace(string(out))
+	t.Cleanup(func() {
+		exec.Command("docker", "rm", "--force", id).Run()
+	})
+
+	out, err = exec.Command("docker", "port", id, "5432/tcp").Output()
+	if err != nil {
+		return config.DatabaseConfig{}, fmt.Errorf("docker port: %v", err)
+	}
+	// e.g. 127.0.0.1:49153, one line per address
+	line := strings.TrimSpace(string(bytes.SplitN(out, []byte("\n"), 2)[0]))
+	_, portText, err := net.SplitHostPort(line)
+	if err != nil {
+		return config.DatabaseConfig{}, err
+	}
+	port, err := strconv.Atoi(portText)
+	if err != nil {
+		return config.DatabaseConfig{}, err
+	}
+
+	server := config.DatabaseConfig{
+		Driver:   "postgres",
+		Host:     "127.0.0.1",
+		Port:     port,
+		Name:     "postgres",
+		User:     "sec",
+		Password: "sec",
+	}
+
+	// The server restarts once after initializing the database
+	deadline := time.Now().Add(time.Minute)
+	for {
+		db, err := sqlx.Connect("postgres", dbURL(server))
+		if err == nil {
+			db.Close()
+			return server, nil
+		}
+		if time.Now().After(deadline) {
+			return config.DatabaseConfig{}, fmt.Errorf("the container didn't start: %v", err)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// findPostgresBin looks for a Postgres program in PATH, then in the
+// directories Debian and Ubuntu install the servers in
+func findPostgresBin(name string) (string, error) {
+	if bin, err := exec.LookPath(name); err == nil {
+		return bin, nil
+	}
+
+	bins, _ := filepath.Glob(filepath.Join("/usr/lib/postgresql/*/bin", name))
+	if len(bins) == 0 {
+		return "", fmt.Errorf("%v not found", name)
+	}
+	return bins[len(bins)-1], nil
+}
+
+func freePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+func dbURL(db config.DatabaseConfig) string {
+	cfg := config.Config{Database: db}
+	return cfg.DBGetURL()
+}
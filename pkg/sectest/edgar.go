@@ -0,0 +1,213 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package sectest runs sec end to end in tests, against a fake EDGAR
+// server and a throwaway database.
+package sectest
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha1"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"text/template"
+)
+
+// EDGAR is a fake www.sec.gov serving fixture files at the paths sec
+// downloads them from. Like EDGAR, every file has an ETag and a
+// Content-Length, which sec uses to skip files it already has.
+type EDGAR struct {
+	*httptest.Server
+
+	t         *testing.T
+	mu        sync.Mutex
+	files     map[string][]byte
+	templates map[string]string
+	requests  []string
+}
+
+// NewEDGAR starts an empty server, closed at the end of the test
+func NewEDGAR(t *testing.T) *EDGAR {
+	e := &EDGAR{
+		t:         t,
+		files:     make(map[string][]byte),
+		templates: make(map[string]string),
+	}
+	e.Server = httptest.NewServer(http.HandlerFunc(e.serve))
+	t.Cleanup(e.Close)
+	return e
+}
+
+// AddDir serves the files of dir at their path relative to dir. Files
+// ending in .tmpl are served without the suffix as text/templates, see
+// AddTemplate.
+func (e *EDGAR) AddDir(dir string) {
+	err := filepath.Walk(dir, func(file string, info os.FileInfo, err error) error {
+		if err != nil || info.IsDir() {
+			return err
+		}
+
+		rel, err := filepath.Rel(dir, file)
+		if err != nil {
+			return err
+		}
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+
+		p := filepath.ToSlash(rel)
+		if strings.HasSuffix(p, ".tmpl") {
+			e.AddTemplate(strings.TrimSuffix(p, ".tmpl"), string(data))
+			return nil
+		}
+		e.AddFile(p, data)
+		return nil
+	})
+	if err != nil {
+		e.t.Fatal(err)
+	}
+}
+
+// AddFile serves data at p
+func (e *EDGAR) AddFile(p string, data []byte) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.files[clean(p)] = data
+}
+
+// AddTemplate serves text at p, rendered on every request with .URL, the
+// URL of the server to use instead of https://www.sec.gov, and with
+// {{length "path"}}, the size of another file of the server. RSS indexes
+// use them for the enclosures of filings.
+func (e *EDGAR) AddTemplate(p string, text string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.templates[clean(p)] = text
+}
+
+// AddZip serves at p a ZIP file of the files of dir. They're added in the
+// order of names, or sorted if no name is given: sec indexes the members
+// of FSDS files in order, and tag.txt has to come before num.txt.
+func (e *EDGAR) AddZip(p string, dir string, names ...string) {
+	if len(names) == 0 {
+		infos, err := ioutil.ReadDir(dir)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		sort.Strings(names)
+	}
+
+	var buf bytes.Buffer
+	w := zip.NewWriter(&buf)
+	for _, name := range names {
+		data, err := ioutil.ReadFile(filepath.Join(dir, name))
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		f, err := w.Create(name)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+		_, err = f.Write(data)
+		if err != nil {
+			e.t.Fatal(err)
+		}
+	}
+	err := w.Close()
+	if err != nil {
+		e.t.Fatal(err)
+	}
+
+	e.AddFile(p, buf.Bytes())
+}
+
+// File returns the content of the file served at p, e.g. to put it in the
+// cache of sec directly
+func (e *EDGAR) File(p string) []byte {
+	data, ok := e.file(clean(p))
+	if !ok {
+		e.t.Fatalf("EDGAR has no file %v", p)
+	}
+	return data
+}
+
+// Requests returns the method and path of every request served, e.g.
+// "GET /files/company_tickers.json"
+func (e *EDGAR) Requests() []string {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	return append([]string(nil), e.requests...)
+}
+
+func (e *EDGAR) file(p string) ([]byte, bool) {
+	e.mu.Lock()
+	data, ok := e.files[p]
+	text, isTemplate := e.templates[p]
+	e.mu.Unlock()
+
+	if ok || !isTemplate {
+		return data, ok
+	}
+
+	funcs := template.FuncMap{
+		"length": func(other string) (int, error) {
+			data, ok := e.file(clean(other))
+			if !ok {
+				return 0, fmt.Errorf("no file %v", other)
+			}
+			return len(data), nil
+		},
+	}
+	tmpl, err := template.New(p).Funcs(funcs).Parse(text)
+	if err != nil {
+		e.t.Errorf("could not parse %v: %v", p, err)
+		return nil, false
+	}
+
+	var buf bytes.Buffer
+	err = tmpl.Execute(&buf, struct{ URL string }{e.URL})
+	if err != nil {
+		e.t.Errorf("could not render %v: %v", p, err)
+		return nil, false
+	}
+	return buf.Bytes(), true
+}
+
+func (e *EDGAR) serve(w http.ResponseWriter, r *http.Request) {
+	e.mu.Lock()
+	e.requests = append(e.requests, r.Method+" "+r.URL.Path)
+	e.mu.Unlock()
+
+	data, ok := e.file(clean(r.URL.Path))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	w.Header().Set("ETag", fmt.Sprintf(`"%x"`, sha1.Sum(data)))
+	w.Header().Set("Content-Length", fmt.Sprint(len(data)))
+	if r.Method == http.MethodHead {
+		return
+	}
+	w.Write(data)
+}
+
+func clean(p string) string {
+	return path.Clean("/" + p)
+}
@@ -0,0 +1,186 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package sectext
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ledongthuc/pdf"
+)
+
+// DefaultOCRTimeout bounds a run of the OCR command when no timeout is
+// configured
+const DefaultOCRTimeout = 2 * time.Minute
+
+// Extractor extracts the text of the kinds of files it handles. Extractors
+// other than the DocumentExtractor return no offsets, there are no bytes of
+// the file to point to.
+type Extractor interface {
+	CanExtract(filename string) bool
+	Extract(filename string, content []byte) (Text, error)
+}
+
+// DocumentExtractor handles HTML, XML and text documents, see Extract.
+type DocumentExtractor struct{}
+
+func (DocumentExtractor) CanExtract(filename string) bool {
+	return IsExtractable(filename)
+}
+
+func (DocumentExtractor) Extract(filename string, content []byte) (Text, error) {
+	return Extract(filename, bytes.NewReader(content))
+}
+
+// PDFExtractor extracts the text layer of PDF exhibits, page by page.
+// Scanned PDFs have none.
+type PDFExtractor struct{}
+
+func (PDFExtractor) CanExtract(filename string) bool {
+	return strings.ToLower(filepath.Ext(filename)) == ".pdf"
+}
+
+func (PDFExtractor) Extract(filename string, content []byte) (text Text, err error) {
+	// The PDF reader panics on some malformed files
+	defer func() {
+		if r := recover(); r != nil {
+			text, err = Text{}, fmt.Errorf("could not read %v: %v", filename, r)
+		}
+	}()
+
+	reader, err := pdf.NewReader(bytes.NewReader(content), int64(len(content)))
+	if err != nil {
+		return Text{}, err
+	}
+
+	var b builder
+	for i := 1; i <= reader.NumPage(); i++ {
+		page := reader.Page(i)
+		if page.V.IsNull() {
+			continue
+		}
+
+		fonts := make(map[string]*pdf.Font)
+		for _, name := range page.Fonts() {
+			font := page.Font(name)
+			fonts[name] = &font
+		}
+
+		pageText, err := page.GetPlainText(fonts)
+		if err != nil {
+			return Text{}, err
+		}
+
+		b.append(FromPlain(pageText), 0)
+	}
+
+	return Text{Text: b.text().Text}, nil
+}
+
+// OCRExtractor runs an external OCR command, such as tesseract, on image
+// exhibits and takes the text it prints. {file} in Args is replaced with
+// the path of the image.
+type OCRExtractor struct {
+	Command string
+	Args    []string
+	Timeout time.Duration
+}
+
+var imageExtensions = map[string]bool{
+	".jpg": true, ".jpeg": true, ".gif": true, ".png": true, ".tif": true, ".tiff": true, ".bmp": true,
+}
+
+func (o OCRExtractor) CanExtract(filename string) bool {
+	return o.Command != "" && imageExtensions[strings.ToLower(filepath.Ext(filename))]
+}
+
+func (o OCRExtractor) Extract(filename string, content []byte) (Text, error) {
+	// The command reads the image from disk, with its extension so it
+	// knows the format
+	image, err := ioutil.TempFile("", "sec-ocr-*"+strings.ToLower(filepath.Ext(filename)))
+	if err != nil {
+		return Text{}, err
+	}
+	defer os.Remove(image.Name())
+
+	_, err = image.Write(content)
+	if err != nil {
+		image.Close()
+		return Text{}, err
+	}
+	err = image.Close()
+	if err != nil {
+		return Text{}, err
+	}
+
+	args := o.Args
+	if len(args) == 0 {
+		args = []string{"{file}", "stdout"}
+	}
+	var commandArgs []string
+	for _, arg := range args {
+		commandArgs = append(commandArgs, strings.ReplaceAll(arg, "{file}", image.Name()))
+	}
+
+	timeout := o.Timeout
+	if timeout == 0 {
+		timeout = DefaultOCRTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, o.Command, commandArgs...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err = cmd.Run()
+	if err != nil {
+		return Text{}, fmt.Errorf("%v %v: %v: %v", o.Command, filename, err, strings.TrimSpace(stderr.String()))
+	}
+
+	return Text{Text: FromPlain(stdout.String()).Text}, nil
+}
+
+// Extractors tries each extractor in turn.
+type Extractors []Extractor
+
+// NewExtractors returns the extractors for documents and PDFs, and for
+// images if an OCR command is configured.
+func NewExtractors(ocr OCRExtractor) Extractors {
+	extractors := Extractors{DocumentExtractor{}, PDFExtractor{}}
+	if ocr.Command != "" {
+		extractors = append(extractors, ocr)
+	}
+	return extractors
+}
+
+// For returns the extractor handling the file, or nil.
+func (e Extractors) For(filename string) Extractor {
+	for _, extractor := range e {
+		if extractor.CanExtract(filename) {
+			return extractor
+		}
+	}
+	return nil
+}
+
+func (e Extractors) CanExtract(filename string) bool {
+	return e.For(filename) != nil
+}
+
+// Extract extracts the text of the file with the first extractor handling
+// it. Files no extractor handles have no text.
+func (e Extractors) Extract(filename string, content []byte) (Text, error) {
+	extractor := e.For(filename)
+	if extractor == nil {
+		return Text{}, nil
+	}
+	return extractor.Extract(filename, content)
+}
@@ -0,0 +1,95 @@
+package sectext
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+// minimalPDF builds a one page PDF showing text in Helvetica, with a valid
+// cross-reference table.
+func minimalPDF(text string) []byte {
+	stream := fmt.Sprintf("BT /F1 12 Tf 72 712 Td (%v) Tj ET", text)
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R /Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%v\nendstream", len(stream), stream),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+	}
+
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.4\n")
+	var offsets []int
+	for i, object := range objects {
+		offsets = append(offsets, b.Len())
+		fmt.Fprintf(&b, "%d 0 obj\n%v\nendobj\n", i+1, object)
+	}
+
+	xref := b.Len()
+	fmt.Fprintf(&b, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&b, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&b, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+
+	return b.Bytes()
+}
+
+func TestPDFExtractor(t *testing.T) {
+	text, err := PDFExtractor{}.Extract("ex99.pdf", minimalPDF("Quarterly results press release"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(text.Text, "Quarterly results press release") {
+		t.Errorf("Extract() = %q", text.Text)
+	}
+
+	_, err = PDFExtractor{}.Extract("broken.pdf", []byte("%PDF-1.4 not really"))
+	if err == nil {
+		t.Errorf("Extract() of a broken PDF returned no error")
+	}
+}
+
+func TestOCRExtractor(t *testing.T) {
+	echo, err := exec.LookPath("echo")
+	if err != nil {
+		t.Skip("no echo command")
+	}
+
+	ocr := OCRExtractor{Command: echo, Args: []string{"text", "of", "{file}"}}
+	if !ocr.CanExtract("ex99.JPG") || ocr.CanExtract("ex99.pdf") {
+		t.Errorf("CanExtract() picks the wrong files")
+	}
+
+	text, err := ocr.Extract("ex99.jpg", []byte("not really an image"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(text.Text, "text of ") || !strings.HasSuffix(text.Text, ".jpg") {
+		t.Errorf("Extract() = %q", text.Text)
+	}
+}
+
+func TestExtractors(t *testing.T) {
+	extractors := NewExtractors(OCRExtractor{})
+
+	tests := map[string]bool{
+		"aapl-20210925.htm":        true,
+		"0000320193-21-000105.txt": true,
+		"ex99.pdf":                 true,
+		"logo.jpg":                 false,
+		"Financial_Report.xlsx":    false,
+	}
+	for filename, want := range tests {
+		if got := extractors.CanExtract(filename); got != want {
+			t.Errorf("CanExtract(%q) = %v, want %v", filename, got, want)
+		}
+	}
+
+	if !NewExtractors(OCRExtractor{Command: "tesseract"}).CanExtract("logo.jpg") {
+		t.Errorf("CanExtract(%q) = false with an OCR command", "logo.jpg")
+	}
+}
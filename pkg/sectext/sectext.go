@@ -0,0 +1,358 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package sectext extracts the text of filed documents for xbrlbody: HTML
+// and inline XBRL documents, plain text documents and full submission .txt
+// files. Markup, scripts, styles and the hidden inline XBRL header are
+// dropped, tables are kept as one row per line with cells separated by
+// tabs, and whitespace is normalized. Offsets map positions in the text
+// back to byte offsets in the original file, so search hits can point into
+// the document.
+package sectext
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// Offset anchors a position in the text to a byte offset in the original
+// file. Text between two anchors comes from the bytes between them.
+type Offset struct {
+	Text   int
+	Source int
+}
+
+type Text struct {
+	Text    string
+	Offsets []Offset
+}
+
+// SourceOffset returns the byte offset in the original file the position
+// in the text comes from, as closely as the anchors allow.
+func (t Text) SourceOffset(textOffset int) int {
+	i := sort.Search(len(t.Offsets), func(i int) bool {
+		return t.Offsets[i].Text > textOffset
+	})
+	if i == 0 {
+		return 0
+	}
+	anchor := t.Offsets[i-1]
+	return anchor.Source + textOffset - anchor.Text
+}
+
+// Flatten returns the offsets as text, source pairs for a bigint[]
+// column.
+func (t Text) Flatten() []int64 {
+	flat := make([]int64, 0, 2*len(t.Offsets))
+	for _, offset := range t.Offsets {
+		flat = append(flat, int64(offset.Text), int64(offset.Source))
+	}
+	return flat
+}
+
+// Unflatten is the reverse of Flatten.
+func Unflatten(flat []int64) []Offset {
+	var offsets []Offset
+	for i := 0; i+1 < len(flat); i += 2 {
+		offsets = append(offsets, Offset{Text: int(flat[i]), Source: int(flat[i+1])})
+	}
+	return offsets
+}
+
+// Separators between pieces of text, stronger ones win
+const (
+	sepNone = iota
+	sepSpace
+	sepTab
+	sepLine
+	sepParagraph
+)
+
+// builder writes normalized text, recording where each piece comes from.
+// Separators are held back until the next piece of text, so empty cells and
+// empty blocks leave no trace.
+type builder struct {
+	buf     strings.Builder
+	offsets []Offset
+	sep     int
+}
+
+func (b *builder) separate(sep int) {
+	if sep > b.sep {
+		b.sep = sep
+	}
+}
+
+func (b *builder) write(text string, source int) {
+	// Leading and trailing whitespace of the piece only separates words
+	trimmed := strings.TrimLeft(text, whitespace)
+	if len(trimmed) < len(text) {
+		b.separate(sepSpace)
+	}
+	source += len(text) - len(trimmed)
+
+	words := strings.Fields(trimmed)
+	if len(words) == 0 {
+		return
+	}
+
+	if b.buf.Len() > 0 {
+		switch b.sep {
+		case sepSpace:
+			b.buf.WriteByte(' ')
+		case sepTab:
+			b.buf.WriteByte('\t')
+		case sepLine:
+			b.buf.WriteByte('\n')
+		case sepParagraph:
+			b.buf.WriteString("\n\n")
+		}
+	}
+
+	b.offsets = append(b.offsets, Offset{Text: b.buf.Len(), Source: source})
+	b.buf.WriteString(strings.Join(words, " "))
+
+	b.sep = sepNone
+	if strings.TrimRight(trimmed, whitespace) != trimmed {
+		b.sep = sepSpace
+	}
+}
+
+// append adds text extracted separately, e.g. another document of a
+// submission, with its offsets shifted by base.
+func (b *builder) append(t Text, base int) {
+	if t.Text == "" {
+		return
+	}
+	if b.buf.Len() > 0 {
+		b.buf.WriteString("\n\n")
+	}
+	for _, offset := range t.Offsets {
+		b.offsets = append(b.offsets, Offset{Text: b.buf.Len() + offset.Text, Source: base + offset.Source})
+	}
+	b.buf.WriteString(t.Text)
+}
+
+func (b *builder) text() Text {
+	return Text{Text: b.buf.String(), Offsets: b.offsets}
+}
+
+// Whitespace, including the non-breaking spaces used for layout
+const whitespace = " \t\r\n\f\v\u00a0"
+
+var blockSeparators = map[string]int{
+	"p": sepParagraph, "h1": sepParagraph, "h2": sepParagraph, "h3": sepParagraph,
+	"h4": sepParagraph, "h5": sepParagraph, "h6": sepParagraph, "table": sepParagraph,
+	"ul": sepParagraph, "ol": sepParagraph, "blockquote": sepParagraph, "pre": sepParagraph,
+	"center": sepParagraph, "div": sepLine, "br": sepLine, "tr": sepLine, "li": sepLine,
+	"dt": sepLine, "dd": sepLine, "hr": sepLine, "caption": sepLine, "title": sepLine,
+	"td": sepTab, "th": sepTab,
+}
+
+// Elements dropped with their content. ix:header holds the hidden facts of
+// inline XBRL documents.
+var skippedElements = map[string]bool{
+	"head": true, "script": true, "style": true, "noscript": true, "template": true,
+	"object": true, "svg": true, "ix:header": true,
+}
+
+// Elements without an end tag
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true,
+	"img": true, "input": true, "link": true, "meta": true, "param": true,
+	"source": true, "track": true, "wbr": true,
+}
+
+var displayNone = regexp.MustCompile(`(?i)display\s*:\s*none`)
+
+func isHidden(z *html.Tokenizer, hasAttr bool) bool {
+	for hasAttr {
+		var key, val []byte
+		key, val, hasAttr = z.TagAttr()
+		if string(key) == "style" && displayNone.Match(val) {
+			return true
+		}
+	}
+	return false
+}
+
+// FromHTML extracts the text of an HTML or inline XBRL document.
+func FromHTML(r io.Reader) (Text, error) {
+	var b builder
+	err := extractHTML(r, &b)
+	if err != nil {
+		return Text{}, err
+	}
+	return b.text(), nil
+}
+
+func extractHTML(r io.Reader, b *builder) error {
+	type element struct {
+		name    string
+		skipped bool
+	}
+
+	var stack []element
+	skipped := 0
+	offset := 0
+
+	z := html.NewTokenizer(r)
+	for {
+		tokenType := z.Next()
+		raw := len(z.Raw())
+		source := offset
+		offset += raw
+
+		switch tokenType {
+		case html.ErrorToken:
+			if z.Err() == io.EOF {
+				return nil
+			}
+			return z.Err()
+
+		case html.TextToken:
+			if skipped == 0 {
+				b.write(string(z.Text()), source)
+			}
+
+		case html.StartTagToken, html.SelfClosingTagToken:
+			name, hasAttr := z.TagName()
+			tag := strings.ToLower(string(name))
+
+			if sep, ok := blockSeparators[tag]; ok {
+				b.separate(sep)
+			}
+
+			if tokenType == html.SelfClosingTagToken || voidElements[tag] {
+				continue
+			}
+
+			skip := skippedElements[tag] || isHidden(z, hasAttr)
+			if skip {
+				skipped++
+			}
+			stack = append(stack, element{name: tag, skipped: skip})
+
+		case html.EndTagToken:
+			name, _ := z.TagName()
+			tag := strings.ToLower(string(name))
+
+			if sep, ok := blockSeparators[tag]; ok && sep != sepTab {
+				b.separate(sep)
+			}
+
+			// Close the element and whatever was left open inside it, as
+			// older filings don't always close <p> or <td>
+			for i := len(stack) - 1; i >= 0; i-- {
+				if stack[i].name != tag {
+					continue
+				}
+				for _, e := range stack[i:] {
+					if e.skipped {
+						skipped--
+					}
+				}
+				stack = stack[:i]
+				break
+			}
+		}
+	}
+}
+
+var (
+	// Markup of plain text filings: <PAGE>, <TABLE>, <S>, <C>, <CAPTION>, <FN>
+	plainTags = regexp.MustCompile(`(?i)</?(page|table|caption|s|c|fn)>`)
+	// Columns of plain text tables are aligned with runs of spaces
+	columnGap = regexp.MustCompile(`[ \t\x{00a0}]{2,}`)
+)
+
+// FromPlain extracts the text of a plain text document. Lines are kept,
+// with columns aligned by runs of spaces separated by tabs instead, and
+// runs of blank lines are reduced to one.
+func FromPlain(text string) Text {
+	var b builder
+	extractPlain(text, &b)
+	return b.text()
+}
+
+func extractPlain(text string, b *builder) {
+	offset := 0
+	for _, line := range strings.SplitAfter(text, "\n") {
+		lineOffset := offset
+		offset += len(line)
+
+		line = plainTags.ReplaceAllStringFunc(line, func(tag string) string {
+			return strings.Repeat(" ", len(tag))
+		})
+
+		if strings.Trim(line, whitespace) == "" {
+			b.separate(sepParagraph)
+			continue
+		}
+
+		b.separate(sepLine)
+		cursor := 0
+		for i, column := range columnGap.Split(strings.Trim(line, whitespace), -1) {
+			if i > 0 {
+				b.separate(sepTab)
+			}
+			cursor += strings.Index(line[cursor:], column)
+			b.write(column, lineOffset+cursor)
+			cursor += len(column)
+		}
+	}
+}
+
+func isHTML(content []byte) bool {
+	head := bytes.ToLower(content[:minInt(len(content), 1024)])
+	return bytes.Contains(head, []byte("<html")) || bytes.Contains(head, []byte("<!doctype html")) ||
+		bytes.Contains(head, []byte("<body")) || bytes.Contains(head, []byte("<div")) ||
+		bytes.Contains(head, []byte("<xbrl")) || bytes.Contains(head, []byte("<?xml"))
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// IsExtractable reports whether text can be extracted from the file.
+func IsExtractable(filename string) bool {
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".htm", ".html", ".xml", ".txt":
+		return true
+	}
+	return false
+}
+
+// Extract extracts the text of a file, picking how from its name and
+// content: HTML and XML documents, full submissions (.txt starting with
+// <SEC-DOCUMENT>) and plain text documents.
+func Extract(filename string, r io.Reader) (Text, error) {
+	content, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Text{}, err
+	}
+
+	switch strings.ToLower(filepath.Ext(filename)) {
+	case ".htm", ".html", ".xml":
+		return FromHTML(bytes.NewReader(content))
+	case ".txt":
+		if IsSubmission(content) {
+			return FromSubmission(content)
+		}
+		if isHTML(content) {
+			return FromHTML(bytes.NewReader(content))
+		}
+		return FromPlain(string(content)), nil
+	}
+
+	return Text{}, nil
+}
@@ -0,0 +1,120 @@
+package sectext
+
+import (
+	"strings"
+	"testing"
+)
+
+const inlineDocument = `<html xmlns:ix="http://www.xbrl.org/2013/inlineXBRL">
+<head><title>exco-20210925</title><style>p { margin: 0 }</style></head>
+<body>
+<div style="display:none"><ix:header><ix:hidden><ix:nonNumeric name="dei:AmendmentFlag">false</ix:nonNumeric></ix:hidden></ix:header></div>
+<script>var x = 1;</script>
+<div><span style="font-weight:700">Item&#160;7.&#160;&#160;Management&#8217;s   Discussion</span></div>
+<p>Net sales
+   grew <ix:nonFraction name="us-gaap:Revenues" scale="6">365,817</ix:nonFraction> AT&amp;T.</p>
+<table>
+<tr><td>Products</td><td></td><td>$</td><td>297,392</td></tr>
+<tr><td>Services</td><td></td><td>$</td><td>68,425</td></tr>
+</table>
+</body>
+</html>`
+
+func TestFromHTML(t *testing.T) {
+	text, err := FromHTML(strings.NewReader(inlineDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Item 7. Management’s Discussion\n\nNet sales grew 365,817 AT&T.\n\nProducts\t$\t297,392\nServices\t$\t68,425"
+	if text.Text != want {
+		t.Errorf("FromHTML() = %q, want %q", text.Text, want)
+	}
+
+	for _, word := range []string{"Net sales", "365,817", "Services"} {
+		i := strings.Index(text.Text, word)
+		source := text.SourceOffset(i)
+		if !strings.HasPrefix(inlineDocument[source:], word) {
+			t.Errorf("SourceOffset(%d) for %q points to %q", i, word, inlineDocument[source:source+len(word)])
+		}
+	}
+
+	flat := text.Flatten()
+	offsets := Unflatten(flat)
+	if len(offsets) != len(text.Offsets) || offsets[len(offsets)-1] != text.Offsets[len(text.Offsets)-1] {
+		t.Errorf("Unflatten(Flatten()) = %v, want %v", offsets, text.Offsets)
+	}
+}
+
+func TestFromPlain(t *testing.T) {
+	plain := "<PAGE>\n  ANNUAL REPORT\n\n\n\n<TABLE>\n<S>                 <C>\nRevenues            $  1,204\n</TABLE>\n"
+
+	text := FromPlain(plain)
+	want := "ANNUAL REPORT\n\nRevenues\t$\t1,204"
+	if text.Text != want {
+		t.Errorf("FromPlain() = %q, want %q", text.Text, want)
+	}
+
+	i := strings.Index(text.Text, "1,204")
+	if source := text.SourceOffset(i); !strings.HasPrefix(plain[source:], "1,204") {
+		t.Errorf("SourceOffset(%d) points to %q", i, plain[source:])
+	}
+}
+
+const submission = `<SEC-DOCUMENT>0000000000-21-000001.txt : 20211029
+<SEC-HEADER>0000000000-21-000001.hdr.sgml : 20211029
+ACCESSION NUMBER:		0000000000-21-000001
+CONFORMED SUBMISSION TYPE:	10-K
+</SEC-HEADER>
+<DOCUMENT>
+<TYPE>10-K
+<SEQUENCE>1
+<FILENAME>exco-20210925.htm
+<TEXT>
+<XBRL>
+<html><body><p>Annual report body.</p></body></html>
+</XBRL>
+</TEXT>
+</DOCUMENT>
+<DOCUMENT>
+<TYPE>GRAPHIC
+<SEQUENCE>2
+<FILENAME>logo.jpg
+<TEXT>
+begin 644 logo.jpg
+M_]C_X!!02D9)1@!!0$!8!@!!#_VP!#!!@&!@<&!0@'!P<)"0@*#!0-#!L+
+end
+</TEXT>
+</DOCUMENT>
+<DOCUMENT>
+<TYPE>EX-31.1
+<SEQUENCE>3
+<FILENAME>ex311.txt
+<TEXT>
+I, Jane Doe, certify that:
+</TEXT>
+</DOCUMENT>
+</SEC-DOCUMENT>`
+
+func TestFromSubmission(t *testing.T) {
+	if !IsSubmission([]byte(submission)) {
+		t.Fatal("IsSubmission() = false")
+	}
+
+	text, err := Extract("0000000000-21-000001.txt", strings.NewReader(submission))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := "Annual report body.\n\nI, Jane Doe, certify that:"
+	if text.Text != want {
+		t.Errorf("FromSubmission() = %q, want %q", text.Text, want)
+	}
+
+	for _, word := range []string{"Annual report", "I, Jane"} {
+		i := strings.Index(text.Text, word)
+		if source := text.SourceOffset(i); !strings.HasPrefix(submission[source:], word) {
+			t.Errorf("SourceOffset(%d) for %q points to %q", i, word, submission[source:])
+		}
+	}
+}
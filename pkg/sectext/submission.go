@@ -0,0 +1,58 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package sectext
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+
+	"github.com/equres/sec/pkg/sgml"
+)
+
+// Document types of a submission without text worth indexing
+var skippedTypes = map[string]bool{
+	"GRAPHIC": true, "ZIP": true, "EXCEL": true, "PDF": true, "XML": true, "JSON": true,
+}
+
+// IsSubmission reports whether content is a full submission .txt, the
+// SGML file EDGAR keeps with every document of a filing.
+func IsSubmission(content []byte) bool {
+	return sgml.IsSubmission(content)
+}
+
+// FromSubmission extracts the text of the documents of a full submission,
+// one after the other. The SGML header, binary documents (uuencoded images,
+// PDFs, ZIPs) and XBRL files are left out.
+func FromSubmission(content []byte) (Text, error) {
+	submission, err := sgml.Parse(content)
+	if errors.Is(err, sgml.ErrNoDocuments) {
+		return Text{}, nil
+	}
+	if err != nil {
+		return Text{}, err
+	}
+
+	var b builder
+	for _, doc := range submission.Documents {
+		if skippedTypes[strings.ToUpper(doc.Type)] || strings.HasPrefix(strings.ToUpper(doc.Type), "EX-101") {
+			continue
+		}
+		if doc.Uuencoded {
+			continue
+		}
+
+		var text Text
+		if isHTML(doc.Content) {
+			text, err = FromHTML(bytes.NewReader(doc.Content))
+			if err != nil {
+				return Text{}, err
+			}
+		} else {
+			text = FromPlain(string(doc.Content))
+		}
+
+		b.append(text, doc.Offset)
+	}
+
+	return b.text(), nil
+}
@@ -0,0 +1,320 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package secticker
+
+import (
+	"database/sql"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/equres/sec/pkg/seccik"
+	"github.com/jmoiron/sqlx"
+)
+
+// TickerChanges are the differences between the tickers listed in a
+// refresh of the tickers file and the ticker history.
+type TickerChanges struct {
+	Listed   []SecTicker
+	Delisted []seccik.TickerSpan
+	Moved    []SecTicker
+}
+
+func tickerKey(cik int, ticker string) string {
+	return strconv.Itoa(cik) + "|" + strings.ToUpper(ticker)
+}
+
+// DiffTickers compares the tickers listed now with the current spans of
+// the history: tickers not in the history are newly listed, current spans
+// not listed anymore are delisted and tickers listed on another exchange
+// have moved.
+func DiffTickers(current []seccik.TickerSpan, listed []SecTicker) TickerChanges {
+	var changes TickerChanges
+
+	spans := make(map[string]seccik.TickerSpan)
+	for _, span := range current {
+		spans[tickerKey(span.CIK, span.Ticker)] = span
+	}
+
+	seen := make(map[string]bool)
+	for _, ticker := range listed {
+		if ticker.Ticker == "" {
+			continue
+		}
+		key := tickerKey(ticker.Cik, ticker.Ticker)
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		span, ok := spans[key]
+		switch {
+		case !ok:
+			changes.Listed = append(changes.Listed, ticker)
+		case ticker.Exchange != "" && ticker.Exchange != span.Exchange:
+			changes.Moved = append(changes.Moved, ticker)
+		}
+	}
+
+	for _, span := range current {
+		if !seen[tickerKey(span.CIK, span.Ticker)] {
+			changes.Delisted = append(changes.Delisted, span)
+		}
+	}
+
+	return changes
+}
+
+// RecordTickers updates the ticker history with the tickers listed in the
+// tickers file on date: new tickers start on date, tickers missing from
+// the file end on date.
+func RecordTickers(db *sqlx.DB, listed []SecTicker, date time.Time) error {
+	// An empty or truncated download would delist every company
+	if len(listed) == 0 {
+		return fmt.Errorf("no tickers listed, not updating the ticker history")
+	}
+
+	var current []seccik.TickerSpan
+	err := db.Select(&current, `
+		SELECT cik, ticker, exchange, valid_from, valid_to
+		FROM sec.ticker_history
+		WHERE valid_to IS NULL;`)
+	if err != nil {
+		return err
+	}
+
+	changes := DiffTickers(current, listed)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	date = day(date)
+
+	for _, ticker := range changes.Listed {
+		_, err = tx.Exec(`
+			INSERT INTO sec.ticker_history (cik, ticker, exchange, valid_from, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW());`, ticker.Cik, ticker.Ticker, ticker.Exchange, date)
+		if err != nil {
+			return err
+		}
+	}
+
+	// Tickers are matched in any case, like in DiffTickers. The parameters
+	// are numbered in the order they appear, SQLite binds them by position.
+	for _, span := range changes.Delisted {
+		validTo := date
+		if validTo.Before(span.ValidFrom) {
+			validTo = span.ValidFrom
+		}
+		_, err = tx.Exec(`
+			UPDATE sec.ticker_history
+			SET valid_to = $1, updated_at = NOW()
+			WHERE cik = $2 AND UPPER(ticker) = UPPER($3) AND valid_to IS NULL;`, validTo, span.CIK, span.Ticker)
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, ticker := range changes.Moved {
+		_, err = tx.Exec(`
+			UPDATE sec.ticker_history
+			SET exchange = $1, updated_at = NOW()
+			WHERE cik = $2 AND UPPER(ticker) = UPPER($3) AND valid_to IS NULL;`, ticker.Exchange, ticker.Cik, ticker.Ticker)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// NameObservation is a name a CIK was seen under on a date: in a filing,
+// in the FSDS data or in the tickers file.
+type NameObservation struct {
+	CIK  int       `db:"cik"`
+	Name string    `db:"name"`
+	Date time.Time `db:"date"`
+}
+
+var nameNoise = regexp.MustCompile(`[^A-Z0-9]+`)
+
+// normalizeName makes spellings of the same name from different sources
+// compare equal: "Apple Inc." and "APPLE INC".
+func normalizeName(name string) string {
+	return strings.TrimSpace(nameNoise.ReplaceAllString(strings.ToUpper(name), " "))
+}
+
+// NameSpans turns observations into the names each CIK had over time. A
+// name starts when it's first seen after another one and ends when the
+// next one is first seen. The latest spelling of a name is kept.
+func NameSpans(observations []NameObservation) []seccik.NameSpan {
+	observations = append([]NameObservation{}, observations...)
+	sort.SliceStable(observations, func(i, j int) bool {
+		if observations[i].CIK != observations[j].CIK {
+			return observations[i].CIK < observations[j].CIK
+		}
+		return observations[i].Date.Before(observations[j].Date)
+	})
+
+	var spans []seccik.NameSpan
+	for _, observation := range observations {
+		if normalizeName(observation.Name) == "" {
+			continue
+		}
+
+		// A name seen on the same day the previous one started replaces it
+		for len(spans) > 0 {
+			last := spans[len(spans)-1]
+			if last.CIK != observation.CIK || !last.ValidFrom.Equal(observation.Date) || normalizeName(last.Name) == normalizeName(observation.Name) {
+				break
+			}
+			spans = spans[:len(spans)-1]
+			if len(spans) > 0 && spans[len(spans)-1].CIK == observation.CIK {
+				spans[len(spans)-1].ValidTo.Valid = false
+			}
+		}
+
+		if len(spans) > 0 {
+			last := &spans[len(spans)-1]
+			if last.CIK == observation.CIK {
+				if normalizeName(last.Name) == normalizeName(observation.Name) {
+					last.Name = observation.Name
+					continue
+				}
+				last.ValidTo.Time, last.ValidTo.Valid = observation.Date, true
+			}
+		}
+
+		spans = append(spans, seccik.NameSpan{
+			CIK:       observation.CIK,
+			Name:      observation.Name,
+			ValidFrom: observation.Date,
+		})
+	}
+
+	return spans
+}
+
+// GetNameObservations returns every name CIKs were seen under. FSDS
+// submissions carry the former name and the date it changed, which pins
+// down when the name changed. Dates are worked out here rather than with
+// Postgres date functions, so it runs on SQLite too.
+func GetNameObservations(db *sqlx.DB) ([]NameObservation, error) {
+	var subs []struct {
+		CIK     int          `db:"cik"`
+		Name    string       `db:"name"`
+		Former  string       `db:"former"`
+		Changed string       `db:"changed"`
+		Filled  sql.NullTime `db:"filled"`
+	}
+	err := db.Select(&subs, `
+		SELECT cik, COALESCE(name, '') AS name, COALESCE(former, '') AS former, COALESCE(changed, '') AS changed, filled
+		FROM fsds.sub;`)
+	if err != nil {
+		return nil, err
+	}
+
+	var observations []NameObservation
+	for _, sub := range subs {
+		if sub.Name != "" && sub.Filled.Valid {
+			observations = append(observations, NameObservation{CIK: sub.CIK, Name: sub.Name, Date: day(sub.Filled.Time)})
+		}
+
+		changed, err := time.Parse("20060102", sub.Changed)
+		if err != nil || sub.Former == "" {
+			continue
+		}
+		observations = append(observations, NameObservation{CIK: sub.CIK, Name: sub.Former, Date: changed.AddDate(0, 0, -1)})
+		if sub.Name != "" {
+			observations = append(observations, NameObservation{CIK: sub.CIK, Name: sub.Name, Date: changed})
+		}
+	}
+
+	var filings []NameObservation
+	err = db.Select(&filings, `
+		SELECT DISTINCT ciknumber AS cik, companyname AS name, fillingdate AS date
+		FROM sec.secItemFile
+		WHERE companyname <> '' AND fillingdate IS NOT NULL;`)
+	if err != nil {
+		return nil, err
+	}
+	for _, filing := range filings {
+		filing.Date = day(filing.Date)
+		observations = append(observations, filing)
+	}
+
+	var tickers []struct {
+		CIK       int          `db:"cik"`
+		Name      string       `db:"name"`
+		CreatedAt sql.NullTime `db:"created_at"`
+		UpdatedAt sql.NullTime `db:"updated_at"`
+	}
+	err = db.Select(&tickers, `
+		SELECT cik, title AS name, created_at, updated_at
+		FROM sec.tickers
+		WHERE title <> '';`)
+	if err != nil {
+		return nil, err
+	}
+	for _, ticker := range tickers {
+		seen := ticker.UpdatedAt
+		if !seen.Valid {
+			seen = ticker.CreatedAt
+		}
+		if !seen.Valid {
+			continue
+		}
+		observations = append(observations, NameObservation{CIK: ticker.CIK, Name: ticker.Name, Date: day(seen.Time)})
+	}
+
+	sort.SliceStable(observations, func(i, j int) bool {
+		if observations[i].CIK != observations[j].CIK {
+			return observations[i].CIK < observations[j].CIK
+		}
+		return observations[i].Date.Before(observations[j].Date)
+	})
+	return observations, nil
+}
+
+// day is the date of t, without the time
+func day(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// RebuildNameHistory recomputes the names of every CIK from what's been
+// indexed so far.
+func RebuildNameHistory(db *sqlx.DB) error {
+	observations, err := GetNameObservations(db)
+	if err != nil {
+		return err
+	}
+
+	spans := NameSpans(observations)
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`DELETE FROM sec.name_history;`)
+	if err != nil {
+		return err
+	}
+
+	for _, span := range spans {
+		_, err = tx.Exec(`
+			INSERT INTO sec.name_history (cik, name, valid_from, valid_to, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, NOW(), NOW());`, span.CIK, span.Name, span.ValidFrom, span.ValidTo)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
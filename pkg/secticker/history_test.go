@@ -0,0 +1,139 @@
+package secticker
+
+import (
+	"database/sql"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/seccik"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func TestDiffTickers(t *testing.T) {
+	current := []seccik.TickerSpan{
+		{CIK: 1, Ticker: "FB", Exchange: "Nasdaq", ValidFrom: date("2012-05-18")},
+		{CIK: 2, Ticker: "TWTR", Exchange: "NYSE", ValidFrom: date("2013-11-07")},
+		{CIK: 3, Ticker: "AAPL", Exchange: "", ValidFrom: date("2021-01-01")},
+	}
+	listed := []SecTicker{
+		{Cik: 1, Ticker: "META", Exchange: "Nasdaq"},
+		{Cik: 3, Ticker: "aapl", Exchange: "Nasdaq"},
+		{Cik: 3, Ticker: "AAPL", Exchange: "Nasdaq"},
+	}
+
+	changes := DiffTickers(current, listed)
+
+	if len(changes.Listed) != 1 || changes.Listed[0].Ticker != "META" {
+		t.Errorf("Listed = %v, want META", changes.Listed)
+	}
+	if len(changes.Delisted) != 2 || changes.Delisted[0].Ticker != "FB" || changes.Delisted[1].Ticker != "TWTR" {
+		t.Errorf("Delisted = %v, want FB and TWTR", changes.Delisted)
+	}
+	if len(changes.Moved) != 1 || changes.Moved[0].Cik != 3 || changes.Moved[0].Exchange != "Nasdaq" {
+		t.Errorf("Moved = %v, want AAPL to Nasdaq", changes.Moved)
+	}
+}
+
+func TestNameSpans(t *testing.T) {
+	observations := []NameObservation{
+		{CIK: 320193, Name: "APPLE INC", Date: date("2008-01-10")},
+		{CIK: 320193, Name: "APPLE COMPUTER INC", Date: date("2006-11-01")},
+		{CIK: 320193, Name: "APPLE COMPUTER INC", Date: date("2007-01-08")},
+		{CIK: 320193, Name: "Apple Inc.", Date: date("2021-10-29")},
+		{CIK: 1, Name: "OLD NAME", Date: date("2010-01-01")},
+		// Renamed on the day of the filing, the FSDS data has both
+		{CIK: 1, Name: "NEW NAME", Date: date("2015-03-02")},
+		{CIK: 1, Name: "TEMPORARY NAME", Date: date("2015-03-02")},
+		{CIK: 1, Name: "FINAL NAME", Date: date("2015-03-02")},
+	}
+
+	got := NameSpans(observations)
+
+	want := []seccik.NameSpan{
+		{CIK: 1, Name: "OLD NAME", ValidFrom: date("2010-01-01"), ValidTo: sql.NullTime{Time: date("2015-03-02"), Valid: true}},
+		{CIK: 1, Name: "FINAL NAME", ValidFrom: date("2015-03-02")},
+		{CIK: 320193, Name: "APPLE COMPUTER INC", ValidFrom: date("2006-11-01"), ValidTo: sql.NullTime{Time: date("2008-01-10"), Valid: true}},
+		{CIK: 320193, Name: "Apple Inc.", ValidFrom: date("2008-01-10")},
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("NameSpans() = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("NameSpans()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestRecordTickers(t *testing.T) {
+	cfg := config.Config{Database: sectest.SQLite(t)}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Tickers recorded in lower case by older versions
+	_, err = db.Exec(`
+		INSERT INTO sec.ciks (cik) VALUES (320193), (1326801), (1418091);
+		INSERT INTO sec.ticker_history (cik, ticker, exchange, valid_from) VALUES
+			(320193, 'aapl', 'Nasdaq', '2021-01-04'),
+			(1326801, 'fb', 'Nasdaq', '2021-01-04'),
+			(1418091, 'twtr', 'NYSE', '2021-01-04');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	listed := []SecTicker{
+		{Cik: 320193, Ticker: "AAPL", Exchange: "NYSE"},
+		{Cik: 1326801, Ticker: "META", Exchange: "Nasdaq"},
+		{Cik: 1418091, Ticker: "TWTR", Exchange: "NYSE"},
+	}
+	err = RecordTickers(db, listed, date("2022-06-09"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var spans []seccik.TickerSpan
+	err = db.Select(&spans, `
+		SELECT cik, ticker, exchange, valid_from, valid_to
+		FROM sec.ticker_history
+		ORDER BY cik, valid_from, ticker;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []seccik.TickerSpan{
+		{CIK: 320193, Ticker: "aapl", Exchange: "NYSE", ValidFrom: date("2021-01-04")},
+		{CIK: 1326801, Ticker: "fb", Exchange: "Nasdaq", ValidFrom: date("2021-01-04"), ValidTo: sql.NullTime{Time: date("2022-06-09"), Valid: true}},
+		{CIK: 1326801, Ticker: "META", Exchange: "Nasdaq", ValidFrom: date("2022-06-09")},
+		{CIK: 1418091, Ticker: "twtr", Exchange: "NYSE", ValidFrom: date("2021-01-04")},
+	}
+	if len(spans) != len(want) {
+		t.Fatalf("ticker history = %v, want %v", spans, want)
+	}
+	for i, w := range want {
+		got := spans[i]
+		if got.CIK != w.CIK || got.Ticker != w.Ticker || got.Exchange != w.Exchange || !got.ValidFrom.Equal(w.ValidFrom) ||
+			got.ValidTo.Valid != w.ValidTo.Valid || !got.ValidTo.Time.Equal(w.ValidTo.Time) {
+			t.Errorf("ticker history[%d] = %+v, want %+v", i, got, w)
+		}
+	}
+}
@@ -62,6 +62,12 @@ func UpdateAll(s *sec.SEC, db *sqlx.DB) error {
 	if err != nil {
 		return err
 	}
+
+	err = RebuildNameHistory(db)
+	if err != nil {
+		secevent.CreateIndexEvent(db, "name_history", "failed", "error_rebuilding_name_history")
+		return err
+	}
 	return nil
 }
 
@@ -124,6 +130,12 @@ func ExchangeFileGet(s *sec.SEC, db *sqlx.DB) error {
 	}
 	defer file.Close()
 
+	// The tickers were listed as of the download
+	info, err := file.Stat()
+	if err != nil {
+		return err
+	}
+
 	data, err := ioutil.ReadAll(file)
 	if err != nil {
 		return err
@@ -146,6 +158,7 @@ func ExchangeFileGet(s *sec.SEC, db *sqlx.DB) error {
 		}
 	}
 
+	var listed []SecTicker
 	for _, v := range fileExchange.Data {
 		// Below is because sometimes the exchange is empty (nil). Added lines to ensure no error when saving
 		cik := 0
@@ -179,6 +192,13 @@ func ExchangeFileGet(s *sec.SEC, db *sqlx.DB) error {
 			secevent.CreateIndexEvent(db, "company_tickers_exchange.json", "failed", "error_inserting_ticker_in_database")
 			return err
 		}
+		listed = append(listed, sec)
+	}
+
+	err = RecordTickers(db, listed, info.ModTime())
+	if err != nil {
+		secevent.CreateIndexEvent(db, "company_tickers_exchange.json", "failed", "error_recording_ticker_history")
+		return err
 	}
 
 	s.Log("\u2713")
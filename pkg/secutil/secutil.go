@@ -12,6 +12,7 @@ import (
 	"net/url"
 	"os"
 	"path/filepath"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -221,17 +222,32 @@ func SearchByFilingDate(db *sqlx.DB, startdate time.Time, enddate time.Time) ([]
 	return secItemFiles, nil
 }
 
+// GetFilingDaysFromMonthYear returns the days of month with filings. Days
+// are compared with DATE() and taken from the dates here rather than with
+// EXTRACT, so it runs on SQLite too.
 func GetFilingDaysFromMonthYear(db *sqlx.DB, year int, month int) ([]int, error) {
-	days := []int{}
-	err := db.Select(&days, `
-		SELECT DISTINCT EXTRACT(day from fillingdate)
+	start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, time.UTC)
+
+	var dates []time.Time
+	err := db.Select(&dates, `
+		SELECT DISTINCT fillingdate
 		FROM sec.secItemFile 
-		WHERE EXTRACT(year from fillingdate) = $1
-		AND EXTRACT(month from fillingdate) = $2
-	`, year, month)
+		WHERE DATE(fillingdate) >= $1
+		AND DATE(fillingdate) < $2
+	`, start.Format("2006-01-02"), start.AddDate(0, 1, 0).Format("2006-01-02"))
 	if err != nil {
 		return nil, err
 	}
+
+	days := []int{}
+	seen := make(map[int]bool)
+	for _, date := range dates {
+		if !seen[date.Day()] {
+			seen[date.Day()] = true
+			days = append(days, date.Day())
+		}
+	}
+	sort.Ints(days)
 	return days, nil
 }
 
@@ -240,11 +256,9 @@ func GetFilingCompaniesFromYearMonthDay(db *sqlx.DB, year int, month int, day in
 	err := db.Select(&items, `
 		SELECT DISTINCT companyname, ciknumber
 		FROM sec.secItemFile 
-		WHERE EXTRACT(year from fillingdate) = $1
-		AND EXTRACT(month from fillingdate) = $2
-		AND EXTRACT(day from fillingdate) = $3
+		WHERE DATE(fillingdate) = $1
 		AND companyname IS NOT NULL;
-	`, year, month, day)
+	`, filingDay(year, month, day))
 	if err != nil {
 		return nil, err
 	}
@@ -259,17 +273,20 @@ func SearchFilingsByYearMonthDayCIK(db *sqlx.DB, year int, month int, day int, c
 		LEFT JOIN sec.tickers
 		ON sec.secitemfile.ciknumber = sec.tickers.cik
 		WHERE sec.secItemFile.companyname IS NOT NULL 
-		AND EXTRACT(year from fillingdate) = $1
-		AND EXTRACT(month from fillingdate) = $2
-		AND EXTRACT(day from fillingdate) = $3
-		AND sec.secItemFile.cikNumber = $4;
-	`, year, month, day, cik)
+		AND DATE(fillingdate) = $1
+		AND sec.secItemFile.cikNumber = $2;
+	`, filingDay(year, month, day), cik)
 	if err != nil {
 		return nil, err
 	}
 	return secItemFiles, nil
 }
 
+// filingDay is the date compared with DATE(fillingdate)
+func filingDay(year int, month int, day int) string {
+	return time.Date(year, time.Month(month), day, 0, 0, 0, 0, time.UTC).Format("2006-01-02")
+}
+
 func CreateFilesFromZIP(s *sec.SEC, zipPath string, files []*zip.File) error {
 	unpackedCachePath := filepath.Dir(filepath.Join(s.Config.Main.CacheDirUnpacked, zipPath))
 	for _, file := range files {
@@ -474,7 +491,7 @@ func IsCurrentYearQuarterCorrect(year int, quarter int) bool {
 
 func GetFailedDownloadEventCount(db *sqlx.DB) (int, error) {
 	var count []int
-	err := db.Select(&count, "SELECT COUNT(*) FROM sec.events WHERE ev ->> 'event' = 'download' AND ev ->> 'status' = 'failed'")
+	err := db.Select(&count, secevent.Query(db, "SELECT COUNT(*) FROM sec.events WHERE ev->>'event' = 'download' AND ev->>'status' = 'failed'"))
 	if err != nil {
 		return 0, err
 	}
@@ -488,7 +505,7 @@ func GetFailedDownloadEventCount(db *sqlx.DB) (int, error) {
 
 func GetSuccessfulDownloadEventCount(db *sqlx.DB) (int, error) {
 	var count []int
-	err := db.Select(&count, "SELECT COUNT(*) FROM sec.events WHERE ev ->> 'event' = 'download' AND ev ->> 'status' = 'success'")
+	err := db.Select(&count, secevent.Query(db, "SELECT COUNT(*) FROM sec.events WHERE ev->>'event' = 'download' AND ev->>'status' = 'success'"))
 	if err != nil {
 		return 0, err
 	}
@@ -531,16 +548,16 @@ func GetFiveRecentFilings(db *sqlx.DB) ([]sec.SECItemFile, error) {
 	var secitemfiles []sec.SECItemFile
 
 	err := db.Select(&secitemfiles, `
-	SELECT * FROM (
-		SELECT DISTINCT ON (ciknumber)
-		ciknumber, companyname, formtype, fillingdate, xbrlurl
+	SELECT ciknumber, companyname, formtype, fillingdate, xbrlurl FROM (
+		SELECT ciknumber, companyname, formtype, fillingdate, xbrlurl,
+			ROW_NUMBER() OVER (PARTITION BY ciknumber) AS row_number
 		FROM sec.secitemfile
-		WHERE RIGHT(xbrlurl, 3) = 'htm' 
-			OR RIGHT(xbrlurl, 4) = 'html'
-			OR RIGHT(xbrlurl, 3) = 'xml'
+		WHERE xbrlurl LIKE '%htm'
+			OR xbrlurl LIKE '%html'
+			OR xbrlurl LIKE '%xml'
 			AND companyname IS NOT NULL
-		ORDER BY ciknumber desc
 	) distinct_ciks
+	WHERE row_number = 1
 	ORDER BY fillingdate desc
 	LIMIT 5;`)
 	if err != nil {
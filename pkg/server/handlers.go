@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,11 +21,19 @@ import (
 	"github.com/equres/sec/pkg/config"
 	"github.com/equres/sec/pkg/sec"
 	"github.com/equres/sec/pkg/seccik"
+	"github.com/equres/sec/pkg/secdiff"
 	"github.com/equres/sec/pkg/secevent"
+	"github.com/equres/sec/pkg/secfiling"
+	"github.com/equres/sec/pkg/secgeo"
+	"github.com/equres/sec/pkg/secmfd"
+	"github.com/equres/sec/pkg/secscreener"
+	"github.com/equres/sec/pkg/secsearch"
+	"github.com/equres/sec/pkg/secsection"
 	"github.com/equres/sec/pkg/secsic"
+	"github.com/equres/sec/pkg/secslug"
+	"github.com/equres/sec/pkg/secutil"
 	"github.com/equres/sec/pkg/secworklist"
 	"github.com/gorilla/mux"
-	"github.com/gosimple/slug"
 )
 
 func (s Server) GenerateRouter() (*mux.Router, error) {
@@ -34,31 +43,47 @@ func (s Server) GenerateRouter() (*mux.Router, error) {
 	if err != nil {
 		return nil, err
 	}
-	router.PathPrefix("/_assets").Handler(http.StripPrefix("/_assets", http.FileServer(http.FS(assets))))
+	assetsHandler, err := AssetsHandler(assets)
+	if err != nil {
+		return nil, err
+	}
+	router.PathPrefix("/_assets").Handler(http.StripPrefix("/_assets", assetsHandler))
+	router.Use(Compress)
 
 	router.HandleFunc("/", s.HandlerHome).Methods("GET")
 	router.HandleFunc("/about", s.HandlerAbout).Methods("GET")
 	router.HandleFunc("/help", s.HandlerHelp).Methods("GET")
 	router.HandleFunc("/signup", s.HandlerSignUp).Methods("GET")
-	router.HandleFunc("/filings/{year}", s.HandlerMonthsPage).Methods("GET")
-	router.HandleFunc("/filings/{year}/{month}", s.HandlerDaysPage).Methods("GET")
-	router.HandleFunc("/filings/{year}/{month}/{day}", s.HandlerCompaniesPage).Methods("GET")
-	router.HandleFunc("/filings/{year}/{month}/{day}/{cik}", s.HandlerFilingsPage).Methods("GET")
-	router.HandleFunc("/company", s.HandlerCompaniesListPage).Methods("GET")
-	router.HandleFunc("/company/{companySlug}", s.HandlerCompanyFilingsPage).Methods("GET")
-	router.HandleFunc("/sic", s.HandlerSICListPage).Methods("GET")
-	router.HandleFunc("/sic/{sic}", s.HandlerSICCompaniesPage).Methods("GET")
+	router.Handle("/filings/{year}", s.CachePage(s.HandlerMonthsPage)).Methods("GET")
+	router.Handle("/filings/{year}/{month}", s.CachePage(s.HandlerDaysPage)).Methods("GET")
+	router.Handle("/filings/{year}/{month}/{day}", s.CachePage(s.HandlerCompaniesPage)).Methods("GET")
+	router.Handle("/filings/{year}/{month}/{day}/{cik}", s.CachePage(s.HandlerFilingsPage)).Methods("GET")
+	router.Handle("/company", s.CachePage(s.HandlerCompaniesListPage)).Methods("GET")
+	router.Handle("/company/{companySlug}", s.CachePage(s.HandlerCompanyFilingsPage)).Methods("GET")
+	router.Handle("/sic", s.CachePage(s.HandlerSICListPage)).Methods("GET")
+	router.Handle("/sic/{sic}", s.CachePage(s.HandlerSICCompaniesPage)).Methods("GET")
+	router.HandleFunc("/sic/presets/{preset}", s.HandlerSICPresetPage).Methods("GET")
+	router.HandleFunc("/screener", s.HandlerScreenerPage).Methods("GET")
+	router.HandleFunc("/states", s.HandlerStatesPage).Methods("GET")
+	router.HandleFunc("/states/{st}", s.HandlerStateCompaniesPage).Methods("GET")
+	router.HandleFunc("/funds", s.HandlerFundsPage).Methods("GET")
+	router.HandleFunc("/fund/{series}/{class}", s.HandlerFundPage).Methods("GET")
+	router.Handle("/filing/{accession}", s.CachePage(s.HandlerFilingPage)).Methods("GET")
+	router.HandleFunc("/diff", s.HandlerDiffPage).Methods("GET")
+	router.HandleFunc("/search", s.HandlerSearchPage).Methods("GET")
 	router.HandleFunc("/stats", s.HandlerStatsPage).Methods("GET")
 	router.HandleFunc("/backup/stats", s.HandlerBackupStatsPage).Methods("GET")
 	router.HandleFunc("/download/stats", s.HandlerDownloadStatsPage).Methods("GET")
 	router.HandleFunc("/url/stats", s.GetStatistics).Methods("GET")
 	router.HandleFunc("/dashboard", s.HandlerDashboard).Methods("GET")
 	router.HandleFunc("/api/v1/uptime", s.HandlerUptime).Methods("GET")
+	router.HandleFunc("/api/v1/ready", s.HandlerReady).Methods("GET")
 	router.HandleFunc("/api/v1/stats", s.HandlerStatsAPI).Methods("GET")
 	router.HandleFunc("/api/v1/stats/backup", s.HandlerBackupStatsAPI).Methods("GET")
 	router.HandleFunc("/api/v1/stats/downloads/past-week", s.HandlerDownloadStatsAPI).Methods("GET")
 	router.HandleFunc("/api/v1/stats/indexes/past-week", s.HandlerIndexStatsAPI).Methods("GET")
 	router.HandleFunc("/api/v1/stats/save", s.Statistics).Methods("POST")
+	router.HandleFunc("/api/v1/companies/suggest", s.HandlerCompanySuggestAPI).Methods("GET")
 	router.PathPrefix("/").HandlerFunc(s.HandlerFiles)
 	return router, nil
 }
@@ -170,7 +195,9 @@ func (s Server) HandlerMonthsPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	monthsJSON, err := s.Cache.MustGet(fmt.Sprintf("%v_%v", cache.SECMonthsInYear, year))
+	monthsJSON, err := s.Cache.GetOrFill(cache.MonthsInYearKey(year), func() (string, error) {
+		return s.SECCache.GenerateMonthsInYearJSON(year)
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -207,7 +234,9 @@ func (s Server) HandlerDaysPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	daysJSON, err := s.Cache.MustGet(fmt.Sprintf("%v_%v_%v", cache.SECDaysInMonth, year, month))
+	daysJSON, err := s.Cache.GetOrFill(cache.DaysInMonthKey(year, month), func() (string, error) {
+		return s.SECCache.GenerateDaysInMonthJSON(year, month)
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -265,7 +294,9 @@ func (s Server) HandlerCompaniesPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	companiesJSON, err := s.Cache.MustGet(fmt.Sprintf("%v_%v_%v_%v", cache.SECCompaniesInDay, year, month, day))
+	companiesJSON, err := s.Cache.GetOrFill(cache.CompaniesInDayKey(year, month, day), func() (string, error) {
+		return s.SECCache.GenerateCompaniesInDayJSON(year, month, day)
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -321,7 +352,9 @@ func (s Server) HandlerFilingsPage(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	filingsJSON, err := s.Cache.MustGet(fmt.Sprintf("%v_%v_%v_%v_%v", cache.SECFilingsInDay, year, month, day, cik))
+	filingsJSON, err := s.Cache.GetOrFill(cache.FilingsInDayKey(year, month, day, cik), func() (string, error) {
+		return s.SECCache.GenerateFilingsInDayJSON(year, month, day, cik)
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -362,7 +395,7 @@ func (s Server) HandlerFilingsPage(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s Server) HandlerCompaniesListPage(w http.ResponseWriter, r *http.Request) {
-	companiesHTML, err := s.Cache.MustGet(cache.SECCompanySlugsHTML)
+	companiesHTML, err := s.Cache.GetOrFill(cache.SECCompanySlugsHTML, s.SECCache.GenerateCompanySlugsHTML)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -381,30 +414,30 @@ func (s Server) HandlerCompaniesListPage(w http.ResponseWriter, r *http.Request)
 }
 
 func (s Server) HandlerCompanyFilingsPage(w http.ResponseWriter, r *http.Request) {
-	companiesJSON, err := s.Cache.MustGet(fmt.Sprintf("%v", cache.SECCompanies))
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	vars := mux.Vars(r)
+	companySlug := vars["companySlug"]
+
+	canonical, err := secslug.Resolve(s.DB, companySlug)
+	if errors.Is(err, secslug.ErrSlugNotFound) {
+		http.NotFound(w, r)
 		return
 	}
-
-	var companies []sec.Company
-	err = json.Unmarshal([]byte(companiesJSON), &companies)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	vars := mux.Vars(r)
-	companySlug := vars["companySlug"]
-	company := GetCompanyFromSlug(companies, companySlug)
-
-	cik, err := strconv.Atoi(company.CIKNumber)
-	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	// Former names and the name-only slugs used before go to the current page
+	if canonical.Slug != companySlug {
+		http.Redirect(w, r, "/company/"+canonical.Slug, http.StatusMovedPermanently)
 		return
 	}
 
-	filingsHTML, err := s.Cache.MustGet(fmt.Sprintf("%v_%v", cache.SECCompanyFilingsHTML, cik))
+	cik := canonical.CIK
+
+	filingsHTML, err := s.Cache.GetOrFill(cache.CompanyFilingsHTMLKey(cik), func() (string, error) {
+		return s.SECCache.GenerateCompanyFilingsHTML(cik)
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -428,11 +461,39 @@ func (s Server) HandlerCompanyFilingsPage(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	names, err := seccik.GetNameHistory(s.DB, cik)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var formerNames []seccik.NameSpan
+	for _, name := range names {
+		if !name.Current() {
+			formerNames = append(formerNames, name)
+		}
+	}
+
+	tickers, err := seccik.GetTickerHistory(s.DB, cik)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var formerTickers []seccik.TickerSpan
+	for _, ticker := range tickers {
+		if !ticker.Current() {
+			formerTickers = append(formerTickers, ticker)
+		}
+	}
+
 	content := make(map[string]interface{})
 	content["FilingsHTML"] = filingsGeneratedHTML
 	content["CompanyTicker"] = companyTicker
 	content["CompanyName"] = companyName
 	content["CompanySlug"] = companySlug
+	content["FormerNames"] = formerNames
+	content["FormerTickers"] = formerTickers
 
 	err = s.RenderTemplate(w, "companyfilings.page.gohtml", content)
 	if err != nil {
@@ -442,21 +503,22 @@ func (s Server) HandlerCompanyFilingsPage(w http.ResponseWriter, r *http.Request
 }
 
 func (s Server) HandlerSICListPage(w http.ResponseWriter, r *http.Request) {
-	sicJSON, err := s.Cache.MustGet(cache.SECSICs)
+	treeJSON, err := s.Cache.GetOrFill(cache.SECSICTree, s.SECCache.GenerateSICTreeJSON)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
-	var sics []sec.SIC
-	err = json.Unmarshal([]byte(sicJSON), &sics)
+	var tree []secsic.Division
+	err = json.Unmarshal([]byte(treeJSON), &tree)
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
 	content := make(map[string]interface{})
-	content["SICs"] = sics
+	content["Divisions"] = tree
+	content["Presets"] = []secsic.Preset{secsic.TechPreset}
 
 	err = s.RenderTemplate(w, "sicslist.page.gohtml", content)
 	if err != nil {
@@ -465,11 +527,47 @@ func (s Server) HandlerSICListPage(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+func (s Server) HandlerSICPresetPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	preset, ok := secsic.Presets[vars["preset"]]
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown SIC preset: %v", vars["preset"]), http.StatusNotFound)
+		return
+	}
+
+	companiesJSON, err := s.Cache.GetOrFill(cache.SICPresetCompaniesKey(preset.Name), func() (string, error) {
+		return s.SECCache.GenerateSICPresetCompaniesJSON(preset.Name)
+	})
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var companies []secsic.PresetCompany
+	err = json.Unmarshal([]byte(companiesJSON), &companies)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["Preset"] = preset
+	content["Companies"] = companies
+
+	err = s.RenderTemplate(w, "sicpreset.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
 func (s Server) HandlerSICCompaniesPage(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	sic := vars["sic"]
 
-	companiesJSON, err := s.Cache.MustGet(fmt.Sprintf("%v_%v", cache.SECCompaniesWithSIC, sic))
+	companiesJSON, err := s.Cache.GetOrFill(cache.CompaniesWithSICKey(sic), func() (string, error) {
+		return s.SECCache.GenerateCompaniesWithSICJSON(sic)
+	})
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
@@ -488,18 +586,16 @@ func (s Server) HandlerSICCompaniesPage(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	companySlugs := GetCompanySlugs(companies)
-	var allCompanies []sec.Company
-	for slug, companyName := range companySlugs {
-		allCompanies = append(allCompanies, sec.Company{
-			CompanyName: companyName.CompanyName,
-			Slug:        slug,
-		})
+	naics, err := secsic.GetNAICSFromSIC(s.DB, sic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	content := make(map[string]interface{})
-	content["Companies"] = allCompanies
+	content["Companies"] = companies
 	content["CategoryName"] = categoryName
+	content["NAICS"] = naics
 
 	err = s.RenderTemplate(w, "companieslistsic.page.gohtml", content)
 	if err != nil {
@@ -508,6 +604,249 @@ func (s Server) HandlerSICCompaniesPage(w http.ResponseWriter, r *http.Request)
 	}
 }
 
+func (s Server) HandlerScreenerPage(w http.ResponseWriter, r *http.Request) {
+	filter := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	content := make(map[string]interface{})
+	content["Filter"] = filter
+
+	if filter != "" {
+		ctx, cancel := context.WithTimeout(r.Context(), secscreener.Timeout)
+		defer cancel()
+
+		q, results, err := secscreener.Screen(ctx, s.DB, filter, secscreener.DefaultLimit)
+		if err != nil {
+			content["Error"] = err.Error()
+		}
+		content["Tags"] = q.Tags
+		content["Results"] = results
+	}
+
+	err := s.RenderTemplate(w, "screener.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (s Server) HandlerStatesPage(w http.ResponseWriter, r *http.Request) {
+	counts, err := secgeo.GetStateCounts(s.DB)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["States"] = counts
+	content["Map"] = secgeo.RenderChoropleth(counts)
+
+	err = s.RenderTemplate(w, "states.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (s Server) HandlerStateCompaniesPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	state, ok := secgeo.GetState(vars["st"])
+	if !ok {
+		http.Error(w, fmt.Sprintf("unknown state: %v", vars["st"]), http.StatusNotFound)
+		return
+	}
+
+	sic := r.URL.Query().Get("sic")
+
+	companies, err := secgeo.GetStateCompanies(s.DB, state.Code, sic)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	sics, err := secgeo.GetStateSICCounts(s.DB, state.Code)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["State"] = state
+	content["SIC"] = sic
+	content["SICs"] = sics
+	content["Companies"] = companies
+
+	err = s.RenderTemplate(w, "statecompanies.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (s Server) HandlerFundsPage(w http.ResponseWriter, r *http.Request) {
+	fee := r.URL.Query().Get("fee")
+
+	page := 1
+	if r.URL.Query().Get("page") != "" {
+		var err error
+		page, err = strconv.Atoi(r.URL.Query().Get("page"))
+		if err != nil || page < 1 {
+			http.Error(w, "please choose a proper page", http.StatusBadRequest)
+			return
+		}
+	}
+
+	funds, err := secmfd.GetFunds(s.DB, fee, page)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["Funds"] = funds
+	content["Fee"] = fee
+	content["Page"] = page
+	content["Offset"] = (page - 1) * secmfd.PageSize
+	if page > 1 {
+		content["PrevPage"] = page - 1
+	}
+	if len(funds) == secmfd.PageSize {
+		content["NextPage"] = page + 1
+	}
+
+	err = s.RenderTemplate(w, "funds.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (s Server) HandlerFundPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	fund, err := secmfd.GetFundDetails(s.DB, vars["series"], vars["class"])
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["Fund"] = fund
+
+	err = s.RenderTemplate(w, "fund.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+func (s Server) HandlerFilingPage(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	accession := vars["accession"]
+
+	if !secfiling.IsAccessionNumber(accession) {
+		http.Error(w, fmt.Sprintf("invalid accession number: %v", accession), http.StatusBadRequest)
+		return
+	}
+
+	filing, err := secfiling.GetFiling(s.DB, accession)
+	if err == secfiling.ErrFilingNotFound {
+		http.Error(w, fmt.Sprintf("filing %v not found", accession), http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["Filing"] = filing
+	content["FullFormType"] = secutil.GetFullFormType(filing.FormType)
+	content["Documents"] = secfiling.RenderDocuments(s.SECCache.S, filing)
+
+	sections, err := secsection.Get(s.DB, accession)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	content["Sections"] = sections
+
+	err = s.RenderTemplate(w, "filing.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+// HandlerDiffPage compares filing a with filing b (/diff?a=...&b=...). With
+// only b, it is compared with the previous filing of the same form.
+func (s Server) HandlerDiffPage(w http.ResponseWriter, r *http.Request) {
+	accessionA := strings.TrimSpace(r.URL.Query().Get("a"))
+	accessionB := strings.TrimSpace(r.URL.Query().Get("b"))
+
+	var sections []string
+	for _, section := range strings.Split(r.URL.Query().Get("section"), ",") {
+		section = strings.TrimSpace(section)
+		if section != "" {
+			sections = append(sections, section)
+		}
+	}
+
+	content := make(map[string]interface{})
+	content["A"] = accessionA
+	content["B"] = accessionB
+	content["Section"] = strings.Join(sections, ",")
+
+	if accessionB != "" {
+		for _, accession := range []string{accessionA, accessionB} {
+			if accession != "" && !secfiling.IsAccessionNumber(accession) {
+				http.Error(w, fmt.Sprintf("invalid accession number: %v", accession), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if accessionA == "" {
+			filingB, err := secfiling.GetFiling(s.DB, accessionB)
+			if err == secfiling.ErrFilingNotFound {
+				http.Error(w, fmt.Sprintf("filing %v not found", accessionB), http.StatusNotFound)
+				return
+			}
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+
+			accessionA, err = secdiff.PreviousFiling(s.DB, filingB)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			if accessionA == "" {
+				http.Error(w, fmt.Sprintf("no %v filed before %v", filingB.FormType, accessionB), http.StatusNotFound)
+				return
+			}
+			content["A"] = accessionA
+		}
+
+		result, err := secdiff.Compare(s.DB, accessionA, accessionB, sections)
+		if errors.Is(err, secfiling.ErrFilingNotFound) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		content["Result"] = result
+	}
+
+	err := s.RenderTemplate(w, "diff.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
 func (s Server) HandlerStatsPage(w http.ResponseWriter, r *http.Request) {
 	content := make(map[string]interface{})
 
@@ -612,6 +951,90 @@ func (s Server) HandlerUptime(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, "OK: ", GlobalUptime)
 }
 
+// HandlerReady tells load balancers whether to send traffic: unlike
+// /api/v1/uptime it fails while the server is draining or when Postgres or
+// Redis can't be reached.
+func (s Server) HandlerReady(w http.ResponseWriter, r *http.Request) {
+	if s.IsShuttingDown() {
+		http.Error(w, "shutting down", http.StatusServiceUnavailable)
+		return
+	}
+
+	err := s.DB.PingContext(r.Context())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("database: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	err = s.Cache.Ping()
+	if err != nil {
+		http.Error(w, fmt.Sprintf("redis: %v", err), http.StatusServiceUnavailable)
+		return
+	}
+
+	fmt.Fprint(w, "OK")
+}
+
+// HandlerSearchPage lists the companies matching q (/search?q=...), for the
+// search form of every page.
+func (s Server) HandlerSearchPage(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+
+	suggestions, err := secsearch.Suggest(s.DB, query, secsearch.MaxLimit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// A single exact match goes straight to the company
+	exact := func(i int) bool {
+		return i < len(suggestions) && suggestions[i].Score >= 3
+	}
+	if exact(0) && !exact(1) {
+		http.Redirect(w, r, "/company/"+suggestions[0].Slug, http.StatusFound)
+		return
+	}
+
+	content := make(map[string]interface{})
+	content["Query"] = query
+	content["Suggestions"] = suggestions
+
+	err = s.RenderTemplate(w, "search.page.gohtml", content)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+}
+
+// HandlerCompanySuggestAPI returns the companies matching q as JSON, best
+// first (/api/v1/companies/suggest?q=...&limit=...).
+func (s Server) HandlerCompanySuggestAPI(w http.ResponseWriter, r *http.Request) {
+	limit := secsearch.DefaultLimit
+	if value := r.URL.Query().Get("limit"); value != "" {
+		var err error
+		limit, err = strconv.Atoi(value)
+		if err != nil {
+			http.Error(w, fmt.Sprintf("invalid limit: %v", value), http.StatusBadRequest)
+			return
+		}
+	}
+
+	suggestions, err := secsearch.Suggest(s.DB, r.URL.Query().Get("q"), limit)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if suggestions == nil {
+		suggestions = []secsearch.Suggestion{}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	err = json.NewEncoder(w).Encode(suggestions)
+	if err != nil {
+		log.Error(err)
+	}
+}
+
 func (s Server) HandlerStatsAPI(w http.ResponseWriter, r *http.Request) {
 	statsJSON, err := s.Cache.Get(cache.SECCacheStats)
 	if err != nil {
@@ -652,51 +1075,6 @@ func (s Server) HandlerIndexStatsAPI(w http.ResponseWriter, r *http.Request) {
 	fmt.Fprint(w, statsJSON)
 }
 
-func (s Server) RenderTemplate(w http.ResponseWriter, tmplName string, data interface{}) error {
-	funcMap := template.FuncMap{
-		"formatAccession": func(accession string) string {
-			return strings.ReplaceAll(accession, "-", "")
-		},
-		"AppVersion": func() string {
-			return fmt.Sprintf("%s %s", s.SHA1Ver, s.BuildTime)
-		},
-		"Increment": func(i int) int {
-			return i + 1
-		},
-		"StatsCSSColor": func(i int) string {
-			if i == 0 {
-				return "red"
-			}
-			if i < 100 {
-				return "orange"
-			}
-			return "green"
-		},
-		// Make a function to return "Never" if the value is empty
-		"IfDateEmpty": func(value string) string {
-			if value == "" {
-				return "Never"
-			}
-			return value
-		},
-		"WebsiteURL": func() string {
-			return s.Config.Main.WebsiteURL
-		},
-	}
-
-	tmpl, err := template.New("tmpl").Funcs(funcMap).ParseFS(s.TemplatesFS, "templates/"+tmplName, "templates/base.layout.gohtml")
-	if err != nil {
-		return err
-	}
-
-	err = tmpl.ExecuteTemplate(w, "base", data)
-	if err != nil {
-		return err
-	}
-
-	return nil
-}
-
 func getIntVar(vars map[string]string, varName string) (int, error) {
 	varStr, ok := vars[varName]
 	if !ok {
@@ -711,22 +1089,6 @@ func getIntVar(vars map[string]string, varName string) (int, error) {
 	return value, nil
 }
 
-func GetCompanySlugs(companies []sec.Company) map[string]sec.Company {
-	companySlugs := make(map[string]sec.Company)
-	for _, company := range companies {
-		companySlugs[slug.Make(company.CompanyName)] = company
-	}
-	return companySlugs
-}
-
-func GetCompanyFromSlug(companies []sec.Company, companySlug string) sec.Company {
-	companySlugs := GetCompanySlugs(companies)
-	if company, ok := companySlugs[companySlug]; ok {
-		return company
-	}
-	return sec.Company{}
-}
-
 func (s Server) GetHourlyDownloadStatsFromRedis(redisConfig config.RedisConfig) (map[string][]secevent.DownloadEventStatsByHour, error) {
 	hourlyDownloadStatsJSON, err := s.Cache.Get(cache.SECHourlyDownloadStats)
 	if err != nil {
@@ -793,39 +1155,21 @@ func (s Server) Statistics(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	keyPrefix := "stats:"
-
-	s.Cache.Redis.HIncrBy(r.Context(), keyPrefix+data.URL, "count", 1)
+	err = s.Cache.IncrURLStat(data.URL)
+	if err != nil {
+		log.Error("could not count the visit of ", data.URL, ": ", err)
+	}
 
 	w.WriteHeader(http.StatusOK)
 }
 
 func (s Server) GetStatistics(w http.ResponseWriter, r *http.Request) {
-	keyPrefix := "stats:"
-
-	keys, err := s.Cache.Redis.Keys(r.Context(), keyPrefix+"*").Result()
+	stats, err := s.Cache.URLStats()
 	if err != nil {
 		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
-
-	stats := make(map[string]int)
-
-	for _, key := range keys {
-		count, err := s.Cache.Redis.HGet(r.Context(), key, "count").Int()
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
-			return
-		}
-
-		url := strings.TrimPrefix(key, keyPrefix)
-
-		if url == "" {
-			continue
-		}
-
-		stats[url] = count
-	}
+	delete(stats, "")
 
 	content := make(map[string]interface{})
 	content["URLStats"] = stats
@@ -0,0 +1,306 @@
+package server
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/andybalholm/brotli"
+	"github.com/equres/sec/pkg/cache"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	// DefaultPageCacheTTL is how long rendered pages are kept in Redis when
+	// pagecachettl is not set in the config file
+	DefaultPageCacheTTL = 10 * time.Minute
+
+	// How long browsers may reuse a cached page or an asset before asking
+	// again with If-None-Match
+	PageMaxAge   = 5 * time.Minute
+	AssetsMaxAge = 24 * time.Hour
+)
+
+// Content types worth compressing. Everything else served from the cache
+// directory (ZIPs, images, PDFs) is already compressed.
+var compressibleTypes = map[string]bool{
+	"text/html":              true,
+	"text/plain":             true,
+	"text/css":               true,
+	"text/csv":               true,
+	"text/javascript":        true,
+	"text/xml":               true,
+	"application/javascript": true,
+	"application/json":       true,
+	"application/xml":        true,
+	"application/xhtml+xml":  true,
+	"image/svg+xml":          true,
+}
+
+// NegotiateEncoding picks the response encoding from the Accept-Encoding
+// header: brotli if accepted, then gzip, otherwise none ("").
+func NegotiateEncoding(acceptEncoding string) string {
+	accepted := make(map[string]bool)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		fields := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(fields[0]))
+
+		q := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				value, err := strconv.ParseFloat(strings.TrimPrefix(param, "q="), 64)
+				if err == nil {
+					q = value
+				}
+			}
+		}
+
+		accepted[coding] = q > 0
+	}
+
+	for _, coding := range []string{"br", "gzip"} {
+		if accepted[coding] {
+			return coding
+		}
+	}
+	return ""
+}
+
+type compressWriter struct {
+	http.ResponseWriter
+	encoding string
+	encoder  io.WriteCloser
+	decided  bool
+}
+
+// decide enables compression once the status and content type are known
+func (cw *compressWriter) decide(status int) {
+	if cw.decided {
+		return
+	}
+	cw.decided = true
+
+	header := cw.Header()
+	if status < 200 || status == http.StatusNoContent || status == http.StatusNotModified || status == http.StatusPartialContent {
+		return
+	}
+	if header.Get("Content-Encoding") != "" {
+		return
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	if !compressibleTypes[mediaType] {
+		return
+	}
+
+	header.Set("Content-Encoding", cw.encoding)
+	header.Del("Content-Length")
+
+	switch cw.encoding {
+	case "br":
+		cw.encoder = brotli.NewWriterLevel(cw.ResponseWriter, brotli.DefaultCompression)
+	case "gzip":
+		cw.encoder = gzip.NewWriter(cw.ResponseWriter)
+	}
+}
+
+func (cw *compressWriter) WriteHeader(status int) {
+	cw.decide(status)
+	cw.ResponseWriter.WriteHeader(status)
+}
+
+func (cw *compressWriter) Write(b []byte) (int, error) {
+	if !cw.decided {
+		if cw.Header().Get("Content-Type") == "" {
+			cw.Header().Set("Content-Type", http.DetectContentType(b))
+		}
+		cw.WriteHeader(http.StatusOK)
+	}
+
+	if cw.encoder != nil {
+		return cw.encoder.Write(b)
+	}
+	return cw.ResponseWriter.Write(b)
+}
+
+func (cw *compressWriter) Close() error {
+	if cw.encoder != nil {
+		return cw.encoder.Close()
+	}
+	return nil
+}
+
+// Compress encodes text responses with brotli or gzip, depending on what
+// the client accepts. Range requests are left alone, as the ranges refer
+// to the uncompressed content.
+func Compress(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Add("Vary", "Accept-Encoding")
+
+		encoding := NegotiateEncoding(r.Header.Get("Accept-Encoding"))
+		if encoding == "" || r.Header.Get("Range") != "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		cw := &compressWriter{ResponseWriter: w, encoding: encoding}
+		defer func() {
+			err := cw.Close()
+			if err != nil {
+				log.Error("could not compress the response of ", r.URL.Path, ": ", err)
+			}
+		}()
+
+		next.ServeHTTP(cw, r)
+	})
+}
+
+func contentETag(b []byte) string {
+	sum := sha1.Sum(b)
+	return fmt.Sprintf(`"%v"`, hex.EncodeToString(sum[:]))
+}
+
+// AssetsHandler serves the embedded /_assets with an ETag computed from
+// their content, so browsers revalidate them only when a deploy changed
+// them. The files have no modification time, so Last-Modified is the time
+// the server started.
+func AssetsHandler(assets fs.FS) (http.Handler, error) {
+	etags := make(map[string]string)
+	err := fs.WalkDir(assets, ".", func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		content, err := fs.ReadFile(assets, path)
+		if err != nil {
+			return err
+		}
+		etags["/"+path] = contentETag(content)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	lastModified := time.Now().UTC().Format(http.TimeFormat)
+	fileServer := http.FileServer(http.FS(assets))
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if etag, ok := etags[r.URL.Path]; ok {
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Last-Modified", lastModified)
+			w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(AssetsMaxAge.Seconds())))
+		}
+		fileServer.ServeHTTP(w, r)
+	}), nil
+}
+
+// CachedPage is the rendered HTML of a page stored in Redis
+type CachedPage struct {
+	Body         []byte
+	ContentType  string
+	ETag         string
+	LastModified time.Time
+}
+
+// pageRecorder buffers the response of a handler so it can be cached
+type pageRecorder struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (pr *pageRecorder) Header() http.Header {
+	return pr.header
+}
+
+func (pr *pageRecorder) WriteHeader(status int) {
+	pr.status = status
+}
+
+func (pr *pageRecorder) Write(b []byte) (int, error) {
+	return pr.body.Write(b)
+}
+
+func (s Server) pageCacheTTL() time.Duration {
+	return durationOrDefault(s.Config.Main.PageCacheTTL, DefaultPageCacheTTL)
+}
+
+// CachePage serves the rendered HTML of the page from Redis and renders it
+// with next on a miss. Pages are keyed by path, so only pages without query
+// parameters should be cached. Errors aren't cached, and nothing is cached
+// in dev mode.
+func (s Server) CachePage(next http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if s.Config.Main.TemplatesDir != "" {
+			next(w, r)
+			return
+		}
+
+		key := cache.PageKey(r.URL.Path)
+
+		pageJSON, err := s.Cache.Get(key)
+		if err == nil {
+			var page CachedPage
+			err = json.Unmarshal([]byte(pageJSON), &page)
+			if err == nil {
+				s.servePage(w, r, page)
+				return
+			}
+		}
+
+		recorder := &pageRecorder{header: make(http.Header), status: http.StatusOK}
+		next(recorder, r)
+
+		if recorder.status != http.StatusOK {
+			for k, v := range recorder.header {
+				w.Header()[k] = v
+			}
+			w.WriteHeader(recorder.status)
+			_, _ = recorder.body.WriteTo(w)
+			return
+		}
+
+		page := CachedPage{
+			Body:         recorder.body.Bytes(),
+			ContentType:  recorder.header.Get("Content-Type"),
+			ETag:         contentETag(recorder.body.Bytes()),
+			LastModified: time.Now().UTC().Truncate(time.Second),
+		}
+
+		pageData, err := json.Marshal(page)
+		if err == nil {
+			err = s.Cache.SetWithTTL(key, string(pageData), s.pageCacheTTL())
+		}
+		if err != nil {
+			log.Error("could not cache the page ", r.URL.Path, ": ", err)
+		}
+
+		s.servePage(w, r, page)
+	})
+}
+
+// servePage writes a cached page with its validators. http.ServeContent
+// answers If-None-Match and If-Modified-Since with 304 Not Modified.
+func (s Server) servePage(w http.ResponseWriter, r *http.Request, page CachedPage) {
+	contentType := page.ContentType
+	if contentType == "" {
+		contentType = "text/html; charset=utf-8"
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("ETag", page.ETag)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", int(PageMaxAge.Seconds())))
+
+	http.ServeContent(w, r, "", page.LastModified, bytes.NewReader(page.Body))
+}
@@ -0,0 +1,136 @@
+package server
+
+import (
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/equres/sec/pkg/cache"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	tests := map[string]string{
+		"":                             "",
+		"identity":                     "",
+		"gzip":                         "gzip",
+		"gzip, deflate, br":            "br",
+		"br;q=0, gzip":                 "gzip",
+		"GZIP;q=0.5":                   "gzip",
+		"br;q=0.0, gzip;q=0":           "",
+		"deflate, gzip;q=1.0, *;q=0.5": "gzip",
+	}
+
+	for acceptEncoding, want := range tests {
+		got := NegotiateEncoding(acceptEncoding)
+		if got != want {
+			t.Errorf("NegotiateEncoding(%q) = %q, want %q", acceptEncoding, got, want)
+		}
+	}
+}
+
+func newTestServer() Server {
+	return Server{Cache: cache.NewMemory(1<<20, "", 0)}
+}
+
+func TestCachePage(t *testing.T) {
+	s := newTestServer()
+
+	calls := 0
+	page := s.CachePage(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/broken" {
+			http.Error(w, "broken", http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte("<p>companies</p>"))
+	})
+
+	get := func(path string, etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		page.ServeHTTP(w, r)
+		return w
+	}
+
+	first := get("/company", "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || first.Body.String() != "<p>companies</p>" || etag == "" {
+		t.Fatalf("first request: %v %q with ETag %q", first.Code, first.Body.String(), etag)
+	}
+
+	second := get("/company", "")
+	if second.Code != http.StatusOK || second.Body.String() != "<p>companies</p>" || second.Header().Get("ETag") != etag {
+		t.Errorf("second request: %v %q with ETag %q", second.Code, second.Body.String(), second.Header().Get("ETag"))
+	}
+	if calls != 1 {
+		t.Errorf("the page was rendered %d times, want 1", calls)
+	}
+
+	notModified := get("/company", etag)
+	if notModified.Code != http.StatusNotModified || notModified.Body.Len() != 0 {
+		t.Errorf("request with If-None-Match: %v %q, want 304", notModified.Code, notModified.Body.String())
+	}
+
+	// Errors aren't cached
+	get("/broken", "")
+	broken := get("/broken", "")
+	if broken.Code != http.StatusInternalServerError || calls != 3 {
+		t.Errorf("broken page: %v after %d renders, want 500 after 3", broken.Code, calls)
+	}
+}
+
+func TestCompress(t *testing.T) {
+	body := strings.Repeat("<p>Total net sales</p>", 100)
+	handler := Compress(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/logo.png" {
+			w.Header().Set("Content-Type", "image/png")
+		}
+		w.Write([]byte(body))
+	}))
+
+	get := func(path string, acceptEncoding string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest(http.MethodGet, path, nil)
+		r.Header.Set("Accept-Encoding", acceptEncoding)
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		"gzip": func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		"br":   func(r io.Reader) (io.Reader, error) { return brotli.NewReader(r), nil },
+	}
+	for encoding, decoder := range decoders {
+		w := get("/", encoding)
+		if w.Header().Get("Content-Encoding") != encoding || w.Header().Get("Vary") != "Accept-Encoding" {
+			t.Errorf("%v: Content-Encoding %q, Vary %q", encoding, w.Header().Get("Content-Encoding"), w.Header().Get("Vary"))
+			continue
+		}
+		r, err := decoder(w.Body)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := ioutil.ReadAll(r)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(decoded) != body {
+			t.Errorf("%v: decoded %d bytes, want the body", encoding, len(decoded))
+		}
+	}
+
+	for _, w := range []*httptest.ResponseRecorder{get("/", ""), get("/logo.png", "gzip")} {
+		if w.Header().Get("Content-Encoding") != "" || w.Body.String() != body {
+			t.Errorf("got Content-Encoding %q, want an uncompressed body", w.Header().Get("Content-Encoding"))
+		}
+	}
+}
@@ -1,15 +1,23 @@
 package server
 
 import (
+	"context"
 	"embed"
 	"fmt"
+	"html/template"
+	"io/fs"
 	"net/http"
+	"path/filepath"
+	"sync/atomic"
 	"time"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/crypto/acme/autocert"
 
 	"github.com/equres/sec/pkg/cache"
 	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/seccache"
 	"github.com/jmoiron/sqlx"
 )
 
@@ -20,6 +28,16 @@ var (
 	GlobalAssetsFS  embed.FS
 )
 
+// Defaults for the timeouts that are not set in the config file
+const (
+	DefaultReadHeaderTimeout = 5 * time.Second
+	DefaultReadTimeout       = 15 * time.Second
+	DefaultWriteTimeout      = 60 * time.Second
+	DefaultIdleTimeout       = 120 * time.Second
+	DefaultShutdownTimeout   = 30 * time.Second
+	DefaultDrainDelay        = 5 * time.Second
+)
+
 type Server struct {
 	DB          *sqlx.DB
 	Config      config.Config
@@ -27,28 +45,86 @@ type Server struct {
 	SHA1Ver     string
 	BuildTime   string
 	Cache       cache.Cache
+
+	// Computes page data missing from the cache
+	SECCache *seccache.SECCache
+
+	// Pages parsed once at startup, see RenderTemplate
+	templates map[string]*template.Template
+
+	// Set once shutdown starts, so /api/v1/ready reports 503 while draining
+	shuttingDown *int32
 }
 
 func NewServer(db *sqlx.DB, config config.Config, templates embed.FS) (Server, error) {
 	s := Server{
-		DB:          db,
-		Config:      config,
-		TemplatesFS: templates,
-		SHA1Ver:     GlobalSHA1Ver,
-		BuildTime:   GlobalBuildTime,
+		DB:           db,
+		Config:       config,
+		TemplatesFS:  templates,
+		SHA1Ver:      GlobalSHA1Ver,
+		BuildTime:    GlobalBuildTime,
+		shuttingDown: new(int32),
 	}
 
 	s.Cache = cache.NewCache(&config)
+	s.SECCache = seccache.NewSECCache(db, &sec.SEC{
+		BaseURL: config.Main.BaseURL,
+		Config:  config,
+		Cache:   s.Cache,
+	})
+
+	templatesDir, err := fs.Sub(templates, "templates")
+	if err != nil {
+		return Server{}, err
+	}
+	pages, err := s.ParseTemplates(templatesDir)
+	if err != nil {
+		return Server{}, err
+	}
+	s.templates = pages
 
 	return s, nil
 }
 
-func (s Server) StartServer(port string) error {
+func durationOrDefault(d time.Duration, def time.Duration) time.Duration {
+	if d <= 0 {
+		return def
+	}
+	return d
+}
+
+// NewHTTPServer returns the http.Server for addr with the timeouts from the
+// config, so slow clients can't hold connections forever.
+func (s Server) NewHTTPServer(addr string, handler http.Handler) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		ReadHeaderTimeout: durationOrDefault(s.Config.Main.ServerReadHeaderTimeout, DefaultReadHeaderTimeout),
+		ReadTimeout:       durationOrDefault(s.Config.Main.ServerReadTimeout, DefaultReadTimeout),
+		WriteTimeout:      durationOrDefault(s.Config.Main.ServerWriteTimeout, DefaultWriteTimeout),
+		IdleTimeout:       durationOrDefault(s.Config.Main.ServerIdleTimeout, DefaultIdleTimeout),
+	}
+}
+
+// StartServer serves until ctx is cancelled (e.g. on SIGTERM), then stops
+// accepting connections, waits for in-flight requests to finish and closes
+// the Redis and DB connections.
+func (s Server) StartServer(ctx context.Context, port string) error {
 	router, err := s.GenerateRouter()
 	if err != nil {
 		return err
 	}
 
+	return s.Serve(ctx, port, router)
+}
+
+// Serve serves handler on port, or on the port of the config if empty,
+// until ctx is cancelled or a listener fails.
+func (s Server) Serve(ctx context.Context, port string, handler http.Handler) error {
+	if (s.Config.Main.TLSCertFile == "") != (s.Config.Main.TLSKeyFile == "") {
+		return fmt.Errorf("main.tlscertfile and main.tlskeyfile must be set together")
+	}
+
 	GlobalUptime = time.Now()
 
 	if port != "" {
@@ -59,12 +135,121 @@ func (s Server) StartServer(port string) error {
 		port = s.Config.Main.ServerPort
 	}
 
+	srv := s.NewHTTPServer(port, handler)
+	servers := []*http.Server{srv}
+
 	log.Info(s.SHA1Ver)
 	log.Info(s.BuildTime)
 	log.Info("Listening on port ", port)
-	err = http.ListenAndServe(port, router)
-	if err != nil {
+
+	errs := make(chan error, 2)
+
+	switch {
+	case s.Config.Main.TLSCertFile != "" && s.Config.Main.TLSKeyFile != "":
+		log.Info("Serving TLS with certificate ", s.Config.Main.TLSCertFile)
+		go func() {
+			errs <- srv.ListenAndServeTLS(s.Config.Main.TLSCertFile, s.Config.Main.TLSKeyFile)
+		}()
+	case len(s.Config.Main.AutocertDomains) > 0:
+		manager := s.AutocertManager()
+		srv.TLSConfig = manager.TLSConfig()
+
+		// ACME http-01 challenges, everything else is redirected to HTTPS
+		httpPort := s.Config.Main.AutocertHTTPPort
+		if httpPort == "" {
+			httpPort = ":80"
+		}
+		challengeSrv := s.NewHTTPServer(httpPort, manager.HTTPHandler(nil))
+		servers = append(servers, challengeSrv)
+
+		log.Info("Serving TLS with ACME certificates for ", s.Config.Main.AutocertDomains)
+		go func() {
+			errs <- challengeSrv.ListenAndServe()
+		}()
+		go func() {
+			errs <- srv.ListenAndServeTLS("", "")
+		}()
+	default:
+		go func() {
+			errs <- srv.ListenAndServe()
+		}()
+	}
+
+	var err error
+	select {
+	case err = <-errs:
+	case <-ctx.Done():
+	}
+
+	// A listener that failed to start, e.g. on a port in use, never served
+	// traffic, so there's nothing for load balancers to drain
+	drain := err == nil || err == http.ErrServerClosed
+	shutdownErr := s.Shutdown(servers, drain)
+	if err != nil && err != http.ErrServerClosed {
 		return err
 	}
-	return nil
+	return shutdownErr
+}
+
+func (s Server) AutocertManager() *autocert.Manager {
+	cacheDir := s.Config.Main.AutocertCacheDir
+	if cacheDir == "" {
+		cacheDir = filepath.Join(s.Config.Main.CacheDir, "autocert")
+	}
+
+	return &autocert.Manager{
+		Prompt:     autocert.AcceptTOS,
+		HostPolicy: autocert.HostWhitelist(s.Config.Main.AutocertDomains...),
+		Cache:      autocert.DirCache(cacheDir),
+		Email:      s.Config.Main.AutocertEmail,
+	}
+}
+
+// Shutdown marks the server not ready, waits for the drain delay so load
+// balancers notice if drain is set, drains the servers within the shutdown
+// timeout and then closes the cache and database connections.
+func (s Server) Shutdown(servers []*http.Server, drain bool) error {
+	atomic.StoreInt32(s.shuttingDown, 1)
+
+	drainDelay := s.Config.Main.ServerDrainDelay
+	if drainDelay == 0 {
+		drainDelay = DefaultDrainDelay
+	}
+	if drain && drainDelay > 0 {
+		log.Info("Shutting down, reporting not ready for ", drainDelay, "...")
+		time.Sleep(drainDelay)
+	}
+
+	log.Info("Shutting down, waiting for in-flight requests to finish...")
+
+	ctx, cancel := context.WithTimeout(context.Background(), durationOrDefault(s.Config.Main.ServerShutdownTimeout, DefaultShutdownTimeout))
+	defer cancel()
+
+	var shutdownErr error
+	for _, srv := range servers {
+		err := srv.Shutdown(ctx)
+		if err != nil {
+			log.Error("could not shut down the server: ", err)
+			shutdownErr = err
+		}
+	}
+
+	err := s.Cache.Close()
+	if err != nil {
+		log.Error("could not close the cache: ", err)
+	}
+
+	err = s.DB.Close()
+	if err != nil {
+		log.Error("could not close the database connection: ", err)
+	}
+
+	log.Info("Server stopped")
+
+	return shutdownErr
+}
+
+// IsShuttingDown reports whether the server started draining connections.
+func (s Server) IsShuttingDown() bool {
+	return s.shuttingDown != nil && atomic.LoadInt32(s.shuttingDown) == 1
 }
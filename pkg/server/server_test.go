@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/equres/sec/pkg/cache"
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func newServingServer(t *testing.T, main config.MainConfig) Server {
+	cfg := config.Config{Main: main, Database: sectest.SQLite(t)}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return Server{
+		DB:           db,
+		Config:       cfg,
+		Cache:        cache.NewMemory(1<<20, "", time.Hour),
+		shuttingDown: new(int32),
+	}
+}
+
+func get(t *testing.T, url string) (int, string) {
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode, string(body)
+}
+
+func TestReady(t *testing.T) {
+	s := newServingServer(t, config.MainConfig{})
+	ts := httptest.NewServer(http.HandlerFunc(s.HandlerReady))
+	defer ts.Close()
+
+	if status, body := get(t, ts.URL); status != http.StatusOK || body != "OK" {
+		t.Errorf("ready = %v %q, want 200 OK", status, body)
+	}
+
+	atomic.StoreInt32(s.shuttingDown, 1)
+	if status, body := get(t, ts.URL); status != http.StatusServiceUnavailable || !strings.Contains(body, "shutting down") {
+		t.Errorf("ready while draining = %v %q, want 503", status, body)
+	}
+
+	atomic.StoreInt32(s.shuttingDown, 0)
+	s.DB.Close()
+	if status, body := get(t, ts.URL); status != http.StatusServiceUnavailable || !strings.Contains(body, "database") {
+		t.Errorf("ready with the database closed = %v %q, want 503", status, body)
+	}
+}
+
+func TestShutdown(t *testing.T) {
+	s := newServingServer(t, config.MainConfig{ServerDrainDelay: 500 * time.Millisecond})
+
+	started, release := make(chan struct{}), make(chan struct{})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/ready", s.HandlerReady)
+	mux.HandleFunc("/slow", func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		w.Write([]byte("done"))
+	})
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	slow := make(chan string)
+	go func() {
+		resp, err := http.Get(ts.URL + "/slow")
+		if err != nil {
+			slow <- err.Error()
+			return
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		slow <- string(body)
+	}()
+
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- s.Shutdown([]*http.Server{ts.Config}, true)
+	}()
+
+	// Still accepting connections while draining, but not ready
+	time.Sleep(100 * time.Millisecond)
+	if !s.IsShuttingDown() {
+		t.Error("IsShuttingDown() = false after Shutdown started")
+	}
+	if status, _ := get(t, ts.URL+"/api/v1/ready"); status != http.StatusServiceUnavailable {
+		t.Errorf("ready while draining = %v, want 503", status)
+	}
+
+	// In-flight requests finish before Shutdown returns
+	close(release)
+	if body := <-slow; body != "done" {
+		t.Errorf("in-flight request = %q, want done", body)
+	}
+	err := <-stopped
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	_, err = http.Get(ts.URL + "/api/v1/ready")
+	if err == nil {
+		t.Error("the server still accepts connections after Shutdown")
+	}
+	if s.DB.Ping() == nil {
+		t.Error("the database connection is still open after Shutdown")
+	}
+}
+
+func TestServePortInUse(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	s := newServingServer(t, config.MainConfig{ServerPort: l.Addr().String(), ServerDrainDelay: time.Minute})
+
+	start := time.Now()
+	err = s.Serve(context.Background(), "", http.NotFoundHandler())
+	if err == nil {
+		t.Fatal("Serve on a port in use returned no error")
+	}
+	if elapsed := time.Since(start); elapsed > 10*time.Second {
+		t.Errorf("Serve returned after %v, it mustn't wait for the drain delay when the listener failed", elapsed)
+	}
+}
+
+func TestServeTLSConfig(t *testing.T) {
+	s := newServingServer(t, config.MainConfig{ServerPort: "127.0.0.1:0", TLSCertFile: "cert.pem"})
+
+	err := s.Serve(context.Background(), "", http.NotFoundHandler())
+	if err == nil {
+		t.Fatal("Serve with a certificate and no key returned no error")
+	}
+}
@@ -0,0 +1,137 @@
+package server
+
+import (
+	"bytes"
+	"database/sql"
+	"fmt"
+	"html/template"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	humanize "github.com/dustin/go-humanize"
+	"github.com/equres/sec/pkg/secslug"
+)
+
+const baseLayout = "base.layout.gohtml"
+
+func (s Server) FuncMap() template.FuncMap {
+	return template.FuncMap{
+		"formatAccession": func(accession string) string {
+			return strings.ReplaceAll(accession, "-", "")
+		},
+		"AppVersion": func() string {
+			return fmt.Sprintf("%s %s", s.SHA1Ver, s.BuildTime)
+		},
+		"Increment": func(i int) int {
+			return i + 1
+		},
+		"Add": func(a int, b int) int {
+			return a + b
+		},
+		"StatsCSSColor": func(i int) string {
+			if i == 0 {
+				return "red"
+			}
+			if i < 100 {
+				return "orange"
+			}
+			return "green"
+		},
+		// Make a function to return "Never" if the value is empty
+		"IfDateEmpty": func(value string) string {
+			if value == "" {
+				return "Never"
+			}
+			return value
+		},
+		"WebsiteURL": func() string {
+			return s.Config.Main.WebsiteURL
+		},
+		// Canonical slug of the company, or made from name if it has none
+		"CompanySlug": func(name string, cik int) string {
+			slug, err := secslug.GetCanonicalSlug(s.DB, cik)
+			if err != nil {
+				return secslug.Make(name, cik)
+			}
+			return slug.Slug
+		},
+		"FormatPercent": func(v sql.NullFloat64) string {
+			if !v.Valid {
+				return "-"
+			}
+			return fmt.Sprintf("%.2f%%", v.Float64*100)
+		},
+		"FormatValue": func(v sql.NullFloat64) string {
+			if !v.Valid {
+				return "-"
+			}
+			return humanize.Commaf(v.Float64)
+		},
+	}
+}
+
+// parseTemplate parses a page of fsys, the templates directory, together
+// with the base layout
+func (s Server) parseTemplate(fsys fs.FS, tmplName string) (*template.Template, error) {
+	return template.New("tmpl").Funcs(s.FuncMap()).ParseFS(fsys, tmplName, baseLayout)
+}
+
+// ParseTemplates parses every page of fsys, the templates directory,
+// together with the base layout, keyed by file name, e.g.
+// "index.page.gohtml".
+func (s Server) ParseTemplates(fsys fs.FS) (map[string]*template.Template, error) {
+	pages, err := fs.Glob(fsys, "*.page.gohtml")
+	if err != nil {
+		return nil, err
+	}
+
+	templates := make(map[string]*template.Template)
+	for _, page := range pages {
+		tmplName := path.Base(page)
+		tmpl, err := s.parseTemplate(fsys, tmplName)
+		if err != nil {
+			return nil, err
+		}
+		templates[tmplName] = tmpl
+	}
+
+	return templates, nil
+}
+
+// RenderTemplate executes a page parsed at startup. In dev mode
+// (templatesdir set in the config) the page is parsed from disk on every
+// request instead, so template changes show up without a rebuild. The page
+// is rendered to a buffer first, so a failing template doesn't send half a
+// page before the error.
+func (s Server) RenderTemplate(w http.ResponseWriter, tmplName string, data interface{}) error {
+	var tmpl *template.Template
+	if s.Config.Main.TemplatesDir != "" {
+		var err error
+		tmpl, err = s.parseTemplate(os.DirFS(s.Config.Main.TemplatesDir), tmplName)
+		if err != nil {
+			return err
+		}
+	} else {
+		var ok bool
+		tmpl, ok = s.templates[tmplName]
+		if !ok {
+			return fmt.Errorf("template %v not found", tmplName)
+		}
+	}
+
+	var buf bytes.Buffer
+	err := tmpl.ExecuteTemplate(&buf, "base", data)
+	if err != nil {
+		return err
+	}
+
+	if w.Header().Get("Content-Type") == "" {
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	}
+
+	_, err = buf.WriteTo(w)
+	return err
+}
@@ -0,0 +1,46 @@
+package server
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+)
+
+func TestParseTemplates(t *testing.T) {
+	templates, err := Server{}.ParseTemplates(os.DirFS("../../templates"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := templates["index.page.gohtml"]; !ok {
+		t.Errorf("index.page.gohtml wasn't parsed: %v", templates)
+	}
+}
+
+func TestRenderTemplateDevMode(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"base.layout.gohtml": `{{ define "base" }}<main>{{ template "content" . }}</main>{{ end }}`,
+		"about.page.gohtml":  `{{ template "base" . }}{{ define "content" }}about {{ . }}{{ end }}`,
+	}
+	for name, text := range files {
+		err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := Server{Config: config.Config{Main: config.MainConfig{TemplatesDir: dir}}}
+	w := httptest.NewRecorder()
+	err := s.RenderTemplate(w, "about.page.gohtml", "sec")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(w.Body.String(), "<main>about sec</main>") || w.Header().Get("Content-Type") != "text/html; charset=utf-8" {
+		t.Errorf("got %q with Content-Type %q", w.Body.String(), w.Header().Get("Content-Type"))
+	}
+}
@@ -0,0 +1,316 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package sgml parses EDGAR full submissions, the .txt files EDGAR keeps
+// with every filing: an SGML header describing the filing and its filers,
+// followed by each document of the filing in a <DOCUMENT> block. Older
+// filings, and any filed without XBRL, exist only in this form. Binary
+// documents are uuencoded in the submission and are decoded here.
+package sgml
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrNoDocuments is returned for files without a <DOCUMENT>, which aren't
+// full submissions or are filings made on paper.
+var ErrNoDocuments = errors.New("no <DOCUMENT> in the submission")
+
+type Submission struct {
+	Header    Header
+	Documents []Document
+}
+
+// Header is the <SEC-HEADER> of a submission, <IMS-HEADER> in filings
+// before 1996.
+type Header struct {
+	AccessionNumber     string
+	SubmissionType      string
+	PublicDocumentCount int
+	PeriodOfReport      string
+	FiledAsOfDate       time.Time
+	Filers              []Filer
+}
+
+// Filer is a company the submission is filed by or about. Role is the
+// block it's listed in: FILER, SUBJECT COMPANY, FILED BY, ...
+type Filer struct {
+	Role                 string
+	CompanyName          string
+	CIK                  int
+	SIC                  int
+	StateOfIncorporation string
+	FiscalYearEnd        string
+	FileNumber           string
+}
+
+// Document is a <DOCUMENT> of a submission. Content is decoded if the
+// document was uuencoded, and stripped of the <XBRL>, <XML>, <PDF> and
+// <JSON> wrappers. Offset is where the content starts in the submission.
+type Document struct {
+	Type        string
+	Sequence    int
+	Filename    string
+	Description string
+	Content     []byte
+	Offset      int
+	Uuencoded   bool
+}
+
+// Name returns the file name of the document, without any directory.
+// Filings from before 2001 don't always have one, their documents are
+// named after the sequence.
+func (d Document) Name() string {
+	if name := path.Base(strings.ReplaceAll(d.Filename, "\\", "/")); d.Filename != "" && name != "." && name != "/" && name != ".." {
+		return name
+	}
+	return fmt.Sprintf("d%d.txt", d.Sequence)
+}
+
+// Filer returns the first filer of the submission: the filer, or the
+// subject company of filings about one.
+func (h Header) Filer() Filer {
+	if len(h.Filers) == 0 {
+		return Filer{}
+	}
+	return h.Filers[0]
+}
+
+// IsSubmission reports whether content is a full submission.
+func IsSubmission(content []byte) bool {
+	head := content[:minInt(len(content), 4096)]
+	return bytes.Contains(head, []byte("<SEC-DOCUMENT>")) || bytes.Contains(head, []byte("<SEC-HEADER>")) ||
+		bytes.Contains(head, []byte("<IMS-DOCUMENT>")) || bytes.HasPrefix(bytes.TrimSpace(head), []byte("<DOCUMENT>"))
+}
+
+// ParseFile parses the full submission at path.
+func ParseFile(path string) (Submission, error) {
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Submission{}, err
+	}
+	return Parse(content)
+}
+
+// Parse parses a full submission.
+func Parse(content []byte) (Submission, error) {
+	var submission Submission
+
+	header, err := parseHeader(content)
+	if err != nil {
+		return Submission{}, err
+	}
+	submission.Header = header
+
+	documents, err := parseDocuments(content)
+	if err != nil {
+		return Submission{}, err
+	}
+	if len(documents) == 0 {
+		return Submission{}, ErrNoDocuments
+	}
+	submission.Documents = documents
+
+	return submission, nil
+}
+
+var (
+	headerStart = regexp.MustCompile(`<(SEC|IMS)-HEADER>`)
+	headerEnd   = regexp.MustCompile(`</(SEC|IMS)-HEADER>`)
+)
+
+// Blocks of the header listing a company
+var filerRoles = map[string]bool{
+	"FILER": true, "SUBJECT COMPANY": true, "FILED BY": true, "REPORTING-OWNER": true, "ISSUER": true,
+	"REPORTING OWNER": true, "FILED FOR": true, "SERIAL COMPANY": true,
+}
+
+var sicCode = regexp.MustCompile(`\[(\d+)\]`)
+
+func parseHeader(content []byte) (Header, error) {
+	var header Header
+
+	start := headerStart.FindIndex(content)
+	if start == nil {
+		return header, nil
+	}
+	block := content[start[1]:]
+	if end := headerEnd.FindIndex(block); end != nil {
+		block = block[:end[0]]
+	}
+
+	var filer *Filer
+	scanner := bufio.NewScanner(bytes.NewReader(block))
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+
+		i := strings.Index(line, ":")
+		if i < 0 {
+			continue
+		}
+		key := strings.TrimSpace(line[:i])
+		value := strings.TrimSpace(line[i+1:])
+
+		if value == "" && filerRoles[key] {
+			header.Filers = append(header.Filers, Filer{Role: key})
+			filer = &header.Filers[len(header.Filers)-1]
+			continue
+		}
+
+		var err error
+		switch key {
+		case "ACCESSION NUMBER":
+			header.AccessionNumber = value
+		case "CONFORMED SUBMISSION TYPE":
+			header.SubmissionType = value
+		case "PUBLIC DOCUMENT COUNT":
+			header.PublicDocumentCount, err = strconv.Atoi(value)
+		case "CONFORMED PERIOD OF REPORT":
+			header.PeriodOfReport = value
+		case "FILED AS OF DATE":
+			header.FiledAsOfDate, err = time.Parse("20060102", value)
+		}
+		if err != nil {
+			return Header{}, fmt.Errorf("could not parse %v %q: %w", key, value, err)
+		}
+
+		if filer == nil || value == "" {
+			continue
+		}
+
+		switch key {
+		case "COMPANY CONFORMED NAME":
+			filer.CompanyName = value
+		case "CENTRAL INDEX KEY":
+			filer.CIK, err = strconv.Atoi(value)
+		case "STANDARD INDUSTRIAL CLASSIFICATION":
+			// Either the code or the description followed by [code]
+			if match := sicCode.FindStringSubmatch(value); match != nil {
+				value = match[1]
+			}
+			filer.SIC, _ = strconv.Atoi(value)
+		case "STATE OF INCORPORATION":
+			filer.StateOfIncorporation = value
+		case "FISCAL YEAR END":
+			filer.FiscalYearEnd = value
+		case "SEC FILE NUMBER":
+			filer.FileNumber = value
+		}
+		if err != nil {
+			return Header{}, fmt.Errorf("could not parse %v %q: %w", key, value, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return Header{}, err
+	}
+
+	return header, nil
+}
+
+var (
+	documentTag = regexp.MustCompile(`(?m)^<(TYPE|SEQUENCE|FILENAME|DESCRIPTION)>([^\r\n]*)`)
+	wrapperTag  = regexp.MustCompile(`^\s*<(XBRL|XML|PDF|JSON)>\r?\n?`)
+)
+
+func parseDocuments(content []byte) ([]Document, error) {
+	var documents []Document
+
+	offset := 0
+	for {
+		start := bytes.Index(content[offset:], []byte("<DOCUMENT>"))
+		if start < 0 {
+			break
+		}
+		start += offset
+
+		end := bytes.Index(content[start:], []byte("</DOCUMENT>"))
+		if end < 0 {
+			end = len(content)
+		} else {
+			end += start
+		}
+		offset = end
+
+		block := content[start:end]
+
+		textStart := bytes.Index(block, []byte("<TEXT>"))
+		if textStart < 0 {
+			textStart = len(block)
+		}
+
+		var document Document
+		for _, match := range documentTag.FindAllSubmatch(block[:textStart], -1) {
+			value := strings.TrimSpace(string(match[2]))
+			switch string(match[1]) {
+			case "TYPE":
+				document.Type = value
+			case "SEQUENCE":
+				document.Sequence, _ = strconv.Atoi(value)
+			case "FILENAME":
+				document.Filename = value
+			case "DESCRIPTION":
+				document.Description = value
+			}
+		}
+		if document.Sequence == 0 {
+			document.Sequence = len(documents) + 1
+		}
+
+		if textStart < len(block) {
+			textStart += len("<TEXT>")
+			if textStart < len(block) && block[textStart] == '\r' {
+				textStart++
+			}
+			if textStart < len(block) && block[textStart] == '\n' {
+				textStart++
+			}
+
+			textEnd := bytes.LastIndex(block, []byte("</TEXT>"))
+			if textEnd < textStart {
+				textEnd = len(block)
+			}
+			text := block[textStart:textEnd]
+
+			if match := wrapperTag.FindSubmatchIndex(text); match != nil {
+				closing := []byte("</" + string(text[match[2]:match[3]]) + ">")
+				if i := bytes.LastIndex(text, closing); i >= match[1] {
+					text = text[:i]
+				}
+				text = text[match[1]:]
+				textStart += match[1]
+			}
+
+			document.Content = text
+			document.Offset = start + textStart
+
+			if isUuencoded(text) {
+				decoded, err := uudecode(text)
+				if err != nil {
+					return nil, fmt.Errorf("document %d %v: %w", document.Sequence, document.Name(), err)
+				}
+				document.Content = decoded
+				document.Uuencoded = true
+			}
+		}
+
+		documents = append(documents, document)
+	}
+
+	return documents, nil
+}
+
+func minInt(a int, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
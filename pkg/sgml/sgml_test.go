@@ -0,0 +1,118 @@
+package sgml
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+	"time"
+)
+
+// A submission from before 2001: IMS header, a document without a file
+// name and a uuencoded graphic whose trailing spaces were stripped
+const submission = `<SEC-DOCUMENT>0000950123-97-000001.txt : 19970102
+<SEC-HEADER>0000950123-97-000001.hdr.sgml : 19970102
+ACCESSION NUMBER:		0000950123-97-000001
+CONFORMED SUBMISSION TYPE:	10-K
+PUBLIC DOCUMENT COUNT:		3
+CONFORMED PERIOD OF REPORT:	19960930
+FILED AS OF DATE:		19970102
+
+FILER:
+
+	COMPANY DATA:
+		COMPANY CONFORMED NAME:			ACME CORP
+		CENTRAL INDEX KEY:			0000012345
+		STANDARD INDUSTRIAL CLASSIFICATION:	ELECTRONIC COMPUTERS [3571]
+		STATE OF INCORPORATION:			DE
+		FISCAL YEAR END:			0930
+
+	FILING VALUES:
+		FORM TYPE:		10-K
+		SEC FILE NUMBER:	001-01234
+</SEC-HEADER>
+<DOCUMENT>
+<TYPE>10-K
+<SEQUENCE>1
+<DESCRIPTION>ANNUAL REPORT
+<TEXT>
+<PAGE>
+                            ANNUAL REPORT
+</TEXT>
+</DOCUMENT>
+<DOCUMENT>
+<TYPE>EX-27
+<SEQUENCE>2
+<FILENAME>ex27.xml
+<TEXT>
+<XML>
+<fds>1</fds>
+</XML>
+</TEXT>
+</DOCUMENT>
+<DOCUMENT>
+<TYPE>GRAPHIC
+<SEQUENCE>3
+<FILENAME>../logo.gif
+<TEXT>
+begin 644 logo.gif
+41TE&.#EA 0 ! (   /___P   "$
+end
+</TEXT>
+</DOCUMENT>
+</SEC-DOCUMENT>`
+
+func TestParse(t *testing.T) {
+	if !IsSubmission([]byte(submission)) {
+		t.Fatal("IsSubmission() = false")
+	}
+
+	got, err := Parse([]byte(submission))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	header := got.Header
+	if header.AccessionNumber != "0000950123-97-000001" || header.SubmissionType != "10-K" || header.PublicDocumentCount != 3 ||
+		header.PeriodOfReport != "19960930" || !header.FiledAsOfDate.Equal(time.Date(1997, 1, 2, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Header = %+v", header)
+	}
+
+	filer := header.Filer()
+	want := Filer{Role: "FILER", CompanyName: "ACME CORP", CIK: 12345, SIC: 3571, StateOfIncorporation: "DE", FiscalYearEnd: "0930", FileNumber: "001-01234"}
+	if filer != want {
+		t.Errorf("Filer() = %+v, want %+v", filer, want)
+	}
+
+	if len(got.Documents) != 3 {
+		t.Fatalf("got %d documents, want 3", len(got.Documents))
+	}
+
+	annual := got.Documents[0]
+	if annual.Type != "10-K" || annual.Sequence != 1 || annual.Description != "ANNUAL REPORT" || annual.Name() != "d1.txt" {
+		t.Errorf("document 1 = %v %v %q %v", annual.Type, annual.Sequence, annual.Description, annual.Name())
+	}
+	if !bytes.HasPrefix([]byte(submission[annual.Offset:]), annual.Content) {
+		t.Errorf("document 1 Offset %d doesn't point to its content", annual.Offset)
+	}
+
+	exhibit := got.Documents[1]
+	if string(exhibit.Content) != "<fds>1</fds>\n" || submission[exhibit.Offset:exhibit.Offset+5] != "<fds>" {
+		t.Errorf("document 2 content = %q at %d", exhibit.Content, exhibit.Offset)
+	}
+
+	graphic := got.Documents[2]
+	gif := []byte("GIF89a\x01\x00\x01\x00\x80\x00\x00\xff\xff\xff\x00\x00\x00!")
+	if !graphic.Uuencoded || !bytes.Equal(graphic.Content, gif) {
+		t.Errorf("document 3 content = %q, want %q", graphic.Content, gif)
+	}
+	if graphic.Name() != "logo.gif" {
+		t.Errorf("document 3 Name() = %q, want %q", graphic.Name(), "logo.gif")
+	}
+}
+
+func TestParseNoDocuments(t *testing.T) {
+	_, err := Parse([]byte("<SEC-HEADER>\nACCESSION NUMBER: 0000950123-97-000001\n</SEC-HEADER>\n"))
+	if !errors.Is(err, ErrNoDocuments) {
+		t.Errorf("Parse() error = %v, want %v", err, ErrNoDocuments)
+	}
+}
@@ -0,0 +1,68 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package sgml
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+)
+
+var uuBegin = regexp.MustCompile(`^\s*begin [0-7]{3,4} `)
+
+func isUuencoded(text []byte) bool {
+	return uuBegin.Match(text[:minInt(len(text), 256)])
+}
+
+// uudecode decodes the uuencoded file in text, from its begin line to its
+// end line. EDGAR strips trailing spaces of the lines, so short lines are
+// padded back. A file cut short without its end line is decoded as far as
+// it goes.
+func uudecode(text []byte) ([]byte, error) {
+	lines := bytes.Split(text, []byte("\n"))
+
+	i := 0
+	for i < len(lines) && !uuBegin.Match(lines[i]) {
+		i++
+	}
+	if i == len(lines) {
+		return nil, fmt.Errorf("no begin line")
+	}
+
+	var decoded bytes.Buffer
+	for _, line := range lines[i+1:] {
+		line = bytes.TrimRight(line, "\r")
+		if bytes.Equal(bytes.TrimSpace(line), []byte("end")) {
+			return decoded.Bytes(), nil
+		}
+		if len(line) == 0 {
+			continue
+		}
+
+		n := int(uuValue(line[0]))
+		if n == 0 {
+			continue
+		}
+
+		chars := line[1:]
+		groups := (n + 2) / 3
+		if len(chars) < 4*groups {
+			chars = append(append([]byte{}, chars...), bytes.Repeat([]byte(" "), 4*groups-len(chars))...)
+		}
+
+		var out []byte
+		for g := 0; g < groups; g++ {
+			c := chars[4*g : 4*g+4]
+			a, b, d, e := uuValue(c[0]), uuValue(c[1]), uuValue(c[2]), uuValue(c[3])
+			out = append(out, a<<2|b>>4, b<<4|d>>2, d<<6|e)
+		}
+		decoded.Write(out[:n])
+	}
+
+	return decoded.Bytes(), nil
+}
+
+// uuValue returns the 6 bits a character stands for, ` and space both
+// being 0.
+func uuValue(c byte) byte {
+	return (c - ' ') & 0x3f
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package xbrl
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Num is a numeric fact in the shape of fsds.num. Segments holds the
+// dimensions of the context other than the co-registrant, e.g.
+// "ProductOrService=IPhone;", and is empty for consolidated values.
+type Num struct {
+	Adsh     string `db:"adsh"`
+	Tag      string `db:"tag"`
+	Version  string `db:"version"`
+	Coreg    string `db:"coreg"`
+	DDate    string `db:"ddate"`
+	Qtrs     string `db:"qtrs"`
+	UOM      string `db:"uom"`
+	Value    string `db:"value"`
+	Segments string `db:"segments"`
+}
+
+const dateLayout = "2006-01-02"
+
+// Standard taxonomies are versioned by year like in fsds.num (us-gaap/2021),
+// company extensions use the accession number as their version.
+var standardNamespace = regexp.MustCompile(`^https?://(?:fasb\.org|xbrl\.sec\.gov|xbrl\.ifrs\.org/taxonomy)/([a-z-]+)/(\d{4})`)
+
+func Version(namespace string, adsh string) string {
+	m := standardNamespace.FindStringSubmatch(namespace)
+	if m == nil {
+		return adsh
+	}
+	return m[1] + "/" + m[2]
+}
+
+// Nums flattens the numeric facts of the instance into fsds.num rows of the
+// given accession number. Facts that are nil or reference a missing context
+// or unit are skipped, and duplicates (iXBRL often displays the same fact
+// in several places) are reported once.
+func (inst *Instance) Nums(adsh string) []Num {
+	var nums []Num
+	seen := make(map[Num]bool)
+
+	for _, fact := range inst.Facts {
+		if !fact.Numeric || fact.Nil {
+			continue
+		}
+
+		context, ok := inst.Contexts[fact.ContextRef]
+		if !ok {
+			continue
+		}
+		unit, ok := inst.Units[fact.UnitRef]
+		if !ok {
+			continue
+		}
+
+		ddate, qtrs, ok := periodDateQtrs(context.Period)
+		if !ok {
+			continue
+		}
+
+		coreg, segments := coregSegments(context.Dimensions)
+
+		num := Num{
+			Adsh:     adsh,
+			Tag:      fact.Local,
+			Version:  Version(fact.Namespace, adsh),
+			Coreg:    coreg,
+			DDate:    ddate,
+			Qtrs:     qtrs,
+			UOM:      unit.String(),
+			Value:    fact.Value,
+			Segments: segments,
+		}
+
+		if seen[num] {
+			continue
+		}
+		seen[num] = true
+
+		nums = append(nums, num)
+	}
+
+	return nums
+}
+
+// periodDateQtrs converts an XBRL period the way the FSDS data sets do:
+// ddate is the end date rounded to the nearest month end, qtrs the duration
+// rounded to whole quarters, 0 for instants.
+func periodDateQtrs(period Period) (string, string, bool) {
+	if period.Instant != "" {
+		end, err := time.Parse(dateLayout, firstDate(period.Instant))
+		if err != nil {
+			return "", "", false
+		}
+		return roundToMonthEnd(end).Format("20060102"), "0", true
+	}
+
+	start, err := time.Parse(dateLayout, firstDate(period.StartDate))
+	if err != nil {
+		return "", "", false
+	}
+	end, err := time.Parse(dateLayout, firstDate(period.EndDate))
+	if err != nil {
+		return "", "", false
+	}
+
+	// XBRL end dates are inclusive
+	days := end.Sub(start).Hours()/24 + 1
+	qtrs := int(math.Round(days / (365.25 / 4)))
+
+	return roundToMonthEnd(end).Format("20060102"), strconv.Itoa(qtrs), true
+}
+
+func firstDate(s string) string {
+	s = strings.TrimSpace(s)
+	if len(s) > 10 {
+		s = s[:10]
+	}
+	return s
+}
+
+func roundToMonthEnd(date time.Time) time.Time {
+	firstOfMonth := time.Date(date.Year(), date.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if date.Day() <= 15 {
+		return firstOfMonth.AddDate(0, 0, -1)
+	}
+	return firstOfMonth.AddDate(0, 1, -1)
+}
+
+// coregSegments splits the dimensions of a context into the co-registrant
+// (dei:LegalEntityAxis) and the remaining segments, written like the
+// segments column of the newer FSDS data sets: "Axis=Member;" with the
+// prefixes and the Axis/Member suffixes removed, sorted by axis.
+func coregSegments(dimensions []Dimension) (string, string) {
+	var coreg string
+	var segments []string
+
+	for _, dimension := range dimensions {
+		_, axis := splitQName(dimension.Axis)
+		member := dimension.Member
+		if !dimension.Typed {
+			_, member = splitQName(member)
+		}
+
+		if axis == "LegalEntityAxis" {
+			coreg = strings.TrimSuffix(member, "Member")
+			continue
+		}
+
+		segments = append(segments, strings.TrimSuffix(axis, "Axis")+"="+strings.TrimSuffix(member, "Member")+";")
+	}
+
+	sort.Strings(segments)
+	return coreg, strings.Join(segments, "")
+}
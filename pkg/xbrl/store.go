@@ -0,0 +1,209 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+package xbrl
+
+import (
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/secevent"
+	"github.com/jmoiron/sqlx"
+)
+
+// File is an XBRL instance or inline XBRL document listed in secItemFile.
+type File struct {
+	CIK       int    `db:"ciknumber"`
+	Accession string `db:"accessionnumber"`
+	XbrlType  string `db:"xbrltype"`
+	XbrlURL   string `db:"xbrlurl"`
+	Inline    bool   `db:"xbrlinlinexbrl"`
+}
+
+// Index statuses of a filing in xbrl.filings
+const (
+	StatusIndexed = "indexed"
+	StatusFailed  = "failed"
+)
+
+// GetFilesToIndex returns the instance documents of filings that have no
+// status in xbrl.filings yet, grouped by accession number. Unless all is
+// set, only filings made after the last indexed FSDS quarter are returned,
+// as older ones are already covered by fsds.num. With retry, filings that
+// failed before are returned again.
+func GetFilesToIndex(db *sqlx.DB, all bool, retry bool) (map[string][]File, []string, error) {
+	var files []File
+	err := db.Select(&files, `
+		SELECT ciknumber, accessionnumber, xbrltype, xbrlurl, COALESCE(xbrlinlinexbrl, false) AS xbrlinlinexbrl
+		FROM sec.secitemfile
+		WHERE (xbrltype = 'EX-101.INS' OR xbrlinlinexbrl = true)
+		AND xbrlurl IS NOT NULL
+		AND NOT EXISTS (SELECT 1 FROM xbrl.filings WHERE filings.adsh = secitemfile.accessionnumber AND NOT ($2 AND filings.status = 'failed'))
+		AND ($1 OR fillingdate > (SELECT COALESCE(MAX(filled), '1900-01-01') FROM fsds.sub))
+		ORDER BY fillingdate, accessionnumber, xbrlsequence;`, all, retry)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filesByAccession := make(map[string][]File)
+	var accessions []string
+	for _, file := range files {
+		if _, ok := filesByAccession[file.Accession]; !ok {
+			accessions = append(accessions, file.Accession)
+		}
+		filesByAccession[file.Accession] = append(filesByAccession[file.Accession], file)
+	}
+
+	return filesByAccession, accessions, nil
+}
+
+// InstanceFiles picks the documents to parse for one filing. Inline filings
+// also come with the instance EDGAR extracts from them, which holds the
+// same facts, so the inline documents are only parsed when it's missing.
+func InstanceFiles(files []File) []File {
+	var instances []File
+	for _, file := range files {
+		if file.XbrlType == "EX-101.INS" {
+			instances = append(instances, file)
+		}
+	}
+	if len(instances) > 0 {
+		return instances
+	}
+	return files
+}
+
+// FilePath returns where the document of fileURL is cached on disk, either
+// downloaded as is or unpacked from the filing ZIP.
+func FilePath(s *sec.SEC, fileURL string) (string, error) {
+	parsedURL, err := url.Parse(fileURL)
+	if err != nil {
+		return "", err
+	}
+
+	for _, dir := range []string{s.Config.Main.CacheDir, s.Config.Main.CacheDirUnpacked} {
+		filePath := filepath.Join(dir, parsedURL.Path)
+		_, err = os.Stat(filePath)
+		if err == nil {
+			return filePath, nil
+		}
+	}
+
+	return "", fmt.Errorf("file %v is not in the cache", fileURL)
+}
+
+func ParseFile(filePath string) (*Instance, error) {
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	return Parse(file)
+}
+
+func NumUpsert(db sqlx.Execer, cik int, nums []Num) error {
+	for _, num := range nums {
+		_, err := db.Exec(`
+			INSERT INTO xbrl.num (adsh, cik, tag, version, coreg, ddate, qtrs, uom, value, segments, created_at, updated_at)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW(), NOW())
+			ON CONFLICT (adsh, tag, version, coreg, ddate, qtrs, uom, segments)
+			DO NOTHING;`, num.Adsh, cik, num.Tag, num.Version, num.Coreg, num.DDate, num.Qtrs, num.UOM, num.Value, num.Segments)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// SetStatus records the index status of a filing, with the error that
+// made it fail if any.
+func SetStatus(db sqlx.Execer, accession string, status string, indexErr string) error {
+	_, err := db.Exec(`
+		INSERT INTO xbrl.filings (adsh, status, error, indexed_at, created_at, updated_at)
+		VALUES ($1, $2, NULLIF($3, ''), NOW(), NOW(), NOW())
+		ON CONFLICT (adsh)
+		DO UPDATE SET status = EXCLUDED.status, error = EXCLUDED.error, indexed_at = EXCLUDED.indexed_at, updated_at = NOW();`, accession, status, indexErr)
+	return err
+}
+
+// instanceNums are the facts of one instance document of a filing
+type instanceNums struct {
+	file     File
+	filePath string
+	nums     []Num
+}
+
+// IndexFiling parses the XBRL documents of one filing and stores all of
+// their facts together with its status in one transaction, so that a
+// filing is never left with part of its facts. A document that is missing
+// or fails to parse marks the whole filing as failed.
+func IndexFiling(s *sec.SEC, db *sqlx.DB, accession string, files []File) error {
+	var instances []instanceNums
+	for _, file := range InstanceFiles(files) {
+		filePath, err := FilePath(s, file.XbrlURL)
+		if err != nil {
+			secevent.CreateIndexEvent(db, file.XbrlURL, "failed", "xbrl_file_not_in_cache")
+			return SetStatus(db, accession, StatusFailed, err.Error())
+		}
+
+		inst, err := ParseFile(filePath)
+		if err != nil {
+			secevent.CreateIndexEvent(db, filePath, "failed", "xbrl_could_not_parse_file")
+			s.Log(fmt.Sprintf("Could not parse %v: %v", filePath, err))
+			return SetStatus(db, accession, StatusFailed, fmt.Sprintf("could not parse %v: %v", filePath, err))
+		}
+
+		instances = append(instances, instanceNums{file: file, filePath: filePath, nums: inst.Nums(accession)})
+	}
+
+	tx, err := db.Beginx()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, instance := range instances {
+		err = NumUpsert(tx, instance.file.CIK, instance.nums)
+		if err != nil {
+			secevent.CreateIndexEvent(db, instance.filePath, "failed", "xbrl_error_inserting_in_database")
+			return err
+		}
+	}
+
+	err = SetStatus(tx, accession, StatusIndexed, "")
+	if err != nil {
+		return err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		return err
+	}
+
+	for _, instance := range instances {
+		secevent.CreateIndexEvent(db, instance.filePath, "success", "")
+	}
+	return nil
+}
+
+// IndexFilings indexes the XBRL facts of every filing without a status in
+// xbrl.filings, and with retry also of those that failed before.
+func IndexFilings(s *sec.SEC, db *sqlx.DB, all bool, retry bool) error {
+	filesByAccession, accessions, err := GetFilesToIndex(db, all, retry)
+	if err != nil {
+		return err
+	}
+
+	for i, accession := range accessions {
+		s.Log(fmt.Sprintf("[%d/%d] Indexing XBRL facts of %v", i+1, len(accessions), accession))
+
+		err = IndexFiling(s, db, accession, filesByAccession[accession])
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,115 @@
+package xbrl
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/equres/sec/pkg/config"
+	"github.com/equres/sec/pkg/database"
+	"github.com/equres/sec/pkg/sec"
+	"github.com/equres/sec/pkg/sectest"
+)
+
+func TestIndexFilings(t *testing.T) {
+	cfg := config.Config{
+		Database: sectest.Postgres(t),
+		Main:     config.MainConfig{CacheDir: t.TempDir()},
+	}
+	db, err := database.ConnectDB(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+
+	err = database.MigrateUp(db, os.DirFS("../.."), cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := sec.NewSEC(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	documents := map[string]string{
+		"/Archives/edgar/data/320193/000032019321000105/aapl-20210925_htm.xml": instanceDocument,
+		"/Archives/edgar/data/320193/000032019322000007/aapl-20211225_htm.xml": "<xbrli:xbrl><us-gaap:Revenues",
+	}
+	for path, document := range documents {
+		err = os.MkdirAll(filepath.Join(cfg.Main.CacheDir, filepath.Dir(path)), 0755)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = ioutil.WriteFile(filepath.Join(cfg.Main.CacheDir, path), []byte(document), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// The first filing is cached, the second one is cached but truncated
+	// and the third one isn't cached
+	_, err = db.Exec(`
+		INSERT INTO sec.ciks (cik) VALUES (320193);
+		INSERT INTO sec.secitemfile (ciknumber, accessionnumber, fillingdate, xbrlsequence, xbrlfile, xbrltype, xbrlurl) VALUES
+			(320193, '0000320193-21-000105', '2021-10-29', 7, 'aapl-20210925_htm.xml', 'EX-101.INS', 'https://www.sec.gov/Archives/edgar/data/320193/000032019321000105/aapl-20210925_htm.xml'),
+			(320193, '0000320193-22-000007', '2022-01-28', 7, 'aapl-20211225_htm.xml', 'EX-101.INS', 'https://www.sec.gov/Archives/edgar/data/320193/000032019322000007/aapl-20211225_htm.xml'),
+			(320193, '0000320193-22-000059', '2022-04-29', 7, 'aapl-20220326_htm.xml', 'EX-101.INS', 'https://www.sec.gov/Archives/edgar/data/320193/000032019322000059/aapl-20220326_htm.xml');`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = IndexFilings(s, db, true, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var filings []struct {
+		Adsh   string `db:"adsh"`
+		Status string `db:"status"`
+		Facts  int    `db:"facts"`
+	}
+	err = db.Select(&filings, `
+		SELECT adsh, status, (SELECT COUNT(*) FROM xbrl.num WHERE num.adsh = filings.adsh) AS facts
+		FROM xbrl.filings
+		ORDER BY adsh;`)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []struct {
+		adsh   string
+		status string
+		facts  int
+	}{
+		{"0000320193-21-000105", StatusIndexed, 5},
+		{"0000320193-22-000007", StatusFailed, 0},
+		{"0000320193-22-000059", StatusFailed, 0},
+	}
+	if len(filings) != len(want) {
+		t.Fatalf("xbrl.filings = %+v, want %+v", filings, want)
+	}
+	for i, w := range want {
+		if filings[i].Adsh != w.adsh || filings[i].Status != w.status || filings[i].Facts != w.facts {
+			t.Errorf("xbrl.filings[%d] = %+v, want %+v", i, filings[i], w)
+		}
+	}
+
+	tests := []struct {
+		retry bool
+		want  int
+	}{
+		{false, 0},
+		{true, 2},
+	}
+	for _, tt := range tests {
+		_, accessions, err := GetFilesToIndex(db, true, tt.retry)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(accessions) != tt.want {
+			t.Errorf("GetFilesToIndex(retry %v) = %v, want %d filings", tt.retry, accessions, tt.want)
+		}
+	}
+}
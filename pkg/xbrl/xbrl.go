@@ -0,0 +1,498 @@
+// Copyright (c) 2021 Koszek Systems. All rights reserved.
+
+// Package xbrl parses XBRL instance documents (EX-101.INS) and inline XBRL
+// (iXBRL) HTML documents into contexts, units and facts, and flattens the
+// numeric facts into rows shaped like fsds.num.
+package xbrl
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"math/big"
+	"strings"
+)
+
+const (
+	NamespaceInstance = "http://www.xbrl.org/2003/instance"
+	NamespaceXSI      = "http://www.w3.org/2001/XMLSchema-instance"
+)
+
+// Inline XBRL 1.0 and 1.1 namespaces
+var inlineNamespaces = map[string]bool{
+	"http://www.xbrl.org/2008/inlineXBRL": true,
+	"http://www.xbrl.org/2013/inlineXBRL": true,
+}
+
+type Period struct {
+	StartDate string
+	EndDate   string
+	Instant   string
+}
+
+type Dimension struct {
+	Axis   string
+	Member string
+	Typed  bool
+}
+
+type Context struct {
+	ID         string
+	Entity     string
+	Period     Period
+	Dimensions []Dimension
+}
+
+type Unit struct {
+	ID          string
+	Numerator   []string
+	Denominator []string
+}
+
+// String returns the unit the way fsds.num writes it: measures without
+// their namespace prefix, e.g. "USD", "shares" or "USD/shares".
+func (u Unit) String() string {
+	s := strings.Join(localNames(u.Numerator), "*")
+	if len(u.Denominator) > 0 {
+		s += "/" + strings.Join(localNames(u.Denominator), "*")
+	}
+	return s
+}
+
+type Fact struct {
+	// Name is the concept as written in the document, e.g. us-gaap:Revenues
+	Name       string
+	Namespace  string
+	Local      string
+	ContextRef string
+	UnitRef    string
+	Decimals   string
+	// Value of numeric facts is a plain decimal number with scale and sign
+	// already applied. Non-numeric facts hold their text content.
+	Value   string
+	Numeric bool
+	Nil     bool
+}
+
+type Instance struct {
+	Contexts map[string]*Context
+	Units    map[string]*Unit
+	Facts    []Fact
+	// Namespaces maps prefixes declared in the document to namespace URIs
+	Namespaces map[string]string
+}
+
+func NewInstance() *Instance {
+	return &Instance{
+		Contexts:   make(map[string]*Context),
+		Units:      make(map[string]*Unit),
+		Namespaces: make(map[string]string),
+	}
+}
+
+// Parse reads a traditional XBRL instance or an inline XBRL document. Both
+// keep their contexts and units in xbrli elements (inline documents inside
+// ix:resources), so one pass over the tokens handles either.
+func Parse(r io.Reader) (*Instance, error) {
+	decoder := xml.NewDecoder(r)
+	// Inline documents are XHTML but in the wild still contain HTML entities
+	decoder.Strict = false
+	decoder.AutoClose = xml.HTMLAutoClose
+	decoder.Entity = xml.HTMLEntity
+
+	inst := NewInstance()
+
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+
+		for _, attr := range start.Attr {
+			if attr.Name.Space == "xmlns" {
+				inst.Namespaces[attr.Name.Local] = attr.Value
+			}
+		}
+
+		switch {
+		case start.Name.Space == NamespaceInstance && start.Name.Local == "context":
+			context, err := parseContext(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			inst.Contexts[context.ID] = context
+		case start.Name.Space == NamespaceInstance && start.Name.Local == "unit":
+			unit, err := parseUnit(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			inst.Units[unit.ID] = unit
+		case isInlineFact(start):
+			facts, _, err := inst.parseInlineFact(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			inst.Facts = append(inst.Facts, facts...)
+		case !inlineNamespaces[start.Name.Space] && attrValue(start, "contextRef") != "":
+			fact, err := inst.parseFact(decoder, start)
+			if err != nil {
+				return nil, err
+			}
+			inst.Facts = append(inst.Facts, fact)
+		}
+	}
+
+	return inst, nil
+}
+
+func parseContext(decoder *xml.Decoder, start xml.StartElement) (*Context, error) {
+	context := &Context{ID: attrValue(start, "id")}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "identifier":
+				context.Entity, err = readText(decoder)
+			case "startDate":
+				context.Period.StartDate, err = readText(decoder)
+			case "endDate":
+				context.Period.EndDate, err = readText(decoder)
+			case "instant":
+				context.Period.Instant, err = readText(decoder)
+			case "explicitMember", "typedMember":
+				var member string
+				member, err = readText(decoder)
+				context.Dimensions = append(context.Dimensions, Dimension{
+					Axis:   attrValue(t, "dimension"),
+					Member: member,
+					Typed:  t.Name.Local == "typedMember",
+				})
+			}
+			if err != nil {
+				return nil, err
+			}
+		case xml.EndElement:
+			if t.Name == start.Name {
+				return context, nil
+			}
+		}
+	}
+}
+
+func parseUnit(decoder *xml.Decoder, start xml.StartElement) (*Unit, error) {
+	unit := &Unit{ID: attrValue(start, "id")}
+	denominator := false
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "unitDenominator":
+				denominator = true
+			case "unitNumerator":
+				denominator = false
+			case "measure":
+				measure, err := readText(decoder)
+				if err != nil {
+					return nil, err
+				}
+				if denominator {
+					unit.Denominator = append(unit.Denominator, measure)
+				} else {
+					unit.Numerator = append(unit.Numerator, measure)
+				}
+			}
+		case xml.EndElement:
+			if t.Name == start.Name {
+				return unit, nil
+			}
+		}
+	}
+}
+
+func (inst *Instance) parseFact(decoder *xml.Decoder, start xml.StartElement) (Fact, error) {
+	value, err := readText(decoder)
+	if err != nil {
+		return Fact{}, err
+	}
+
+	fact := Fact{
+		Namespace:  start.Name.Space,
+		Local:      start.Name.Local,
+		ContextRef: attrValue(start, "contextRef"),
+		UnitRef:    attrValue(start, "unitRef"),
+		Decimals:   attrValue(start, "decimals"),
+		Value:      value,
+		Nil:        attrValueNS(start, NamespaceXSI, "nil") == "true",
+	}
+	fact.Name = fact.Local
+	for prefix, namespace := range inst.Namespaces {
+		if namespace == fact.Namespace && prefix != "" {
+			fact.Name = prefix + ":" + fact.Local
+			break
+		}
+	}
+	fact.Numeric = fact.UnitRef != ""
+
+	if fact.Numeric && !fact.Nil {
+		fact.Value, err = normalizeNumber(value, 0, false)
+		if err != nil {
+			return Fact{}, fmt.Errorf("fact %v in context %v: %v", fact.Local, fact.ContextRef, err)
+		}
+	}
+
+	return fact, nil
+}
+
+func isInlineFact(start xml.StartElement) bool {
+	return inlineNamespaces[start.Name.Space] && (start.Name.Local == "nonFraction" || start.Name.Local == "nonNumeric")
+}
+
+// parseInlineFact reads an ix:nonFraction or ix:nonNumeric element. The
+// displayed text of ix:nonFraction is transformed with its format, then the
+// scale (e.g. "in millions" = 6) and sign attributes are applied. It returns
+// the fact followed by the facts nested in it, e.g. the ix:nonFraction cells
+// of a table in an ix:nonNumeric text block, and its displayed text.
+func (inst *Instance) parseInlineFact(decoder *xml.Decoder, start xml.StartElement) ([]Fact, string, error) {
+	value, nested, err := inst.readInlineText(decoder)
+	if err != nil {
+		return nil, "", err
+	}
+
+	fact, err := inst.newInlineFact(start, value)
+	if err != nil {
+		return nil, "", err
+	}
+	return append([]Fact{fact}, nested...), value, nil
+}
+
+func (inst *Instance) newInlineFact(start xml.StartElement, value string) (Fact, error) {
+	name := attrValue(start, "name")
+	prefix, local := splitQName(name)
+
+	fact := Fact{
+		Name:       name,
+		Namespace:  inst.Namespaces[prefix],
+		Local:      local,
+		ContextRef: attrValue(start, "contextRef"),
+		UnitRef:    attrValue(start, "unitRef"),
+		Decimals:   attrValue(start, "decimals"),
+		Value:      strings.TrimSpace(value),
+		Numeric:    start.Name.Local == "nonFraction",
+		Nil:        attrValueNS(start, NamespaceXSI, "nil") == "true",
+	}
+
+	if !fact.Numeric || fact.Nil {
+		return fact, nil
+	}
+
+	number, err := transform(value, attrValue(start, "format"))
+	if err != nil {
+		return Fact{}, fmt.Errorf("fact %v in context %v: %v", name, fact.ContextRef, err)
+	}
+
+	scale := 0
+	if attrValue(start, "scale") != "" {
+		_, err = fmt.Sscan(attrValue(start, "scale"), &scale)
+		if err != nil {
+			return Fact{}, fmt.Errorf("fact %v in context %v: invalid scale %v", name, fact.ContextRef, attrValue(start, "scale"))
+		}
+	}
+
+	fact.Value, err = normalizeNumber(number, scale, attrValue(start, "sign") == "-")
+	if err != nil {
+		return Fact{}, fmt.Errorf("fact %v in context %v: %v", name, fact.ContextRef, err)
+	}
+
+	return fact, nil
+}
+
+// transform applies an inline XBRL transformation rule (ixt/ixt-sec
+// registries) to the displayed value and returns a plain number.
+func transform(value string, format string) (string, error) {
+	_, rule := splitQName(format)
+	rule = strings.ToLower(strings.ReplaceAll(rule, "-", ""))
+	value = strings.TrimSpace(value)
+
+	switch rule {
+	case "zerodash", "fixedzero", "numdash":
+		return "0", nil
+	case "numwordsen", "numworden":
+		switch strings.ToLower(value) {
+		case "no", "none", "nil", "zero", "":
+			return "0", nil
+		}
+	case "numcommadecimal", "numdotcomma", "numspacecomma":
+		value = strings.NewReplacer(".", "", " ", "", " ", "", ",", ".").Replace(value)
+	}
+
+	var b strings.Builder
+	for _, r := range value {
+		if (r >= '0' && r <= '9') || r == '.' {
+			b.WriteRune(r)
+		}
+	}
+
+	if b.Len() == 0 {
+		// Dashes and blanks are how filers display zero
+		if strings.Trim(value, "-–—  ") == "" {
+			return "0", nil
+		}
+		return "", fmt.Errorf("could not read number %q with format %q", value, format)
+	}
+
+	return b.String(), nil
+}
+
+// normalizeNumber multiplies value by 10^scale and negates it if asked,
+// using exact rational arithmetic so large values keep every digit.
+func normalizeNumber(value string, scale int, negate bool) (string, error) {
+	number, ok := new(big.Rat).SetString(strings.TrimSpace(value))
+	if !ok {
+		return "", fmt.Errorf("invalid number %q", value)
+	}
+
+	if scale != 0 {
+		power := new(big.Rat).SetInt(new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(abs(scale))), nil))
+		if scale > 0 {
+			number.Mul(number, power)
+		} else {
+			number.Quo(number, power)
+		}
+	}
+
+	if negate {
+		number.Neg(number)
+	}
+
+	if number.IsInt() {
+		return number.Num().String(), nil
+	}
+
+	s := number.FloatString(10)
+	s = strings.TrimRight(s, "0")
+	s = strings.TrimSuffix(s, ".")
+	return s, nil
+}
+
+// readText returns the text content of the current element, including that
+// of nested elements, and consumes its end element.
+func readText(decoder *xml.Decoder) (string, error) {
+	var b strings.Builder
+	depth := 1
+
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			b.Write(t)
+		}
+	}
+
+	return strings.TrimSpace(b.String()), nil
+}
+
+// readInlineText is readText for inline facts: the facts nested in the
+// current element are parsed and returned, and their text is part of it.
+// The text isn't trimmed, so it can be part of the text of the parent.
+func (inst *Instance) readInlineText(decoder *xml.Decoder) (string, []Fact, error) {
+	var b strings.Builder
+	var nested []Fact
+	depth := 1
+
+	for depth > 0 {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", nil, err
+		}
+
+		switch t := token.(type) {
+		case xml.StartElement:
+			if isInlineFact(t) {
+				facts, text, err := inst.parseInlineFact(decoder, t)
+				if err != nil {
+					return "", nil, err
+				}
+				nested = append(nested, facts...)
+				b.WriteString(text)
+				continue
+			}
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.CharData:
+			b.Write(t)
+		}
+	}
+
+	return b.String(), nested, nil
+}
+
+func attrValue(start xml.StartElement, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == local && attr.Name.Space == "" {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func attrValueNS(start xml.StartElement, space string, local string) string {
+	for _, attr := range start.Attr {
+		if attr.Name.Local == local && (attr.Name.Space == space || attr.Name.Space == "xsi") {
+			return attr.Value
+		}
+	}
+	return ""
+}
+
+func splitQName(name string) (string, string) {
+	i := strings.Index(name, ":")
+	if i < 0 {
+		return "", name
+	}
+	return name[:i], name[i+1:]
+}
+
+func localNames(names []string) []string {
+	var locals []string
+	for _, name := range names {
+		_, local := splitQName(name)
+		locals = append(locals, local)
+	}
+	return locals
+}
+
+func abs(i int) int {
+	if i < 0 {
+		return -i
+	}
+	return i
+}
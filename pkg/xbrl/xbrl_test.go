@@ -0,0 +1,173 @@
+package xbrl
+
+import (
+	"strings"
+	"testing"
+)
+
+const instanceDocument = `<?xml version="1.0" encoding="utf-8"?>
+<xbrli:xbrl xmlns:xbrli="http://www.xbrl.org/2003/instance" xmlns:us-gaap="http://fasb.org/us-gaap/2021-01-31" xmlns:iso4217="http://www.xbrl.org/2003/iso4217" xmlns:xbrldi="http://xbrl.org/2006/xbrldi" xmlns:xsi="http://www.w3.org/2001/XMLSchema-instance" xmlns:aapl="http://www.apple.com/20210925">
+  <xbrli:context id="FY2021">
+    <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier></xbrli:entity>
+    <xbrli:period><xbrli:startDate>2020-09-27</xbrli:startDate><xbrli:endDate>2021-09-25</xbrli:endDate></xbrli:period>
+  </xbrli:context>
+  <xbrli:context id="FY2021_iPhone">
+    <xbrli:entity>
+      <xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier>
+      <xbrli:segment><xbrldi:explicitMember dimension="srt:ProductOrServiceAxis">aapl:IPhoneMember</xbrldi:explicitMember></xbrli:segment>
+    </xbrli:entity>
+    <xbrli:period><xbrli:startDate>2020-09-27</xbrli:startDate><xbrli:endDate>2021-09-25</xbrli:endDate></xbrli:period>
+  </xbrli:context>
+  <xbrli:context id="I2021">
+    <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000320193</xbrli:identifier></xbrli:entity>
+    <xbrli:period><xbrli:instant>2021-09-25</xbrli:instant></xbrli:period>
+  </xbrli:context>
+  <xbrli:unit id="usd"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+  <xbrli:unit id="usdPerShare">
+    <xbrli:divide>
+      <xbrli:unitNumerator><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unitNumerator>
+      <xbrli:unitDenominator><xbrli:measure>xbrli:shares</xbrli:measure></xbrli:unitDenominator>
+    </xbrli:divide>
+  </xbrli:unit>
+  <us-gaap:Revenues contextRef="FY2021" unitRef="usd" decimals="-6">365817000000</us-gaap:Revenues>
+  <us-gaap:Revenues contextRef="FY2021_iPhone" unitRef="usd" decimals="-6">191973000000</us-gaap:Revenues>
+  <us-gaap:EarningsPerShareDiluted contextRef="FY2021" unitRef="usdPerShare" decimals="2">5.61</us-gaap:EarningsPerShareDiluted>
+  <us-gaap:Assets contextRef="I2021" unitRef="usd" decimals="-6">351002000000</us-gaap:Assets>
+  <us-gaap:Goodwill contextRef="I2021" unitRef="usd" xsi:nil="true"/>
+  <aapl:CustomConcept contextRef="I2021" unitRef="usd" decimals="0">42</aapl:CustomConcept>
+</xbrli:xbrl>`
+
+const inlineDocument = `<?xml version="1.0" encoding="utf-8"?>
+<html xmlns="http://www.w3.org/1999/xhtml" xmlns:ix="http://www.xbrl.org/2013/inlineXBRL" xmlns:xbrli="http://www.xbrl.org/2003/instance" xmlns:us-gaap="http://fasb.org/us-gaap/2022" xmlns:dei="http://xbrl.sec.gov/dei/2022" xmlns:iso4217="http://www.xbrl.org/2003/iso4217" xmlns:ixt="http://www.xbrl.org/inlineXBRL/transformation/2020-02-12" xmlns:xbrldi="http://xbrl.org/2006/xbrldi">
+<head><title>10-Q</title></head>
+<body>
+  <div style="display:none">
+    <ix:header>
+      <ix:hidden>
+        <ix:nonNumeric name="dei:DocumentType" contextRef="Q1">10-Q</ix:nonNumeric>
+      </ix:hidden>
+      <ix:resources>
+        <xbrli:context id="Q1">
+          <xbrli:entity><xbrli:identifier scheme="http://www.sec.gov/CIK">0000000001</xbrli:identifier></xbrli:entity>
+          <xbrli:period><xbrli:startDate>2022-01-01</xbrli:startDate><xbrli:endDate>2022-03-31</xbrli:endDate></xbrli:period>
+        </xbrli:context>
+        <xbrli:context id="Q1_Sub">
+          <xbrli:entity>
+            <xbrli:identifier scheme="http://www.sec.gov/CIK">0000000001</xbrli:identifier>
+            <xbrli:segment><xbrldi:explicitMember dimension="dei:LegalEntityAxis">us-gaap:SubsidiariesMember</xbrldi:explicitMember></xbrli:segment>
+          </xbrli:entity>
+          <xbrli:period><xbrli:startDate>2022-01-01</xbrli:startDate><xbrli:endDate>2022-03-31</xbrli:endDate></xbrli:period>
+        </xbrli:context>
+        <xbrli:unit id="USD"><xbrli:measure>iso4217:USD</xbrli:measure></xbrli:unit>
+      </ix:resources>
+    </ix:header>
+  </div>
+  <table>
+    <tr><td>Revenues</td><td>$&nbsp;<ix:nonFraction name="us-gaap:Revenues" contextRef="Q1" unitRef="USD" decimals="-5" scale="6" format="ixt:num-dot-decimal">1,234.5</ix:nonFraction></td></tr>
+    <tr><td>Net loss</td><td>(<ix:nonFraction name="us-gaap:NetIncomeLoss" contextRef="Q1" unitRef="USD" decimals="-3" scale="3" sign="-" format="ixt:num-dot-decimal">12,000</ix:nonFraction>)</td></tr>
+    <tr><td>Impairment</td><td><ix:nonFraction name="us-gaap:GoodwillImpairmentLoss" contextRef="Q1" unitRef="USD" decimals="0" format="ixt:fixed-zero">—</ix:nonFraction></td></tr>
+    <tr><td>Subsidiary revenues</td><td><ix:nonFraction name="us-gaap:Revenues" contextRef="Q1_Sub" unitRef="USD" decimals="0" scale="6" format="ixt:num-comma-decimal">1.000,25</ix:nonFraction></td></tr>
+  </table>
+  <ix:nonNumeric name="us-gaap:ScheduleOfOperatingExpensesTextBlock" contextRef="Q1" escape="true">
+    <table>
+      <tr><td>Research and development</td><td><ix:nonFraction name="us-gaap:ResearchAndDevelopmentExpense" contextRef="Q1" unitRef="USD" decimals="-5" scale="6" format="ixt:num-dot-decimal">250.1</ix:nonFraction></td></tr>
+    </table>
+  </ix:nonNumeric>
+  <p>Revenues again: <ix:nonFraction name="us-gaap:Revenues" contextRef="Q1" unitRef="USD" decimals="-5" scale="6" format="ixt:num-dot-decimal">1,234.5</ix:nonFraction><br></p>
+</body>
+</html>`
+
+func numsByKey(nums []Num) map[string]Num {
+	m := make(map[string]Num)
+	for _, num := range nums {
+		m[num.Tag+"|"+num.Coreg+"|"+num.Segments] = num
+	}
+	return m
+}
+
+func TestParseInstance(t *testing.T) {
+	inst, err := Parse(strings.NewReader(instanceDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if inst.Contexts["FY2021"].Entity != "0000320193" {
+		t.Errorf("entity: got %q", inst.Contexts["FY2021"].Entity)
+	}
+
+	nums := inst.Nums("0000320193-21-000105")
+	if len(nums) != 5 {
+		t.Fatalf("got %d nums, want 5: %+v", len(nums), nums)
+	}
+
+	byKey := numsByKey(nums)
+	want := []Num{
+		{Tag: "Revenues", Version: "us-gaap/2021", DDate: "20210930", Qtrs: "4", UOM: "USD", Value: "365817000000"},
+		{Tag: "Revenues", Version: "us-gaap/2021", DDate: "20210930", Qtrs: "4", UOM: "USD", Value: "191973000000", Segments: "ProductOrService=IPhone;"},
+		{Tag: "EarningsPerShareDiluted", Version: "us-gaap/2021", DDate: "20210930", Qtrs: "4", UOM: "USD/shares", Value: "5.61"},
+		{Tag: "Assets", Version: "us-gaap/2021", DDate: "20210930", Qtrs: "0", UOM: "USD", Value: "351002000000"},
+		{Tag: "CustomConcept", Version: "0000320193-21-000105", DDate: "20210930", Qtrs: "0", UOM: "USD", Value: "42"},
+	}
+	for _, w := range want {
+		w.Adsh = "0000320193-21-000105"
+		got, ok := byKey[w.Tag+"|"+w.Coreg+"|"+w.Segments]
+		if !ok {
+			t.Errorf("missing %v %v", w.Tag, w.Segments)
+			continue
+		}
+		if got != w {
+			t.Errorf("got %+v, want %+v", got, w)
+		}
+	}
+}
+
+func TestParseInline(t *testing.T) {
+	inst, err := Parse(strings.NewReader(inlineDocument))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var documentType, textBlock string
+	for _, fact := range inst.Facts {
+		switch fact.Name {
+		case "dei:DocumentType":
+			documentType = fact.Value
+		case "us-gaap:ScheduleOfOperatingExpensesTextBlock":
+			textBlock = fact.Value
+		}
+	}
+	if documentType != "10-Q" {
+		t.Errorf("dei:DocumentType: got %q, want 10-Q", documentType)
+	}
+	// Facts nested in a text block are facts of their own
+	if !strings.Contains(textBlock, "Research and development") || !strings.Contains(textBlock, "250.1") {
+		t.Errorf("us-gaap:ScheduleOfOperatingExpensesTextBlock: got %q, want the table", textBlock)
+	}
+
+	nums := inst.Nums("0000000001-22-000001")
+	if len(nums) != 5 {
+		t.Fatalf("got %d nums, want 5 (duplicates removed): %+v", len(nums), nums)
+	}
+
+	byKey := numsByKey(nums)
+	tests := map[string]string{
+		"Revenues||":                      "1234500000",
+		"NetIncomeLoss||":                 "-12000000",
+		"GoodwillImpairmentLoss||":        "0",
+		"Revenues|Subsidiaries|":          "1000250000",
+		"ResearchAndDevelopmentExpense||": "250100000",
+	}
+	for key, value := range tests {
+		num, ok := byKey[key]
+		if !ok {
+			t.Errorf("missing %v", key)
+			continue
+		}
+		if num.Value != value {
+			t.Errorf("%v: got %v, want %v", key, num.Value, value)
+		}
+		if num.DDate != "20220331" || num.Qtrs != "1" || num.Version != "us-gaap/2022" {
+			t.Errorf("%v: got ddate %v qtrs %v version %v", key, num.DDate, num.Qtrs, num.Version)
+		}
+	}
+}